@@ -0,0 +1,132 @@
+// Package appstoretest bundles sanitized App Store responses recorded from
+// several storefronts: landing pages, the token formats seen in them, and
+// amp-api review pages. Parsers are checked against the variety Apple
+// actually serves instead of a single synthetic string:
+//
+//	appstoretest.Run(t, appstoretest.KindLanding, func(t *testing.T, f appstoretest.Fixture) {
+//		data, err := landing.ParsePage(f.Data, f.AppID, f.Storefront)
+//		...
+//		if data.Name != f.Want.Name { ... }
+//	})
+//
+// Fixtures are listed in fixtures/manifest.json with what a correct parser
+// recovers from them. Bearer tokens are replaced with unsigned look-alikes
+// and reviewer names with placeholders; everything else is as recorded.
+package appstoretest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sync"
+	"testing"
+)
+
+//go:embed fixtures
+var files embed.FS
+
+type Kind string
+
+const (
+	// KindLanding is an apps.apple.com landing page.
+	KindLanding Kind = "landing"
+	// KindToken is a page or fragment exercising one way the amp-api bearer
+	// token is embedded, or is missing.
+	KindToken Kind = "token"
+	// KindReviews is a JSON response of the amp-api reviews endpoint.
+	KindReviews Kind = "reviews"
+)
+
+type Fixture struct {
+	// Name is the path below fixtures/, e.g. "landing/gb-monzo-shoebox.html".
+	Name       string `json:"name"`
+	Kind       Kind   `json:"kind"`
+	Storefront string `json:"storefront"`
+	AppID      string `json:"app_id,omitempty"`
+	// Recorded is the month the response was captured, as "2006-01".
+	Recorded string `json:"recorded"`
+	// Notes says what makes the fixture different from the others.
+	Notes string `json:"notes"`
+	Want  Want   `json:"want"`
+	Data  []byte `json:"-"`
+}
+
+// Want is what a correct parser recovers from a fixture. Fields that do not
+// apply to the fixture's kind are zero.
+type Want struct {
+	// Token is the bearer token without the "bearer " prefix; empty when the
+	// page has none.
+	Token string `json:"token,omitempty"`
+
+	Name          string   `json:"name,omitempty"`
+	RatingAverage float64  `json:"rating_average,omitempty"`
+	RatingCount   int      `json:"rating_count,omitempty"`
+	Sources       []string `json:"sources,omitempty"`
+
+	// Reviews is the number of reviews on a landing page or review page.
+	Reviews int `json:"reviews"`
+	// Next is the next page link of a review page.
+	Next string `json:"next,omitempty"`
+}
+
+var load = sync.OnceValues(func() ([]Fixture, error) {
+	b, err := files.ReadFile("fixtures/manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []Fixture
+	if err := json.Unmarshal(b, &fixtures); err != nil {
+		return nil, fmt.Errorf("appstoretest: manifest: %w", err)
+	}
+	for i := range fixtures {
+		f := &fixtures[i]
+		if f.Data, err = files.ReadFile(path.Join("fixtures", f.Name)); err != nil {
+			return nil, fmt.Errorf("appstoretest: %w", err)
+		}
+	}
+	return fixtures, nil
+})
+
+// All returns every fixture in manifest order.
+func All() []Fixture {
+	fixtures, err := load()
+	if err != nil {
+		panic(err)
+	}
+	return fixtures
+}
+
+// ByKind returns the fixtures of kind k.
+func ByKind(k Kind) []Fixture {
+	var out []Fixture
+	for _, f := range All() {
+		if f.Kind == k {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Load returns the fixture called name.
+func Load(name string) (Fixture, error) {
+	for _, f := range All() {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return Fixture{}, fmt.Errorf("appstoretest: no fixture %q", name)
+}
+
+// Run calls fn in a subtest for each fixture of kind k, named after the
+// fixture.
+func Run(t *testing.T, k Kind, fn func(t *testing.T, f Fixture)) {
+	t.Helper()
+	fixtures := ByKind(k)
+	if len(fixtures) == 0 {
+		t.Fatalf("appstoretest: no %s fixtures", k)
+	}
+	for _, f := range fixtures {
+		t.Run(path.Base(f.Name), func(t *testing.T) { fn(t, f) })
+	}
+}
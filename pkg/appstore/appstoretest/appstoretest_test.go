@@ -0,0 +1,78 @@
+package appstoretest
+
+import (
+	"encoding/json"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManifestCoversFiles(t *testing.T) {
+	listed := make(map[string]bool)
+	storefronts := make(map[Kind]map[string]bool)
+	for _, f := range All() {
+		if listed[f.Name] {
+			t.Errorf("%s listed twice", f.Name)
+		}
+		listed[f.Name] = true
+		if !strings.HasPrefix(f.Name, string(f.Kind)+"/") {
+			t.Errorf("%s: kind %q does not match its directory", f.Name, f.Kind)
+		}
+		if f.Storefront == "" || f.Notes == "" || len(f.Data) == 0 {
+			t.Errorf("%s: incomplete entry %+v", f.Name, f)
+		}
+		if storefronts[f.Kind] == nil {
+			storefronts[f.Kind] = make(map[string]bool)
+		}
+		storefronts[f.Kind][f.Storefront] = true
+	}
+	fs.WalkDir(files, "fixtures", func(p string, d fs.DirEntry, err error) error {
+		if name := strings.TrimPrefix(p, "fixtures/"); !d.IsDir() && name != "manifest.json" && !listed[name] {
+			t.Errorf("%s is not in the manifest", name)
+		}
+		return err
+	})
+	for _, k := range []Kind{KindLanding, KindToken, KindReviews} {
+		if len(storefronts[k]) < 3 {
+			t.Errorf("%s fixtures cover storefronts %v, want at least 3", k, storefronts[k])
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	f, err := Load("landing/gb-monzo-shoebox.html")
+	if err != nil || f.Kind != KindLanding || f.AppID != "1482920575" {
+		t.Fatalf("Load() = %+v, %v", f, err)
+	}
+	if _, err := Load("landing/missing.html"); err == nil {
+		t.Error("Load() of a missing fixture succeeded")
+	}
+}
+
+func TestReviewPages(t *testing.T) {
+	Run(t, KindReviews, func(t *testing.T, f Fixture) {
+		var page struct {
+			Next string `json:"next"`
+			Data []struct {
+				ID         string `json:"id"`
+				Type       string `json:"type"`
+				Attributes struct {
+					Date   string `json:"date"`
+					Rating int    `json:"rating"`
+				} `json:"attributes"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(f.Data, &page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Data) != f.Want.Reviews || page.Next != f.Want.Next {
+			t.Fatalf("%d reviews, next %q; want %d, %q", len(page.Data), page.Next, f.Want.Reviews, f.Want.Next)
+		}
+		for _, r := range page.Data {
+			if _, err := time.Parse(time.RFC3339, r.Attributes.Date); err != nil || r.ID == "" || r.Type != "user-reviews" || r.Attributes.Rating < 1 || r.Attributes.Rating > 5 {
+				t.Errorf("review %+v: %v", r, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,247 @@
+// Package archive implements the versioned NDJSON format used to hand review
+// datasets between pipeline steps via object storage.
+//
+// An archive is a header line, one review per line, and a trailer line:
+//
+//	{"_kind":"header","format":"clientpulse.reviews","version":1,...}
+//	{"id":"1","app_id":"389801252",...}
+//	{"_kind":"trailer","count":1,"sha256":"..."}
+//
+// The trailer checksum covers the exact bytes of all review lines, including
+// their newlines, so readers detect truncated or corrupted uploads.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/quiby-ai/common/pkg/appstore/review"
+)
+
+const (
+	FormatName    = "clientpulse.reviews"
+	FormatVersion = 1
+
+	kindHeader  = "header"
+	kindTrailer = "trailer"
+
+	maxLineSize = 4 << 20
+)
+
+var (
+	ErrMissingHeader      = errors.New("archive: missing header")
+	ErrUnsupportedFormat  = errors.New("archive: unsupported format")
+	ErrUnsupportedVersion = errors.New("archive: unsupported format version")
+	ErrTruncated          = errors.New("archive: truncated, trailer not found")
+	ErrChecksumMismatch   = errors.New("archive: checksum mismatch")
+	ErrCountMismatch      = errors.New("archive: record count mismatch")
+	ErrDataAfterTrailer   = errors.New("archive: data after trailer")
+	ErrWriterClosed       = errors.New("archive: writer closed")
+)
+
+// Header describes the dataset. Format and Version are filled in by NewWriter.
+type Header struct {
+	Format    string            `json:"format"`
+	Version   int               `json:"version"`
+	AppID     string            `json:"app_id"`
+	Countries []string          `json:"countries,omitempty"`
+	DateFrom  string            `json:"date_from,omitempty"`
+	DateTo    string            `json:"date_to,omitempty"`
+	SagaID    string            `json:"saga_id,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+type Trailer struct {
+	Count  int64  `json:"count"`
+	SHA256 string `json:"sha256"`
+}
+
+type headerLine struct {
+	Kind string `json:"_kind"`
+	Header
+}
+
+type trailerLine struct {
+	Kind string `json:"_kind"`
+	Trailer
+}
+
+type Writer struct {
+	w      *bufio.Writer
+	hash   hash.Hash
+	count  int64
+	closed bool
+}
+
+// NewWriter writes the header and returns a Writer for the review lines.
+// Close must be called to write the trailer; it does not close w.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	h.Format = FormatName
+	h.Version = FormatVersion
+	if h.CreatedAt.IsZero() {
+		h.CreatedAt = time.Now().UTC()
+	}
+
+	aw := &Writer{w: bufio.NewWriter(w), hash: sha256.New()}
+	if err := aw.writeLine(headerLine{Kind: kindHeader, Header: h}, false); err != nil {
+		return nil, fmt.Errorf("archive: write header: %w", err)
+	}
+	return aw, nil
+}
+
+func (w *Writer) Write(r review.Review) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	if err := w.writeLine(r, true); err != nil {
+		return fmt.Errorf("archive: write review %s: %w", r.ID, err)
+	}
+	w.count++
+	return nil
+}
+
+// Count returns the number of reviews written so far.
+func (w *Writer) Count() int64 {
+	return w.count
+}
+
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	t := trailerLine{Kind: kindTrailer, Trailer: Trailer{
+		Count:  w.count,
+		SHA256: hex.EncodeToString(w.hash.Sum(nil)),
+	}}
+	if err := w.writeLine(t, false); err != nil {
+		return fmt.Errorf("archive: write trailer: %w", err)
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) writeLine(v any, hashed bool) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if hashed {
+		w.hash.Write(b)
+	}
+	_, err = w.w.Write(b)
+	return err
+}
+
+type Reader struct {
+	s       *bufio.Scanner
+	header  Header
+	hash    hash.Hash
+	count   int64
+	trailer *Trailer
+	err     error
+}
+
+// NewReader reads and checks the header. Reviews are then read with Next.
+func NewReader(r io.Reader) (*Reader, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, fmt.Errorf("archive: read header: %w", err)
+		}
+		return nil, ErrMissingHeader
+	}
+
+	var h headerLine
+	if err := json.Unmarshal(s.Bytes(), &h); err != nil || h.Kind != kindHeader {
+		return nil, ErrMissingHeader
+	}
+	if h.Format != FormatName {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, h.Format)
+	}
+	if h.Version < 1 || h.Version > FormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, h.Version)
+	}
+
+	return &Reader{s: s, header: h.Header, hash: sha256.New()}, nil
+}
+
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Next returns the next review. It returns io.EOF once the trailer has been
+// read and both the count and checksum match.
+func (r *Reader) Next() (review.Review, error) {
+	if r.trailer != nil {
+		return review.Review{}, r.err
+	}
+
+	if !r.s.Scan() {
+		if err := r.s.Err(); err != nil {
+			return review.Review{}, fmt.Errorf("archive: read: %w", err)
+		}
+		return review.Review{}, ErrTruncated
+	}
+	line := r.s.Bytes()
+
+	if bytes.Contains(line, []byte(`"_kind"`)) {
+		var t trailerLine
+		if err := json.Unmarshal(line, &t); err == nil && t.Kind == kindTrailer {
+			r.err = r.finish(t.Trailer)
+			return review.Review{}, r.err
+		}
+	}
+
+	var rv review.Review
+	if err := json.Unmarshal(line, &rv); err != nil {
+		return review.Review{}, fmt.Errorf("archive: decode line %d: %w", r.count+2, err)
+	}
+	r.hash.Write(line)
+	r.hash.Write([]byte{'\n'})
+	r.count++
+	return rv, nil
+}
+
+// ReadAll reads every remaining review.
+func (r *Reader) ReadAll() ([]review.Review, error) {
+	var out []review.Review
+	for {
+		rv, err := r.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+		out = append(out, rv)
+	}
+}
+
+func (r *Reader) finish(t Trailer) error {
+	r.trailer = &t
+	if t.Count != r.count {
+		return fmt.Errorf("%w: trailer says %d, read %d", ErrCountMismatch, t.Count, r.count)
+	}
+	if sum := hex.EncodeToString(r.hash.Sum(nil)); sum != t.SHA256 {
+		return fmt.Errorf("%w: trailer says %s, computed %s", ErrChecksumMismatch, t.SHA256, sum)
+	}
+	for r.s.Scan() {
+		if len(bytes.TrimSpace(r.s.Bytes())) > 0 {
+			return ErrDataAfterTrailer
+		}
+	}
+	return io.EOF
+}
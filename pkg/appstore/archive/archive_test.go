@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quiby-ai/common/pkg/appstore/review"
+)
+
+func sampleReviews() []review.Review {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []review.Review{
+		{ID: "1", AppID: "389801252", Country: "us", Rating: 5, Title: "Great", Body: "Love it", CreatedAt: created},
+		{ID: "2", AppID: "389801252", Country: "gb", Rating: 1, Body: "Crashes\non launch", CreatedAt: created,
+			Response: &review.DeveloperResponse{Body: "Fixed in 2.0", ModifiedAt: created}},
+	}
+}
+
+func writeArchive(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{AppID: "389801252", Countries: []string{"us", "gb"}, SagaID: "saga-1"})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	for _, r := range sampleReviews() {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := writeArchive(t)
+	if lines := strings.Count(string(data), "\n"); lines != 4 {
+		t.Errorf("expected 4 lines, got %d", lines)
+	}
+
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	h := r.Header()
+	if h.Format != FormatName || h.Version != FormatVersion || h.AppID != "389801252" || h.SagaID != "saga-1" {
+		t.Errorf("unexpected header %+v", h)
+	}
+
+	got, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	want := sampleReviews()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d reviews, got %d", len(want), len(got))
+	}
+	if got[1].Body != want[1].Body || got[1].Response == nil || got[1].Response.Body != "Fixed in 2.0" {
+		t.Errorf("unexpected review %+v", got[1])
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after trailer, got %v", err)
+	}
+}
+
+func TestReaderDetectsCorruption(t *testing.T) {
+	data := writeArchive(t)
+	lines := strings.SplitAfter(string(data), "\n")
+
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"truncated", strings.Join(lines[:3], ""), ErrTruncated},
+		{"tampered", strings.Replace(string(data), "Love it", "Hate it", 1), ErrChecksumMismatch},
+		{"dropped line", lines[0] + lines[2] + lines[3], ErrCountMismatch},
+		{"trailing data", string(data) + lines[1], ErrDataAfterTrailer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			if _, err := r.ReadAll(); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNewReaderRejectsUnknownFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"empty", "", ErrMissingHeader},
+		{"no header", `{"id":"1"}` + "\n", ErrMissingHeader},
+		{"other format", `{"_kind":"header","format":"other","version":1}` + "\n", ErrUnsupportedFormat},
+		{"future version", `{"_kind":"header","format":"clientpulse.reviews","version":99}` + "\n", ErrUnsupportedVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReader(strings.NewReader(tt.data)); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
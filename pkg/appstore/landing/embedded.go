@@ -0,0 +1,356 @@
+package landing
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quiby-ai/common/pkg/appstore/review"
+)
+
+// Sources of embedded page data, as reported in PageData.Sources.
+const (
+	SourceShoebox    = "shoebox"
+	SourceServerData = "serialized-server-data"
+	SourceJSONLD     = "json-ld"
+)
+
+var ErrNoEmbeddedData = errors.New("no embedded page data found")
+
+var (
+	scriptRe    = regexp.MustCompile(`(?is)<script([^>]*)>(.*?)</script>`)
+	scriptIDRe  = regexp.MustCompile(`(?i)\bid\s*=\s*["']([^"']+)["']`)
+	scriptTypRe = regexp.MustCompile(`(?i)\btype\s*=\s*["']([^"']+)["']`)
+)
+
+// RatingSummary is the aggregate rating shown on a landing page. Histogram,
+// when present, holds the number of ratings per star from 5 down to 1.
+type RatingSummary struct {
+	Average   float64 `json:"average"`
+	Count     int     `json:"count"`
+	Histogram []int   `json:"histogram,omitempty"`
+}
+
+// PageData is what could be recovered from the JSON embedded in a landing
+// page, without calling the amp-api.
+type PageData struct {
+	Name    string          `json:"name,omitempty"`
+	Rating  *RatingSummary  `json:"rating,omitempty"`
+	Reviews []review.Review `json:"reviews,omitempty"`
+	Sources []string        `json:"sources"`
+}
+
+// ParsePage extracts the rating summary and featured reviews from a landing
+// page. It reads the fastboot shoebox cache, the serialized-server-data blob
+// and the JSON-LD block, in that order, and keeps the first value found for
+// each field. It is a degradation path for when amp-api tokens are blocked:
+// pages only carry a handful of featured reviews.
+//
+// appID and country are copied onto the returned reviews. JSON-LD reviews
+// have no ID, so one is derived from their author, date and body.
+func ParsePage(html []byte, appID, country string) (*PageData, error) {
+	data := &PageData{}
+	for _, m := range scriptRe.FindAllSubmatch(html, -1) {
+		attrs, body := string(m[1]), m[2]
+		var (
+			part   *PageData
+			source string
+		)
+		switch id, typ := attrValue(scriptIDRe, attrs), attrValue(scriptTypRe, attrs); {
+		case typ == "fastboot/shoebox" && strings.HasPrefix(id, "shoebox-media-api-cache"):
+			part, source = parseShoebox(body), SourceShoebox
+		case id == "serialized-server-data":
+			part, source = parseServerData(body), SourceServerData
+		case typ == "application/ld+json":
+			part, source = parseJSONLD(body), SourceJSONLD
+		default:
+			continue
+		}
+		if part == nil {
+			continue
+		}
+		data.merge(part, source)
+	}
+	if len(data.Sources) == 0 {
+		return nil, ErrNoEmbeddedData
+	}
+	for i := range data.Reviews {
+		data.Reviews[i].AppID = appID
+		data.Reviews[i].Country = NormalizeCountryCode(country)
+	}
+	return data, nil
+}
+
+func attrValue(re *regexp.Regexp, attrs string) string {
+	if m := re.FindStringSubmatch(attrs); len(m) == 2 {
+		return m[1]
+	}
+	return ""
+}
+
+func (d *PageData) merge(part *PageData, source string) {
+	if part.Name == "" && part.Rating == nil && len(part.Reviews) == 0 {
+		return
+	}
+	d.Sources = append(d.Sources, source)
+	if d.Name == "" {
+		d.Name = part.Name
+	}
+	if d.Rating == nil {
+		d.Rating = part.Rating
+	}
+	if len(d.Reviews) == 0 {
+		d.Reviews = part.Reviews
+	}
+}
+
+// The shoebox maps amp-api request keys to the JSON-encoded responses the
+// page was rendered from.
+type ampResponse struct {
+	Data []struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			Name       string `json:"name"`
+			UserRating *struct {
+				Value           float64 `json:"value"`
+				RatingCount     int     `json:"ratingCount"`
+				RatingCountList []int   `json:"ratingCountList"`
+			} `json:"userRating"`
+		} `json:"attributes"`
+		Relationships struct {
+			Reviews struct {
+				Data []ampReview `json:"data"`
+			} `json:"reviews"`
+		} `json:"relationships"`
+	} `json:"d"`
+}
+
+type ampReview struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Date              string `json:"date"`
+		Review            string `json:"review"`
+		Rating            int    `json:"rating"`
+		IsEdited          bool   `json:"isEdited"`
+		UserName          string `json:"userName"`
+		Title             string `json:"title"`
+		DeveloperResponse *struct {
+			ID       int64  `json:"id"`
+			Body     string `json:"body"`
+			Modified string `json:"modified"`
+		} `json:"developerResponse"`
+	} `json:"attributes"`
+}
+
+func parseShoebox(body []byte) *PageData {
+	var cache map[string]json.RawMessage
+	if err := json.Unmarshal(body, &cache); err != nil {
+		return nil
+	}
+	data := &PageData{}
+	for _, key := range slices.Sorted(maps.Keys(cache)) {
+		raw := cache[key]
+		// Values are usually JSON strings holding JSON, but may be inlined.
+		var encoded string
+		if json.Unmarshal(raw, &encoded) == nil {
+			raw = json.RawMessage(encoded)
+		}
+		var resp ampResponse
+		if json.Unmarshal(raw, &resp) != nil {
+			continue
+		}
+		for _, d := range resp.Data {
+			if d.Type != "apps" && d.Attributes.UserRating == nil {
+				continue
+			}
+			if data.Name == "" {
+				data.Name = d.Attributes.Name
+			}
+			if r := d.Attributes.UserRating; r != nil && data.Rating == nil {
+				data.Rating = &RatingSummary{Average: r.Value, Count: r.RatingCount, Histogram: starsDescending(r.RatingCountList)}
+			}
+			for _, ar := range d.Relationships.Reviews.Data {
+				data.Reviews = append(data.Reviews, ar.review())
+			}
+		}
+	}
+	return data
+}
+
+func (ar ampReview) review() review.Review {
+	a := ar.Attributes
+	r := review.Review{
+		ID:        ar.ID,
+		Rating:    a.Rating,
+		Title:     a.Title,
+		Body:      a.Review,
+		Author:    a.UserName,
+		IsEdited:  a.IsEdited,
+		CreatedAt: parseDate(a.Date),
+	}
+	if dr := a.DeveloperResponse; dr != nil && dr.Body != "" {
+		r.Response = &review.DeveloperResponse{Body: dr.Body, ModifiedAt: parseDate(dr.Modified)}
+		if dr.ID != 0 {
+			r.Response.ID = strconv.FormatInt(dr.ID, 10)
+		}
+	}
+	return r
+}
+
+// starsDescending turns amp-api's 1..5 star counts into 5..1.
+func starsDescending(counts []int) []int {
+	if len(counts) != 5 {
+		return nil
+	}
+	out := make([]int, 5)
+	for i, n := range counts {
+		out[4-i] = n
+	}
+	return out
+}
+
+// serverData is the subset of the serialized-server-data blob rendered by
+// the newer App Store web app.
+type serverData []struct {
+	Data struct {
+		Title        string `json:"title"`
+		ShelfMapping struct {
+			ProductRatings struct {
+				Items []struct {
+					RatingAverage        float64 `json:"ratingAverage"`
+					TotalNumberOfRatings int     `json:"totalNumberOfRatings"`
+					RatingCounts         []int   `json:"ratingCounts"`
+				} `json:"items"`
+			} `json:"productRatings"`
+			AllProductReviews struct {
+				Items []struct {
+					Review struct {
+						ID           string `json:"id"`
+						Title        string `json:"title"`
+						Contents     string `json:"contents"`
+						Rating       int    `json:"rating"`
+						ReviewerName string `json:"reviewerName"`
+						Date         string `json:"date"`
+						Response     *struct {
+							Contents string `json:"contents"`
+							Date     string `json:"date"`
+						} `json:"response"`
+					} `json:"review"`
+				} `json:"items"`
+			} `json:"allProductReviews"`
+		} `json:"shelfMapping"`
+	} `json:"data"`
+}
+
+func parseServerData(body []byte) *PageData {
+	var sd serverData
+	if err := json.Unmarshal(body, &sd); err != nil {
+		return nil
+	}
+	data := &PageData{}
+	for _, entry := range sd {
+		shelves := entry.Data.ShelfMapping
+		if data.Name == "" {
+			data.Name = entry.Data.Title
+		}
+		if items := shelves.ProductRatings.Items; len(items) > 0 && data.Rating == nil {
+			data.Rating = &RatingSummary{
+				Average:   items[0].RatingAverage,
+				Count:     items[0].TotalNumberOfRatings,
+				Histogram: items[0].RatingCounts,
+			}
+			if len(data.Rating.Histogram) != 5 {
+				data.Rating.Histogram = nil
+			}
+		}
+		for _, item := range shelves.AllProductReviews.Items {
+			sr := item.Review
+			r := review.Review{
+				ID:        sr.ID,
+				Rating:    sr.Rating,
+				Title:     sr.Title,
+				Body:      sr.Contents,
+				Author:    sr.ReviewerName,
+				CreatedAt: parseDate(sr.Date),
+			}
+			if sr.Response != nil && sr.Response.Contents != "" {
+				r.Response = &review.DeveloperResponse{Body: sr.Response.Contents, ModifiedAt: parseDate(sr.Response.Date)}
+			}
+			data.Reviews = append(data.Reviews, r)
+		}
+	}
+	return data
+}
+
+type jsonLD struct {
+	Type            string `json:"@type"`
+	Name            string `json:"name"`
+	AggregateRating *struct {
+		RatingValue json.Number `json:"ratingValue"`
+		ReviewCount json.Number `json:"reviewCount"`
+		RatingCount json.Number `json:"ratingCount"`
+	} `json:"aggregateRating"`
+	Review []struct {
+		Name          string `json:"name"`
+		ReviewBody    string `json:"reviewBody"`
+		DatePublished string `json:"datePublished"`
+		Author        struct {
+			Name string `json:"name"`
+		} `json:"author"`
+		ReviewRating struct {
+			RatingValue json.Number `json:"ratingValue"`
+		} `json:"reviewRating"`
+	} `json:"review"`
+}
+
+func parseJSONLD(body []byte) *PageData {
+	var ld jsonLD
+	if err := json.Unmarshal(body, &ld); err != nil {
+		return nil
+	}
+	if ld.Type != "SoftwareApplication" && ld.Type != "MobileApplication" {
+		return nil
+	}
+	data := &PageData{Name: ld.Name}
+	if ar := ld.AggregateRating; ar != nil {
+		avg, _ := ar.RatingValue.Float64()
+		count, err := ar.RatingCount.Int64()
+		if err != nil {
+			count, _ = ar.ReviewCount.Int64()
+		}
+		data.Rating = &RatingSummary{Average: avg, Count: int(count)}
+	}
+	for _, lr := range ld.Review {
+		rating, _ := lr.ReviewRating.RatingValue.Float64()
+		data.Reviews = append(data.Reviews, review.Review{
+			ID:        derivedReviewID(lr.Author.Name, lr.DatePublished, lr.ReviewBody),
+			Rating:    int(rating),
+			Title:     lr.Name,
+			Body:      lr.ReviewBody,
+			Author:    lr.Author.Name,
+			CreatedAt: parseDate(lr.DatePublished),
+		})
+	}
+	return data
+}
+
+func derivedReviewID(parts ...string) string {
+	h := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return "ld-" + hex.EncodeToString(h[:8])
+}
+
+func parseDate(s string) time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05Z0700", "2006-01-02", "Jan 2, 2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,122 @@
+package landing
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/quiby-ai/common/pkg/appstore/appstoretest"
+)
+
+const shoeboxPayload = `{"d":[{"id":"389801252","type":"apps","attributes":{"name":"Instagram","userRating":{"value":4.7,"ratingCount":1200,"ratingCountList":[50,30,20,100,1000]}},"relationships":{"reviews":{"data":[{"id":"9001","type":"user-reviews","attributes":{"date":"2024-01-02T03:04:05Z","review":"Love it","rating":5,"isEdited":true,"userName":"ann","title":"Great","developerResponse":{"id":77,"body":"Thanks!","modified":"2024-01-03T00:00:00Z"}}}]}}}]}`
+
+const jsonLDPayload = `{"@context":"http://schema.org","@type":"SoftwareApplication","name":"Instagram","aggregateRating":{"@type":"AggregateRating","ratingValue":4.6,"reviewCount":900},"review":[{"@type":"Review","name":"Meh","reviewBody":"Crashes","datePublished":"2024-02-01","author":{"@type":"Person","name":"bob"},"reviewRating":{"@type":"Rating","ratingValue":2}}]}`
+
+const serverDataPayload = `[{"intent":{},"data":{"title":"Instagram","shelfMapping":{"productRatings":{"items":[{"ratingAverage":4.5,"totalNumberOfRatings":800,"ratingCounts":[600,100,50,30,20]}]},"allProductReviews":{"items":[{"review":{"id":"42","title":"Nice","contents":"Works","rating":4,"reviewerName":"cat","date":"2024-03-01T10:00:00Z","response":{"contents":"Glad","date":"2024-03-02T10:00:00Z"}}}]}}}}]`
+
+func TestParsePageShoebox(t *testing.T) {
+	html := `<html><script type="fastboot/shoebox" id="shoebox-media-api-cache-apps">{"apps.389801252":` +
+		strconv.Quote(shoeboxPayload) + `}</script>` +
+		`<script name="schema:software-application" type="application/ld+json">` + jsonLDPayload + `</script></html>`
+
+	data, err := ParsePage([]byte(html), "389801252", "US")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(data.Sources) != 2 || data.Sources[0] != SourceShoebox || data.Sources[1] != SourceJSONLD {
+		t.Errorf("sources = %v", data.Sources)
+	}
+	if data.Name != "Instagram" {
+		t.Errorf("name = %q", data.Name)
+	}
+	if data.Rating == nil || data.Rating.Average != 4.7 || data.Rating.Count != 1200 {
+		t.Fatalf("rating = %+v", data.Rating)
+	}
+	if h := data.Rating.Histogram; len(h) != 5 || h[0] != 1000 || h[4] != 50 {
+		t.Errorf("histogram = %v, want 5 stars first", h)
+	}
+	if len(data.Reviews) != 1 {
+		t.Fatalf("reviews = %+v", data.Reviews)
+	}
+	r := data.Reviews[0]
+	if r.ID != "9001" || r.AppID != "389801252" || r.Country != "us" || r.Rating != 5 || !r.IsEdited || r.Author != "ann" {
+		t.Errorf("review = %+v", r)
+	}
+	if !r.CreatedAt.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("created at = %v", r.CreatedAt)
+	}
+	if r.Response == nil || r.Response.ID != "77" || r.Response.Body != "Thanks!" {
+		t.Errorf("developer response = %+v", r.Response)
+	}
+}
+
+func TestParsePageJSONLDOnly(t *testing.T) {
+	html := `<script type="application/ld+json">{"@type":"BreadcrumbList","name":"crumbs"}</script>` +
+		`<script type="application/ld+json">` + jsonLDPayload + `</script>`
+
+	data, err := ParsePage([]byte(html), "1", "gb")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data.Rating == nil || data.Rating.Average != 4.6 || data.Rating.Count != 900 {
+		t.Errorf("rating = %+v", data.Rating)
+	}
+	if len(data.Reviews) != 1 {
+		t.Fatalf("reviews = %+v", data.Reviews)
+	}
+	r := data.Reviews[0]
+	if r.ID == "" || r.Rating != 2 || r.Title != "Meh" || r.Body != "Crashes" {
+		t.Errorf("review = %+v", r)
+	}
+	again, _ := ParsePage([]byte(html), "1", "gb")
+	if again.Reviews[0].ID != r.ID {
+		t.Errorf("derived id not stable: %q vs %q", again.Reviews[0].ID, r.ID)
+	}
+}
+
+func TestParsePageServerData(t *testing.T) {
+	html := `<script type="application/json" id="serialized-server-data">` + serverDataPayload + `</script>`
+
+	data, err := ParsePage([]byte(html), "1", "us")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data.Rating == nil || data.Rating.Average != 4.5 || data.Rating.Count != 800 || data.Rating.Histogram[0] != 600 {
+		t.Errorf("rating = %+v", data.Rating)
+	}
+	if len(data.Reviews) != 1 || data.Reviews[0].ID != "42" || data.Reviews[0].Response == nil {
+		t.Errorf("reviews = %+v", data.Reviews)
+	}
+}
+
+func TestParsePageNoData(t *testing.T) {
+	_, err := ParsePage([]byte(`<html><script>var x = 1;</script></html>`), "1", "us")
+	if !errors.Is(err, ErrNoEmbeddedData) {
+		t.Errorf("expected ErrNoEmbeddedData, got %v", err)
+	}
+}
+
+func TestParsePageFixtures(t *testing.T) {
+	appstoretest.Run(t, appstoretest.KindLanding, func(t *testing.T, f appstoretest.Fixture) {
+		data, err := ParsePage(f.Data, f.AppID, f.Storefront)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data.Name != f.Want.Name || !slices.Equal(data.Sources, f.Want.Sources) {
+			t.Errorf("name %q, sources %v; want %q, %v", data.Name, data.Sources, f.Want.Name, f.Want.Sources)
+		}
+		if data.Rating == nil || data.Rating.Average != f.Want.RatingAverage || data.Rating.Count != f.Want.RatingCount {
+			t.Errorf("rating = %+v, want %v from %d", data.Rating, f.Want.RatingAverage, f.Want.RatingCount)
+		}
+		if len(data.Reviews) != f.Want.Reviews {
+			t.Fatalf("%d reviews, want %d", len(data.Reviews), f.Want.Reviews)
+		}
+		for _, r := range data.Reviews {
+			if r.ID == "" || r.Body == "" || r.Rating < 1 || r.CreatedAt.IsZero() || r.Country != f.Storefront {
+				t.Errorf("review = %+v", r)
+			}
+		}
+	})
+}
@@ -0,0 +1,266 @@
+// Package profile holds per-app fetch settings, so tuning for one customer
+// lives in a JSON document next to the app instead of in service configs.
+//
+// A Set has a default profile and overrides per app ID:
+//
+//	{
+//	  "default": {"countries": ["us"], "page_size": 50, "concurrency": 4},
+//	  "apps": {
+//	    "389801252": {"countries": ["us", "gb", "de"], "source": "api", "lookback_days": 90}
+//	  }
+//	}
+//
+// Fields left out of an app's profile fall back to the default profile and
+// then to the package defaults.
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/quiby-ai/common/pkg/appstore/landing"
+)
+
+const (
+	DefaultPageSize     = 50
+	DefaultConcurrency  = 4
+	DefaultLookbackDays = 30
+
+	MaxPageSize    = 200
+	MaxConcurrency = 32
+
+	dayLayout = "2006-01-02"
+)
+
+// Source is where reviews are fetched from.
+type Source string
+
+const (
+	// SourceRSS is the public customer reviews RSS feed. It needs no
+	// credentials but only serves the most recent reviews.
+	SourceRSS Source = "rss"
+	// SourceAPI is the authenticated reviews API.
+	SourceAPI Source = "api"
+)
+
+var (
+	ErrCountryInvalid     = errors.New("profile: country must be a 2-letter ISO code")
+	ErrPageSizeInvalid    = errors.New("profile: page size out of range")
+	ErrConcurrencyInvalid = errors.New("profile: concurrency out of range")
+	ErrSourceInvalid      = errors.New("profile: unknown source")
+	ErrWindowInvalid      = errors.New("profile: invalid date window")
+)
+
+var countryCodeRegex = regexp.MustCompile(`^[a-z]{2}$`)
+
+// FetchProfile configures how reviews of one app are fetched. Zero values
+// mean "not set" and are filled by Resolve.
+type FetchProfile struct {
+	Countries   []string `json:"countries,omitempty"`
+	PageSize    int      `json:"page_size,omitempty"`
+	Concurrency int      `json:"concurrency,omitempty"`
+	// Source is the preferred source. With Fallback, the other source is
+	// tried when it fails.
+	Source   Source `json:"source,omitempty"`
+	Fallback *bool  `json:"fallback,omitempty"`
+	// DateFrom and DateTo (YYYY-MM-DD) pin the window. Otherwise the window
+	// is the last LookbackDays days.
+	DateFrom     string `json:"date_from,omitempty"`
+	DateTo       string `json:"date_to,omitempty"`
+	LookbackDays int    `json:"lookback_days,omitempty"`
+}
+
+// Defaults returns the profile used when nothing is configured.
+func Defaults() FetchProfile {
+	fallback := true
+	return FetchProfile{
+		Countries:    []string{"us"},
+		PageSize:     DefaultPageSize,
+		Concurrency:  DefaultConcurrency,
+		Source:       SourceRSS,
+		Fallback:     &fallback,
+		LookbackDays: DefaultLookbackDays,
+	}
+}
+
+// Merge returns p with unset fields taken from base.
+func (p FetchProfile) Merge(base FetchProfile) FetchProfile {
+	if len(p.Countries) == 0 {
+		p.Countries = base.Countries
+	}
+	if p.PageSize == 0 {
+		p.PageSize = base.PageSize
+	}
+	if p.Concurrency == 0 {
+		p.Concurrency = base.Concurrency
+	}
+	if p.Source == "" {
+		p.Source = base.Source
+	}
+	if p.Fallback == nil {
+		p.Fallback = base.Fallback
+	}
+	if p.DateFrom == "" && p.DateTo == "" && p.LookbackDays == 0 {
+		p.DateFrom, p.DateTo, p.LookbackDays = base.DateFrom, base.DateTo, base.LookbackDays
+	}
+	return p
+}
+
+// Validate checks the fields that are set. Country codes are expected in
+// lower case, as Normalize leaves them.
+func (p FetchProfile) Validate() error {
+	for _, c := range p.Countries {
+		if !countryCodeRegex.MatchString(c) {
+			return fmt.Errorf("%w: %q", ErrCountryInvalid, c)
+		}
+	}
+	if p.PageSize < 0 || p.PageSize > MaxPageSize {
+		return fmt.Errorf("%w: %d", ErrPageSizeInvalid, p.PageSize)
+	}
+	if p.Concurrency < 0 || p.Concurrency > MaxConcurrency {
+		return fmt.Errorf("%w: %d", ErrConcurrencyInvalid, p.Concurrency)
+	}
+	switch p.Source {
+	case "", SourceRSS, SourceAPI:
+	default:
+		return fmt.Errorf("%w: %q", ErrSourceInvalid, p.Source)
+	}
+	return p.validateWindow()
+}
+
+func (p FetchProfile) validateWindow() error {
+	if p.LookbackDays < 0 {
+		return fmt.Errorf("%w: negative lookback", ErrWindowInvalid)
+	}
+	if (p.DateFrom == "") != (p.DateTo == "") {
+		return fmt.Errorf("%w: date_from and date_to must be set together", ErrWindowInvalid)
+	}
+	if p.DateFrom == "" {
+		return nil
+	}
+	from, err := time.Parse(dayLayout, p.DateFrom)
+	if err != nil {
+		return fmt.Errorf("%w: date_from: %v", ErrWindowInvalid, err)
+	}
+	to, err := time.Parse(dayLayout, p.DateTo)
+	if err != nil {
+		return fmt.Errorf("%w: date_to: %v", ErrWindowInvalid, err)
+	}
+	if to.Before(from) {
+		return fmt.Errorf("%w: date_to before date_from", ErrWindowInvalid)
+	}
+	return nil
+}
+
+// Normalize lower-cases and trims country codes and drops duplicates.
+func (p FetchProfile) Normalize() FetchProfile {
+	if len(p.Countries) == 0 {
+		return p
+	}
+	seen := make(map[string]bool, len(p.Countries))
+	countries := make([]string, 0, len(p.Countries))
+	for _, c := range p.Countries {
+		c = landing.NormalizeCountryCode(c)
+		if !seen[c] {
+			seen[c] = true
+			countries = append(countries, c)
+		}
+	}
+	p.Countries = countries
+	return p
+}
+
+// FallbackEnabled reports whether the other source is tried when Source
+// fails.
+func (p FetchProfile) FallbackEnabled() bool {
+	return p.Fallback != nil && *p.Fallback
+}
+
+// Window returns the dates to fetch, in the YYYY-MM-DD form of
+// events.ExtractRequest. A pinned window is returned as is; otherwise it
+// ends on now's UTC day and spans LookbackDays days.
+func (p FetchProfile) Window(now time.Time) (from, to string) {
+	if p.DateFrom != "" {
+		return p.DateFrom, p.DateTo
+	}
+	days := p.LookbackDays
+	if days <= 0 {
+		days = DefaultLookbackDays
+	}
+	end := now.UTC()
+	return end.AddDate(0, 0, -(days - 1)).Format(dayLayout), end.Format(dayLayout)
+}
+
+// Set is the stored form: a default profile and per-app overrides.
+type Set struct {
+	Default FetchProfile            `json:"default"`
+	Apps    map[string]FetchProfile `json:"apps,omitempty"`
+}
+
+// Resolve returns the effective profile for appID: its override, then the
+// set's default, then Defaults.
+func (s *Set) Resolve(appID string) FetchProfile {
+	base := s.Default.Merge(Defaults())
+	if p, ok := s.Apps[appID]; ok {
+		return p.Merge(base)
+	}
+	return base
+}
+
+// Validate checks the default and every override.
+func (s *Set) Validate() error {
+	if err := s.Default.Validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for appID, p := range s.Apps {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("app %s: %w", appID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Set) normalize() {
+	s.Default = s.Default.Normalize()
+	for appID, p := range s.Apps {
+		s.Apps[appID] = p.Normalize()
+	}
+}
+
+// Decode reads a Set as JSON, normalizes and validates it. Unknown fields
+// are rejected so typos do not silently fall back to defaults.
+func Decode(r io.Reader) (*Set, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var s Set
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("profile: decode: %w", err)
+	}
+	s.normalize()
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LoadFile reads a Set from a JSON file.
+func LoadFile(path string) (*Set, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+// Encode writes s as indented JSON.
+func (s *Set) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
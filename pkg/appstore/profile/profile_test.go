@@ -0,0 +1,94 @@
+package profile
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const setJSON = `{
+  "default": {"countries": ["US"], "concurrency": 2},
+  "apps": {
+    "389801252": {"countries": ["us", " GB", "de", "US"], "source": "api", "fallback": false, "lookback_days": 7},
+    "284882215": {"date_from": "2024-01-01", "date_to": "2024-01-31"}
+  }
+}`
+
+func TestResolve(t *testing.T) {
+	set, err := Decode(strings.NewReader(setJSON))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	now := time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC)
+
+	p := set.Resolve("389801252")
+	if !reflect.DeepEqual(p.Countries, []string{"us", "gb", "de"}) {
+		t.Errorf("countries = %v", p.Countries)
+	}
+	if p.PageSize != DefaultPageSize || p.Concurrency != 2 || p.Source != SourceAPI || p.FallbackEnabled() {
+		t.Errorf("unexpected profile %+v", p)
+	}
+	if from, to := p.Window(now); from != "2024-03-04" || to != "2024-03-10" {
+		t.Errorf("window = %s..%s", from, to)
+	}
+
+	p = set.Resolve("284882215")
+	if from, to := p.Window(now); from != "2024-01-01" || to != "2024-01-31" {
+		t.Errorf("pinned window = %s..%s", from, to)
+	}
+	if !reflect.DeepEqual(p.Countries, []string{"us"}) || p.Source != SourceRSS || !p.FallbackEnabled() {
+		t.Errorf("unexpected profile %+v", p)
+	}
+
+	p = set.Resolve("unknown")
+	if from, _ := p.Window(now); from != "2024-02-10" {
+		t.Errorf("default window starts %s", from)
+	}
+}
+
+func TestDecodeRejectsInvalidProfiles(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want error
+	}{
+		{"country", `{"default": {"countries": ["usa"]}}`, ErrCountryInvalid},
+		{"page size", `{"apps": {"1": {"page_size": 500}}}`, ErrPageSizeInvalid},
+		{"concurrency", `{"apps": {"1": {"concurrency": -1}}}`, ErrConcurrencyInvalid},
+		{"source", `{"default": {"source": "scrape"}}`, ErrSourceInvalid},
+		{"half window", `{"default": {"date_from": "2024-01-01"}}`, ErrWindowInvalid},
+		{"reversed window", `{"default": {"date_from": "2024-02-01", "date_to": "2024-01-01"}}`, ErrWindowInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(strings.NewReader(tt.json)); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Decode(strings.NewReader(`{"default": {"page_sise": 10}}`)); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	set, err := Decode(strings.NewReader(setJSON))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := set.Encode(&buf); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	again, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(set, again) {
+		t.Errorf("round trip changed the set:\n%+v\n%+v", set, again)
+	}
+}
@@ -0,0 +1,199 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dayLayout = "2006-01-02"
+
+var (
+	ErrStorefrontRequired = errors.New("storefront is required")
+	ErrQuotaExceeded      = errors.New("daily request quota exceeded")
+)
+
+// Key identifies one accounting bucket: requests to a storefront for an app
+// on a UTC day.
+type Key struct {
+	Storefront string `json:"storefront"`
+	AppID      string `json:"app_id"`
+	Day        string `json:"day"`
+}
+
+type Usage struct {
+	Key
+	Requests int64 `json:"requests"`
+}
+
+// Store persists usage counters. Implementations must be safe for concurrent
+// use; Add is called once per recorded request.
+type Store interface {
+	Add(ctx context.Context, key Key, n int64) error
+	Load(ctx context.Context, fromDay, toDay string) ([]Usage, error)
+}
+
+type Config struct {
+	// DailyLimit caps requests per storefront per day. Zero means unlimited.
+	DailyLimit int64
+	// Now overrides the clock, mainly for tests.
+	Now func() time.Time
+}
+
+type Tracker struct {
+	store Store
+	cfg   Config
+}
+
+func NewTracker(store Store, cfg Config) *Tracker {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Tracker{store: store, cfg: cfg}
+}
+
+// Record counts one request made for appID against storefront.
+func (t *Tracker) Record(ctx context.Context, storefront, appID string) error {
+	key, err := t.key(storefront, appID)
+	if err != nil {
+		return err
+	}
+	return t.store.Add(ctx, key, 1)
+}
+
+// Allow reports whether another request to storefront fits into today's
+// DailyLimit. It returns ErrQuotaExceeded when it does not, so callers can
+// defer the extract and surface why.
+func (t *Tracker) Allow(ctx context.Context, storefront string) error {
+	if t.cfg.DailyLimit <= 0 {
+		return nil
+	}
+	used, err := t.StorefrontUsage(ctx, storefront, t.cfg.Now())
+	if err != nil {
+		return err
+	}
+	if used >= t.cfg.DailyLimit {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// StorefrontUsage returns the number of requests made to storefront on the
+// UTC day containing day.
+func (t *Tracker) StorefrontUsage(ctx context.Context, storefront string, day time.Time) (int64, error) {
+	storefront = normalizeStorefront(storefront)
+	d := day.UTC().Format(dayLayout)
+	usage, err := t.store.Load(ctx, d, d)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, u := range usage {
+		if u.Storefront == storefront {
+			total += u.Requests
+		}
+	}
+	return total, nil
+}
+
+type Report struct {
+	From         string           `json:"from"`
+	To           string           `json:"to"`
+	Total        int64            `json:"total"`
+	ByStorefront map[string]int64 `json:"by_storefront"`
+	ByApp        map[string]int64 `json:"by_app"`
+	ByDay        map[string]int64 `json:"by_day"`
+	Usage        []Usage          `json:"usage"`
+}
+
+// Report aggregates usage for the UTC days between from and to, inclusive.
+func (t *Tracker) Report(ctx context.Context, from, to time.Time) (Report, error) {
+	r := Report{
+		From:         from.UTC().Format(dayLayout),
+		To:           to.UTC().Format(dayLayout),
+		ByStorefront: map[string]int64{},
+		ByApp:        map[string]int64{},
+		ByDay:        map[string]int64{},
+	}
+
+	usage, err := t.store.Load(ctx, r.From, r.To)
+	if err != nil {
+		return r, err
+	}
+	sortUsage(usage)
+
+	for _, u := range usage {
+		r.Total += u.Requests
+		r.ByStorefront[u.Storefront] += u.Requests
+		r.ByApp[u.AppID] += u.Requests
+		r.ByDay[u.Day] += u.Requests
+	}
+	r.Usage = usage
+	return r, nil
+}
+
+func (t *Tracker) key(storefront, appID string) (Key, error) {
+	storefront = normalizeStorefront(storefront)
+	if storefront == "" {
+		return Key{}, ErrStorefrontRequired
+	}
+	return Key{
+		Storefront: storefront,
+		AppID:      strings.TrimSpace(appID),
+		Day:        t.cfg.Now().UTC().Format(dayLayout),
+	}, nil
+}
+
+func normalizeStorefront(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func sortUsage(usage []Usage) {
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.Storefront != b.Storefront {
+			return a.Storefront < b.Storefront
+		}
+		return a.AppID < b.AppID
+	})
+}
+
+// MemoryStore keeps usage in process memory. It is the default Store and is
+// suitable for single-instance workers and tests.
+type MemoryStore struct {
+	mu     sync.Mutex
+	counts map[Key]int64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counts: make(map[Key]int64)}
+}
+
+func (s *MemoryStore) Add(_ context.Context, key Key, n int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[key] += n
+	return nil
+}
+
+func (s *MemoryStore) Load(_ context.Context, fromDay, toDay string) ([]Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Usage
+	for k, n := range s.counts {
+		if k.Day >= fromDay && k.Day <= toDay {
+			out = append(out, Usage{Key: k, Requests: n})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,77 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTrackerReport(t *testing.T) {
+	now := time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC)
+	tracker := NewTracker(nil, Config{Now: func() time.Time { return now }})
+	ctx := context.Background()
+
+	for _, call := range []struct{ storefront, app string }{
+		{"US", "389801252"},
+		{"us", "389801252"},
+		{"gb", "389801252"},
+		{"us", "284882215"},
+	} {
+		if err := tracker.Record(ctx, call.storefront, call.app); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	now = now.Add(time.Hour) // next UTC day
+	if err := tracker.Record(ctx, "us", "389801252"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	report, err := tracker.Report(ctx, now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	if report.Total != 5 {
+		t.Errorf("expected total 5, got %d", report.Total)
+	}
+	if report.ByStorefront["us"] != 4 || report.ByStorefront["gb"] != 1 {
+		t.Errorf("unexpected storefront breakdown %v", report.ByStorefront)
+	}
+	if report.ByApp["389801252"] != 4 {
+		t.Errorf("unexpected app breakdown %v", report.ByApp)
+	}
+	if report.ByDay["2024-03-10"] != 4 || report.ByDay["2024-03-11"] != 1 {
+		t.Errorf("unexpected day breakdown %v", report.ByDay)
+	}
+	if len(report.Usage) != 4 || report.Usage[0].Day != "2024-03-10" {
+		t.Errorf("expected usage sorted by day, got %+v", report.Usage)
+	}
+}
+
+func TestTrackerAllow(t *testing.T) {
+	tracker := NewTracker(NewMemoryStore(), Config{DailyLimit: 2})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := tracker.Allow(ctx, "us"); err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		tracker.Record(ctx, "us", "1")
+	}
+
+	if err := tracker.Allow(ctx, "us"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if err := tracker.Allow(ctx, "gb"); err != nil {
+		t.Errorf("expected other storefronts to be unaffected, got %v", err)
+	}
+}
+
+func TestTrackerRecordRequiresStorefront(t *testing.T) {
+	tracker := NewTracker(nil, Config{})
+	if err := tracker.Record(context.Background(), " ", "1"); !errors.Is(err, ErrStorefrontRequired) {
+		t.Errorf("expected ErrStorefrontRequired, got %v", err)
+	}
+}
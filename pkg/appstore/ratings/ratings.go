@@ -0,0 +1,267 @@
+// Package ratings keeps periodic snapshots of an app's star ratings
+// histogram per storefront and computes what changed between them.
+package ratings
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAppIDRequired       = errors.New("app ID is required")
+	ErrCountryRequired     = errors.New("country is required")
+	ErrNotEnoughSnapshots  = errors.New("at least two snapshots are needed for a delta")
+	ErrSnapshotsMismatched = errors.New("snapshots belong to different apps or countries")
+)
+
+// Histogram counts ratings by stars: Histogram[0] is one star, Histogram[4]
+// five stars. It is encoded as a JSON array of five numbers.
+type Histogram [5]int64
+
+// Total is the number of ratings.
+func (h Histogram) Total() int64 {
+	var n int64
+	for _, c := range h {
+		n += c
+	}
+	return n
+}
+
+// Average is the mean star rating, or 0 without ratings.
+func (h Histogram) Average() float64 {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+	var sum int64
+	for i, c := range h {
+		sum += int64(i+1) * c
+	}
+	return float64(sum) / float64(total)
+}
+
+// Sub returns h - o per star.
+func (h Histogram) Sub(o Histogram) Histogram {
+	var d Histogram
+	for i := range h {
+		d[i] = h[i] - o[i]
+	}
+	return d
+}
+
+// Snapshot is the histogram of one app in one storefront at a point in time.
+type Snapshot struct {
+	AppID     string    `json:"app_id"`
+	Country   string    `json:"country"`
+	TakenAt   time.Time `json:"taken_at"`
+	Histogram Histogram `json:"histogram"`
+}
+
+// RatingsDelta is the change between two snapshots of the same app and
+// storefront.
+type RatingsDelta struct {
+	AppID   string    `json:"app_id"`
+	Country string    `json:"country"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	// Change is the per-star difference. Entries can be negative when
+	// ratings were removed.
+	Change        Histogram `json:"change"`
+	TotalChange   int64     `json:"total_change"`
+	AverageBefore float64   `json:"average_before"`
+	AverageAfter  float64   `json:"average_after"`
+	AverageChange float64   `json:"average_change"`
+	// NewRatingsAverage is the average of the ratings added in between. It
+	// is only set when no star count went down, since otherwise additions
+	// and removals cannot be told apart.
+	NewRatingsAverage float64 `json:"new_ratings_average,omitempty"`
+	// Reset is set when the total went down, typically because the
+	// developer reset the summary rating with a new version.
+	Reset bool `json:"reset,omitempty"`
+}
+
+// Compute returns the delta from prev to cur.
+func Compute(prev, cur Snapshot) (RatingsDelta, error) {
+	if prev.AppID != cur.AppID || prev.Country != cur.Country {
+		return RatingsDelta{}, ErrSnapshotsMismatched
+	}
+	change := cur.Histogram.Sub(prev.Histogram)
+	d := RatingsDelta{
+		AppID:         cur.AppID,
+		Country:       cur.Country,
+		From:          prev.TakenAt,
+		To:            cur.TakenAt,
+		Change:        change,
+		TotalChange:   change.Total(),
+		AverageBefore: prev.Histogram.Average(),
+		AverageAfter:  cur.Histogram.Average(),
+	}
+	d.AverageChange = d.AverageAfter - d.AverageBefore
+	d.Reset = d.TotalChange < 0
+
+	additive := true
+	for _, c := range change {
+		if c < 0 {
+			additive = false
+		}
+	}
+	if additive {
+		d.NewRatingsAverage = change.Average()
+	}
+	return d, nil
+}
+
+// Store persists snapshots. Implementations must be safe for concurrent use.
+type Store interface {
+	Save(ctx context.Context, s Snapshot) error
+	// Load returns the snapshots of appID in country taken between from and
+	// to, inclusive, oldest first.
+	Load(ctx context.Context, appID, country string, from, to time.Time) ([]Snapshot, error)
+	// Latest returns the most recent snapshot, if any.
+	Latest(ctx context.Context, appID, country string) (Snapshot, bool, error)
+}
+
+type Config struct {
+	// MinInterval skips Record calls closer than this to the previous
+	// snapshot, so frequent pollers do not bloat the store. Zero keeps
+	// every snapshot.
+	MinInterval time.Duration
+	// Now overrides the clock, mainly for tests.
+	Now func() time.Time
+}
+
+// RatingsTracker records snapshots and computes deltas between them.
+type RatingsTracker struct {
+	store Store
+	cfg   Config
+}
+
+func NewRatingsTracker(store Store, cfg Config) *RatingsTracker {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &RatingsTracker{store: store, cfg: cfg}
+}
+
+// Record stores the current histogram of appID in country and returns the
+// delta to the previous snapshot. ok is false when there was no previous
+// snapshot or the snapshot was skipped because of MinInterval.
+func (t *RatingsTracker) Record(ctx context.Context, appID, country string, h Histogram) (delta RatingsDelta, ok bool, err error) {
+	cur := Snapshot{
+		AppID:     strings.TrimSpace(appID),
+		Country:   normalizeCountry(country),
+		TakenAt:   t.cfg.Now().UTC(),
+		Histogram: h,
+	}
+	if cur.AppID == "" {
+		return RatingsDelta{}, false, ErrAppIDRequired
+	}
+	if cur.Country == "" {
+		return RatingsDelta{}, false, ErrCountryRequired
+	}
+
+	prev, found, err := t.store.Latest(ctx, cur.AppID, cur.Country)
+	if err != nil {
+		return RatingsDelta{}, false, err
+	}
+	if found && t.cfg.MinInterval > 0 && cur.TakenAt.Sub(prev.TakenAt) < t.cfg.MinInterval {
+		return RatingsDelta{}, false, nil
+	}
+	if err := t.store.Save(ctx, cur); err != nil {
+		return RatingsDelta{}, false, err
+	}
+	if !found {
+		return RatingsDelta{}, false, nil
+	}
+	delta, err = Compute(prev, cur)
+	return delta, err == nil, err
+}
+
+// Delta compares the first and last snapshot taken between from and to.
+func (t *RatingsTracker) Delta(ctx context.Context, appID, country string, from, to time.Time) (RatingsDelta, error) {
+	snaps, err := t.store.Load(ctx, strings.TrimSpace(appID), normalizeCountry(country), from, to)
+	if err != nil {
+		return RatingsDelta{}, err
+	}
+	if len(snaps) < 2 {
+		return RatingsDelta{}, ErrNotEnoughSnapshots
+	}
+	return Compute(snaps[0], snaps[len(snaps)-1])
+}
+
+// Series returns the deltas between consecutive snapshots taken between
+// from and to, e.g. to chart daily rating changes.
+func (t *RatingsTracker) Series(ctx context.Context, appID, country string, from, to time.Time) ([]RatingsDelta, error) {
+	snaps, err := t.store.Load(ctx, strings.TrimSpace(appID), normalizeCountry(country), from, to)
+	if err != nil {
+		return nil, err
+	}
+	var out []RatingsDelta
+	for i := 1; i < len(snaps); i++ {
+		d, err := Compute(snaps[i-1], snaps[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func normalizeCountry(c string) string {
+	return strings.ToLower(strings.TrimSpace(c))
+}
+
+type seriesKey struct {
+	appID   string
+	country string
+}
+
+// MemoryStore keeps snapshots in process memory. It is the default Store
+// and is suitable for single-instance workers and tests.
+type MemoryStore struct {
+	mu    sync.Mutex
+	snaps map[seriesKey][]Snapshot
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{snaps: make(map[seriesKey][]Snapshot)}
+}
+
+func (s *MemoryStore) Save(_ context.Context, snap Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := seriesKey{snap.AppID, snap.Country}
+	list := append(s.snaps[k], snap)
+	sort.SliceStable(list, func(i, j int) bool { return list[i].TakenAt.Before(list[j].TakenAt) })
+	s.snaps[k] = list
+	return nil
+}
+
+func (s *MemoryStore) Load(_ context.Context, appID, country string, from, to time.Time) ([]Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Snapshot
+	for _, snap := range s.snaps[seriesKey{appID, country}] {
+		if !snap.TakenAt.Before(from) && !snap.TakenAt.After(to) {
+			out = append(out, snap)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) Latest(_ context.Context, appID, country string) (Snapshot, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.snaps[seriesKey{appID, country}]
+	if len(list) == 0 {
+		return Snapshot{}, false, nil
+	}
+	return list[len(list)-1], true, nil
+}
@@ -0,0 +1,73 @@
+package ratings
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRatingsTrackerRecordAndDelta(t *testing.T) {
+	now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	tracker := NewRatingsTracker(nil, Config{MinInterval: time.Hour, Now: func() time.Time { return now }})
+	ctx := context.Background()
+
+	if _, ok, err := tracker.Record(ctx, "389801252", "US", Histogram{10, 0, 0, 0, 10}); err != nil || ok {
+		t.Fatalf("first Record() = %v, %v", ok, err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok, err := tracker.Record(ctx, "389801252", "us", Histogram{99, 0, 0, 0, 99}); err != nil || ok {
+		t.Fatalf("Record() within MinInterval = %v, %v", ok, err)
+	}
+
+	now = now.Add(24 * time.Hour)
+	delta, ok, err := tracker.Record(ctx, "389801252", "us", Histogram{10, 0, 0, 2, 18})
+	if err != nil || !ok {
+		t.Fatalf("Record() = %v, %v", ok, err)
+	}
+	if delta.Change != (Histogram{0, 0, 0, 2, 8}) || delta.TotalChange != 10 {
+		t.Errorf("change = %v, total %d", delta.Change, delta.TotalChange)
+	}
+	if delta.AverageBefore != 3 || math.Abs(delta.NewRatingsAverage-4.8) > 1e-9 || delta.Reset {
+		t.Errorf("unexpected delta %+v", delta)
+	}
+
+	now = now.Add(24 * time.Hour)
+	delta, _, err = tracker.Record(ctx, "389801252", "us", Histogram{1, 0, 0, 0, 1})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if !delta.Reset || delta.NewRatingsAverage != 0 {
+		t.Errorf("expected a reset without new ratings average, got %+v", delta)
+	}
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	total, err := tracker.Delta(ctx, "389801252", "us", from, now)
+	if err != nil {
+		t.Fatalf("Delta() error = %v", err)
+	}
+	if total.TotalChange != -18 || !total.From.Equal(from.AddDate(0, 0, 9)) {
+		t.Errorf("unexpected total delta %+v", total)
+	}
+
+	series, err := tracker.Series(ctx, "389801252", "us", from, now)
+	if err != nil || len(series) != 2 {
+		t.Fatalf("Series() = %d deltas, %v", len(series), err)
+	}
+
+	if _, err := tracker.Delta(ctx, "389801252", "gb", from, now); !errors.Is(err, ErrNotEnoughSnapshots) {
+		t.Errorf("Delta() for unknown series error = %v", err)
+	}
+	if _, _, err := tracker.Record(ctx, "389801252", " ", Histogram{}); !errors.Is(err, ErrCountryRequired) {
+		t.Errorf("Record() without country error = %v", err)
+	}
+}
+
+func TestComputeRejectsMismatchedSnapshots(t *testing.T) {
+	_, err := Compute(Snapshot{AppID: "1", Country: "us"}, Snapshot{AppID: "1", Country: "gb"})
+	if !errors.Is(err, ErrSnapshotsMismatched) {
+		t.Errorf("err = %v", err)
+	}
+}
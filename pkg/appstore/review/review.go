@@ -0,0 +1,25 @@
+package review
+
+import "time"
+
+// Review is a single App Store customer review as fetched by the extract
+// services.
+type Review struct {
+	ID         string             `json:"id"`
+	AppID      string             `json:"app_id"`
+	Country    string             `json:"country"`
+	Rating     int                `json:"rating"`
+	Title      string             `json:"title,omitempty"`
+	Body       string             `json:"body"`
+	Author     string             `json:"author,omitempty"`
+	AppVersion string             `json:"app_version,omitempty"`
+	IsEdited   bool               `json:"is_edited,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Response   *DeveloperResponse `json:"developer_response,omitempty"`
+}
+
+type DeveloperResponse struct {
+	ID         string    `json:"id,omitempty"`
+	Body       string    `json:"body"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
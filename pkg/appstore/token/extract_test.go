@@ -2,6 +2,8 @@ package token
 
 import (
 	"testing"
+
+	"github.com/quiby-ai/common/pkg/appstore/appstoretest"
 )
 
 func TestExtractBearerToken(t *testing.T) {
@@ -117,3 +119,20 @@ func TestExtractBearerTokenRealExample(t *testing.T) {
 		t.Errorf("Expected non-empty line, got empty")
 	}
 }
+
+func TestExtractBearerTokenFixtures(t *testing.T) {
+	check := func(t *testing.T, f appstoretest.Fixture) {
+		got, _, ok := ExtractBearerToken(string(f.Data))
+		if f.Want.Token == "" {
+			if ok || got != "" {
+				t.Errorf("got token %q from a page without one", got)
+			}
+			return
+		}
+		if !ok || got != "bearer "+f.Want.Token {
+			t.Errorf("ExtractBearerToken() = %q, %v; want %q", got, ok, "bearer "+f.Want.Token)
+		}
+	}
+	appstoretest.Run(t, appstoretest.KindToken, check)
+	appstoretest.Run(t, appstoretest.KindLanding, check)
+}
@@ -0,0 +1,476 @@
+// Package watch polls App Store storefronts for new reviews and reports
+// them as they appear, for near-real-time alerting. Each watched app and
+// country keeps its own state, so restarts pick up where the last poll
+// stopped instead of re-reporting old reviews.
+package watch
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/quiby-ai/common/pkg/appstore/review"
+	"github.com/quiby-ai/common/pkg/events"
+)
+
+var (
+	ErrAppIDRequired   = errors.New("app ID is required")
+	ErrCountryRequired = errors.New("country is required")
+)
+
+const (
+	defaultInterval   = 5 * time.Minute
+	defaultJitter     = 0.2
+	defaultMaxBackoff = time.Hour
+	defaultAppID      = "review-watcher"
+)
+
+// Fetcher returns the most recent reviews of an app in one storefront. One
+// page is enough as long as fewer reviews than a page arrive between polls.
+type Fetcher interface {
+	LatestReviews(ctx context.Context, appID, country string) ([]review.Review, error)
+}
+
+type FetcherFunc func(ctx context.Context, appID, country string) ([]review.Review, error)
+
+func (f FetcherFunc) LatestReviews(ctx context.Context, appID, country string) ([]review.Review, error) {
+	return f(ctx, appID, country)
+}
+
+// Watch is one app in one storefront.
+type Watch struct {
+	AppID   string `json:"app_id"`
+	Country string `json:"country"`
+	// AppName and TenantID are copied into published extract requests.
+	AppName  string `json:"app_name,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+func (w Watch) key() watchKey {
+	return watchKey{w.AppID, w.Country}
+}
+
+// State is what the watcher remembers about a watch between polls.
+type State struct {
+	AppID   string `json:"app_id"`
+	Country string `json:"country"`
+	// Newest is the creation time of the newest review seen so far.
+	Newest time.Time `json:"newest"`
+	// NewestIDs are the reviews created exactly at Newest, so reviews
+	// sharing a timestamp are neither lost nor reported twice.
+	NewestIDs []string `json:"newest_ids,omitempty"`
+	// BaselineAt is when the first successful poll recorded what already
+	// existed. Reviews are only reported after it.
+	BaselineAt time.Time `json:"baseline_at,omitempty"`
+	// UnpublishedFrom and UnpublishedTo span the creation times of reviews
+	// already passed to OnNew whose extract request failed to publish. The
+	// next poll publishes them again, together with any newer reviews.
+	UnpublishedFrom time.Time `json:"unpublished_from,omitempty"`
+	UnpublishedTo   time.Time `json:"unpublished_to,omitempty"`
+	LastPoll        time.Time `json:"last_poll"`
+	// Failures counts consecutive failed polls; it drives the backoff.
+	Failures  int    `json:"failures,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// StateStore persists State per watch. Implementations must be safe for
+// concurrent use.
+type StateStore interface {
+	Load(ctx context.Context, appID, country string) (State, bool, error)
+	Save(ctx context.Context, s State) error
+}
+
+// NewReviews are the reviews that appeared since the previous poll, oldest
+// first.
+type NewReviews struct {
+	Watch   Watch
+	Reviews []review.Review
+}
+
+type Config struct {
+	// Interval between polls of one watch. Default 5m.
+	Interval time.Duration
+	// Jitter spreads polls by up to this fraction of Interval either way,
+	// so many watches do not hit the App Store in lockstep. Default 0.2;
+	// negative disables it.
+	Jitter float64
+	// MaxBackoff caps the interval after consecutive failures, which
+	// doubles it each time. Default 1h.
+	MaxBackoff time.Duration
+
+	// OnNew is called with the new reviews of a poll. If it fails, the
+	// reviews are reported again on the next poll.
+	OnNew func(ctx context.Context, n NewReviews) error
+	// Publisher, when set, receives a pipeline.extract_reviews.request for
+	// the days the new reviews were written on, so the pipeline ingests
+	// them right away. A failed publish is retried on the next poll
+	// without calling OnNew again.
+	Publisher events.EventPublisher
+	// AppID names the publishing service in Meta.AppID of those requests,
+	// not the watched app. Default "review-watcher".
+	AppID string
+	// OnError is called when a poll fails.
+	OnError func(w Watch, err error)
+
+	// Now overrides the clock, mainly for tests.
+	Now func() time.Time
+}
+
+type watchKey struct {
+	appID   string
+	country string
+}
+
+type scheduled struct {
+	watch Watch
+	next  time.Time
+}
+
+// ReviewWatcher polls its watches one at a time, each on its own jittered
+// schedule.
+type ReviewWatcher struct {
+	fetcher Fetcher
+	store   StateStore
+	cfg     Config
+
+	mu      sync.Mutex
+	watches map[watchKey]*scheduled
+	wake    chan struct{}
+}
+
+// NewReviewWatcher creates a watcher that reads reviews with fetcher and
+// keeps state in store, or in memory if nil.
+func NewReviewWatcher(fetcher Fetcher, store StateStore, cfg Config) *ReviewWatcher {
+	if store == nil {
+		store = NewMemoryStateStore()
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.Jitter == 0 {
+		cfg.Jitter = defaultJitter
+	}
+	cfg.Jitter = min(max(cfg.Jitter, 0), 1)
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.AppID == "" {
+		cfg.AppID = defaultAppID
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &ReviewWatcher{
+		fetcher: fetcher,
+		store:   store,
+		cfg:     cfg,
+		watches: make(map[watchKey]*scheduled),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add starts watching w. Its first poll happens within one jittered
+// interval and only records a baseline: reviews that already exist are not
+// reported. Adding a watch again updates its AppName and TenantID.
+func (rw *ReviewWatcher) Add(w Watch) error {
+	w, err := normalize(w)
+	if err != nil {
+		return err
+	}
+	rw.mu.Lock()
+	if s, ok := rw.watches[w.key()]; ok {
+		s.watch = w
+	} else {
+		first := time.Duration(rand.Float64() * float64(rw.cfg.Interval) * rw.cfg.Jitter)
+		rw.watches[w.key()] = &scheduled{watch: w, next: rw.cfg.Now().Add(first)}
+	}
+	rw.mu.Unlock()
+	rw.notify()
+	return nil
+}
+
+// Remove stops watching appID in country. Its state is kept.
+func (rw *ReviewWatcher) Remove(appID, country string) {
+	rw.mu.Lock()
+	delete(rw.watches, watchKey{strings.TrimSpace(appID), normalizeCountry(country)})
+	rw.mu.Unlock()
+	rw.notify()
+}
+
+// Watches returns the current watches.
+func (rw *ReviewWatcher) Watches() []Watch {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	out := make([]Watch, 0, len(rw.watches))
+	for _, s := range rw.watches {
+		out = append(out, s.watch)
+	}
+	slices.SortFunc(out, func(a, b Watch) int {
+		return strings.Compare(a.AppID+"/"+a.Country, b.AppID+"/"+b.Country)
+	})
+	return out
+}
+
+func (rw *ReviewWatcher) notify() {
+	select {
+	case rw.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls due watches until ctx is done and then returns ctx.Err().
+// Failed polls are reported to OnError and retried with backoff.
+func (rw *ReviewWatcher) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		w, wait, ok := rw.due()
+		if ok {
+			_, err := rw.Poll(ctx, w)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil && rw.cfg.OnError != nil {
+				rw.cfg.OnError(w, err)
+			}
+			rw.reschedule(ctx, w)
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-rw.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// due returns the watch that is due first, or how long to wait for one.
+func (rw *ReviewWatcher) due() (Watch, time.Duration, bool) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	var first *scheduled
+	for _, s := range rw.watches {
+		if first == nil || s.next.Before(first.next) {
+			first = s
+		}
+	}
+	if first == nil {
+		return Watch{}, time.Hour, false
+	}
+	if wait := first.next.Sub(rw.cfg.Now()); wait > 0 {
+		return Watch{}, wait, false
+	}
+	return first.watch, 0, true
+}
+
+func (rw *ReviewWatcher) reschedule(ctx context.Context, w Watch) {
+	failures := 0
+	if st, ok, err := rw.store.Load(ctx, w.AppID, w.Country); err == nil && ok {
+		failures = st.Failures
+	}
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if s, ok := rw.watches[w.key()]; ok {
+		s.next = rw.cfg.Now().Add(rw.nextDelay(failures))
+	}
+}
+
+// nextDelay is the jittered interval, doubled per consecutive failure up to
+// MaxBackoff.
+func (rw *ReviewWatcher) nextDelay(failures int) time.Duration {
+	d := rw.cfg.Interval
+	for range min(failures, 32) {
+		if d *= 2; d >= rw.cfg.MaxBackoff {
+			d = rw.cfg.MaxBackoff
+			break
+		}
+	}
+	if rw.cfg.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + rw.cfg.Jitter*(2*rand.Float64()-1)))
+	}
+	return d
+}
+
+// Poll fetches w once, reports reviews that appeared since the previous
+// poll and advances its state. The first poll of a watch only records a
+// baseline. Run calls it on schedule; it can also be driven by an external
+// scheduler.
+func (rw *ReviewWatcher) Poll(ctx context.Context, w Watch) (NewReviews, error) {
+	w, err := normalize(w)
+	if err != nil {
+		return NewReviews{}, err
+	}
+	st, _, err := rw.store.Load(ctx, w.AppID, w.Country)
+	if err != nil {
+		return NewReviews{}, err
+	}
+	st.AppID, st.Country = w.AppID, w.Country
+	st.LastPoll = rw.cfg.Now().UTC()
+
+	reviews, err := rw.fetcher.LatestReviews(ctx, w.AppID, w.Country)
+	if err != nil {
+		return NewReviews{}, rw.fail(ctx, st, err)
+	}
+
+	fresh := newSince(st, reviews)
+	next := advance(st, fresh)
+	if st.BaselineAt.IsZero() {
+		// Remember the newest review without reporting history.
+		next = advance(st, reviews)
+		next.BaselineAt = st.LastPoll
+		fresh = nil
+	}
+	n := NewReviews{Watch: w, Reviews: fresh}
+
+	if len(fresh) > 0 && rw.cfg.OnNew != nil {
+		if err := rw.cfg.OnNew(ctx, n); err != nil {
+			return n, rw.fail(ctx, st, err)
+		}
+	}
+	if rw.cfg.Publisher != nil {
+		// From here on the reviews count as reported; only the extract
+		// request is retried if publishing fails.
+		for _, r := range fresh {
+			next.UnpublishedFrom = minTime(next.UnpublishedFrom, r.CreatedAt)
+			next.UnpublishedTo = maxTime(next.UnpublishedTo, r.CreatedAt)
+		}
+		if !next.UnpublishedTo.IsZero() {
+			if err := rw.publish(ctx, w, next.UnpublishedFrom, next.UnpublishedTo); err != nil {
+				return n, rw.fail(ctx, next, err)
+			}
+			next.UnpublishedFrom, next.UnpublishedTo = time.Time{}, time.Time{}
+		}
+	}
+
+	next.Failures, next.LastError = 0, ""
+	return n, rw.store.Save(ctx, next)
+}
+
+// fail records a failed poll, saving st as the state to resume from.
+func (rw *ReviewWatcher) fail(ctx context.Context, st State, err error) error {
+	st.Failures++
+	st.LastError = err.Error()
+	if serr := rw.store.Save(ctx, st); serr != nil {
+		return errors.Join(err, serr)
+	}
+	return err
+}
+
+// publish requests extraction of w's reviews created between from and to.
+func (rw *ReviewWatcher) publish(ctx context.Context, w Watch, from, to time.Time) error {
+	appName := w.AppName
+	if appName == "" {
+		appName = w.AppID
+	}
+	sagaID := uuid.NewString()
+	env := events.BuildEnvelopeWithMeta(events.ExtractRequest{
+		AppID:     w.AppID,
+		AppName:   appName,
+		Countries: []string{w.Country},
+		DateFrom:  from.UTC().Format(time.DateOnly),
+		DateTo:    to.UTC().Format(time.DateOnly),
+	}, events.PipelineExtractRequest, sagaID, rw.cfg.AppID, events.InitiatorSystem)
+	env.Meta.TenantID = w.TenantID
+	return rw.cfg.Publisher.PublishEvent(ctx, []byte(sagaID), env)
+}
+
+// newSince returns the reviews newer than st, oldest first.
+func newSince(st State, reviews []review.Review) []review.Review {
+	var out []review.Review
+	for _, r := range reviews {
+		if r.CreatedAt.After(st.Newest) ||
+			(r.CreatedAt.Equal(st.Newest) && !slices.Contains(st.NewestIDs, r.ID)) {
+			out = append(out, r)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b review.Review) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return out
+}
+
+// advance moves st past reviews.
+func advance(st State, reviews []review.Review) State {
+	for _, r := range reviews {
+		switch {
+		case r.CreatedAt.After(st.Newest):
+			st.Newest = r.CreatedAt
+			st.NewestIDs = []string{r.ID}
+		case r.CreatedAt.Equal(st.Newest) && !slices.Contains(st.NewestIDs, r.ID):
+			st.NewestIDs = append(slices.Clip(st.NewestIDs), r.ID)
+		}
+	}
+	return st
+}
+
+func normalize(w Watch) (Watch, error) {
+	w.AppID = strings.TrimSpace(w.AppID)
+	w.Country = normalizeCountry(w.Country)
+	if w.AppID == "" {
+		return w, ErrAppIDRequired
+	}
+	if w.Country == "" {
+		return w, ErrCountryRequired
+	}
+	return w, nil
+}
+
+func normalizeCountry(c string) string {
+	return strings.ToLower(strings.TrimSpace(c))
+}
+
+// minTime returns the earlier of a and b; a zero a counts as unset.
+func minTime(a, b time.Time) time.Time {
+	if a.IsZero() || b.Before(a) {
+		return b
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// MemoryStateStore keeps watch state in process memory. It is the default
+// StateStore; state is lost on restart, so the first poll after one only
+// records a new baseline.
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	states map[watchKey]State
+}
+
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{states: make(map[watchKey]State)}
+}
+
+func (s *MemoryStateStore) Load(_ context.Context, appID, country string) (State, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[watchKey{appID, country}]
+	st.NewestIDs = slices.Clone(st.NewestIDs)
+	return st, ok, nil
+}
+
+func (s *MemoryStateStore) Save(_ context.Context, st State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.NewestIDs = slices.Clone(st.NewestIDs)
+	s.states[watchKey{st.AppID, st.Country}] = st
+	return nil
+}
@@ -0,0 +1,232 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quiby-ai/common/pkg/appstore/review"
+	"github.com/quiby-ai/common/pkg/events"
+)
+
+type fakeStore struct {
+	mu      sync.Mutex
+	reviews []review.Review
+	err     error
+}
+
+func (f *fakeStore) LatestReviews(context.Context, string, string) ([]review.Review, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]review.Review(nil), f.reviews...), f.err
+}
+
+func (f *fakeStore) add(r ...review.Review) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reviews = append(r, f.reviews...) // newest first
+}
+
+type recordingPublisher struct {
+	envelopes []events.Envelope[any]
+	err       error
+}
+
+func (p *recordingPublisher) PublishEvent(_ context.Context, _ []byte, env events.Envelope[any]) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.envelopes = append(p.envelopes, env)
+	return nil
+}
+
+func rv(id string, at time.Time) review.Review {
+	return review.Review{ID: id, AppID: "389801252", Country: "us", Rating: 5, Body: id, CreatedAt: at}
+}
+
+func TestPollReportsOnlyNewReviews(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	src := &fakeStore{}
+	src.add(rv("1", t0), rv("2", t0.Add(time.Minute)))
+
+	pub := &recordingPublisher{}
+	var reported [][]review.Review
+	failNext := false
+	w := NewReviewWatcher(src, nil, Config{
+		Publisher: pub,
+		AppID:     "alerts",
+		OnNew: func(_ context.Context, n NewReviews) error {
+			if failNext {
+				failNext = false
+				return errors.New("alert channel down")
+			}
+			reported = append(reported, n.Reviews)
+			return nil
+		},
+	})
+	watch := Watch{AppID: "389801252", Country: "US", AppName: "Example", TenantID: "acme"}
+
+	// The first poll is a baseline.
+	if n, err := w.Poll(ctx, watch); err != nil || len(n.Reviews) != 0 {
+		t.Fatalf("baseline Poll() = %d reviews, %v", len(n.Reviews), err)
+	}
+
+	// Review 3 shares the timestamp of review 2.
+	src.add(rv("3", t0.Add(time.Minute)), rv("4", t0.Add(2*time.Minute)))
+	failNext = true
+	if _, err := w.Poll(ctx, watch); err == nil {
+		t.Fatal("Poll() with failing OnNew: expected error")
+	}
+	n, err := w.Poll(ctx, watch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Reviews) != 2 || n.Reviews[0].ID != "3" || n.Reviews[1].ID != "4" {
+		t.Fatalf("new reviews = %+v", n.Reviews)
+	}
+	if n, _ := w.Poll(ctx, watch); len(n.Reviews) != 0 {
+		t.Fatalf("repeat Poll() = %+v", n.Reviews)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("OnNew called %d times", len(reported))
+	}
+
+	if len(pub.envelopes) != 1 {
+		t.Fatalf("published %d events", len(pub.envelopes))
+	}
+	env := pub.envelopes[0]
+	req := env.Payload.(events.ExtractRequest)
+	if env.Type != events.PipelineExtractRequest || env.Meta.TenantID != "acme" || env.Meta.AppID != "alerts" ||
+		req.AppID != "389801252" || req.AppName != "Example" || req.Countries[0] != "us" || req.DateFrom != "2024-03-10" {
+		t.Fatalf("envelope = %+v", env)
+	}
+	if errs := events.ValidatePayload(req); len(errs) > 0 {
+		t.Fatalf("invalid payload: %v", errs)
+	}
+}
+
+func TestPollRetriesOnlyFailedPublish(t *testing.T) {
+	ctx := context.Background()
+	t0 := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	src := &fakeStore{}
+	src.add(rv("1", t0))
+
+	pub := &recordingPublisher{}
+	var alerts int
+	store := NewMemoryStateStore()
+	w := NewReviewWatcher(src, store, Config{
+		Publisher: pub,
+		OnNew:     func(context.Context, NewReviews) error { alerts++; return nil },
+	})
+	watch := Watch{AppID: "389801252", Country: "us"}
+	if _, err := w.Poll(ctx, watch); err != nil {
+		t.Fatal(err)
+	}
+
+	src.add(rv("2", t0.Add(24*time.Hour)))
+	pub.err = errors.New("broker down")
+	if _, err := w.Poll(ctx, watch); err == nil {
+		t.Fatal("Poll() with failing Publisher: expected error")
+	}
+	st, _, _ := store.Load(ctx, "389801252", "us")
+	if st.Failures != 1 || !st.UnpublishedFrom.Equal(t0.Add(24*time.Hour)) {
+		t.Fatalf("state = %+v", st)
+	}
+
+	// The retry publishes the pending day together with a newer review
+	// and alerts only about the newer one.
+	src.add(rv("3", t0.Add(48*time.Hour)))
+	pub.err = nil
+	n, err := w.Poll(ctx, watch)
+	if err != nil || len(n.Reviews) != 1 || n.Reviews[0].ID != "3" {
+		t.Fatalf("Poll() = %+v, %v", n.Reviews, err)
+	}
+	if alerts != 2 {
+		t.Errorf("OnNew called %d times, want 2", alerts)
+	}
+	if len(pub.envelopes) != 1 {
+		t.Fatalf("published %d events", len(pub.envelopes))
+	}
+	req := pub.envelopes[0].Payload.(events.ExtractRequest)
+	if req.DateFrom != "2024-03-11" || req.DateTo != "2024-03-12" {
+		t.Errorf("request covers %s..%s", req.DateFrom, req.DateTo)
+	}
+	st, _, _ = store.Load(ctx, "389801252", "us")
+	if st.Failures != 0 || !st.UnpublishedFrom.IsZero() || !st.UnpublishedTo.IsZero() {
+		t.Fatalf("state = %+v", st)
+	}
+}
+
+func TestFailedFirstPollKeepsBaseline(t *testing.T) {
+	ctx := context.Background()
+	src := &fakeStore{err: errors.New("503")}
+	src.add(rv("1", time.Now()))
+	store := NewMemoryStateStore()
+	w := NewReviewWatcher(src, store, Config{})
+	watch := Watch{AppID: "389801252", Country: "us"}
+
+	if _, err := w.Poll(ctx, watch); err == nil {
+		t.Fatal("expected error")
+	}
+	st, _, _ := store.Load(ctx, "389801252", "us")
+	if st.Failures != 1 || st.LastError != "503" {
+		t.Fatalf("state = %+v", st)
+	}
+
+	src.err = nil
+	if n, err := w.Poll(ctx, watch); err != nil || len(n.Reviews) != 0 {
+		t.Fatalf("Poll() after failure = %+v, %v; want baseline", n.Reviews, err)
+	}
+	st, _, _ = store.Load(ctx, "389801252", "us")
+	if st.Failures != 0 || st.BaselineAt.IsZero() {
+		t.Fatalf("state = %+v", st)
+	}
+}
+
+func TestNextDelayBackoffAndJitter(t *testing.T) {
+	w := NewReviewWatcher(&fakeStore{}, nil, Config{Interval: time.Minute, Jitter: 0.5, MaxBackoff: 10 * time.Minute})
+	for range 100 {
+		if d := w.nextDelay(0); d < 30*time.Second || d > 90*time.Second {
+			t.Fatalf("nextDelay(0) = %v", d)
+		}
+		if d := w.nextDelay(10); d < 5*time.Minute || d > 15*time.Minute {
+			t.Fatalf("nextDelay(10) = %v", d)
+		}
+	}
+}
+
+func TestRunPollsWatches(t *testing.T) {
+	src := &fakeStore{}
+	src.add(rv("1", time.Now()))
+	polled := make(chan struct{}, 10)
+	w := NewReviewWatcher(FetcherFunc(func(ctx context.Context, appID, country string) ([]review.Review, error) {
+		polled <- struct{}{}
+		return src.LatestReviews(ctx, appID, country)
+	}), nil, Config{Interval: 5 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	if err := w.Add(Watch{AppID: "389801252", Country: "us"}); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		select {
+		case <-polled:
+		case <-ctx.Done():
+			t.Fatal("watch was not polled")
+		}
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() = %v", err)
+	}
+	if err := w.Add(Watch{AppID: "", Country: "us"}); !errors.Is(err, ErrAppIDRequired) {
+		t.Fatalf("Add() = %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+const (
+	defaultAPIKeyHeader = "X-API-Key"
+	apiKeyLength        = 32
+)
+
+// APIKey is what an APIKeyStore keeps per key. The key itself is never
+// stored, only its SHA-256 hash (see HashAPIKey).
+type APIKey struct {
+	// ID names the key in logs and admin screens; it is not secret.
+	ID   string
+	Hash string
+	// Owner is the user or service the key acts as. It becomes
+	// Identity.UserID.
+	Owner     string
+	TenantID  string
+	Roles     []string
+	Scopes    []string
+	ExpiresAt time.Time // zero: never expires
+	Revoked   bool
+}
+
+// APIKeyStore looks API keys up by hash. Implementations must be safe for
+// concurrent use.
+type APIKeyStore interface {
+	// Lookup returns ErrAPIKeyNotFound for unknown hashes.
+	Lookup(ctx context.Context, hash string) (APIKey, error)
+}
+
+type APIKeyConfig struct {
+	Store APIKeyStore
+	// Header carries the key. Default "X-API-Key".
+	Header string
+	// Skipper lets matching requests through unauthenticated.
+	Skipper Skipper
+}
+
+// HashAPIKey returns the hex SHA-256 of key, the form stored in APIKey.Hash.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey returns a new random key with prefix, e.g. "cp_live_", and
+// its hash. Show the key to its owner once and store only the hash.
+func GenerateAPIKey(prefix string) (key, hash string, err error) {
+	secret, err := randomToken(apiKeyLength)
+	if err != nil {
+		return "", "", err
+	}
+	key = prefix + secret
+	return key, HashAPIKey(key), nil
+}
+
+// APIKeyMiddleware authenticates server-to-server callers by the key in
+// cfg.Header and stores an Identity with Source SourceAPIKey. Unknown,
+// expired and revoked keys get 401; store failures 503.
+func APIKeyMiddleware(cfg APIKeyConfig) func(http.Handler) http.Handler {
+	header := cfg.Header
+	if header == "" {
+		header = defaultAPIKeyHeader
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r, skipped := skip(cfg.Skipper, r); skipped {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			presented := r.Header.Get(header)
+			if presented == "" {
+				writeError(w, r, http.StatusUnauthorized, CodeMissingCredentials, "API key required")
+				return
+			}
+
+			hash := HashAPIKey(presented)
+			key, err := cfg.Store.Lookup(r.Context(), hash)
+			if errors.Is(err, ErrAPIKeyNotFound) {
+				writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Invalid API key")
+				return
+			}
+			if err != nil {
+				writeError(w, r, http.StatusServiceUnavailable, CodeUnavailable, "")
+				return
+			}
+			// Do not rely on the store having matched the hash exactly.
+			if !SecureCompare(key.Hash, hash) || key.Revoked ||
+				(!key.ExpiresAt.IsZero() && !time.Now().Before(key.ExpiresAt)) {
+				writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "Invalid API key")
+				return
+			}
+
+			ctx := WithIdentity(r.Context(), &Identity{
+				UserID:   key.Owner,
+				TenantID: key.TenantID,
+				Roles:    key.Roles,
+				Scopes:   key.Scopes,
+				Source:   SourceAPIKey,
+				APIKey:   &key,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// MemoryAPIKeyStore keeps API keys in process memory, e.g. loaded from
+// configuration at startup.
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]APIKey
+}
+
+func NewMemoryAPIKeyStore(keys ...APIKey) *MemoryAPIKeyStore {
+	s := &MemoryAPIKeyStore{keys: make(map[string]APIKey, len(keys))}
+	for _, k := range keys {
+		s.keys[k.Hash] = k
+	}
+	return s
+}
+
+// Add stores key, replacing any key with the same hash.
+func (s *MemoryAPIKeyStore) Add(key APIKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key.Hash] = key
+}
+
+func (s *MemoryAPIKeyStore) Lookup(_ context.Context, hash string) (APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[hash]
+	if !ok {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type failingKeyStore struct{}
+
+func (failingKeyStore) Lookup(context.Context, string) (APIKey, error) {
+	return APIKey{}, errors.New("db down")
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	live, liveHash, err := GenerateAPIKey("cp_live_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, expiredHash, _ := GenerateAPIKey("cp_live_")
+	revoked, revokedHash, _ := GenerateAPIKey("cp_live_")
+	store := NewMemoryAPIKeyStore(
+		APIKey{ID: "k1", Hash: liveHash, Owner: "svc-billing", Scopes: []string{"reviews:read"}},
+		APIKey{ID: "k2", Hash: expiredHash, Owner: "svc-old", ExpiresAt: time.Now().Add(-time.Minute)},
+		APIKey{ID: "k3", Hash: revokedHash, Owner: "svc-gone", Revoked: true},
+	)
+
+	var got *Identity
+	h := APIKeyMiddleware(APIKeyConfig{Store: store, Skipper: SkipProbes()})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = IdentityFromContext(r.Context())
+	}))
+	serve := func(path, key string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("/", live); code != http.StatusOK {
+		t.Fatalf("live key = %d", code)
+	}
+	if got.UserID != "svc-billing" || got.Source != SourceAPIKey || got.APIKey.ID != "k1" || !got.HasScope("reviews:read") {
+		t.Fatalf("identity = %+v", got)
+	}
+	for name, key := range map[string]string{"missing": "", "unknown": "cp_live_nope", "expired": expired, "revoked": revoked} {
+		if code := serve("/", key); code != http.StatusUnauthorized {
+			t.Errorf("%s key = %d, want 401", name, code)
+		}
+	}
+	if code := serve("/healthz", ""); code != http.StatusOK {
+		t.Errorf("skipped path = %d, want 200", code)
+	}
+
+	h = APIKeyMiddleware(APIKeyConfig{Store: failingKeyStore{}})(http.NotFoundHandler())
+	if code := serve("/", live); code != http.StatusServiceUnavailable {
+		t.Errorf("store failure = %d, want 503", code)
+	}
+}
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+// BindingMode is how FingerprintBinding treats a token presented by another
+// client.
+type BindingMode int
+
+const (
+	// BindingStrict rejects tokens whose fingerprint does not match the
+	// request, and tokens without one, including those minted by
+	// ExchangeToken.
+	BindingStrict BindingMode = iota
+	// BindingLenient rejects mismatches but accepts unbound tokens, e.g.
+	// while tokens issued before binding was enabled are still valid, or in
+	// services that also accept exchanged tokens.
+	BindingLenient
+	// BindingReport accepts every token and only calls OnMismatch, to
+	// measure false positives before enforcing.
+	BindingReport
+)
+
+const (
+	defaultBindingIPv4Prefix = 24
+	defaultBindingIPv6Prefix = 64
+)
+
+// FingerprintBinding binds access tokens to the client that obtained them: a
+// hash of its User-Agent and the network prefix of its address is issued as
+// the cfp claim, and RequireAuth compares it with the request presenting the
+// token. A token replayed from another network or browser is rejected with
+// CodeTokenBindingMismatch.
+//
+// Only tokens issued by this package's login and refresh handlers are bound.
+// Refreshing re-binds the token, so clients that change networks refresh
+// instead of logging in again.
+type FingerprintBinding struct {
+	// Mode is how mismatches and unbound tokens are handled. Default
+	// BindingStrict.
+	Mode BindingMode
+	// IPv4Prefix and IPv6Prefix are how many leading bits of the client
+	// address are bound. Default 24 and 64, so clients keep their tokens
+	// when their address changes within their provider's network. A
+	// negative value leaves the address out.
+	IPv4Prefix int
+	IPv6Prefix int
+	// ClientIP returns the client address of r. Default is the host of
+	// r.RemoteAddr; behind a proxy, read the address it forwards instead.
+	ClientIP func(r *http.Request) string
+	// OnMismatch, when set, is called for every token presented with a
+	// different fingerprint, in every mode.
+	OnMismatch func(r *http.Request, claims *AccessClaims)
+}
+
+// Fingerprint returns the fingerprint of the client sending r, as issued in
+// the cfp claim.
+func (b *FingerprintBinding) Fingerprint(r *http.Request) string {
+	h := sha256.New()
+	h.Write([]byte(r.UserAgent()))
+	h.Write([]byte{0})
+	h.Write([]byte(b.networkPrefix(r)))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16])
+}
+
+func (b *FingerprintBinding) networkPrefix(r *http.Request) string {
+	var raw string
+	if b.ClientIP != nil {
+		raw = b.ClientIP(r)
+	} else if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		raw = host
+	} else {
+		raw = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return raw
+	}
+	addr = addr.Unmap()
+	bits := b.IPv6Prefix
+	if bits == 0 {
+		bits = defaultBindingIPv6Prefix
+	}
+	if addr.Is4() {
+		bits = b.IPv4Prefix
+		if bits == 0 {
+			bits = defaultBindingIPv4Prefix
+		}
+	}
+	if bits < 0 {
+		return ""
+	}
+	prefix, err := addr.Prefix(min(bits, addr.BitLen()))
+	if err != nil {
+		return raw
+	}
+	return prefix.String()
+}
+
+// bind sets the fingerprint of r on user, if binding is enabled.
+func (b *FingerprintBinding) bind(user UserIdentity, r *http.Request) UserIdentity {
+	if b != nil {
+		user.Fingerprint = b.Fingerprint(r)
+	}
+	return user
+}
+
+// check compares the token's fingerprint with the client sending r.
+func (b *FingerprintBinding) check(r *http.Request, claims *AccessClaims) *authError {
+	if b == nil || b.Mode == BindingReport && b.OnMismatch == nil {
+		return nil
+	}
+	if claims.Fingerprint == "" {
+		if b.Mode == BindingStrict {
+			return &authError{http.StatusUnauthorized, CodeTokenBindingMismatch, "Token not bound to a client"}
+		}
+		return nil
+	}
+	if SecureCompare(claims.Fingerprint, b.Fingerprint(r)) {
+		return nil
+	}
+	if b.OnMismatch != nil {
+		b.OnMismatch(r, claims)
+	}
+	if b.Mode == BindingReport {
+		return nil
+	}
+	return &authError{http.StatusUnauthorized, CodeTokenBindingMismatch, "Token bound to another client"}
+}
@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func clientRequest(remoteAddr, userAgent string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("User-Agent", userAgent)
+	return req
+}
+
+func TestFingerprint(t *testing.T) {
+	b := &FingerprintBinding{}
+	home := b.Fingerprint(clientRequest("203.0.113.7:5000", "app/1.0"))
+
+	for _, tc := range []struct {
+		remoteAddr, userAgent string
+		same                  bool
+	}{
+		{"203.0.113.200:6000", "app/1.0", true},
+		{"[::ffff:203.0.113.9]:6000", "app/1.0", true},
+		{"198.51.100.7:5000", "app/1.0", false},
+		{"203.0.113.7:5000", "curl/8.0", false},
+	} {
+		got := b.Fingerprint(clientRequest(tc.remoteAddr, tc.userAgent))
+		if (got == home) != tc.same {
+			t.Errorf("%s %s: same fingerprint = %v, want %v", tc.remoteAddr, tc.userAgent, got == home, tc.same)
+		}
+	}
+
+	v6 := b.Fingerprint(clientRequest("[2001:db8:1:2::10]:443", "app/1.0"))
+	if v6 != b.Fingerprint(clientRequest("[2001:db8:1:2:ffff::1]:443", "app/1.0")) ||
+		v6 == b.Fingerprint(clientRequest("[2001:db8:1:3::10]:443", "app/1.0")) {
+		t.Error("IPv6 addresses not bound by /64")
+	}
+
+	uaOnly := &FingerprintBinding{IPv4Prefix: -1}
+	if uaOnly.Fingerprint(clientRequest("203.0.113.7:5000", "app/1.0")) != uaOnly.Fingerprint(clientRequest("198.51.100.7:5000", "app/1.0")) {
+		t.Error("negative prefix still binds the address")
+	}
+
+	proxied := &FingerprintBinding{ClientIP: func(r *http.Request) string { return r.Header.Get("X-Real-IP") }}
+	req := clientRequest("10.0.0.1:5000", "app/1.0")
+	req.Header.Set("X-Real-IP", "203.0.113.7")
+	if proxied.Fingerprint(req) != home {
+		t.Error("ClientIP not used")
+	}
+}
+
+func TestRequireAuthBinding(t *testing.T) {
+	binding := &FingerprintBinding{}
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute, Binding: binding}
+	home := clientRequest("203.0.113.7:5000", "app/1.0")
+	bound, _ := IssueAccessJWT(UserIdentity{UserID: "u1", Fingerprint: binding.Fingerprint(home)}, cfg)
+	unbound, _ := IssueAccessJWT(UserIdentity{UserID: "u1"}, cfg)
+
+	var mismatches int
+	binding.OnMismatch = func(_ *http.Request, claims *AccessClaims) {
+		if claims.Subject != "u1" {
+			t.Errorf("OnMismatch claims = %+v", claims)
+		}
+		mismatches++
+	}
+	h := RequireAuth(cfg, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	serve := func(token, remoteAddr string) *httptest.ResponseRecorder {
+		req := clientRequest(remoteAddr, "app/1.0")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		mode       BindingMode
+		token      string
+		remoteAddr string
+		want       int
+	}{
+		{BindingStrict, bound, "203.0.113.99:1", http.StatusOK},
+		{BindingStrict, bound, "198.51.100.7:1", http.StatusUnauthorized},
+		{BindingStrict, unbound, "203.0.113.7:1", http.StatusUnauthorized},
+		{BindingLenient, bound, "198.51.100.7:1", http.StatusUnauthorized},
+		{BindingLenient, unbound, "203.0.113.7:1", http.StatusOK},
+		{BindingReport, bound, "198.51.100.7:1", http.StatusOK},
+	} {
+		binding.Mode = tc.mode
+		rec := serve(tc.token, tc.remoteAddr)
+		if rec.Code != tc.want {
+			t.Errorf("mode %d from %s: status = %d, want %d", tc.mode, tc.remoteAddr, rec.Code, tc.want)
+		}
+		if rec.Code == http.StatusUnauthorized {
+			if e := decodeErrorBody(t, rec); e.Code != CodeTokenBindingMismatch {
+				t.Errorf("mode %d from %s: code = %s", tc.mode, tc.remoteAddr, e.Code)
+			}
+		}
+	}
+	if mismatches != 3 {
+		t.Errorf("OnMismatch called %d times, want 3", mismatches)
+	}
+}
+
+func TestRefreshHandlerBindsToken(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute, RefreshTokens: NewRefreshTokens(nil, time.Hour), Binding: &FingerprintBinding{}}
+	refresh, err := cfg.RefreshTokens.Issue(t.Context(), UserIdentity{UserID: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := postJSON(t, t.Context(), RefreshHandler(cfg), refreshRequest{RefreshToken: refresh})
+	var resp TokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("refresh = %d %s", rec.Code, rec.Body)
+	}
+	claims, err := ParseAccessJWT(resp.AccessToken, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// postJSON sends from httptest's default address and no User-Agent.
+	if want := cfg.Binding.Fingerprint(httptest.NewRequest(http.MethodPost, "/", nil)); claims.Fingerprint != want {
+		t.Errorf("cfp = %q, want %q", claims.Fingerprint, want)
+	}
+}
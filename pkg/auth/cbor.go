@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// A minimal CBOR (RFC 8949) decoder for WebAuthn attestation objects and
+// COSE keys. Authenticators emit CTAP2 canonical CBOR, so only definite
+// lengths and the types used there are supported: integers, byte and text
+// strings, arrays, maps, booleans and null.
+
+var errCBOR = errors.New("malformed CBOR")
+
+const cborMaxDepth = 16
+
+// decodeCBOR decodes the first CBOR item in b and returns it with the number
+// of bytes it took. Integers decode to int64, maps to map[any]any.
+func decodeCBOR(b []byte) (any, int, error) {
+	return decodeCBORItem(b, 0)
+}
+
+func decodeCBORItem(b []byte, depth int) (any, int, error) {
+	if depth > cborMaxDepth {
+		return nil, 0, fmt.Errorf("%w: nested too deep", errCBOR)
+	}
+	if len(b) == 0 {
+		return nil, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, 1, nil
+		case 21:
+			return true, 1, nil
+		case 22:
+			return nil, 1, nil
+		}
+		return nil, 0, fmt.Errorf("%w: unsupported simple value %d", errCBOR, info)
+	}
+	arg, n, err := cborArgument(b, info)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch major {
+	case 0:
+		if arg > 1<<63-1 {
+			return nil, 0, fmt.Errorf("%w: integer overflow", errCBOR)
+		}
+		return int64(arg), n, nil
+	case 1:
+		if arg > 1<<63-1 {
+			return nil, 0, fmt.Errorf("%w: integer overflow", errCBOR)
+		}
+		return -1 - int64(arg), n, nil
+	case 2, 3:
+		if arg > uint64(len(b)-n) {
+			return nil, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		end := n + int(arg)
+		if major == 3 {
+			return string(b[n:end]), end, nil
+		}
+		return append([]byte(nil), b[n:end]...), end, nil
+	case 4:
+		if arg > uint64(len(b)) {
+			return nil, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		items := make([]any, 0, arg)
+		for range arg {
+			item, m, err := decodeCBORItem(b[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, item)
+			n += m
+		}
+		return items, n, nil
+	case 5:
+		if arg > uint64(len(b)) {
+			return nil, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		m := make(map[any]any, arg)
+		for range arg {
+			key, k, err := decodeCBORItem(b[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += k
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, 0, fmt.Errorf("%w: unsupported map key %T", errCBOR, key)
+			}
+			if _, dup := m[key]; dup {
+				return nil, 0, fmt.Errorf("%w: duplicate map key %v", errCBOR, key)
+			}
+			value, v, err := decodeCBORItem(b[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			n += v
+			m[key] = value
+		}
+		return m, n, nil
+	}
+	return nil, 0, fmt.Errorf("%w: unsupported major type %d", errCBOR, major)
+}
+
+// cborArgument reads the argument encoded in info and the bytes following
+// the initial byte, returning it with the header length.
+func cborArgument(b []byte, info byte) (uint64, int, error) {
+	switch {
+	case info < 24:
+		return uint64(info), 1, nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(b) < 1+size {
+			return 0, 0, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		var arg uint64
+		switch size {
+		case 1:
+			arg = uint64(b[1])
+		case 2:
+			arg = uint64(binary.BigEndian.Uint16(b[1:]))
+		case 4:
+			arg = uint64(binary.BigEndian.Uint32(b[1:]))
+		case 8:
+			arg = binary.BigEndian.Uint64(b[1:])
+		}
+		return arg, 1 + size, nil
+	}
+	return 0, 0, fmt.Errorf("%w: indefinite or reserved length", errCBOR)
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrReservedClaim is returned when a custom claim would overwrite one
+	// of the claims the package sets itself, e.g. "sub" or "roles".
+	ErrReservedClaim  = errors.New("custom claim uses a reserved name")
+	ErrNoCustomClaims = errors.New("no custom claims")
+)
+
+// reservedClaims are the JSON names of AccessClaims' own fields.
+var reservedClaims = claimNames(reflect.TypeFor[AccessClaims]())
+
+func claimNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous {
+			for name := range claimNames(f.Type) {
+				names[name] = true
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// encodeCustomClaims turns the map or struct set as UserIdentity.Claims into
+// top-level token claims.
+func encodeCustomClaims(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode custom claims: %w", err)
+	}
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, fmt.Errorf("custom claims must encode to a JSON object, got %s", b)
+	}
+	for name := range claims {
+		if reservedClaims[name] {
+			return nil, fmt.Errorf("%w: %q", ErrReservedClaim, name)
+		}
+	}
+	if len(claims) == 0 {
+		return nil, nil
+	}
+	return claims, nil
+}
+
+// accessClaimsFields has AccessClaims' fields without its JSON methods.
+type accessClaimsFields AccessClaims
+
+func (c AccessClaims) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(accessClaimsFields(c))
+	if err != nil || len(c.Custom) == 0 {
+		return b, err
+	}
+	all := make(map[string]json.RawMessage, len(c.Custom)+8)
+	for name, v := range c.Custom {
+		all[name] = v
+	}
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	return json.Marshal(all)
+}
+
+func (c *AccessClaims) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, (*accessClaimsFields)(c)); err != nil {
+		return err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	c.Custom = nil
+	for name, v := range all {
+		if reservedClaims[name] {
+			continue
+		}
+		if c.Custom == nil {
+			c.Custom = make(map[string]json.RawMessage)
+		}
+		c.Custom[name] = v
+	}
+	return nil
+}
+
+// DecodeCustom decodes the custom claims into v, a pointer to a struct with
+// json tags or to a map. It returns ErrNoCustomClaims if the token has none.
+func (c *AccessClaims) DecodeCustom(v any) error {
+	if len(c.Custom) == 0 {
+		return ErrNoCustomClaims
+	}
+	b, err := json.Marshal(c.Custom)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// CustomClaimsFromContext decodes the custom claims of the request's access
+// token into a T:
+//
+//	type Plan struct {
+//		Plan  string `json:"plan"`
+//		Seats int    `json:"seats"`
+//	}
+//	plan, err := auth.CustomClaimsFromContext[Plan](r.Context())
+//
+// It fails if the request was not authenticated with a JWT or the token has
+// no custom claims.
+func CustomClaimsFromContext[T any](ctx context.Context) (T, error) {
+	var v T
+	id, ok := IdentityFromContext(ctx)
+	if !ok || id.Claims == nil {
+		return v, ErrNoCustomClaims
+	}
+	err := id.Claims.DecodeCustom(&v)
+	return v, err
+}
@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type planClaims struct {
+	Plan  string `json:"plan"`
+	Seats int    `json:"seats"`
+}
+
+func TestCustomClaimsRoundTrip(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute}
+	token, err := IssueAccessJWT(UserIdentity{
+		UserID:   "42",
+		TenantID: "acme",
+		Claims:   planClaims{Plan: "pro", Seats: 5},
+	}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got planClaims
+	var gotErr error
+	h := RequireAuth(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, gotErr = CustomClaimsFromContext[planClaims](r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotErr != nil || got != (planClaims{Plan: "pro", Seats: 5}) {
+		t.Fatalf("CustomClaimsFromContext() = %+v, %v", got, gotErr)
+	}
+
+	claims, err := ParseAccessJWT(token, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.TenantID != "acme" || len(claims.Custom) != 2 {
+		t.Fatalf("claims = %+v", claims)
+	}
+	var m map[string]any
+	if err := claims.DecodeCustom(&m); err != nil || m["plan"] != "pro" {
+		t.Fatalf("DecodeCustom() = %v, %v", m, err)
+	}
+}
+
+func TestCustomClaimsErrors(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute}
+
+	for _, reserved := range []string{"sub", "roles", "tenant", "exp"} {
+		_, err := IssueAccessJWT(UserIdentity{UserID: "42", Claims: map[string]any{reserved: "x"}}, cfg)
+		if !errors.Is(err, ErrReservedClaim) {
+			t.Errorf("claim %q: err = %v, want ErrReservedClaim", reserved, err)
+		}
+	}
+	if _, err := IssueAccessJWT(UserIdentity{UserID: "42", Claims: []string{"pro"}}, cfg); err == nil {
+		t.Error("non-object claims: expected error")
+	}
+
+	token, _ := IssueAccessJWT(UserIdentity{UserID: "42"}, cfg)
+	claims, _ := ParseAccessJWT(token, cfg)
+	if err := claims.DecodeCustom(&planClaims{}); !errors.Is(err, ErrNoCustomClaims) {
+		t.Fatalf("DecodeCustom() err = %v, want ErrNoCustomClaims", err)
+	}
+}
@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// SecureCompare reports whether a and b are equal, taking the same time
+// wherever they differ. Both are hashed first, so the time does not reveal
+// the length of the secret either. Use it for API keys, webhook secrets and
+// any other credential supplied by a caller; never compare those with ==.
+func SecureCompare(a, b string) bool {
+	return SecureCompareBytes([]byte(a), []byte(b))
+}
+
+// SecureCompareBytes is SecureCompare for byte slices.
+func SecureCompareBytes(a, b []byte) bool {
+	ha := sha256.Sum256(a)
+	hb := sha256.Sum256(b)
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// CheckHMACSHA256 reports whether signature is the hex-encoded HMAC-SHA256 of
+// message under key. Upper- and lower-case hex are accepted.
+func CheckHMACSHA256(key, message []byte, signature string) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(got, hmacSHA256(key, message))
+}
+
+func hmacSHA256(key, message []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
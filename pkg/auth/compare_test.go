@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+)
+
+func TestSecureCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"key_live_123", "key_live_123", true},
+		{"", "", true},
+		{"key_live_123", "key_live_124", false},
+		{"key_live_123", "key_live_12", false},
+		{"key_live_123", "", false},
+	}
+	for _, tt := range tests {
+		if got := SecureCompare(tt.a, tt.b); got != tt.want {
+			t.Errorf("SecureCompare(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHMACSHA256(t *testing.T) {
+	key, msg := []byte("webhook-secret"), []byte(`{"event":"ping"}`)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	if !CheckHMACSHA256(key, msg, sig) || !CheckHMACSHA256(key, msg, strings.ToUpper(sig)) {
+		t.Fatal("valid signature rejected")
+	}
+	for _, bad := range []string{"", "zz", sig[:len(sig)-2], sig[:len(sig)-1] + "0"} {
+		if CheckHMACSHA256(key, msg, bad) {
+			t.Errorf("CheckHMACSHA256 accepted %q", bad)
+		}
+	}
+}
+
+func TestValidateInitData(t *testing.T) {
+	const token = "123:bot-token"
+	now := time.Now()
+	payload := map[string]string{"user": `{"id":42,"first_name":"Ann"}`, "query_id": "q1"}
+	raw := "query_id=q1&user=" + `{"id":42,"first_name":"Ann"}` +
+		"&auth_date=" + strconv.FormatInt(now.Unix(), 10) + "&hash=" + initdata.Sign(payload, token, now)
+
+	if err := validateInitData(raw, token, time.Hour); err != nil {
+		t.Fatalf("valid init data: %v", err)
+	}
+	if err := validateInitData(raw, "other-token", time.Hour); !errors.Is(err, initdata.ErrSignInvalid) {
+		t.Fatalf("wrong token: err = %v", err)
+	}
+	tampered := strings.Replace(raw, "q1", "q2", 1)
+	if err := validateInitData(tampered, token, time.Hour); !errors.Is(err, initdata.ErrSignInvalid) {
+		t.Fatalf("tampered: err = %v", err)
+	}
+	if err := validateInitData("user=x", token, time.Hour); !errors.Is(err, initdata.ErrSignMissing) {
+		t.Fatalf("missing hash: err = %v", err)
+	}
+}
+
+func FuzzSecureCompare(f *testing.F) {
+	f.Add("secret", "secret")
+	f.Add("secret", "secreT")
+	f.Add("", "x")
+	f.Fuzz(func(t *testing.T, a, b string) {
+		if got := SecureCompare(a, b); got != (a == b) {
+			t.Fatalf("SecureCompare(%q, %q) = %v", a, b, got)
+		}
+	})
+}
+
+func FuzzCheckHMACSHA256(f *testing.F) {
+	f.Add([]byte("key"), []byte("message"), "00")
+	f.Fuzz(func(t *testing.T, key, msg []byte, sig string) {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(msg)
+		want := strings.EqualFold(sig, hex.EncodeToString(mac.Sum(nil)))
+		if got := CheckHMACSHA256(key, msg, sig); got != want {
+			t.Fatalf("CheckHMACSHA256(%x, %x, %q) = %v, want %v", key, msg, sig, got, want)
+		}
+	})
+}
+
+// TestSecureCompareTiming guards against a regression to an early-exit
+// comparison: a guess that is wrong in its first byte must take as long as
+// one that is wrong only in its last byte. Wall-clock ratios are noisy on
+// shared machines, so it only runs when AUTH_TIMING_TESTS is set.
+func TestSecureCompareTiming(t *testing.T) {
+	if os.Getenv("AUTH_TIMING_TESTS") == "" {
+		t.Skip("set AUTH_TIMING_TESTS=1 to run the timing test")
+	}
+	secret := strings.Repeat("k", 4096)
+	early := "x" + secret[1:]
+	late := secret[:len(secret)-1] + "x"
+
+	measure := func(guess string) time.Duration {
+		best := time.Duration(1<<63 - 1)
+		for round := 0; round < 5; round++ {
+			start := time.Now()
+			for i := 0; i < 2000; i++ {
+				SecureCompare(secret, guess)
+			}
+			best = min(best, time.Since(start))
+		}
+		return best
+	}
+	measure(secret) // warm up
+
+	e, l := measure(early), measure(late)
+	ratio := float64(max(e, l)) / float64(min(e, l))
+	if ratio > 1.5 {
+		t.Fatalf("comparison time depends on mismatch position: early %v, late %v", e, l)
+	}
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Source names the mechanism that authenticated a request.
+type Source string
+
+const (
+	SourceTelegram Source = "telegram"
+	SourceJWT      Source = "jwt"
+	SourceAPIKey   Source = "apikey"
+)
+
+// Identity is the authenticated caller, stored in the request context by
+// every auth middleware regardless of transport. Services should read it with
+// IdentityFromContext instead of depending on a specific middleware.
+type Identity struct {
+	UserID   string
+	TenantID string
+	Roles    []string
+	// Scopes are the OAuth-style scopes granted to the token, e.g.
+	// "reviews:read".
+	Scopes   []string
+	Features []string
+	Source   Source
+
+	// Telegram is set when Source is SourceTelegram.
+	Telegram *TelegramUser
+	// TelegramBotID is the bot the Telegram init data was signed for, when
+	// Source is SourceTelegram.
+	TelegramBotID int64
+	// Claims is set when Source is SourceJWT.
+	Claims *AccessClaims
+	// APIKey is set when Source is SourceAPIKey.
+	APIKey *APIKey
+}
+
+// HasRole reports whether the identity has role.
+func (i *Identity) HasRole(role string) bool {
+	return slices.Contains(i.Roles, role)
+}
+
+type ctxKey string
+
+const identityKey ctxKey = "identity"
+
+// WithIdentity stores id in ctx. A request an outer middleware skipped
+// counts as authenticated again, so authorization middlewares check id.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	if AuthSkipped(ctx) {
+		ctx = context.WithValue(ctx, skippedKey, false)
+	}
+	return context.WithValue(ctx, identityKey, id)
+}
+
+// IdentityFromContext returns the identity stored by WithIdentity or by one
+// of the auth middlewares.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey).(*Identity)
+	return id, ok && id != nil
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+)
+
+// identityOf serves req through mw and returns the context the handler saw.
+func identityOf(t *testing.T, mw func(http.Handler) http.Handler, req *http.Request) context.Context {
+	t.Helper()
+	var ctx context.Context
+	rec := httptest.NewRecorder()
+	mw(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) { ctx = r.Context() })).ServeHTTP(rec, req)
+	if ctx == nil {
+		t.Fatalf("request rejected: %d %s", rec.Code, rec.Body)
+	}
+	return ctx
+}
+
+func TestJWTIdentity(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute}
+	token, err := IssueAccessJWT(UserIdentity{UserID: "u1", TenantID: "t1", Roles: []string{"owner"}, Features: []string{"Beta"}}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	ctx := identityOf(t, func(next http.Handler) http.Handler { return RequireAuth(cfg, next) }, req)
+
+	id, ok := IdentityFromContext(ctx)
+	if !ok || id.Source != SourceJWT || id.UserID != "u1" || id.TenantID != "t1" || !id.HasRole("owner") || id.Claims == nil || id.Telegram != nil {
+		t.Fatalf("identity = %+v, %v", id, ok)
+	}
+	if userID, ok := GetUserIDFromContext(ctx); !ok || userID != "u1" {
+		t.Errorf("GetUserIDFromContext() = %q, %v", userID, ok)
+	}
+	if !HasFeature(ctx, "beta") || len(FeaturesFromContext(ctx)) != 1 {
+		t.Errorf("features = %v", FeaturesFromContext(ctx))
+	}
+	if _, ok := GetUserFromContext(ctx); ok {
+		t.Error("GetUserFromContext() found a Telegram user on a JWT request")
+	}
+}
+
+func TestTelegramIdentity(t *testing.T) {
+	const token = "123456:ABC"
+	now := time.Now()
+	user := `{"id":42,"first_name":"Ann","username":"ann"}`
+	raw := "user=" + url.QueryEscape(user) + "&auth_date=" + strconv.FormatInt(now.Unix(), 10) +
+		"&hash=" + initdata.Sign(map[string]string{"user": user}, token, now)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "tma "+raw)
+	ctx := identityOf(t, TelegramAuthMiddleware(token), req)
+
+	id, ok := IdentityFromContext(ctx)
+	if !ok || id.Source != SourceTelegram || id.UserID != "42" || id.TelegramBotID != 123456 || id.Claims != nil {
+		t.Fatalf("identity = %+v, %v", id, ok)
+	}
+	if tg, ok := GetUserFromContext(ctx); !ok || tg != id.Telegram || tg.ID != 42 || tg.Username != "ann" {
+		t.Errorf("GetUserFromContext() = %+v, %v", tg, ok)
+	}
+	if userID, ok := GetUserIDFromContext(ctx); !ok || userID != "42" {
+		t.Errorf("GetUserIDFromContext() = %q, %v", userID, ok)
+	}
+}
+
+func TestIdentityFromContextEmpty(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := IdentityFromContext(ctx); ok {
+		t.Error("IdentityFromContext() on an empty context")
+	}
+	if _, ok := IdentityFromContext(WithIdentity(ctx, nil)); ok {
+		t.Error("IdentityFromContext() returned a nil identity")
+	}
+	if _, ok := GetUserIDFromContext(WithIdentity(ctx, &Identity{Source: SourceAPIKey})); ok {
+		t.Error("GetUserIDFromContext() with an empty user ID")
+	}
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DefaultTokenCookieName is the cookie carrying the access token when
+// TokenCookie.Name is empty.
+const DefaultTokenCookieName = "access_token"
+
+// TokenCookie carries the access token of browser clients, which cannot keep
+// a bearer token out of reach of scripts. The cookie is HttpOnly and Secure.
+//
+// Cookies are sent by the browser on its own, so endpoints that change
+// state need CSRF protection beyond SameSite=Lax, e.g. a custom request
+// header or SameSite=Strict.
+type TokenCookie struct {
+	// Name defaults to DefaultTokenCookieName. A "__Host-" prefix makes
+	// browsers reject the cookie unless it is Secure, has Path "/" and no
+	// Domain.
+	Name   string
+	Domain string
+	// Path defaults to "/".
+	Path string
+	// SameSite defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+	// Insecure drops the Secure attribute, for local development over
+	// plain HTTP only.
+	Insecure bool
+}
+
+func (c *TokenCookie) name() string {
+	if c == nil || c.Name == "" {
+		return DefaultTokenCookieName
+	}
+	return c.Name
+}
+
+func (c *TokenCookie) cookie(value string, maxAge int) *http.Cookie {
+	if c == nil {
+		c = &TokenCookie{}
+	}
+	cookie := &http.Cookie{
+		Name:     c.name(),
+		Value:    value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   !c.Insecure,
+		SameSite: c.SameSite,
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteLaxMode
+	}
+	return cookie
+}
+
+// SetTokenCookie stores token in the cookie described by cfg.Cookie, expiring
+// with the token after cfg.AccessTTL. Call it on login; the exchange and
+// refresh handlers do so themselves when cfg.Cookie is set.
+func SetTokenCookie(w http.ResponseWriter, token string, cfg *JWTConfig) {
+	http.SetCookie(w, cfg.Cookie.cookie(token, int(cfg.AccessTTL.Seconds())))
+}
+
+// ClearTokenCookie removes the cookie set by SetTokenCookie. Call it on
+// logout; to also reject copies of the token until it expires, revoke it
+// with RevokeAccessJWT.
+func ClearTokenCookie(w http.ResponseWriter, cfg *JWTConfig) {
+	http.SetCookie(w, cfg.Cookie.cookie("", -1))
+}
+
+// accessToken returns the bearer token of r, taken from the Authorization
+// header or, when cfg.Cookie is set and the header is absent, from the token
+// cookie.
+func accessToken(r *http.Request, cfg *JWTConfig) string {
+	header := r.Header.Get("Authorization")
+	if header == "" && cfg.Cookie != nil {
+		if c, err := r.Cookie(cfg.Cookie.name()); err == nil {
+			return c.Value
+		}
+		return ""
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return ""
+	}
+	return token
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequireAuthTokenCookie(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute, Cookie: &TokenCookie{Name: "__Host-session"}}
+	token, _ := IssueAccessJWT(UserIdentity{UserID: "u1"}, cfg)
+
+	rec := httptest.NewRecorder()
+	SetTokenCookie(rec, token, cfg)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies = %v", cookies)
+	}
+	c := cookies[0]
+	if c.Name != "__Host-session" || c.Value != token || c.Path != "/" || c.MaxAge != 60 ||
+		!c.HttpOnly || !c.Secure || c.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie = %+v", c)
+	}
+
+	var userID string
+	h := RequireAuth(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ = GetUserIDFromContext(r.Context())
+	}))
+	serve := func(header string, cookie *http.Cookie) int {
+		userID = ""
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve("", c); code != http.StatusOK || userID != "u1" {
+		t.Fatalf("cookie auth = %d, user %q", code, userID)
+	}
+	if code := serve("Bearer bogus", c); code != http.StatusUnauthorized {
+		t.Errorf("header must win over the cookie, got %d", code)
+	}
+	if code := serve("", &http.Cookie{Name: "access_token", Value: token}); code != http.StatusUnauthorized {
+		t.Errorf("other cookie name accepted: %d", code)
+	}
+
+	cfg.Cookie = nil
+	if code := serve("", c); code != http.StatusUnauthorized {
+		t.Errorf("cookie accepted without JWTConfig.Cookie: %d", code)
+	}
+}
+
+func TestClearTokenCookie(t *testing.T) {
+	cfg := &JWTConfig{Cookie: &TokenCookie{Domain: "example.com", Path: "/app", SameSite: http.SameSiteStrictMode, Insecure: true}}
+	rec := httptest.NewRecorder()
+	ClearTokenCookie(rec, cfg)
+	c := rec.Result().Cookies()[0]
+	if c.Name != DefaultTokenCookieName || c.Value != "" || c.MaxAge != -1 || c.Domain != "example.com" ||
+		c.Path != "/app" || c.Secure || c.SameSite != http.SameSiteStrictMode {
+		t.Errorf("cookie = %+v", c)
+	}
+}
+
+func TestRefreshHandlerSetsTokenCookie(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute, RefreshTokens: NewRefreshTokens(nil, time.Hour), Cookie: &TokenCookie{}}
+	refresh, err := cfg.RefreshTokens.Issue(t.Context(), UserIdentity{UserID: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := postJSON(t, t.Context(), RefreshHandler(cfg), refreshRequest{RefreshToken: refresh})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh = %d %s", rec.Code, rec.Body)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != DefaultTokenCookieName {
+		t.Fatalf("cookies = %v", cookies)
+	}
+	if claims, err := ParseAccessJWT(cookies[0].Value, cfg); err != nil || claims.Subject != "u1" {
+		t.Errorf("cookie token: %+v, %v", claims, err)
+	}
+}
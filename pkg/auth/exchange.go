@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrEmptyAudience    = errors.New("audience cannot be empty")
+	ErrScopeNotAllowed  = errors.New("requested scope exceeds parent token")
+	ErrParentTokenSpent = errors.New("parent token expires too soon")
+)
+
+const defaultExchangeTTL = 5 * time.Minute
+
+// ActorClaims identifies the service acting on behalf of the subject (RFC 8693
+// "act"). Each exchange nests the previous actor, so the full call chain is
+// preserved.
+type ActorClaims struct {
+	Subject string       `json:"sub"`
+	Actor   *ActorClaims `json:"act,omitempty"`
+}
+
+// ExchangeToken validates parentToken and mints a shorter-lived token for
+// calling the downstream service identified by audience. The new token keeps
+// the parent's subject and features, records the caller (cfg.Audience, i.e.
+// the service the parent token was issued to) as the actor, and carries the
+// subject that started the chain in orig_sub.
+//
+// scope is a space-separated list. It must be a subset of the parent's
+// scopes, and an empty scope keeps the parent's. A parent without scopes
+// only yields tokens without scopes.
+func ExchangeToken(ctx context.Context, parentToken, audience, scope string, cfg *JWTConfig) (string, error) {
+	if audience == "" {
+		return "", ErrEmptyAudience
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	parent, err := parseAccessJWT(ctx, parentToken, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	allowed := strings.Fields(parent.Scope)
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	for _, s := range scopes {
+		if !slices.Contains(allowed, s) {
+			return "", fmt.Errorf("%w: %s", ErrScopeNotAllowed, s)
+		}
+	}
+
+	ttl := cfg.ExchangeTTL
+	if ttl <= 0 {
+		ttl = defaultExchangeTTL
+	}
+	now := time.Now()
+	expires := now.Add(ttl)
+	if parent.ExpiresAt != nil && parent.ExpiresAt.Before(expires) {
+		expires = parent.ExpiresAt.Time
+	}
+	if !expires.After(now) {
+		return "", ErrParentTokenSpent
+	}
+
+	original := parent.OriginalSubject
+	if original == "" {
+		original = parent.Subject
+	}
+
+	claims := AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   parent.Subject,
+			Issuer:    cfg.Issuer,
+			Audience:  []string{audience},
+			ExpiresAt: jwt.NewNumericDate(expires),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        generateTokenID(),
+		},
+		TenantID:        parent.TenantID,
+		Roles:           parent.Roles,
+		Features:        parent.Features,
+		Scope:           strings.Join(scopes, " "),
+		Actor:           &ActorClaims{Subject: cfg.Audience, Actor: parent.Actor},
+		OriginalSubject: original,
+		Custom:          parent.Custom,
+	}
+
+	return signToken(claims, cfg)
+}
@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// exchangeParent signs a gateway token for u1 expiring after ttl.
+func exchangeParent(t *testing.T, scope string, ttl time.Duration) string {
+	t.Helper()
+	now := time.Now()
+	token, err := signToken(AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "u1",
+			Audience:  []string{"gateway"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Roles:    []string{"admin"},
+		Features: []string{"beta-export"},
+		Scope:    scope,
+	}, &JWTConfig{SecretKey: []byte("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestExchangeTokenScope(t *testing.T) {
+	ctx := context.Background()
+	cfg := &JWTConfig{SecretKey: []byte("secret"), Audience: "gateway"}
+	scoped := exchangeParent(t, "reviews:read reviews:write", time.Hour)
+
+	for _, tc := range []struct {
+		name, parent, scope, want string
+		err                       error
+	}{
+		{"narrowed", scoped, "reviews:read", "reviews:read", nil},
+		{"empty keeps the parent's", scoped, "", "reviews:read reviews:write", nil},
+		{"widened", scoped, "reviews:read admin", "", ErrScopeNotAllowed},
+		{"unscoped parent", exchangeParent(t, "", time.Hour), "admin", "", ErrScopeNotAllowed},
+		{"unscoped parent, no scope", exchangeParent(t, "", time.Hour), "", "", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := ExchangeToken(ctx, tc.parent, "reviews", tc.scope, cfg)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("ExchangeToken() error = %v, want %v", err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			claims, err := ParseAccessJWT(token, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Scope != tc.want {
+				t.Errorf("scope = %q, want %q", claims.Scope, tc.want)
+			}
+		})
+	}
+}
+
+func TestExchangeTokenActorChain(t *testing.T) {
+	ctx := context.Background()
+	gateway := &JWTConfig{SecretKey: []byte("secret"), Audience: "gateway"}
+	reviews := &JWTConfig{SecretKey: []byte("secret"), Audience: "reviews"}
+
+	first, err := ExchangeToken(ctx, exchangeParent(t, "", time.Hour), "reviews", "", gateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ExchangeToken(ctx, first, "insights", "", reviews)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ParseAccessJWT(second, reviews)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "u1" || claims.OriginalSubject != "u1" {
+		t.Errorf("sub = %q, orig_sub = %q", claims.Subject, claims.OriginalSubject)
+	}
+	if a := claims.Actor; a == nil || a.Subject != "reviews" || a.Actor == nil || a.Actor.Subject != "gateway" || a.Actor.Actor != nil {
+		t.Errorf("act = %+v, want reviews acting for gateway", a)
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != "insights" {
+		t.Errorf("aud = %v", claims.Audience)
+	}
+	if len(claims.Roles) != 1 || len(claims.Features) != 1 {
+		t.Errorf("roles = %v, features = %v, want the parent's", claims.Roles, claims.Features)
+	}
+}
+
+func TestExchangeTokenTTL(t *testing.T) {
+	ctx := context.Background()
+	cfg := &JWTConfig{SecretKey: []byte("secret"), Audience: "gateway"}
+	expiry := func(token string) time.Duration {
+		t.Helper()
+		claims, err := ParseAccessJWT(token, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Until(claims.ExpiresAt.Time)
+	}
+
+	token, err := ExchangeToken(ctx, exchangeParent(t, "", time.Hour), "reviews", "", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := expiry(token); ttl > defaultExchangeTTL || ttl < defaultExchangeTTL-time.Minute {
+		t.Errorf("default TTL = %s, want %s", ttl, defaultExchangeTTL)
+	}
+
+	token, err = ExchangeToken(ctx, exchangeParent(t, "", 2*time.Minute), "reviews", "", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := expiry(token); ttl > 2*time.Minute {
+		t.Errorf("TTL = %s, outlives the parent token", ttl)
+	}
+
+	// Within the leeway the parent still parses, but has nothing left to
+	// hand on.
+	lenient := &JWTConfig{SecretKey: []byte("secret"), Audience: "gateway", Leeway: time.Minute}
+	if _, err := ExchangeToken(ctx, exchangeParent(t, "", -10*time.Second), "reviews", "", lenient); !errors.Is(err, ErrParentTokenSpent) {
+		t.Errorf("spent parent: err = %v, want ErrParentTokenSpent", err)
+	}
+}
+
+func TestExchangeTokenRejects(t *testing.T) {
+	ctx := context.Background()
+	cfg := &JWTConfig{SecretKey: []byte("secret"), Audience: "gateway"}
+	valid := exchangeParent(t, "", time.Hour)
+
+	for _, tc := range []struct {
+		name, parent, audience string
+		cfg                    *JWTConfig
+		want                   error
+	}{
+		{"expired", exchangeParent(t, "", -time.Minute), "reviews", cfg, jwt.ErrTokenExpired},
+		{"malformed", "not-a-token", "reviews", cfg, jwt.ErrTokenMalformed},
+		{"wrong key", valid, "reviews", &JWTConfig{SecretKey: []byte("other")}, jwt.ErrTokenSignatureInvalid},
+		{"no audience", valid, "", cfg, ErrEmptyAudience},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := ExchangeToken(ctx, tc.parent, tc.audience, "", tc.cfg)
+			if !errors.Is(err, tc.want) || token != "" {
+				t.Fatalf("ExchangeToken() = %q, %v, want %v", token, err, tc.want)
+			}
+		})
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := ExchangeToken(canceled, valid, "reviews", "", cfg); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled context: err = %v", err)
+	}
+}
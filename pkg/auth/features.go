@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// WithFeatures stores the caller's feature flags on the Identity in ctx,
+// creating one if needed. RequireAuth sets them from the access token.
+func WithFeatures(ctx context.Context, features []string) context.Context {
+	var id Identity
+	if cur, ok := IdentityFromContext(ctx); ok {
+		id = *cur
+	}
+	id.Features = normalizeFeatures(features)
+	return WithIdentity(ctx, &id)
+}
+
+func FeaturesFromContext(ctx context.Context) []string {
+	if id, ok := IdentityFromContext(ctx); ok {
+		return id.Features
+	}
+	return nil
+}
+
+// HasFeature reports whether the authenticated caller has flag enabled.
+// Flags are matched case-insensitively.
+func HasFeature(ctx context.Context, flag string) bool {
+	_, found := slices.BinarySearch(FeaturesFromContext(ctx), strings.ToLower(strings.TrimSpace(flag)))
+	return found
+}
+
+// RequireFeature rejects requests whose caller does not have flag enabled.
+// It must run after RequireAuth and passes requests RequireAuth skipped.
+func RequireFeature(flag string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !AuthSkipped(r.Context()) && !HasFeature(r.Context(), flag) {
+			writeError(w, r, http.StatusForbidden, CodeFeatureRequired, "Feature not enabled")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func normalizeFeatures(features []string) []string {
+	if len(features) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(features))
+	for _, f := range features {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			out = append(out, f)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
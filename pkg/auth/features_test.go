@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHasFeature(t *testing.T) {
+	ctx := WithFeatures(context.Background(), []string{" Beta-Export ", "ai-replies", "", "AI-Replies"})
+	for flag, want := range map[string]bool{
+		"beta-export":  true,
+		"BETA-EXPORT":  true,
+		" ai-replies ": true,
+		"insights":     false,
+		"":             false,
+	} {
+		if got := HasFeature(ctx, flag); got != want {
+			t.Errorf("HasFeature(%q) = %v, want %v", flag, got, want)
+		}
+	}
+	if got := FeaturesFromContext(ctx); len(got) != 2 {
+		t.Errorf("FeaturesFromContext() = %q, want two normalized flags", got)
+	}
+	if HasFeature(context.Background(), "beta-export") {
+		t.Error("HasFeature without an identity")
+	}
+
+	user := WithFeatures(WithIdentity(context.Background(), &Identity{UserID: "u1"}), []string{"beta-export"})
+	if id, ok := GetUserIDFromContext(user); !ok || id != "u1" || !HasFeature(user, "beta-export") {
+		t.Errorf("WithFeatures dropped the identity: %q %v", id, ok)
+	}
+}
+
+func TestRequireFeature(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute, Skipper: SkipPaths("/healthz")}
+	beta, _ := IssueAccessJWT(UserIdentity{UserID: "u1", Features: []string{"Beta-Export"}}, cfg)
+	plain, _ := IssueAccessJWT(UserIdentity{UserID: "u2"}, cfg)
+
+	var served int
+	h := RequireAuth(cfg, RequireFeature("beta-export", http.HandlerFunc(func(http.ResponseWriter, *http.Request) { served++ })))
+	serve := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("/export", beta); rec.Code != http.StatusOK {
+		t.Errorf("flag enabled: status = %d", rec.Code)
+	}
+	rec := serve("/export", plain)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("flag missing: status = %d, want 403", rec.Code)
+	} else if e := decodeErrorBody(t, rec); e.Code != CodeFeatureRequired {
+		t.Errorf("flag missing: code = %s", e.Code)
+	}
+	if rec := serve("/healthz", ""); rec.Code != http.StatusOK {
+		t.Errorf("skipped request: status = %d", rec.Code)
+	}
+	if served != 2 {
+		t.Errorf("handler served %d requests, want 2", served)
+	}
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The gRPC interceptors authenticate calls the way RequireAuth and
+// TelegramAuth authenticate requests: the credentials are read from the
+// "authorization" metadata, as "Bearer <jwt>" or "tma <init-data>", and the
+// Identity is stored in the call's context for IdentityFromContext and the
+// other accessors. A Skipper sees the full method name as the URL path, so
+// SkipPaths("/grpc.health.v1.Health/Check") skips health checks.
+//
+// Rejected calls fail with codes.Unauthenticated, PermissionDenied or
+// Unavailable; the status message starts with the ErrorCode, e.g.
+// "token_expired: Token expired".
+
+// UnaryJWTInterceptor is RequireAuth for unary gRPC calls.
+func UnaryJWTInterceptor(cfg *JWTConfig) grpc.UnaryServerInterceptor {
+	return unaryInterceptor(cfg.Skipper, cfg.authenticate)
+}
+
+// StreamJWTInterceptor is RequireAuth for streaming gRPC calls.
+func StreamJWTInterceptor(cfg *JWTConfig) grpc.StreamServerInterceptor {
+	return streamInterceptor(cfg.Skipper, cfg.authenticate)
+}
+
+// UnaryTelegramInterceptor is TelegramAuth for unary gRPC calls.
+func UnaryTelegramInterceptor(cfg TelegramConfig) grpc.UnaryServerInterceptor {
+	return unaryInterceptor(cfg.Skipper, cfg.authenticate)
+}
+
+// StreamTelegramInterceptor is TelegramAuth for streaming gRPC calls.
+func StreamTelegramInterceptor(cfg TelegramConfig) grpc.StreamServerInterceptor {
+	return streamInterceptor(cfg.Skipper, cfg.authenticate)
+}
+
+type authenticator func(r *http.Request) (*Identity, *authError)
+
+func unaryInterceptor(skipper Skipper, authenticate authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticateCall(ctx, info.FullMethod, skipper, authenticate)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamInterceptor(skipper Skipper, authenticate authenticator) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateCall(ss.Context(), info.FullMethod, skipper, authenticate)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &identityStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// identityStream is a ServerStream with the authenticated context.
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticateCall runs authenticate on a request standing in for the call,
+// and returns the call's context with the identity.
+func authenticateCall(ctx context.Context, fullMethod string, skipper Skipper, authenticate authenticator) (context.Context, error) {
+	r, skipped := skip(skipper, grpcRequest(ctx, fullMethod))
+	if skipped {
+		return r.Context(), nil
+	}
+	identity, aerr := authenticate(r)
+	if aerr != nil {
+		return nil, aerr.grpcStatus()
+	}
+	return WithIdentity(ctx, identity), nil
+}
+
+// grpcRequest describes a gRPC call as the HTTP/2 request it travels in:
+// a POST to the full method name from the peer's address, with the metadata
+// as headers.
+func grpcRequest(ctx context.Context, fullMethod string) *http.Request {
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := make(http.Header, len(md))
+	for k, v := range md {
+		header[http.CanonicalHeaderKey(k)] = v
+	}
+	r := &http.Request{
+		Method:     http.MethodPost,
+		URL:        &url.URL{Path: fullMethod},
+		Proto:      "HTTP/2.0",
+		ProtoMajor: 2,
+		Header:     header,
+		RequestURI: fullMethod,
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+	}
+	return r.WithContext(ctx)
+}
+
+func (e *authError) grpcStatus() error {
+	code := codes.Internal
+	switch e.status {
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	}
+	return status.Error(code, e.Error())
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeServerStream) Context() context.Context { return s.ctx }
+
+func TestUnaryJWTInterceptor(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute, Skipper: SkipPaths("/grpc.health.v1.Health/Check")}
+	token, _ := IssueAccessJWT(UserIdentity{UserID: "u1", Roles: []string{"admin"}}, cfg)
+	expired, _ := IssueAccessJWT(UserIdentity{UserID: "u1"}, &JWTConfig{SecretKey: []byte("secret"), AccessTTL: -time.Minute})
+	interceptor := UnaryJWTInterceptor(cfg)
+
+	call := func(method string, md metadata.MD) (*Identity, error) {
+		var id *Identity
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, _ any) (any, error) {
+			id, _ = IdentityFromContext(ctx)
+			if AuthSkipped(ctx) {
+				id = &Identity{Source: "skipped"}
+			}
+			return nil, nil
+		})
+		return id, err
+	}
+
+	id, err := call("/reviews.v1.Reviews/List", metadata.Pairs("authorization", "Bearer "+token))
+	if err != nil || id == nil || id.UserID != "u1" || id.Source != SourceJWT || !id.HasRole("admin") {
+		t.Fatalf("identity = %+v, err = %v", id, err)
+	}
+
+	for _, tt := range []struct {
+		md   metadata.MD
+		code codes.Code
+		want ErrorCode
+	}{
+		{nil, codes.Unauthenticated, CodeMissingCredentials},
+		{metadata.Pairs("authorization", "Bearer "+expired), codes.Unauthenticated, CodeTokenExpired},
+		{metadata.Pairs("authorization", "Bearer garbage"), codes.Unauthenticated, CodeInvalidToken},
+	} {
+		_, err := call("/reviews.v1.Reviews/List", tt.md)
+		if st, _ := status.FromError(err); st.Code() != tt.code || !strings.HasPrefix(st.Message(), string(tt.want)+": ") {
+			t.Errorf("%v: status = %v", tt.md, st)
+		}
+	}
+
+	if id, err := call("/grpc.health.v1.Health/Check", nil); err != nil || id == nil || id.Source != "skipped" {
+		t.Errorf("skipped method: %+v, %v", id, err)
+	}
+}
+
+func TestStreamTelegramInterceptor(t *testing.T) {
+	const token = "111:bot-token"
+	now := time.Now()
+	user := `{"id":42,"first_name":"Ann"}`
+	raw := "user=" + url.QueryEscape(user) + "&auth_date=" + strconv.FormatInt(now.Unix(), 10) +
+		"&hash=" + initdata.Sign(map[string]string{"user": user}, token, now)
+	interceptor := StreamTelegramInterceptor(TelegramConfig{BotToken: token})
+
+	call := func(md metadata.MD) (*Identity, error) {
+		var id *Identity
+		ss := fakeServerStream{ctx: metadata.NewIncomingContext(context.Background(), md)}
+		err := interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: "/chat.v1.Chat/Stream"}, func(_ any, ss grpc.ServerStream) error {
+			id, _ = IdentityFromContext(ss.Context())
+			return nil
+		})
+		return id, err
+	}
+
+	id, err := call(metadata.Pairs("authorization", "tma "+raw))
+	if err != nil || id == nil || id.UserID != "42" || id.TelegramBotID != 111 {
+		t.Fatalf("identity = %+v, err = %v", id, err)
+	}
+	if u, ok := GetUserFromContext(WithIdentity(context.Background(), id)); !ok || u.FirstName != "Ann" {
+		t.Errorf("telegram user = %+v", u)
+	}
+
+	_, err = call(metadata.Pairs("authorization", "tma "+strings.Replace(raw, "Ann", "Bob", 1)))
+	if st, _ := status.FromError(err); st.Code() != codes.Unauthenticated || !strings.HasPrefix(st.Message(), string(CodeInvalidInitData)) {
+		t.Errorf("tampered init data: %v", st)
+	}
+}
@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v5"
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+)
+
+// ErrorCode is the machine-readable reason of an auth failure, sent in
+// error responses next to the HTTP status.
+type ErrorCode string
+
+const (
+	// Generic codes, one per status.
+	CodeUnauthorized     ErrorCode = "unauthorized"
+	CodeForbidden        ErrorCode = "forbidden"
+	CodeBadRequest       ErrorCode = "bad_request"
+	CodeMethodNotAllowed ErrorCode = "method_not_allowed"
+	CodeRequestTooLarge  ErrorCode = "request_too_large"
+	CodeUnavailable      ErrorCode = "unavailable"
+	CodeInternal         ErrorCode = "internal_error"
+
+	// 401: the caller has to (re)authenticate.
+	CodeMissingCredentials   ErrorCode = "missing_credentials"
+	CodeInvalidToken         ErrorCode = "invalid_token"
+	CodeTokenExpired         ErrorCode = "token_expired"
+	CodeTokenRevoked         ErrorCode = "token_revoked"
+	CodeTokenBindingMismatch ErrorCode = "token_binding_mismatch"
+	CodeInvalidInitData      ErrorCode = "invalid_init_data"
+	CodeInitDataExpired      ErrorCode = "init_data_expired"
+	CodeInvalidSignature     ErrorCode = "invalid_signature"
+
+	// 403: the caller is known but not allowed.
+	CodeIdentityRejected ErrorCode = "identity_rejected"
+	CodeFeatureRequired  ErrorCode = "feature_required"
+	CodeNotChatMember    ErrorCode = "not_chat_member"
+	CodeBotNotAllowed    ErrorCode = "bot_not_allowed"
+)
+
+// ErrorResponse is an auth failure about to be sent to the client.
+type ErrorResponse struct {
+	Status  int       `json:"-"`
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// ErrorRenderer writes e as the response to r.
+type ErrorRenderer func(w http.ResponseWriter, r *http.Request, e ErrorResponse)
+
+var errorRenderer atomic.Pointer[ErrorRenderer]
+
+// SetErrorRenderer replaces how every middleware and handler of this package
+// writes failures, e.g. to match a service's own error envelope. Nil
+// restores JSONErrors. Call it during startup.
+func SetErrorRenderer(render ErrorRenderer) {
+	if render == nil {
+		errorRenderer.Store(nil)
+		return
+	}
+	errorRenderer.Store(&render)
+}
+
+// JSONErrors is the default ErrorRenderer. It writes
+//
+//	{"error": {"code": "token_expired", "message": "Token expired"}}
+func JSONErrors(w http.ResponseWriter, _ *http.Request, e ErrorResponse) {
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "no-store")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(struct {
+		Error ErrorResponse `json:"error"`
+	}{e})
+}
+
+// PlainTextErrors writes only the message as text/plain, the way
+// http.Error does.
+func PlainTextErrors(w http.ResponseWriter, _ *http.Request, e ErrorResponse) {
+	http.Error(w, e.Message, e.Status)
+}
+
+// allowPost answers requests other than POST with 405.
+func allowPost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodPost {
+		return true
+	}
+	w.Header().Set("Allow", http.MethodPost)
+	writeError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "")
+	return false
+}
+
+// authError is a rejected request. The HTTP middlewares write it with
+// writeError; the gRPC interceptors turn it into a status.
+type authError struct {
+	status  int
+	code    ErrorCode
+	message string
+}
+
+func (e *authError) Error() string {
+	return string(e.code) + ": " + e.message
+}
+
+func (e *authError) write(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, e.status, e.code, e.message)
+}
+
+// tokenError rejects a bearer token that failed validation.
+func tokenError(err error) *authError {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return &authError{http.StatusUnauthorized, CodeTokenExpired, "Token expired"}
+	case errors.Is(err, ErrTokenRevoked):
+		return &authError{http.StatusUnauthorized, CodeTokenRevoked, "Token revoked"}
+	default:
+		return &authError{http.StatusUnauthorized, CodeInvalidToken, "Invalid token"}
+	}
+}
+
+// initDataError rejects Telegram init data or Login Widget data that failed
+// validation.
+func initDataError(err error) *authError {
+	if errors.Is(err, initdata.ErrExpired) {
+		return &authError{http.StatusUnauthorized, CodeInitDataExpired, "Init data expired"}
+	}
+	return &authError{http.StatusUnauthorized, CodeInvalidInitData, "Invalid init data: " + err.Error()}
+}
+
+// writeError renders a failure with the configured ErrorRenderer. An empty
+// message becomes the status text.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code ErrorCode, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	render := JSONErrors
+	if p := errorRenderer.Load(); p != nil {
+		render = *p
+	}
+	render(w, r, ErrorResponse{Status: status, Code: code, Message: message})
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeErrorBody(t *testing.T, rec *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+	var body struct {
+		Error ErrorResponse `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q: %v", rec.Body, err)
+	}
+	return body.Error
+}
+
+func TestRequireAuthErrorCodes(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute}
+	expiredCfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: -time.Minute}
+	valid, _ := IssueAccessJWT(UserIdentity{UserID: "u1"}, cfg)
+	expired, _ := IssueAccessJWT(UserIdentity{UserID: "u1"}, expiredCfg)
+	h := RequireAuth(cfg, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	tests := []struct {
+		header string
+		want   ErrorCode
+	}{
+		{"", CodeMissingCredentials},
+		{"Bearer ", CodeMissingCredentials},
+		{"Bearer " + valid + "x", CodeInvalidToken},
+		{"Bearer " + expired, CodeTokenExpired},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", tt.header)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%q: status = %d, want 401", tt.header, rec.Code)
+		}
+		if e := decodeErrorBody(t, rec); e.Code != tt.want || e.Message == "" {
+			t.Errorf("%q: error = %+v, want code %s", tt.header, e, tt.want)
+		}
+	}
+}
+
+func TestSetErrorRenderer(t *testing.T) {
+	h := RequireRole("admin")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(WithIdentity(req.Context(), &Identity{UserID: "u1", Roles: []string{"viewer"}}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if e := decodeErrorBody(t, serve()); e.Code != CodeForbidden || e.Message != "Forbidden" {
+		t.Fatalf("default error = %+v", e)
+	}
+
+	SetErrorRenderer(PlainTextErrors)
+	rec := serve()
+	if rec.Code != http.StatusForbidden || strings.TrimSpace(rec.Body.String()) != "Forbidden" {
+		t.Fatalf("plain text = %d %q", rec.Code, rec.Body)
+	}
+
+	var got ErrorResponse
+	SetErrorRenderer(func(w http.ResponseWriter, _ *http.Request, e ErrorResponse) {
+		got = e
+		w.WriteHeader(http.StatusTeapot)
+	})
+	if rec := serve(); rec.Code != http.StatusTeapot || got.Status != http.StatusForbidden || got.Code != CodeForbidden {
+		t.Fatalf("custom renderer: %d, got %+v", rec.Code, got)
+	}
+
+	SetErrorRenderer(nil)
+	if e := decodeErrorBody(t, serve()); e.Code != CodeForbidden {
+		t.Fatalf("after reset = %+v", e)
+	}
+}
@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrIdentityRejected is returned by an IdentityResolver when the user is
+// known but must not be given a token (banned, disabled, ...).
+var ErrIdentityRejected = errors.New("identity rejected")
+
+// IdentityResolver maps an authenticated Telegram user to the internal
+// account, creating the account if it does not exist yet.
+type IdentityResolver interface {
+	ResolveTelegramUser(ctx context.Context, user *TelegramUser) (UserIdentity, error)
+}
+
+type IdentityResolverFunc func(ctx context.Context, user *TelegramUser) (UserIdentity, error)
+
+func (f IdentityResolverFunc) ResolveTelegramUser(ctx context.Context, user *TelegramUser) (UserIdentity, error) {
+	return f(ctx, user)
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	// RefreshToken is set when JWTConfig.RefreshTokens is configured.
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// TelegramExchangeHandler exchanges Telegram initData (Authorization: tma ...)
+// for an access JWT, plus a refresh token if cfg.RefreshTokens is set. The
+// resolver decides which internal account the token is issued for.
+func TelegramExchangeHandler(botToken string, resolver IdentityResolver, cfg *JWTConfig) http.Handler {
+	return TelegramExchange(TelegramConfig{BotToken: botToken}, resolver, cfg)
+}
+
+// TelegramExchange is TelegramExchangeHandler with the init data checked as
+// configured by tg, e.g. by Telegram's signature rather than a bot token.
+func TelegramExchange(tg TelegramConfig, resolver IdentityResolver, cfg *JWTConfig) http.Handler {
+	exchange := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowPost(w, r) {
+			return
+		}
+
+		user, ok := GetUserFromContext(r.Context())
+		if !ok {
+			writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "")
+			return
+		}
+
+		identity, err := resolver.ResolveTelegramUser(r.Context(), user)
+		respondWithTokens(w, r, identity, err, cfg)
+	})
+
+	return TelegramAuth(tg)(exchange)
+}
+
+// respondWithTokens answers a login with the tokens for identity, as
+// resolved by an identity resolver that returned err.
+func respondWithTokens(w http.ResponseWriter, r *http.Request, identity UserIdentity, err error, cfg *JWTConfig) {
+	if err != nil {
+		if errors.Is(err, ErrIdentityRejected) {
+			writeError(w, r, http.StatusForbidden, CodeIdentityRejected, "")
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "")
+		return
+	}
+	if identity.UserID == "" {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "")
+		return
+	}
+
+	token, err := IssueAccessJWT(cfg.Binding.bind(identity, r), cfg)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, CodeInternal, "")
+		return
+	}
+
+	resp := TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(cfg.AccessTTL.Seconds()),
+	}
+	if cfg.RefreshTokens != nil {
+		if resp.RefreshToken, err = cfg.RefreshTokens.Issue(r.Context(), identity); err != nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "")
+			return
+		}
+	}
+	if cfg.Cookie != nil {
+		SetTokenCookie(w, token, cfg)
+	}
+	writeTokenResponse(w, resp)
+}
+
+func writeTokenResponse(w http.ResponseWriter, resp TokenResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+)
+
+func TestTelegramExchangeHandler(t *testing.T) {
+	const token = "123456:ABC"
+	now := time.Now()
+	signed := func(id int64) string {
+		user := `{"id":` + strconv.FormatInt(id, 10) + `,"first_name":"Ann"}`
+		return "user=" + url.QueryEscape(user) + "&auth_date=" + strconv.FormatInt(now.Unix(), 10) +
+			"&hash=" + initdata.Sign(map[string]string{"user": user}, token, now)
+	}
+
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute}
+	resolver := IdentityResolverFunc(func(_ context.Context, u *TelegramUser) (UserIdentity, error) {
+		switch u.ID {
+		case 13:
+			return UserIdentity{}, ErrIdentityRejected
+		case 99:
+			return UserIdentity{}, errors.New("database down")
+		}
+		return UserIdentity{UserID: "u-" + strconv.FormatInt(u.ID, 10), Roles: []string{"owner"}}, nil
+	})
+	h := TelegramExchangeHandler(token, resolver, cfg)
+	serve := func(method, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/auth/telegram", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodPost, "tma "+signed(42))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("exchange: %d %s", rec.Code, rec.Body)
+	}
+	var resp TokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.TokenType != "Bearer" || resp.ExpiresIn != 60 {
+		t.Fatalf("response = %+v, %v", resp, err)
+	}
+	claims, err := ParseAccessJWT(resp.AccessToken, cfg)
+	if err != nil || claims.Subject != "u-42" || len(claims.Roles) != 1 {
+		t.Fatalf("claims = %+v, %v", claims, err)
+	}
+
+	for _, tc := range []struct {
+		name, method, authorization string
+		want                        int
+		code                        ErrorCode
+	}{
+		{"GET", http.MethodGet, "tma " + signed(42), http.StatusMethodNotAllowed, CodeMethodNotAllowed},
+		{"no init data", http.MethodPost, "", http.StatusUnauthorized, CodeMissingCredentials},
+		{"bad signature", http.MethodPost, "tma " + signed(42) + "0", http.StatusUnauthorized, CodeInvalidInitData},
+		{"truncated", http.MethodPost, "tma " + signed(42)[:20], http.StatusUnauthorized, CodeInvalidInitData},
+		{"rejected", http.MethodPost, "tma " + signed(13), http.StatusForbidden, CodeIdentityRejected},
+		{"resolver error", http.MethodPost, "tma " + signed(99), http.StatusInternalServerError, CodeInternal},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(tc.method, tc.authorization)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+			if e := decodeErrorBody(t, rec); e.Code != tc.code {
+				t.Errorf("code = %s, want %s", e.Code, tc.code)
+			}
+		})
+	}
+	if rec := serve(http.MethodGet, "tma "+signed(42)); rec.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("Allow = %q", rec.Header().Get("Allow"))
+	}
+}
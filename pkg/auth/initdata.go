@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+)
+
+var (
+	// TelegramPublicKey is the Ed25519 key Telegram signs Mini App init
+	// data with for third parties, in the "signature" field.
+	TelegramPublicKey = mustPublicKey("e7bf03a2fa4602af4580703d88dda5bb59f32ed8b02a56c187fe7d34caed242d")
+	// TelegramTestPublicKey is TelegramPublicKey for Telegram's test
+	// environment.
+	TelegramTestPublicKey = mustPublicKey("40055058a4ee38156a06562e52eece92a771bcd8346a8c4615cb7376eddf72ec")
+)
+
+func mustPublicKey(h string) ed25519.PublicKey {
+	b, err := hex.DecodeString(h)
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		panic("auth: invalid Ed25519 public key " + h)
+	}
+	return b
+}
+
+// validateInitData checks the signature and age of Telegram Mini App init
+// data like initdata.Validate, but compares the hash in constant time. It
+// returns the initdata package errors.
+func validateInitData(raw, botToken string, expIn time.Duration) error {
+	q, pairs, err := initDataPairs(raw, "hash")
+	if err != nil {
+		return err
+	}
+	hash := q.Get("hash")
+	if hash == "" {
+		return initdata.ErrSignMissing
+	}
+	if err := checkAuthDate(q, expIn); err != nil {
+		return err
+	}
+
+	secret := hmacSHA256([]byte("WebAppData"), []byte(botToken))
+	if !CheckHMACSHA256(secret, []byte(strings.Join(pairs, "\n")), hash) {
+		return initdata.ErrSignInvalid
+	}
+	return nil
+}
+
+// validateThirdPartyInitData checks init data by the Ed25519 signature
+// Telegram adds for third parties, which needs only the ID of the bot the
+// Mini App belongs to and Telegram's public key, not the bot token. It
+// returns the initdata package errors.
+func validateThirdPartyInitData(raw string, botID int64, publicKey ed25519.PublicKey, expIn time.Duration) error {
+	q, pairs, err := initDataPairs(raw, "hash", "signature")
+	if err != nil {
+		return err
+	}
+	// Telegram leaves out the base64 padding.
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(q.Get("signature"), "="))
+	if err != nil || len(signature) == 0 {
+		return initdata.ErrSignMissing
+	}
+	if err := checkAuthDate(q, expIn); err != nil {
+		return err
+	}
+
+	msg := strconv.FormatInt(botID, 10) + ":WebAppData\n" + strings.Join(pairs, "\n")
+	if botID == 0 || !ed25519.Verify(publicKey, []byte(msg), signature) {
+		return initdata.ErrSignInvalid
+	}
+	return nil
+}
+
+// initDataPairs parses raw init data and returns its sorted "key=value"
+// pairs without the keys in unsigned.
+func initDataPairs(raw string, unsigned ...string) (url.Values, []string, error) {
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse init data as query: %w: %w", err, initdata.ErrUnexpectedFormat)
+	}
+	pairs := make([]string, 0, len(q))
+	for k, v := range q {
+		if !slices.Contains(unsigned, k) {
+			pairs = append(pairs, k+"="+v[0])
+		}
+	}
+	sort.Strings(pairs)
+	return q, pairs, nil
+}
+
+// checkAuthDate rejects init data older than expIn; zero disables the
+// check.
+func checkAuthDate(q url.Values, expIn time.Duration) error {
+	if expIn <= 0 {
+		return nil
+	}
+	if q.Get("auth_date") == "" {
+		return initdata.ErrAuthDateMissing
+	}
+	sec, err := strconv.ParseInt(q.Get("auth_date"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse auth_date: %w: %w", err, initdata.ErrAuthDateInvalid)
+	}
+	if time.Unix(sec, 0).Add(expIn).Before(time.Now()) {
+		return initdata.ErrExpired
+	}
+	return nil
+}
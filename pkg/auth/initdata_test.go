@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+)
+
+// signThirdParty adds the Ed25519 signature Telegram puts on init data for
+// third parties, made with key instead of Telegram's.
+func signThirdParty(q url.Values, botID int64, key ed25519.PrivateKey) string {
+	var pairs []string
+	for k := range q {
+		pairs = append(pairs, k+"="+q.Get(k))
+	}
+	sort.Strings(pairs)
+	msg := strconv.FormatInt(botID, 10) + ":WebAppData\n" + strings.Join(pairs, "\n")
+	q.Set("signature", base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(msg))))
+	q.Set("hash", "0000") // signed by the bot token, which third parties cannot check
+	return q.Encode()
+}
+
+func TestValidateThirdPartyInitData(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	const botID = 7342037359
+	raw := signThirdParty(url.Values{
+		"user":      {`{"id":42,"first_name":"Ann"}`},
+		"auth_date": {strconv.FormatInt(time.Now().Unix(), 10)},
+	}, botID, priv)
+
+	if err := validateThirdPartyInitData(raw, botID, pub, time.Hour); err != nil {
+		t.Fatalf("valid init data: %v", err)
+	}
+	if err := validateThirdPartyInitData(raw, botID+1, pub, time.Hour); !errors.Is(err, initdata.ErrSignInvalid) {
+		t.Fatalf("other bot: err = %v", err)
+	}
+	if err := validateThirdPartyInitData(raw, botID, TelegramPublicKey, time.Hour); !errors.Is(err, initdata.ErrSignInvalid) {
+		t.Fatalf("other key: err = %v", err)
+	}
+	tampered := strings.Replace(raw, "Ann", "Bob", 1)
+	if err := validateThirdPartyInitData(tampered, botID, pub, time.Hour); !errors.Is(err, initdata.ErrSignInvalid) {
+		t.Fatalf("tampered: err = %v", err)
+	}
+	if err := validateThirdPartyInitData("user=x&hash=00", botID, pub, time.Hour); !errors.Is(err, initdata.ErrSignMissing) {
+		t.Fatalf("missing signature: err = %v", err)
+	}
+
+	old := signThirdParty(url.Values{"auth_date": {strconv.FormatInt(time.Now().Add(-2*time.Hour).Unix(), 10)}}, botID, priv)
+	if err := validateThirdPartyInitData(old, botID, pub, time.Hour); !errors.Is(err, initdata.ErrExpired) {
+		t.Fatalf("expired: err = %v", err)
+	}
+}
+
+func TestTelegramAuthWithoutBotToken(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	const botID = 7342037359
+	raw := signThirdParty(url.Values{
+		"user":      {`{"id":42,"first_name":"Ann"}`},
+		"auth_date": {strconv.FormatInt(time.Now().Unix(), 10)},
+	}, botID, priv)
+
+	var got *TelegramUser
+	h := TelegramAuth(TelegramConfig{BotID: botID, PublicKey: pub})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = GetUserFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "tma "+raw)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || got == nil || got.ID != 42 {
+		t.Fatalf("got %d, user %+v", rec.Code, got)
+	}
+
+	// Telegram's own key does not accept our test signature.
+	h = TelegramAuth(TelegramConfig{BotID: botID})(http.NotFoundHandler())
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("default key: got %d, want 401", rec.Code)
+	}
+}
+
+func TestTelegramAuthMultipleBots(t *testing.T) {
+	const staging, regional = "111:staging-token", "222:regional-token"
+	now := time.Now()
+	signed := func(token string) string {
+		user := `{"id":42,"first_name":"Ann"}`
+		return "user=" + url.QueryEscape(user) + "&auth_date=" + strconv.FormatInt(now.Unix(), 10) +
+			"&hash=" + initdata.Sign(map[string]string{"user": user}, token, now)
+	}
+	serve := func(mw func(http.Handler) http.Handler, path, raw string) (int, *Identity) {
+		var id *Identity
+		h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, _ = IdentityFromContext(r.Context())
+		}))
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "tma "+raw)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code, id
+	}
+
+	mw := TelegramAuthMiddleware(staging, regional)
+	for _, tc := range []struct {
+		token string
+		bot   int64
+	}{{staging, 111}, {regional, 222}} {
+		code, id := serve(mw, "/", signed(tc.token))
+		if code != http.StatusOK || id.TelegramBotID != tc.bot {
+			t.Fatalf("token %s: %d, identity %+v", tc.token, code, id)
+		}
+	}
+	if code, _ := serve(mw, "/", signed("333:other")); code != http.StatusUnauthorized {
+		t.Fatalf("unknown bot: %d, want 401", code)
+	}
+
+	byPath := TelegramAuth(TelegramConfig{ResolveBot: func(r *http.Request) (TelegramBot, error) {
+		switch r.URL.Path {
+		case "/staging":
+			return TelegramBot{Token: staging}, nil
+		case "/regional":
+			return TelegramBot{Token: regional}, nil
+		}
+		return TelegramBot{}, errors.New("unknown bot")
+	}})
+	if code, _ := serve(byPath, "/regional", signed(regional)); code != http.StatusOK {
+		t.Fatalf("resolved bot: %d", code)
+	}
+	if code, _ := serve(byPath, "/staging", signed(regional)); code != http.StatusUnauthorized {
+		t.Fatalf("other resolved bot: %d, want 401", code)
+	}
+	if code, _ := serve(byPath, "/", signed(regional)); code != http.StatusUnauthorized {
+		t.Fatalf("unresolved bot: %d, want 401", code)
+	}
+}
+
+func TestTelegramAuthMaxAge(t *testing.T) {
+	const token = "111:bot-token"
+	signedAt := func(at time.Time) string {
+		user := `{"id":42,"first_name":"Ann"}`
+		return "user=" + url.QueryEscape(user) + "&auth_date=" + strconv.FormatInt(at.Unix(), 10) +
+			"&hash=" + initdata.Sign(map[string]string{"user": user}, token, at)
+	}
+	serve := func(cfg TelegramConfig, raw string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "tma "+raw)
+		rec := httptest.NewRecorder()
+		TelegramAuth(cfg)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	halfHour := signedAt(time.Now().Add(-30 * time.Minute))
+	twoDays := signedAt(time.Now().Add(-48 * time.Hour))
+	tests := []struct {
+		name   string
+		maxAge time.Duration
+		raw    string
+		want   int
+	}{
+		{"default accepts 30m", 0, halfHour, http.StatusOK},
+		{"default rejects 48h", 0, twoDays, http.StatusUnauthorized},
+		{"10m rejects 30m", 10 * time.Minute, halfHour, http.StatusUnauthorized},
+		{"disabled accepts 48h", -1, twoDays, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := serve(TelegramConfig{BotToken: token, MaxAge: tt.maxAge}, tt.raw); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
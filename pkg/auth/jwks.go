@@ -0,0 +1,267 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKeyID = errors.New("unknown key id")
+	ErrJWKSFetch    = errors.New("jwks fetch failed")
+)
+
+const maxJWKSBody = 1 << 20
+
+type JWKSConfig struct {
+	// URL serves the key set, e.g. an OIDC provider's jwks_uri.
+	URL string
+	// RefreshInterval is how long a fetched set is used before it is
+	// fetched again. Default 1h.
+	RefreshInterval time.Duration
+	// MinRefreshInterval limits refetches triggered by tokens with an
+	// unknown kid, so garbage tokens cannot hammer the provider. Default 1m.
+	MinRefreshInterval time.Duration
+	HTTPClient         *http.Client
+}
+
+// JWKS is a cached JSON Web Key Set. Keys are refetched when the set is older
+// than RefreshInterval or a token names a kid the cache does not know, which
+// picks up rotated keys without a restart. If a refetch fails the cached keys
+// keep working.
+type JWKS struct {
+	cfg    JWKSConfig
+	client *http.Client
+	now    func() time.Time
+
+	fetchMu sync.Mutex // one fetch at a time
+
+	mu          sync.Mutex
+	keys        map[string]jwkKey
+	fetched     time.Time
+	lastAttempt time.Time
+}
+
+type jwkKey struct {
+	key crypto.PublicKey
+	alg string // empty when the JWK does not pin one
+}
+
+func NewJWKS(cfg JWKSConfig) *JWKS {
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = time.Hour
+	}
+	if cfg.MinRefreshInterval <= 0 {
+		cfg.MinRefreshInterval = time.Minute
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &JWKS{cfg: cfg, client: client, now: time.Now}
+}
+
+// Refresh fetches the key set now. On failure the cached keys are kept.
+func (s *JWKS) Refresh(ctx context.Context) error {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+	return s.refreshLocked(ctx)
+}
+
+func (s *JWKS) refreshLocked(ctx context.Context) error {
+	s.mu.Lock()
+	s.lastAttempt = s.now()
+	s.mu.Unlock()
+
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.fetched = s.now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *JWKS) fetch(ctx context.Context) (map[string]jwkKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJWKSFetch, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJWKSFetch, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrJWKSFetch, resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSBody)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("%w: decode: %v", ErrJWKSFetch, err)
+	}
+	keys := make(map[string]jwkKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			// Skip keys of types we do not verify with rather than
+			// rejecting the whole set.
+			continue
+		}
+		keys[k.Kid] = jwkKey{key: pub, alg: k.Alg}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no usable signing keys", ErrJWKSFetch)
+	}
+	return keys, nil
+}
+
+// lookup returns the key for kid, refetching the set when it is stale or
+// does not know kid.
+func (s *JWKS) lookup(ctx context.Context, kid string) (jwkKey, error) {
+	key, found, stale, _ := s.cached(kid)
+	if found && !stale {
+		return key, nil
+	}
+
+	s.fetchMu.Lock()
+	// Another caller may have refreshed while we waited.
+	key, found, stale, attempted := s.cached(kid)
+	var err error
+	if (!found || stale) && s.now().Sub(attempted) >= s.cfg.MinRefreshInterval {
+		err = s.refreshLocked(ctx)
+		key, found, _, _ = s.cached(kid)
+	}
+	s.fetchMu.Unlock()
+
+	if found {
+		return key, nil
+	}
+	if err != nil {
+		return jwkKey{}, err
+	}
+	return jwkKey{}, fmt.Errorf("%w: %q", ErrUnknownKeyID, kid)
+}
+
+// cached looks kid up without fetching. An empty kid matches the only key
+// of a single-key set.
+func (s *JWKS) cached(kid string) (key jwkKey, found, stale bool, lastAttempt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, found = s.keys[kid]
+	if !found && kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			key, found = k, true
+		}
+	}
+	stale = s.fetched.IsZero() || s.now().Sub(s.fetched) >= s.cfg.RefreshInterval
+	return key, found, stale, s.lastAttempt
+}
+
+// keyfunc resolves the verification key of a token by its kid header,
+// fetching the set under ctx when needed.
+func (s *JWKS) keyfunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		k, err := s.lookup(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if k.alg != "" && k.alg != token.Method.Alg() {
+			return nil, fmt.Errorf("key %q is for %s, token is signed with %s", kid, k.alg, token.Method.Alg())
+		}
+		return k.key, nil
+	}
+}
+
+// jsonWebKey is the subset of RFC 7517 used for RSA and P-256 signing keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		// crypto/ecdh rejects points that are not on the curve.
+		point := make([]byte, 65)
+		point[0] = 4
+		if len(x.Bytes()) > 32 || len(y.Bytes()) > 32 {
+			return nil, errors.New("invalid EC point")
+		}
+		x.FillBytes(point[1:33])
+		y.FillBytes(point[33:])
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing key parameter")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type jwksServer struct {
+	mu      sync.Mutex
+	keys    []jsonWebKey
+	fetches int
+	fail    bool
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetches++
+	if s.fail {
+		http.Error(w, "down", http.StatusBadGateway)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+}
+
+func (s *jwksServer) set(keys ...jsonWebKey) {
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+}
+
+func b64Int(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+
+func rsaJWK(kid string, k *rsa.PrivateKey) jsonWebKey {
+	return jsonWebKey{Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256", N: b64Int(k.N), E: b64Int(big.NewInt(int64(k.E)))}
+}
+
+func ecJWK(kid string, k *ecdsa.PrivateKey) jsonWebKey {
+	return jsonWebKey{Kty: "EC", Kid: kid, Crv: "P-256", X: b64Int(k.X), Y: b64Int(k.Y)}
+}
+
+func TestJWKSValidatesAndRotates(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	keys := &jwksServer{}
+	keys.set(rsaJWK("2024-01", oldKey))
+	server := httptest.NewServer(keys)
+	defer server.Close()
+
+	jwks := NewJWKS(JWKSConfig{URL: server.URL, MinRefreshInterval: time.Millisecond})
+	verifier := &JWTConfig{JWKS: jwks}
+
+	oldToken, err := IssueAccessJWT(UserIdentity{UserID: "1"},
+		&JWTConfig{AccessTTL: time.Minute, SigningMethod: SigningMethodRS256, PrivateKey: oldKey, KeyID: "2024-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateAccessJWT(oldToken, verifier); err != nil {
+		t.Fatalf("old key: %v", err)
+	}
+
+	// The provider starts signing with a new key published next to the old one.
+	keys.set(rsaJWK("2024-01", oldKey), ecJWK("2024-02", newKey))
+	newToken, err := IssueAccessJWT(UserIdentity{UserID: "2"},
+		&JWTConfig{AccessTTL: time.Minute, SigningMethod: SigningMethodES256, PrivateKey: newKey, KeyID: "2024-02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, err := ValidateAccessJWT(newToken, verifier); err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+	if _, err := ValidateAccessJWT(oldToken, verifier); err != nil {
+		t.Fatalf("old key after rotation: %v", err)
+	}
+	if keys.fetches != 2 {
+		t.Errorf("fetches = %d, want 2", keys.fetches)
+	}
+}
+
+func TestJWKSThrottlesUnknownKid(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := &jwksServer{}
+	keys.set(rsaJWK("a", key))
+	server := httptest.NewServer(keys)
+	defer server.Close()
+
+	verifier := &JWTConfig{JWKS: NewJWKS(JWKSConfig{URL: server.URL})}
+	forged, _ := IssueAccessJWT(UserIdentity{UserID: "1"},
+		&JWTConfig{AccessTTL: time.Minute, SigningMethod: SigningMethodRS256, PrivateKey: key, KeyID: "unknown"})
+	for range 5 {
+		if _, err := ValidateAccessJWT(forged, verifier); !errors.Is(err, ErrUnknownKeyID) {
+			t.Fatalf("err = %v, want ErrUnknownKeyID", err)
+		}
+	}
+	if keys.fetches != 1 {
+		t.Errorf("fetches = %d, want 1", keys.fetches)
+	}
+}
+
+func TestJWKSKeepsKeysWhenRefreshFails(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := &jwksServer{}
+	keys.set(rsaJWK("a", key))
+	server := httptest.NewServer(keys)
+	defer server.Close()
+
+	jwks := NewJWKS(JWKSConfig{URL: server.URL, RefreshInterval: time.Millisecond, MinRefreshInterval: time.Millisecond})
+	verifier := &JWTConfig{JWKS: jwks}
+	token, _ := IssueAccessJWT(UserIdentity{UserID: "1"},
+		&JWTConfig{AccessTTL: time.Minute, SigningMethod: SigningMethodRS256, PrivateKey: key, KeyID: "a"})
+	if _, err := ValidateAccessJWT(token, verifier); err != nil {
+		t.Fatal(err)
+	}
+
+	keys.mu.Lock()
+	keys.fail = true
+	keys.mu.Unlock()
+	time.Sleep(2 * time.Millisecond)
+	if _, err := ValidateAccessJWT(token, verifier); err != nil {
+		t.Fatalf("stale keys not used after failed refresh: %v", err)
+	}
+}
+
+func TestJWKSFetchUsesRequestContext(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := &jwksServer{}
+	keys.set(rsaJWK("a", key))
+	server := httptest.NewServer(keys)
+	defer server.Close()
+
+	verifier := &JWTConfig{JWKS: NewJWKS(JWKSConfig{URL: server.URL})}
+	token, _ := IssueAccessJWT(UserIdentity{UserID: "1"},
+		&JWTConfig{AccessTTL: time.Minute, SigningMethod: SigningMethodRS256, PrivateKey: key, KeyID: "a"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := parseAccessJWT(ctx, token, verifier); !errors.Is(err, ErrJWKSFetch) {
+		t.Fatalf("err = %v, want ErrJWKSFetch", err)
+	}
+	if keys.fetches != 0 {
+		t.Errorf("fetches = %d, want 0 with a cancelled context", keys.fetches)
+	}
+}
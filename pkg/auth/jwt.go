@@ -4,11 +4,14 @@ package auth
 
 import (
 	"context"
+	"crypto"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,93 +23,287 @@ type JWTConfig struct {
 	Audience  string
 	AccessTTL time.Duration
 	SecretKey []byte // HS256 key
+
+	// SigningMethod is "HS256" (default), "RS256" or "ES256". Tokens signed
+	// with any other algorithm are rejected.
+	SigningMethod string
+	// PrivateKey signs RS256 (*rsa.PrivateKey) or ES256 (*ecdsa.PrivateKey,
+	// P-256) tokens. Services that only validate leave it nil.
+	PrivateKey crypto.Signer
+	// PublicKey verifies RS256 or ES256 tokens. Defaults to the public half
+	// of PrivateKey.
+	PublicKey crypto.PublicKey
+	// JWKS, when set, verifies tokens with the key named by their kid header
+	// instead of PublicKey. RS256 and ES256 are accepted unless
+	// SigningMethod narrows it to one.
+	JWKS *JWKS
+	// KeyID is set as the kid header of issued tokens, so validators using a
+	// JWKS find the key.
+	KeyID string
+
+	// IssuerMatch and AudienceMatch enforce the iss and aud claims of
+	// parsed tokens, so tokens of another environment signed with a shared
+	// key are rejected. Both default to MatchNone.
+	IssuerMatch   ClaimMatch
+	AudienceMatch ClaimMatch
+	// ValidIssuers and ValidAudiences are accepted next to Issuer and
+	// Audience under MatchAnyOf, e.g. while migrating to a new issuer.
+	ValidIssuers   []string
+	ValidAudiences []string
+
+	// Leeway tolerates clock skew between the issuer and this service when
+	// checking exp and nbf, and turns on the iat check with the same
+	// tolerance. Zero allows no skew and leaves iat unchecked.
+	Leeway time.Duration
+
+	// ExchangeTTL caps the lifetime of tokens minted by ExchangeToken.
+	// Default 5m; never longer than the parent token.
+	ExchangeTTL time.Duration
+
+	// RefreshTokens, when set, makes TelegramExchangeHandler also return a
+	// refresh token, redeemed with RefreshHandler.
+	RefreshTokens *RefreshTokens
+
+	// Revocations, when set, is checked for the jti of every token parsed,
+	// so stolen tokens can be invalidated before they expire.
+	Revocations RevocationStore
+
+	// Skipper lets matching requests through RequireAuth unauthenticated.
+	Skipper Skipper
+
+	// Cookie, when set, makes RequireAuth accept the access token from this
+	// cookie when no Authorization header is sent, and the exchange and
+	// refresh handlers set it along with their JSON response.
+	Cookie *TokenCookie
+
+	// Binding, when set, binds tokens issued by the login and refresh
+	// handlers to the client's fingerprint, checked by RequireAuth.
+	Binding *FingerprintBinding
 }
 
+// ClaimMatch is how ParseAccessJWT checks the iss or aud claim.
+type ClaimMatch int
+
+const (
+	// MatchNone accepts any value, as tokens were checked before
+	// IssuerMatch and AudienceMatch existed.
+	MatchNone ClaimMatch = iota
+	// MatchExact requires iss to equal Issuer, or aud to contain Audience.
+	MatchExact
+	// MatchAnyOf requires iss to be Issuer or one of ValidIssuers, or aud to
+	// contain Audience or one of ValidAudiences.
+	MatchAnyOf
+)
+
+// ErrClaimMatchConfig is returned for every token when IssuerMatch or
+// AudienceMatch is set without a value to match.
+var ErrClaimMatchConfig = errors.New("issuer or audience match configured without values")
+
 type UserIdentity struct {
-	UserID string
+	UserID   string
+	TenantID string
+	Roles    []string
+	Scopes   []string // issued as the space-separated scope claim
+	Features []string // feature flags embedded into the token at issuance
+	// Claims are extra top-level claims, given as a map or a struct with
+	// json tags, e.g. the customer's plan. Read them back with
+	// CustomClaimsFromContext.
+	Claims any
+	// Fingerprint binds the token to a client, see FingerprintBinding. The
+	// handlers set it from the request when JWTConfig.Binding is set.
+	Fingerprint string
 }
 
-type jwtCtxKey string
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	TenantID string   `json:"tenant,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Features []string `json:"features,omitempty"`
 
-const (
-	TokenLength = 16
+	// Scope is the space-separated list of granted scopes.
+	Scope string `json:"scope,omitempty"`
 
-	jwtUserKey jwtCtxKey = "user_id"
-)
+	// Set on tokens minted by ExchangeToken.
+	Actor           *ActorClaims `json:"act,omitempty"`
+	OriginalSubject string       `json:"orig_sub,omitempty"`
+
+	// Fingerprint is set on tokens bound to a client, see
+	// FingerprintBinding.
+	Fingerprint string `json:"cfp,omitempty"`
+
+	// Custom holds the claims not listed above, set from
+	// UserIdentity.Claims at issuance.
+	Custom map[string]json.RawMessage `json:"-"`
+}
+
+const TokenLength = 16
 
 func IssueAccessJWT(user UserIdentity, cfg *JWTConfig) (string, error) {
-	if len(cfg.SecretKey) == 0 {
-		return "", errors.New("secret key cannot be empty")
+	custom, err := encodeCustomClaims(user.Claims)
+	if err != nil {
+		return "", err
 	}
-
 	now := time.Now()
-	claims := jwt.RegisteredClaims{
-		Subject:   user.UserID,
-		Issuer:    cfg.Issuer,
-		Audience:  []string{cfg.Audience},
-		ExpiresAt: jwt.NewNumericDate(now.Add(cfg.AccessTTL)),
-		IssuedAt:  jwt.NewNumericDate(now),
-		ID:        generateTokenID(),
+	claims := AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.UserID,
+			Issuer:    cfg.Issuer,
+			Audience:  []string{cfg.Audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(cfg.AccessTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        generateTokenID(),
+		},
+		TenantID:    user.TenantID,
+		Roles:       user.Roles,
+		Scope:       strings.Join(normalizeScopes(user.Scopes), " "),
+		Features:    normalizeFeatures(user.Features),
+		Fingerprint: user.Fingerprint,
+		Custom:      custom,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(cfg.SecretKey)
+	return signToken(claims, cfg)
 }
 
 func ValidateAccessJWT(tokenString string, cfg *JWTConfig) (userID string, err error) {
-	if len(cfg.SecretKey) == 0 {
-		return "", errors.New("secret key cannot be empty")
+	claims, err := ParseAccessJWT(tokenString, cfg)
+	if err != nil {
+		return "", err
 	}
+	return claims.Subject, nil
+}
 
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return cfg.SecretKey, nil
-	})
+// ParseAccessJWT verifies tokenString and returns its claims. Tokens revoked
+// in cfg.Revocations are rejected with ErrTokenRevoked.
+func ParseAccessJWT(tokenString string, cfg *JWTConfig) (*AccessClaims, error) {
+	return parseAccessJWT(context.Background(), tokenString, cfg)
+}
 
+func parseAccessJWT(ctx context.Context, tokenString string, cfg *JWTConfig) (*AccessClaims, error) {
+	keyfunc, methods, err := cfg.keyfunc(ctx)
 	if err != nil {
-		return "", fmt.Errorf("invalid token: %w", err)
+		return nil, err
 	}
 
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods)}
+	if leeway := cfg.leeway(); leeway > 0 {
+		opts = append(opts, jwt.WithLeeway(leeway), jwt.WithIssuedAt())
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &AccessClaims{}, keyfunc, opts...)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	claims, ok := token.Claims.(*AccessClaims)
 	if !ok || !token.Valid {
-		return "", errors.New("invalid token claims")
+		return nil, errors.New("invalid token claims")
+	}
+	if err := cfg.checkIssuerAudience(claims); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if err := cfg.checkRevoked(ctx, claims); err != nil {
+		return nil, err
 	}
 
-	return claims.Subject, nil
+	return claims, nil
 }
 
-func RequireAuth(cfg *JWTConfig, next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
+// checkIssuerAudience enforces IssuerMatch and AudienceMatch.
+func (cfg *JWTConfig) checkIssuerAudience(claims *AccessClaims) error {
+	if cfg.IssuerMatch != MatchNone {
+		accepted := claimValues(cfg.IssuerMatch, cfg.Issuer, cfg.ValidIssuers)
+		if len(accepted) == 0 {
+			return ErrClaimMatchConfig
+		}
+		if !slices.Contains(accepted, claims.Issuer) {
+			return jwt.ErrTokenInvalidIssuer
+		}
+	}
+	if cfg.AudienceMatch != MatchNone {
+		accepted := claimValues(cfg.AudienceMatch, cfg.Audience, cfg.ValidAudiences)
+		if len(accepted) == 0 {
+			return ErrClaimMatchConfig
+		}
+		if !slices.ContainsFunc(claims.Audience, func(aud string) bool { return slices.Contains(accepted, aud) }) {
+			return jwt.ErrTokenInvalidAudience
+		}
+	}
+	return nil
+}
 
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+// claimValues returns the non-empty values accepted under match.
+func claimValues(match ClaimMatch, value string, valid []string) []string {
+	var accepted []string
+	if value != "" {
+		accepted = append(accepted, value)
+	}
+	if match == MatchAnyOf {
+		for _, v := range valid {
+			if v != "" {
+				accepted = append(accepted, v)
+			}
 		}
+	}
+	return accepted
+}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+func (cfg *JWTConfig) leeway() time.Duration {
+	return max(cfg.Leeway, 0)
+}
 
-		if tokenString == "" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+func RequireAuth(cfg *JWTConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r, skipped := skip(cfg.Skipper, r); skipped {
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		userID, err := ValidateAccessJWT(tokenString, cfg)
-		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		identity, aerr := cfg.authenticate(r)
+		if aerr != nil {
+			aerr.write(w, r)
 			return
 		}
-
-		ctx := context.WithValue(r.Context(), jwtUserKey, userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
 	})
 }
 
+// authenticate checks the access token of r.
+func (cfg *JWTConfig) authenticate(r *http.Request) (*Identity, *authError) {
+	tokenString := accessToken(r, cfg)
+	if tokenString == "" {
+		return nil, &authError{http.StatusUnauthorized, CodeMissingCredentials, "Bearer token required"}
+	}
+
+	claims, err := parseAccessJWT(r.Context(), tokenString, cfg)
+	if errors.Is(err, ErrRevocationLookup) {
+		return nil, &authError{http.StatusServiceUnavailable, CodeUnavailable, "Token revocation check failed"}
+	}
+	if err != nil {
+		return nil, tokenError(err)
+	}
+	if aerr := cfg.Binding.check(r, claims); aerr != nil {
+		return nil, aerr
+	}
+
+	return &Identity{
+		UserID:   claims.Subject,
+		TenantID: claims.TenantID,
+		Roles:    claims.Roles,
+		Scopes:   strings.Fields(claims.Scope),
+		Features: normalizeFeatures(claims.Features),
+		Source:   SourceJWT,
+		Claims:   claims,
+	}, nil
+}
+
+// GetUserIDFromContext returns the user ID of the authenticated identity,
+// whichever middleware stored it.
 func GetUserIDFromContext(ctx context.Context) (string, bool) {
-	userID, ok := ctx.Value(jwtUserKey).(string)
-	return userID, ok
+	id, ok := IdentityFromContext(ctx)
+	if !ok || id.UserID == "" {
+		return "", false
+	}
+	return id.UserID, true
 }
 
 func generateTokenID() string {
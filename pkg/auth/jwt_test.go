@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestParseAccessJWTLeeway(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *jwt.NumericDate { return jwt.NewNumericDate(now.Add(d)) }
+
+	for _, tc := range []struct {
+		name   string
+		claims jwt.RegisteredClaims
+		leeway time.Duration
+		want   error
+	}{
+		{"expired, no leeway", jwt.RegisteredClaims{ExpiresAt: at(-10 * time.Second)}, 0, jwt.ErrTokenExpired},
+		{"expired within leeway", jwt.RegisteredClaims{ExpiresAt: at(-10 * time.Second)}, 30 * time.Second, nil},
+		{"expired beyond leeway", jwt.RegisteredClaims{ExpiresAt: at(-time.Minute)}, 30 * time.Second, jwt.ErrTokenExpired},
+		{"iat unchecked without leeway", jwt.RegisteredClaims{IssuedAt: at(time.Minute)}, 0, nil},
+		{"issued in the future within leeway", jwt.RegisteredClaims{IssuedAt: at(10 * time.Second)}, 30 * time.Second, nil},
+		{"issued in the future", jwt.RegisteredClaims{IssuedAt: at(time.Minute)}, 30 * time.Second, jwt.ErrTokenUsedBeforeIssued},
+		{"not yet valid within leeway", jwt.RegisteredClaims{NotBefore: at(10 * time.Second)}, 30 * time.Second, nil},
+		{"not yet valid", jwt.RegisteredClaims{NotBefore: at(10 * time.Second)}, 0, jwt.ErrTokenNotValidYet},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &JWTConfig{SecretKey: []byte("secret"), Leeway: tc.leeway}
+			tc.claims.Subject = "u1"
+			token, err := signToken(AccessClaims{RegisteredClaims: tc.claims}, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := ParseAccessJWT(token, cfg)
+			if tc.want == nil && (err != nil || claims.Subject != "u1") {
+				t.Fatalf("ParseAccessJWT() = %+v, %v", claims, err)
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Fatalf("ParseAccessJWT() error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseAccessJWTIssuerAudience(t *testing.T) {
+	issue := func(iss string, aud ...string) string {
+		token, err := signToken(AccessClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "u1", Issuer: iss, Audience: aud}}, &JWTConfig{SecretKey: []byte("secret")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	prod := issue("auth.prod", "reviews")
+	staging := issue("auth.staging", "reviews")
+	other := issue("auth.prod", "billing", "insights")
+
+	for _, tc := range []struct {
+		name  string
+		cfg   JWTConfig
+		token string
+		want  error
+	}{
+		{"not enforced", JWTConfig{Issuer: "auth.prod", Audience: "reviews"}, staging, nil},
+		{"exact issuer", JWTConfig{Issuer: "auth.prod", IssuerMatch: MatchExact}, prod, nil},
+		{"wrong issuer", JWTConfig{Issuer: "auth.prod", IssuerMatch: MatchExact}, staging, jwt.ErrTokenInvalidIssuer},
+		{"exact ignores ValidIssuers", JWTConfig{Issuer: "auth.prod", ValidIssuers: []string{"auth.staging"}, IssuerMatch: MatchExact}, staging, jwt.ErrTokenInvalidIssuer},
+		{"issuer in set", JWTConfig{Issuer: "auth.prod", ValidIssuers: []string{"auth.staging"}, IssuerMatch: MatchAnyOf}, staging, nil},
+		{"exact audience", JWTConfig{Audience: "reviews", AudienceMatch: MatchExact}, prod, nil},
+		{"wrong audience", JWTConfig{Audience: "reviews", AudienceMatch: MatchExact}, other, jwt.ErrTokenInvalidAudience},
+		{"audience in set", JWTConfig{ValidAudiences: []string{"exports", "insights"}, AudienceMatch: MatchAnyOf}, other, nil},
+		{"no issuer to match", JWTConfig{IssuerMatch: MatchExact}, prod, ErrClaimMatchConfig},
+		{"no audience to match", JWTConfig{AudienceMatch: MatchAnyOf}, prod, ErrClaimMatchConfig},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.SecretKey = []byte("secret")
+			claims, err := ParseAccessJWT(tc.token, &tc.cfg)
+			if tc.want == nil && (err != nil || claims.Subject != "u1") {
+				t.Fatalf("ParseAccessJWT() = %+v, %v", claims, err)
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Fatalf("ParseAccessJWT() error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+	cfg := &JWTConfig{SecretKey: []byte("secret"), Issuer: "auth.prod", IssuerMatch: MatchExact}
+	if _, err := ValidateAccessJWT(staging, cfg); !errors.Is(err, jwt.ErrTokenInvalidIssuer) {
+		t.Errorf("ValidateAccessJWT() error = %v", err)
+	}
+}
+
+// The Bearer prefix check in RequireAuth was once inverted, letting requests
+// without a token through and rejecting every valid one.
+func TestRequireAuthBearerScheme(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute}
+	token, err := IssueAccessJWT(UserIdentity{UserID: "u1"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := RequireAuth(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, _ := GetUserIDFromContext(r.Context()); id != "u1" {
+			t.Errorf("user = %q", id)
+		}
+	}))
+
+	for _, tc := range []struct {
+		header string
+		want   int
+	}{
+		{"Bearer " + token, http.StatusOK},
+		{"", http.StatusUnauthorized},
+		{token, http.StatusUnauthorized},
+		{"Basic dTE6cGFzcw==", http.StatusUnauthorized},
+		{"bearer" + token, http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("Authorization %.20q: status = %d, want %d", tc.header, rec.Code, tc.want)
+		}
+	}
+}
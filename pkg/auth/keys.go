@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	SigningMethodHS256 = "HS256"
+	SigningMethodRS256 = "RS256"
+	SigningMethodES256 = "ES256"
+)
+
+var (
+	ErrUnsupportedSigningMethod = errors.New("unsupported signing method")
+	ErrMissingKey               = errors.New("signing key not configured")
+	ErrKeyMismatch              = errors.New("key does not match signing method")
+)
+
+func (cfg *JWTConfig) signingMethod() (jwt.SigningMethod, error) {
+	switch cfg.SigningMethod {
+	case "", SigningMethodHS256:
+		return jwt.SigningMethodHS256, nil
+	case SigningMethodRS256:
+		return jwt.SigningMethodRS256, nil
+	case SigningMethodES256:
+		return jwt.SigningMethodES256, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedSigningMethod, cfg.SigningMethod)
+	}
+}
+
+// signKey returns the key that signs tokens for cfg's method.
+func (cfg *JWTConfig) signKey() (any, error) {
+	switch cfg.SigningMethod {
+	case "", SigningMethodHS256:
+		if len(cfg.SecretKey) == 0 {
+			return nil, errors.New("secret key cannot be empty")
+		}
+		return cfg.SecretKey, nil
+	case SigningMethodRS256:
+		if key, ok := cfg.PrivateKey.(*rsa.PrivateKey); ok {
+			return key, nil
+		}
+	case SigningMethodES256:
+		if key, ok := cfg.PrivateKey.(*ecdsa.PrivateKey); ok && key.Curve == elliptic.P256() {
+			return key, nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedSigningMethod, cfg.SigningMethod)
+	}
+	if cfg.PrivateKey == nil {
+		return nil, fmt.Errorf("%w: %s needs PrivateKey", ErrMissingKey, cfg.SigningMethod)
+	}
+	return nil, fmt.Errorf("%w: %T for %s", ErrKeyMismatch, cfg.PrivateKey, cfg.SigningMethod)
+}
+
+// verifyKey returns the key that checks signatures for cfg's method.
+func (cfg *JWTConfig) verifyKey() (any, error) {
+	pub := cfg.PublicKey
+	if pub == nil && cfg.PrivateKey != nil {
+		pub = cfg.PrivateKey.Public()
+	}
+	switch cfg.SigningMethod {
+	case "", SigningMethodHS256:
+		if len(cfg.SecretKey) == 0 {
+			return nil, errors.New("secret key cannot be empty")
+		}
+		return cfg.SecretKey, nil
+	case SigningMethodRS256:
+		if key, ok := pub.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+	case SigningMethodES256:
+		if key, ok := pub.(*ecdsa.PublicKey); ok && key.Curve == elliptic.P256() {
+			return key, nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedSigningMethod, cfg.SigningMethod)
+	}
+	if pub == nil {
+		return nil, fmt.Errorf("%w: %s needs PublicKey or PrivateKey", ErrMissingKey, cfg.SigningMethod)
+	}
+	return nil, fmt.Errorf("%w: %T for %s", ErrKeyMismatch, pub, cfg.SigningMethod)
+}
+
+// keyfunc returns how tokens are verified under cfg and the algorithms
+// accepted. JWKS fetches made by the returned function are bound to ctx.
+func (cfg *JWTConfig) keyfunc(ctx context.Context) (jwt.Keyfunc, []string, error) {
+	if cfg.JWKS != nil {
+		if cfg.SigningMethod == "" {
+			return cfg.JWKS.keyfunc(ctx), []string{SigningMethodRS256, SigningMethodES256}, nil
+		}
+		if cfg.SigningMethod != SigningMethodRS256 && cfg.SigningMethod != SigningMethodES256 {
+			return nil, nil, fmt.Errorf("%w: %q with JWKS", ErrUnsupportedSigningMethod, cfg.SigningMethod)
+		}
+		return cfg.JWKS.keyfunc(ctx), []string{cfg.SigningMethod}, nil
+	}
+	method, err := cfg.signingMethod()
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := cfg.verifyKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	return func(*jwt.Token) (any, error) { return key, nil }, []string{method.Alg()}, nil
+}
+
+func signToken(claims jwt.Claims, cfg *JWTConfig) (string, error) {
+	method, err := cfg.signingMethod()
+	if err != nil {
+		return "", err
+	}
+	key, err := cfg.signKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if cfg.KeyID != "" {
+		token.Header["kid"] = cfg.KeyID
+	}
+	return token.SignedString(key)
+}
+
+// ParsePublicKeyPEM parses an RSA or ECDSA public key from a PEM block, as
+// published by an auth service: PKIX ("PUBLIC KEY"), PKCS#1 ("RSA PUBLIC
+// KEY") or a certificate.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	default:
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+}
+
+// ParsePrivateKeyPEM parses an RSA or ECDSA private key from a PEM block in
+// PKCS#8 ("PRIVATE KEY"), PKCS#1 ("RSA PRIVATE KEY") or SEC 1 ("EC PRIVATE
+// KEY") form.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
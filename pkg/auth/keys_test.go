@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAsymmetricSigning(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		method string
+		issuer *JWTConfig
+	}{
+		{SigningMethodRS256, &JWTConfig{SigningMethod: SigningMethodRS256, PrivateKey: rsaKey}},
+		{SigningMethodES256, &JWTConfig{SigningMethod: SigningMethodES256, PrivateKey: ecKey}},
+	} {
+		t.Run(tc.method, func(t *testing.T) {
+			tc.issuer.AccessTTL = time.Minute
+			token, err := IssueAccessJWT(UserIdentity{UserID: "42"}, tc.issuer)
+			if err != nil {
+				t.Fatalf("IssueAccessJWT() error = %v", err)
+			}
+
+			// The validating service only holds the public key, as PEM.
+			der, err := x509.MarshalPKIXPublicKey(tc.issuer.PrivateKey.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			pub, err := ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+			if err != nil {
+				t.Fatalf("ParsePublicKeyPEM() error = %v", err)
+			}
+			verifier := &JWTConfig{SigningMethod: tc.method, PublicKey: pub}
+			if userID, err := ValidateAccessJWT(token, verifier); err != nil || userID != "42" {
+				t.Fatalf("ValidateAccessJWT() = %q, %v", userID, err)
+			}
+		})
+	}
+}
+
+func TestAsymmetricRejectsOtherAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := &JWTConfig{SigningMethod: SigningMethodRS256, PublicKey: &rsaKey.PublicKey}
+
+	// HS256 signed with the public key bytes must not pass as RS256.
+	pubDER := x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "42"}).SignedString(pubDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateAccessJWT(forged, verifier); err == nil {
+		t.Fatal("HS256 token accepted by RS256 config")
+	}
+
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.RegisteredClaims{Subject: "42"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateAccessJWT(unsigned, verifier); err == nil {
+		t.Fatal("unsigned token accepted")
+	}
+}
+
+func TestSigningKeyErrors(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		cfg  *JWTConfig
+		want error
+	}{
+		{&JWTConfig{SigningMethod: "PS512"}, ErrUnsupportedSigningMethod},
+		{&JWTConfig{SigningMethod: SigningMethodRS256}, ErrMissingKey},
+		{&JWTConfig{SigningMethod: SigningMethodES256, PrivateKey: rsaKey}, ErrKeyMismatch},
+	}
+	for _, tt := range tests {
+		if _, err := IssueAccessJWT(UserIdentity{UserID: "1"}, tt.cfg); !errors.Is(err, tt.want) {
+			t.Errorf("IssueAccessJWT(%s) error = %v, want %v", tt.cfg.SigningMethod, err, tt.want)
+		}
+	}
+}
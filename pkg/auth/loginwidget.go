@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+)
+
+const maxLoginWidgetBody = 4 << 10
+
+// ValidateLoginWidget checks the data the Telegram Login Widget hands to a
+// website (id, first_name, ..., auth_date and hash) and returns the user.
+// Unlike Mini App init data, the widget signs the fields with the SHA-256 of
+// the bot token itself as the HMAC key. Data older than maxAge is rejected;
+// zero disables the check. It returns the same initdata package errors as
+// TelegramAuth, e.g. initdata.ErrSignInvalid.
+func ValidateLoginWidget(data url.Values, botToken string, maxAge time.Duration) (*TelegramUser, error) {
+	hash := data.Get("hash")
+	if hash == "" {
+		return nil, initdata.ErrSignMissing
+	}
+
+	pairs := make([]string, 0, len(data))
+	for k, v := range data {
+		if k != "hash" && len(v) > 0 {
+			pairs = append(pairs, k+"="+v[0])
+		}
+	}
+	sort.Strings(pairs)
+	secret := sha256.Sum256([]byte(botToken))
+	if !CheckHMACSHA256(secret[:], []byte(strings.Join(pairs, "\n")), hash) {
+		return nil, initdata.ErrSignInvalid
+	}
+
+	if err := checkAuthDate(data, maxAge); err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.ParseInt(data.Get("id"), 10, 64)
+	if err != nil || id == 0 {
+		return nil, fmt.Errorf("parse id: %w", initdata.ErrUnexpectedFormat)
+	}
+	return &TelegramUser{
+		ID:        id,
+		FirstName: data.Get("first_name"),
+		LastName:  data.Get("last_name"),
+		Username:  data.Get("username"),
+		PhotoURL:  data.Get("photo_url"),
+	}, nil
+}
+
+// parseLoginWidget reads the widget data from a POST body: either the JSON
+// object passed to the widget's onauth callback or a form with the same
+// fields.
+func parseLoginWidget(r *http.Request) (url.Values, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxLoginWidgetBody))
+	if err != nil {
+		return nil, err
+	}
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "application/json" {
+		return url.ParseQuery(string(body))
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+	data := make(url.Values, len(fields))
+	for k, v := range fields {
+		switch v := v.(type) {
+		case string:
+			data.Set(k, v)
+		case json.Number:
+			data.Set(k, v.String())
+		default:
+			return nil, fmt.Errorf("login widget field %q is not a string or number", k)
+		}
+	}
+	return data, nil
+}
+
+// LoginWidgetHandler exchanges Telegram Login Widget data posted by a website
+// for an access JWT, plus a refresh token if cfg.RefreshTokens is set. It is
+// TelegramExchangeHandler for the web dashboard, which signs users in with the
+// widget instead of running as a Mini App.
+func LoginWidgetHandler(botToken string, resolver IdentityResolver, cfg *JWTConfig) http.Handler {
+	return LoginWidget(TelegramConfig{BotToken: botToken}, resolver, cfg)
+}
+
+// LoginWidget is LoginWidgetHandler with the bot token and MaxAge taken from
+// tg. The widget is always signed with the bot token, so the other bot
+// settings are ignored.
+func LoginWidget(tg TelegramConfig, resolver IdentityResolver, cfg *JWTConfig) http.Handler {
+	maxAge := tg.maxAge()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowPost(w, r) {
+			return
+		}
+
+		data, err := parseLoginWidget(r)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeBadRequest, "")
+			return
+		}
+		user, err := ValidateLoginWidget(data, tg.BotToken, maxAge)
+		if err != nil {
+			initDataError(err).write(w, r)
+			return
+		}
+
+		identity, err := resolver.ResolveTelegramUser(r.Context(), user)
+		respondWithTokens(w, r, identity, err, cfg)
+	})
+}
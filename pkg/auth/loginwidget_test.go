@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+)
+
+// signLoginWidget signs data the way Telegram does for the Login Widget.
+func signLoginWidget(data url.Values, botToken string) {
+	var pairs []string
+	for k := range data {
+		pairs = append(pairs, k+"="+data.Get(k))
+	}
+	sort.Strings(pairs)
+	secret := sha256.Sum256([]byte(botToken))
+	data.Set("hash", hex.EncodeToString(hmacSHA256(secret[:], []byte(strings.Join(pairs, "\n")))))
+}
+
+func TestValidateLoginWidget(t *testing.T) {
+	const token = "123456:ABC"
+	fresh := url.Values{
+		"id":         {"42"},
+		"first_name": {"Ada"},
+		"username":   {"ada"},
+		"auth_date":  {strconv.FormatInt(time.Now().Unix(), 10)},
+	}
+	signLoginWidget(fresh, token)
+
+	user, err := ValidateLoginWidget(fresh, token, time.Hour)
+	if err != nil || user.ID != 42 || user.Username != "ada" {
+		t.Fatalf("ValidateLoginWidget() = %+v, %v", user, err)
+	}
+
+	if _, err := ValidateLoginWidget(fresh, "other-token", time.Hour); !errors.Is(err, initdata.ErrSignInvalid) {
+		t.Fatalf("other token err = %v", err)
+	}
+
+	tampered := url.Values{}
+	for k, v := range fresh {
+		tampered[k] = v
+	}
+	tampered.Set("id", "43")
+	if _, err := ValidateLoginWidget(tampered, token, time.Hour); !errors.Is(err, initdata.ErrSignInvalid) {
+		t.Fatalf("tampered err = %v", err)
+	}
+
+	old := url.Values{"id": {"42"}, "auth_date": {strconv.FormatInt(time.Now().Add(-2*time.Hour).Unix(), 10)}}
+	signLoginWidget(old, token)
+	if _, err := ValidateLoginWidget(old, token, time.Hour); !errors.Is(err, initdata.ErrExpired) {
+		t.Fatalf("expired err = %v", err)
+	}
+	if _, err := ValidateLoginWidget(url.Values{"id": {"42"}}, token, time.Hour); !errors.Is(err, initdata.ErrSignMissing) {
+		t.Fatalf("unsigned err = %v", err)
+	}
+}
+
+func TestLoginWidgetHandler(t *testing.T) {
+	const token = "123456:ABC"
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute}
+	resolver := IdentityResolverFunc(func(_ context.Context, u *TelegramUser) (UserIdentity, error) {
+		return UserIdentity{UserID: "u-" + strconv.FormatInt(u.ID, 10)}, nil
+	})
+	h := LoginWidgetHandler(token, resolver, cfg)
+
+	data := url.Values{"id": {"42"}, "first_name": {"Ada"}, "auth_date": {strconv.FormatInt(time.Now().Unix(), 10)}}
+	signLoginWidget(data, token)
+
+	// The onauth callback object has numeric id and auth_date.
+	id, _ := strconv.Atoi(data.Get("id"))
+	authDate, _ := strconv.Atoi(data.Get("auth_date"))
+	body, _ := json.Marshal(map[string]any{"id": id, "first_name": "Ada", "auth_date": authDate, "hash": data.Get("hash")})
+	req := httptest.NewRequest(http.MethodPost, "/auth/telegram-widget", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("JSON login: %d %s", rec.Code, rec.Body)
+	}
+	var resp TokenResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if claims, err := ParseAccessJWT(resp.AccessToken, cfg); err != nil || claims.Subject != "u-42" {
+		t.Fatalf("claims = %+v, err = %v", claims, err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/telegram-widget", strings.NewReader(data.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("form login: %d %s", rec.Code, rec.Body)
+	}
+
+	data.Set("first_name", "Eve")
+	req = httptest.NewRequest(http.MethodPost, "/auth/telegram-widget", strings.NewReader(data.Encode()))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("tampered login = %d, want 401", rec.Code)
+	}
+}
+
+func TestLoginWidgetMaxAge(t *testing.T) {
+	const token = "123456:ABC"
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute}
+	resolver := IdentityResolverFunc(func(_ context.Context, u *TelegramUser) (UserIdentity, error) {
+		return UserIdentity{UserID: "u-" + strconv.FormatInt(u.ID, 10)}, nil
+	})
+	signed := func(age time.Duration) string {
+		data := url.Values{"id": {"42"}, "auth_date": {strconv.FormatInt(time.Now().Add(-age).Unix(), 10)}}
+		signLoginWidget(data, token)
+		return data.Encode()
+	}
+
+	for _, tc := range []struct {
+		name   string
+		maxAge time.Duration
+		age    time.Duration
+		want   int
+	}{
+		{"default accepts a day", 0, 23 * time.Hour, http.StatusOK},
+		{"default rejects older", 0, 25 * time.Hour, http.StatusUnauthorized},
+		{"within max age", time.Hour, 30 * time.Minute, http.StatusOK},
+		{"beyond max age", time.Hour, 2 * time.Hour, http.StatusUnauthorized},
+		{"no expiration", -1, 30 * 24 * time.Hour, http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := LoginWidget(TelegramConfig{BotToken: token, MaxAge: tc.maxAge}, resolver, cfg)
+			req := httptest.NewRequest(http.MethodPost, "/auth/telegram-widget", strings.NewReader(signed(tc.age)))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+}
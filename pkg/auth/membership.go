@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTelegramAPIURL = "https://api.telegram.org"
+
+// ErrMembershipLookup is returned when the Bot API could not answer whether a
+// user belongs to the chat.
+var ErrMembershipLookup = errors.New("telegram membership lookup failed")
+
+type MembershipConfig struct {
+	BotToken string
+	// ChatID is the numeric chat ID or @channelusername. The bot must be a
+	// member (an administrator for channels) of that chat.
+	ChatID string
+	// TTL is how long a positive answer is cached. Default 10m.
+	TTL time.Duration
+	// NegativeTTL is how long a negative answer is cached, kept short so users
+	// who just joined get in quickly. Default 1m.
+	NegativeTTL time.Duration
+	// APIURL overrides the Bot API base URL. Default https://api.telegram.org.
+	APIURL     string
+	HTTPClient *http.Client
+
+	// Skipper lets matching requests through RequireChatMember unchecked.
+	Skipper Skipper
+}
+
+// ChatMembershipChecker answers whether a Telegram user is a member of a
+// configured chat, caching Bot API getChatMember lookups.
+type ChatMembershipChecker struct {
+	cfg    MembershipConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[int64]membershipEntry
+}
+
+type membershipEntry struct {
+	member  bool
+	expires time.Time
+}
+
+func NewChatMembershipChecker(cfg MembershipConfig) *ChatMembershipChecker {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 10 * time.Minute
+	}
+	if cfg.NegativeTTL <= 0 {
+		cfg.NegativeTTL = time.Minute
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = defaultTelegramAPIURL
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &ChatMembershipChecker{
+		cfg:     cfg,
+		client:  client,
+		now:     time.Now,
+		entries: make(map[int64]membershipEntry),
+	}
+}
+
+// IsMember reports whether userID belongs to the configured chat. Lookup
+// failures are not cached.
+func (c *ChatMembershipChecker) IsMember(ctx context.Context, userID int64) (bool, error) {
+	now := c.now()
+	c.mu.Lock()
+	e, ok := c.entries[userID]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.member, nil
+	}
+
+	member, err := c.lookup(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	ttl := c.cfg.TTL
+	if !member {
+		ttl = c.cfg.NegativeTTL
+	}
+	c.mu.Lock()
+	for id, old := range c.entries {
+		if !now.Before(old.expires) {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[userID] = membershipEntry{member: member, expires: now.Add(ttl)}
+	c.mu.Unlock()
+	return member, nil
+}
+
+type getChatMemberResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Result      struct {
+		Status   string `json:"status"`
+		IsMember bool   `json:"is_member"`
+	} `json:"result"`
+}
+
+func (c *ChatMembershipChecker) lookup(ctx context.Context, userID int64) (bool, error) {
+	q := url.Values{}
+	q.Set("chat_id", c.cfg.ChatID)
+	q.Set("user_id", strconv.FormatInt(userID, 10))
+	endpoint := c.cfg.APIURL + "/bot" + c.cfg.BotToken + "/getChatMember?" + q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrMembershipLookup, err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// The URL embeds the bot token; never let it leak into errors.
+		return false, fmt.Errorf("%w: request failed", ErrMembershipLookup)
+	}
+	defer resp.Body.Close()
+
+	var body getChatMemberResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, fmt.Errorf("%w: status %d", ErrMembershipLookup, resp.StatusCode)
+	}
+	if !body.OK {
+		if resp.StatusCode == http.StatusBadRequest && notParticipant(body.Description) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%w: status %d: %s", ErrMembershipLookup, resp.StatusCode, body.Description)
+	}
+
+	switch body.Result.Status {
+	case "creator", "administrator", "member":
+		return true, nil
+	case "restricted":
+		return body.Result.IsMember, nil
+	default:
+		return false, nil
+	}
+}
+
+// notParticipant reports whether a getChatMember error description means the
+// user is not in the chat, as opposed to the chat or bot being misconfigured
+// ("chat not found", "bot is not a member of the supergroup chat").
+func notParticipant(description string) bool {
+	d := strings.ToLower(description)
+	for _, s := range []string{"user not found", "member not found", "participant_id_invalid", "user_not_participant"} {
+		if strings.Contains(d, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireChatMember rejects Telegram users who are not members of the
+// checker's chat. It must run after TelegramAuthMiddleware. Only requests
+// matching MembershipConfig.Skipper pass unchecked, not those another
+// middleware skipped.
+func RequireChatMember(checker *ChatMembershipChecker, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r, skipped := skip(checker.cfg.Skipper, r); skipped {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, ok := GetUserFromContext(r.Context())
+		if !ok {
+			writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "")
+			return
+		}
+		member, err := checker.IsMember(r.Context(), user.ID)
+		if err != nil {
+			writeError(w, r, http.StatusServiceUnavailable, CodeUnavailable, "")
+			return
+		}
+		if !member {
+			writeError(w, r, http.StatusForbidden, CodeNotChatMember, "Not a member of the workspace chat")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
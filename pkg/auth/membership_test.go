@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeBotAPI answers getChatMember with the response stored for each user_id.
+func fakeBotAPI(t *testing.T, lookups *atomic.Int32, responses map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		if !strings.HasSuffix(r.URL.Path, "/getChatMember") || r.URL.Query().Get("chat_id") != "@workspace" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		resp, ok := responses[r.URL.Query().Get("user_id")]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			resp = `{"ok":false,"description":"Internal Server Error"}`
+		} else if strings.Contains(resp, `"ok":false`) {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRequireChatMember(t *testing.T) {
+	var lookups atomic.Int32
+	server := fakeBotAPI(t, &lookups, map[string]string{
+		"1": `{"ok":true,"result":{"status":"member"}}`,
+		"2": `{"ok":true,"result":{"status":"left"}}`,
+		"3": `{"ok":true,"result":{"status":"kicked"}}`,
+		"4": `{"ok":true,"result":{"status":"restricted","is_member":true}}`,
+		"5": `{"ok":false,"description":"Bad Request: user not found"}`,
+		"7": `{"ok":false,"description":"Bad Request: chat not found"}`,
+		"8": `{"ok":false,"description":"Bad Request: PARTICIPANT_ID_INVALID"}`,
+	})
+	checker := NewChatMembershipChecker(MembershipConfig{BotToken: "123:ABC", ChatID: "@workspace", APIURL: server.URL})
+	h := RequireChatMember(checker, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	serve := func(userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if userID != 0 {
+			ctx := WithIdentity(req.Context(), &Identity{Source: SourceTelegram, Telegram: &TelegramUser{ID: userID}})
+			req = req.WithContext(ctx)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		name   string
+		userID int64
+		want   int
+		code   ErrorCode
+	}{
+		{"member", 1, http.StatusOK, ""},
+		{"left", 2, http.StatusForbidden, CodeNotChatMember},
+		{"kicked", 3, http.StatusForbidden, CodeNotChatMember},
+		{"restricted member", 4, http.StatusOK, ""},
+		{"never joined", 5, http.StatusForbidden, CodeNotChatMember},
+		{"lookup fails closed", 6, http.StatusServiceUnavailable, CodeUnavailable},
+		{"chat misconfigured", 7, http.StatusServiceUnavailable, CodeUnavailable},
+		{"not a participant", 8, http.StatusForbidden, CodeNotChatMember},
+		{"no Telegram user", 0, http.StatusUnauthorized, CodeUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(tc.userID)
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.want)
+			}
+			if tc.code != "" {
+				if e := decodeErrorBody(t, rec); e.Code != tc.code {
+					t.Errorf("code = %s, want %s", e.Code, tc.code)
+				}
+			}
+		})
+	}
+}
+
+func TestChatMembershipCache(t *testing.T) {
+	var lookups atomic.Int32
+	server := fakeBotAPI(t, &lookups, map[string]string{
+		"1": `{"ok":true,"result":{"status":"member"}}`,
+		"2": `{"ok":true,"result":{"status":"left"}}`,
+		"8": `{"ok":false,"description":"Bad Request: chat not found"}`,
+	})
+	now := time.Unix(1700000000, 0)
+	checker := NewChatMembershipChecker(MembershipConfig{BotToken: "123:ABC", ChatID: "@workspace", APIURL: server.URL})
+	checker.now = func() time.Time { return now }
+	ctx := context.Background()
+	check := func(userID int64, want bool, wantLookups int32) {
+		t.Helper()
+		member, err := checker.IsMember(ctx, userID)
+		if err != nil || member != want {
+			t.Fatalf("IsMember(%d) = %v, %v, want %v", userID, member, err, want)
+		}
+		if n := lookups.Load(); n != wantLookups {
+			t.Fatalf("lookups = %d, want %d", n, wantLookups)
+		}
+	}
+
+	check(1, true, 1)
+	check(1, true, 1)
+	check(2, false, 2)
+	check(2, false, 2)
+
+	// Negative answers expire after a minute, positive ones after ten.
+	now = now.Add(2 * time.Minute)
+	check(2, false, 3)
+	check(1, true, 3)
+	now = now.Add(10 * time.Minute)
+	check(1, true, 4)
+
+	// Failures, including 400s about the chat rather than the user, are
+	// not cached.
+	for _, userID := range []int64{8, 8, 9, 9} {
+		_, err := checker.IsMember(ctx, userID)
+		if !errors.Is(err, ErrMembershipLookup) || strings.Contains(err.Error(), "123:ABC") {
+			t.Fatalf("IsMember(%d) for a failing lookup: err = %v", userID, err)
+		}
+	}
+	if n := lookups.Load(); n != 8 {
+		t.Errorf("lookups = %d, want 8", n)
+	}
+}
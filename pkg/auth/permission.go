@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrNoIdentity is returned by CheckPermission when no middleware
+// authenticated the request.
+var ErrNoIdentity = errors.New("no identity in context")
+
+// PermissionChecker decides whether an identity may perform action on
+// resource, e.g. ("reviews:export", "app/389801252"). It is where a service
+// plugs in its policy engine, such as OPA or a permissions table. Errors
+// mean the decision could not be made, not a denial.
+type PermissionChecker interface {
+	Allowed(ctx context.Context, id *Identity, action, resource string) (bool, error)
+}
+
+type PermissionCheckerFunc func(ctx context.Context, id *Identity, action, resource string) (bool, error)
+
+func (f PermissionCheckerFunc) Allowed(ctx context.Context, id *Identity, action, resource string) (bool, error) {
+	return f(ctx, id, action, resource)
+}
+
+type permissionKey struct {
+	action, resource string
+}
+
+// permissionCache holds the decisions made for one request.
+type permissionCache struct {
+	mu        sync.Mutex
+	decisions map[permissionKey]bool
+}
+
+const permissionCacheKey ctxKey = "auth_permission_cache"
+
+// WithPermissionCache returns ctx with an empty decision cache, so repeated
+// CheckPermission calls for the same action and resource ask the checker
+// once. RequirePermissionFn adds one to the requests it lets through. The
+// cache assumes one checker and one identity per context.
+func WithPermissionCache(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(permissionCacheKey).(*permissionCache); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, permissionCacheKey, &permissionCache{decisions: make(map[permissionKey]bool)})
+}
+
+// CheckPermission asks checker whether the identity in ctx may perform
+// action on resource, answering from the request's cache when it can.
+// Errors are not cached.
+func CheckPermission(ctx context.Context, checker PermissionChecker, action, resource string) (bool, error) {
+	id, ok := IdentityFromContext(ctx)
+	if !ok {
+		return false, ErrNoIdentity
+	}
+	cache, _ := ctx.Value(permissionCacheKey).(*permissionCache)
+	key := permissionKey{action, resource}
+	if cache != nil {
+		cache.mu.Lock()
+		allowed, ok := cache.decisions[key]
+		cache.mu.Unlock()
+		if ok {
+			return allowed, nil
+		}
+	}
+
+	allowed, err := checker.Allowed(ctx, id, action, resource)
+	if err != nil {
+		return false, err
+	}
+	if cache != nil {
+		cache.mu.Lock()
+		cache.decisions[key] = allowed
+		cache.mu.Unlock()
+	}
+	return allowed, nil
+}
+
+// RequirePermissionFn lets through callers that checker allows to perform
+// action on the resource named by resource(r), and answers 403 to the rest,
+// 401 if no middleware authenticated the request, and 503 if the checker
+// fails. Like RequireRole it must run after an auth middleware and passes
+// requests that middleware skipped.
+//
+//	export := auth.RequirePermissionFn(policy, "reviews:export", func(r *http.Request) string {
+//		return "app/" + r.PathValue("appID")
+//	})
+//	mux.Handle("POST /apps/{appID}/export", auth.RequireAuth(cfg, export(exportHandler)))
+//
+// The decision is cached for the rest of the request, so handlers can call
+// CheckPermission for the same pair without asking the checker again.
+func RequirePermissionFn(checker PermissionChecker, action string, resource func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if AuthSkipped(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := WithPermissionCache(r.Context())
+			allowed, err := CheckPermission(ctx, checker, action, resource(r))
+			switch {
+			case errors.Is(err, ErrNoIdentity):
+				writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "")
+				return
+			case err != nil:
+				writeError(w, r, http.StatusServiceUnavailable, CodeUnavailable, "")
+				return
+			case !allowed:
+				writeError(w, r, http.StatusForbidden, CodeForbidden, "")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
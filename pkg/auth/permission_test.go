@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequirePermissionFn(t *testing.T) {
+	calls := 0
+	var failing bool
+	policy := PermissionCheckerFunc(func(_ context.Context, id *Identity, action, resource string) (bool, error) {
+		calls++
+		if failing {
+			return false, errors.New("policy engine down")
+		}
+		return id.UserID == "owner" && action == "reviews:export" && resource == "app/1", nil
+	})
+
+	mux := http.NewServeMux()
+	export := RequirePermissionFn(policy, "reviews:export", func(r *http.Request) string {
+		return "app/" + r.PathValue("appID")
+	})
+	mux.Handle("/apps/{appID}/export", export(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Asked again by the handler: answered from the request cache.
+		if ok, err := CheckPermission(r.Context(), policy, "reviews:export", "app/"+r.PathValue("appID")); !ok || err != nil {
+			t.Errorf("CheckPermission in handler = %v, %v", ok, err)
+		}
+	})))
+
+	serve := func(id *Identity, path string) int {
+		ctx := context.Background()
+		if id != nil {
+			ctx = WithIdentity(ctx, id)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil).WithContext(ctx))
+		return rec.Code
+	}
+
+	owner := &Identity{UserID: "owner", Source: SourceJWT}
+	if code := serve(owner, "/apps/1/export"); code != http.StatusOK || calls != 1 {
+		t.Fatalf("owner: %d after %d checks, want 200 after 1", code, calls)
+	}
+	if code := serve(owner, "/apps/2/export"); code != http.StatusForbidden {
+		t.Fatalf("other app: %d, want 403", code)
+	}
+	if code := serve(&Identity{UserID: "viewer"}, "/apps/1/export"); code != http.StatusForbidden {
+		t.Fatalf("viewer: %d, want 403", code)
+	}
+	if code := serve(nil, "/apps/1/export"); code != http.StatusUnauthorized {
+		t.Fatalf("anonymous: %d, want 401", code)
+	}
+	failing = true
+	if code := serve(owner, "/apps/1/export"); code != http.StatusServiceUnavailable {
+		t.Fatalf("failing checker: %d, want 503", code)
+	}
+}
+
+func TestCheckPermissionCache(t *testing.T) {
+	calls := 0
+	policy := PermissionCheckerFunc(func(context.Context, *Identity, string, string) (bool, error) {
+		calls++
+		return true, nil
+	})
+	ctx := WithIdentity(context.Background(), &Identity{UserID: "u1"})
+
+	CheckPermission(ctx, policy, "read", "a")
+	CheckPermission(ctx, policy, "read", "a")
+	if calls != 2 {
+		t.Fatalf("without a cache: %d checks, want 2", calls)
+	}
+
+	calls = 0
+	ctx = WithPermissionCache(ctx)
+	CheckPermission(ctx, policy, "read", "a")
+	CheckPermission(WithPermissionCache(ctx), policy, "read", "a") // keeps the existing cache
+	CheckPermission(ctx, policy, "read", "b")
+	if calls != 2 {
+		t.Fatalf("with a cache: %d checks, want 2", calls)
+	}
+
+	if _, err := CheckPermission(context.Background(), policy, "read", "a"); !errors.Is(err, ErrNoIdentity) {
+		t.Fatalf("no identity: err = %v", err)
+	}
+}
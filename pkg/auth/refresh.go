@@ -0,0 +1,316 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	// ErrRefreshTokenReused is returned when an already rotated token is
+	// presented again. The whole token family is revoked, since either the
+	// client or an attacker holds a stolen copy.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+	ErrRefreshNotFound    = errors.New("refresh token not found")
+)
+
+const (
+	defaultRefreshTTL  = 30 * 24 * time.Hour
+	refreshTokenLength = 32
+	maxRefreshBody     = 4 << 10
+)
+
+// RefreshRecord is what a RefreshStore keeps per refresh token. The token
+// itself is never stored, only its SHA-256 hash.
+type RefreshRecord struct {
+	Hash string
+	// FamilyID is shared by all tokens rotated from the same login.
+	FamilyID  string
+	User      UserIdentity
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// Used is set once the token has been rotated.
+	Used    bool
+	Revoked bool
+}
+
+// RefreshStore persists refresh tokens. Implementations must be safe for
+// concurrent use.
+type RefreshStore interface {
+	Create(ctx context.Context, rec RefreshRecord) error
+	// Get returns ErrRefreshNotFound for unknown hashes.
+	Get(ctx context.Context, hash string) (RefreshRecord, error)
+	// MarkUsed atomically flags the token as used. It reports false if it
+	// already was, so two concurrent rotations cannot both succeed.
+	MarkUsed(ctx context.Context, hash string) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+}
+
+// RefreshTokens issues long-lived opaque refresh tokens and rotates them:
+// every use returns a new token and spends the old one. Presenting a spent
+// token revokes its whole family.
+type RefreshTokens struct {
+	store RefreshStore
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// NewRefreshTokens creates a refresh token issuer backed by store, or by an
+// in-memory store if nil. Tokens are valid for ttl after their last
+// rotation; default 30 days.
+func NewRefreshTokens(store RefreshStore, ttl time.Duration) *RefreshTokens {
+	if store == nil {
+		store = NewMemoryRefreshStore()
+	}
+	if ttl <= 0 {
+		ttl = defaultRefreshTTL
+	}
+	return &RefreshTokens{store: store, ttl: ttl, now: time.Now}
+}
+
+// Issue starts a new token family for user, e.g. after a Telegram login.
+func (t *RefreshTokens) Issue(ctx context.Context, user UserIdentity) (string, error) {
+	family, err := randomToken(TokenLength)
+	if err != nil {
+		return "", err
+	}
+	return t.issue(ctx, family, user)
+}
+
+func (t *RefreshTokens) issue(ctx context.Context, family string, user UserIdentity) (string, error) {
+	token, err := randomToken(refreshTokenLength)
+	if err != nil {
+		return "", err
+	}
+	now := t.now()
+	err = t.store.Create(ctx, RefreshRecord{
+		Hash:      hashRefreshToken(token),
+		FamilyID:  family,
+		User:      user,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(t.ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Rotate spends token and returns its replacement together with the user it
+// was issued for.
+func (t *RefreshTokens) Rotate(ctx context.Context, token string) (string, UserIdentity, error) {
+	rec, err := t.lookup(ctx, token)
+	if err != nil {
+		return "", UserIdentity{}, err
+	}
+	if rec.Used {
+		return "", UserIdentity{}, t.reused(ctx, rec)
+	}
+	fresh, err := t.store.MarkUsed(ctx, rec.Hash)
+	if err != nil {
+		return "", UserIdentity{}, err
+	}
+	if !fresh {
+		return "", UserIdentity{}, t.reused(ctx, rec)
+	}
+	next, err := t.issue(ctx, rec.FamilyID, rec.User)
+	if err != nil {
+		return "", UserIdentity{}, err
+	}
+	return next, rec.User, nil
+}
+
+// Revoke invalidates token and every token rotated from the same login,
+// e.g. on logout.
+func (t *RefreshTokens) Revoke(ctx context.Context, token string) error {
+	rec, err := t.store.Get(ctx, hashRefreshToken(token))
+	if errors.Is(err, ErrRefreshNotFound) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+	return t.store.RevokeFamily(ctx, rec.FamilyID)
+}
+
+func (t *RefreshTokens) lookup(ctx context.Context, token string) (RefreshRecord, error) {
+	if token == "" {
+		return RefreshRecord{}, ErrInvalidRefreshToken
+	}
+	rec, err := t.store.Get(ctx, hashRefreshToken(token))
+	if errors.Is(err, ErrRefreshNotFound) {
+		return RefreshRecord{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return RefreshRecord{}, err
+	}
+	if rec.Revoked {
+		return RefreshRecord{}, ErrInvalidRefreshToken
+	}
+	if !t.now().Before(rec.ExpiresAt) {
+		return RefreshRecord{}, ErrRefreshTokenExpired
+	}
+	return rec, nil
+}
+
+func (t *RefreshTokens) reused(ctx context.Context, rec RefreshRecord) error {
+	if err := t.store.RevokeFamily(ctx, rec.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshHandler exchanges a refresh token, posted as
+// {"refresh_token": "..."}, for a new access token and a rotated refresh
+// token using cfg.RefreshTokens.
+func RefreshHandler(cfg *JWTConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowPost(w, r) {
+			return
+		}
+		if cfg.RefreshTokens == nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "")
+			return
+		}
+
+		var req refreshRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxRefreshBody)).Decode(&req); err != nil || req.RefreshToken == "" {
+			writeError(w, r, http.StatusBadRequest, CodeBadRequest, "")
+			return
+		}
+
+		next, user, err := cfg.RefreshTokens.Rotate(r.Context(), req.RefreshToken)
+		switch {
+		case errors.Is(err, ErrRefreshTokenExpired):
+			writeError(w, r, http.StatusUnauthorized, CodeTokenExpired, "Refresh token expired")
+			return
+		case errors.Is(err, ErrRefreshTokenReused):
+			writeError(w, r, http.StatusUnauthorized, CodeTokenRevoked, "Refresh token reused")
+			return
+		case errors.Is(err, ErrInvalidRefreshToken):
+			writeError(w, r, http.StatusUnauthorized, CodeInvalidToken, "Invalid refresh token")
+			return
+		case err != nil:
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "")
+			return
+		}
+
+		access, err := IssueAccessJWT(cfg.Binding.bind(user, r), cfg)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "")
+			return
+		}
+		if cfg.Cookie != nil {
+			SetTokenCookie(w, access, cfg)
+		}
+		writeTokenResponse(w, TokenResponse{
+			AccessToken:  access,
+			TokenType:    "Bearer",
+			ExpiresIn:    int64(cfg.AccessTTL.Seconds()),
+			RefreshToken: next,
+		})
+	})
+}
+
+// MemoryRefreshStore keeps refresh tokens in process memory. It is the
+// default RefreshStore and suits single-instance services and tests; tokens
+// do not survive a restart. Expired tokens are dropped every 256 tokens
+// created.
+type MemoryRefreshStore struct {
+	mu      sync.Mutex
+	records map[string]RefreshRecord
+	now     func() time.Time
+	ops     int
+}
+
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{records: make(map[string]RefreshRecord), now: time.Now}
+}
+
+func (s *MemoryRefreshStore) Create(_ context.Context, rec RefreshRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ops++; s.ops%256 == 0 {
+		now := s.now()
+		for hash, old := range s.records {
+			if now.After(old.ExpiresAt) {
+				delete(s.records, hash)
+			}
+		}
+	}
+	s.records[rec.Hash] = rec
+	return nil
+}
+
+func (s *MemoryRefreshStore) Get(_ context.Context, hash string) (RefreshRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[hash]
+	if !ok {
+		return RefreshRecord{}, ErrRefreshNotFound
+	}
+	return rec, nil
+}
+
+func (s *MemoryRefreshStore) MarkUsed(_ context.Context, hash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[hash]
+	if !ok {
+		return false, ErrRefreshNotFound
+	}
+	if rec.Used {
+		return false, nil
+	}
+	rec.Used = true
+	s.records[hash] = rec
+	return true, nil
+}
+
+// RevokeFamily revokes the family's tokens and drops those already expired.
+func (s *MemoryRefreshStore) RevokeFamily(_ context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for hash, rec := range s.records {
+		if rec.FamilyID != familyID {
+			continue
+		}
+		if now.After(rec.ExpiresAt) {
+			delete(s.records, hash)
+			continue
+		}
+		rec.Revoked = true
+		s.records[hash] = rec
+	}
+	return nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRefreshRotationAndReuse(t *testing.T) {
+	ctx := context.Background()
+	tokens := NewRefreshTokens(nil, time.Hour)
+	user := UserIdentity{UserID: "42", Roles: []string{"admin"}}
+
+	first, err := tokens.Issue(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, got, err := tokens.Rotate(ctx, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != "42" || second == first {
+		t.Fatalf("Rotate() = %q, %+v", second, got)
+	}
+
+	// Replaying the spent token revokes the family, including the token
+	// the legitimate client holds.
+	if _, _, err := tokens.Rotate(ctx, first); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reuse err = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, _, err := tokens.Rotate(ctx, second); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("after reuse err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRefreshExpiry(t *testing.T) {
+	ctx := context.Background()
+	tokens := NewRefreshTokens(nil, time.Minute)
+	now := time.Now()
+	tokens.now = func() time.Time { return now }
+
+	token, _ := tokens.Issue(ctx, UserIdentity{UserID: "1"})
+	now = now.Add(2 * time.Minute)
+	if _, _, err := tokens.Rotate(ctx, token); !errors.Is(err, ErrRefreshTokenExpired) {
+		t.Fatalf("err = %v, want ErrRefreshTokenExpired", err)
+	}
+	if _, _, err := tokens.Rotate(ctx, "garbage"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("err = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestMemoryRefreshStorePrunesExpired(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryRefreshStore()
+	tokens := NewRefreshTokens(s, time.Minute)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	tokens.now = s.now
+
+	for i := 0; i < 200; i++ {
+		tokens.Issue(ctx, UserIdentity{UserID: "1"})
+	}
+	now = now.Add(2 * time.Minute)
+	live, _ := tokens.Issue(ctx, UserIdentity{UserID: "2"})
+	for i := 0; i < 100; i++ {
+		tokens.Issue(ctx, UserIdentity{UserID: "3"})
+	}
+
+	s.mu.Lock()
+	n := len(s.records)
+	s.mu.Unlock()
+	if n != 101 {
+		t.Errorf("records = %d, want the 101 unexpired ones", n)
+	}
+	if _, user, err := tokens.Rotate(ctx, live); err != nil || user.UserID != "2" {
+		t.Errorf("Rotate() = %+v, %v", user, err)
+	}
+}
+
+func TestRefreshHandler(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute, RefreshTokens: NewRefreshTokens(nil, 0)}
+	token, _ := cfg.RefreshTokens.Issue(context.Background(), UserIdentity{UserID: "7"})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		RefreshHandler(cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(`{"refresh_token":"` + token + `"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var resp TokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.RefreshToken == "" || resp.RefreshToken == token {
+		t.Errorf("refresh token not rotated: %q", resp.RefreshToken)
+	}
+	if userID, err := ValidateAccessJWT(resp.AccessToken, cfg); err != nil || userID != "7" {
+		t.Errorf("access token: %q, %v", userID, err)
+	}
+
+	if rec := post(`{"refresh_token":"` + token + `"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reused token status = %d, want 401", rec.Code)
+	}
+	if rec := post(`not json`); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad body status = %d, want 400", rec.Code)
+	}
+}
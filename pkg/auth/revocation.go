@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTokenRevoked = errors.New("token revoked")
+	// ErrRevocationLookup is returned when the RevocationStore could not be
+	// asked. Tokens are rejected rather than let through unchecked.
+	ErrRevocationLookup = errors.New("revocation lookup failed")
+)
+
+// RevocationStore records revoked access tokens by their jti until they
+// would have expired anyway, i.e. until expiresAt, which RevokeAccessJWT
+// extends by JWTConfig.Leeway. Implementations must be safe for concurrent use.
+type RevocationStore interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// RevokeAccessJWT parses token with cfg and records its jti in
+// cfg.Revocations, so it is rejected before it expires. Revoking a token
+// twice is not an error.
+func RevokeAccessJWT(ctx context.Context, token string, cfg *JWTConfig) error {
+	if cfg.Revocations == nil {
+		return errors.New("no revocation store configured")
+	}
+	claims, err := parseAccessJWT(ctx, token, cfg)
+	if errors.Is(err, ErrTokenRevoked) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if claims.ID == "" {
+		return errors.New("token has no jti")
+	}
+	expires := time.Now().Add(cfg.AccessTTL)
+	if claims.ExpiresAt != nil {
+		expires = claims.ExpiresAt.Time
+	}
+	// The parser accepts the token until exp + Leeway, so the revocation
+	// has to outlive it by as much.
+	return cfg.Revocations.Revoke(ctx, claims.ID, expires.Add(cfg.leeway()))
+}
+
+// checkRevoked fails if claims' jti was revoked. Tokens without a jti cannot
+// be revoked and pass.
+func (cfg *JWTConfig) checkRevoked(ctx context.Context, claims *AccessClaims) error {
+	if cfg.Revocations == nil || claims.ID == "" {
+		return nil
+	}
+	revoked, err := cfg.Revocations.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRevocationLookup, err)
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// MemoryRevocationStore keeps revoked token IDs in process memory, dropping
+// them once the token has expired. It suits single-instance services and
+// tests; revocations do not survive a restart or reach other replicas.
+type MemoryRevocationStore struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+	now     func() time.Time
+	ops     int
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{revoked: make(map[string]time.Time), now: time.Now}
+}
+
+func (s *MemoryRevocationStore) Revoke(_ context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.ops++; s.ops%256 == 0 {
+		for id, exp := range s.revoked {
+			if now.After(exp) {
+				delete(s.revoked, id)
+			}
+		}
+	}
+	if now.After(expiresAt) {
+		return nil
+	}
+	s.revoked[jti] = expiresAt
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(_ context.Context, jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exp, ok := s.revoked[jti]
+	return ok && !s.now().After(exp), nil
+}
+
+// RedisClient is the subset of a Redis client used by RedisRevocationStore,
+// so the package does not pin a client library. Adapt go-redis with e.g.
+//
+//	func (a adapter) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+//		return a.c.Set(ctx, key, value, ttl).Err()
+//	}
+//	func (a adapter) Exists(ctx context.Context, key string) (bool, error) {
+//		n, err := a.c.Exists(ctx, key).Result()
+//		return n > 0, err
+//	}
+type RedisClient interface {
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+const defaultRevocationPrefix = "auth:revoked:"
+
+// RedisRevocationStore shares revocations between replicas through Redis.
+// Each revoked jti is a key that expires with the token.
+type RedisRevocationStore struct {
+	client RedisClient
+	prefix string
+	now    func() time.Time
+}
+
+// NewRedisRevocationStore stores revocations under prefix + jti; prefix
+// defaults to "auth:revoked:".
+func NewRedisRevocationStore(client RedisClient, prefix string) *RedisRevocationStore {
+	if prefix == "" {
+		prefix = defaultRevocationPrefix
+	}
+	return &RedisRevocationStore{client: client, prefix: prefix, now: time.Now}
+}
+
+func (s *RedisRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := expiresAt.Sub(s.now())
+	if ttl <= 0 {
+		return nil
+	}
+	// Round up so the key never expires before the token does.
+	ttl = ttl.Truncate(time.Second) + time.Second
+	return s.client.Set(ctx, s.prefix+jti, "1", ttl)
+}
+
+func (s *RedisRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.client.Exists(ctx, s.prefix+jti)
+}
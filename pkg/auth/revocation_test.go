@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeRedis struct {
+	mu   sync.Mutex
+	keys map[string]time.Duration
+	err  error
+}
+
+func (r *fakeRedis) Set(_ context.Context, key, _ string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keys == nil {
+		r.keys = map[string]time.Duration{}
+	}
+	r.keys[key] = ttl
+	return r.err
+}
+
+func (r *fakeRedis) Exists(_ context.Context, key string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.keys[key]
+	return ok, r.err
+}
+
+func TestRevokedTokenIsRejected(t *testing.T) {
+	for name, store := range map[string]RevocationStore{
+		"memory": NewMemoryRevocationStore(),
+		"redis":  NewRedisRevocationStore(&fakeRedis{}, ""),
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute, Revocations: store}
+			stolen, _ := IssueAccessJWT(UserIdentity{UserID: "1"}, cfg)
+			other, _ := IssueAccessJWT(UserIdentity{UserID: "1"}, cfg)
+
+			if err := RevokeAccessJWT(context.Background(), stolen, cfg); err != nil {
+				t.Fatal(err)
+			}
+			if err := RevokeAccessJWT(context.Background(), stolen, cfg); err != nil {
+				t.Fatalf("second revoke: %v", err)
+			}
+			if _, err := ValidateAccessJWT(stolen, cfg); !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("err = %v, want ErrTokenRevoked", err)
+			}
+			if _, err := ValidateAccessJWT(other, cfg); err != nil {
+				t.Errorf("unrevoked token: %v", err)
+			}
+		})
+	}
+}
+
+func TestRequireAuthRevocationLookupFailure(t *testing.T) {
+	redis := &fakeRedis{}
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute, Revocations: NewRedisRevocationStore(redis, "")}
+	token, _ := IssueAccessJWT(UserIdentity{UserID: "1"}, cfg)
+	redis.err = errors.New("connection refused")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	RequireAuth(cfg, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("handler reached without a revocation check")
+	})).ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
+
+func TestMemoryRevocationExpires(t *testing.T) {
+	s := NewMemoryRevocationStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	s.Revoke(context.Background(), "jti", now.Add(time.Minute))
+	if ok, _ := s.IsRevoked(context.Background(), "jti"); !ok {
+		t.Fatal("not revoked")
+	}
+	now = now.Add(2 * time.Minute)
+	if ok, _ := s.IsRevoked(context.Background(), "jti"); ok {
+		t.Error("revocation outlived the token")
+	}
+}
+
+func TestRevocationOutlivesLeeway(t *testing.T) {
+	redis := &fakeRedis{}
+	for name, store := range map[string]RevocationStore{
+		"memory": NewMemoryRevocationStore(),
+		"redis":  NewRedisRevocationStore(redis, ""),
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Expired 5s ago, still accepted within the 30s leeway.
+			cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: -5 * time.Second, Leeway: 30 * time.Second, Revocations: store}
+			token, _ := IssueAccessJWT(UserIdentity{UserID: "1"}, cfg)
+			if _, err := ValidateAccessJWT(token, cfg); err != nil {
+				t.Fatalf("token outside leeway: %v", err)
+			}
+			if err := RevokeAccessJWT(context.Background(), token, cfg); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ValidateAccessJWT(token, cfg); !errors.Is(err, ErrTokenRevoked) {
+				t.Errorf("revoked token inside the leeway window: err = %v, want ErrTokenRevoked", err)
+			}
+		})
+	}
+	for key, ttl := range redis.keys {
+		if ttl < 20*time.Second {
+			t.Errorf("%s kept for %s, want until exp + leeway", key, ttl)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// HasScope reports whether the identity was granted scope.
+func (i *Identity) HasScope(scope string) bool {
+	return slices.Contains(i.Scopes, scope)
+}
+
+// RequireRole lets through callers that have at least one of roles and
+// answers 403 to the rest, or 401 if no middleware authenticated the
+// request. It must run after an auth middleware and passes requests that
+// middleware skipped.
+//
+//	mux.Handle("/admin", auth.RequireAuth(cfg, auth.RequireRole("admin")(adminHandler)))
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return requireIdentity(func(id *Identity) bool {
+		return slices.ContainsFunc(roles, id.HasRole)
+	})
+}
+
+// RequireScope lets through callers whose token grants every one of scopes,
+// e.g. "reviews:read", and answers 403 to the rest, or 401 if no middleware
+// authenticated the request. It passes requests the auth middleware skipped.
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return requireIdentity(func(id *Identity) bool {
+		for _, s := range scopes {
+			if !id.HasScope(s) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func requireIdentity(allowed func(*Identity) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if AuthSkipped(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id, ok := IdentityFromContext(r.Context())
+			if !ok {
+				writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "")
+				return
+			}
+			if !allowed(id) {
+				writeError(w, r, http.StatusForbidden, CodeForbidden, "")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// normalizeScopes trims, de-duplicates and sorts scopes. Unlike feature
+// flags, scopes keep their case.
+func normalizeScopes(scopes []string) []string {
+	var out []string
+	for _, s := range scopes {
+		out = append(out, strings.Fields(s)...)
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequireRoleAndScope(t *testing.T) {
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute}
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	admin, _ := IssueAccessJWT(UserIdentity{UserID: "1", Roles: []string{"admin"}, Scopes: []string{"reviews:read reviews:write"}}, cfg)
+	reader, _ := IssueAccessJWT(UserIdentity{UserID: "2", Roles: []string{"viewer"}, Scopes: []string{"reviews:read"}}, cfg)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		token   string
+		want    int
+	}{
+		{"role granted", RequireRole("admin", "owner")(ok), admin, http.StatusOK},
+		{"role missing", RequireRole("admin")(ok), reader, http.StatusForbidden},
+		{"scope granted", RequireScope("reviews:read")(ok), reader, http.StatusOK},
+		{"all scopes needed", RequireScope("reviews:read", "reviews:write")(ok), reader, http.StatusForbidden},
+		{"all scopes granted", RequireScope("reviews:read", "reviews:write")(ok), admin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			RequireAuth(cfg, tt.handler).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	RequireRole("admin")(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status = %d, want 401", rec.Code)
+	}
+}
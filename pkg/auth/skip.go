@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Skipper reports whether a request bypasses authentication, e.g. health
+// checks, metrics scrapes or webhooks that carry their own signature.
+type Skipper func(r *http.Request) bool
+
+// SkipPaths skips requests whose path is exactly one of paths. Paths are
+// compared once cleaned, so "/healthz/" and "/x/../healthz" match
+// "/healthz" while "/healthz/../admin" does not.
+func SkipPaths(paths ...string) Skipper {
+	set := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		set[cleanPath(p)] = true
+	}
+	return func(r *http.Request) bool {
+		return set[cleanPath(r.URL.Path)]
+	}
+}
+
+// SkipPrefixes skips requests under any of prefixes. Prefixes match whole
+// path segments: "/webhooks" skips "/webhooks" and "/webhooks/stripe" but
+// not "/webhooksx". Like SkipPaths it matches the cleaned path, so
+// "/webhooks/../admin" is not skipped.
+func SkipPrefixes(prefixes ...string) Skipper {
+	cleaned := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		cleaned[i] = strings.TrimSuffix(cleanPath(p), "/")
+	}
+	return func(r *http.Request) bool {
+		urlPath := cleanPath(r.URL.Path)
+		for _, p := range cleaned {
+			if urlPath == p || strings.HasPrefix(urlPath, p+"/") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// cleanPath returns p rooted and with dot segments and duplicate slashes
+// resolved, as http.ServeMux routes it.
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// SkipAny skips a request when any of skippers does. Nil skippers are
+// ignored.
+func SkipAny(skippers ...Skipper) Skipper {
+	return func(r *http.Request) bool {
+		for _, s := range skippers {
+			if s != nil && s(r) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SkipProbes skips the usual health, readiness and metrics endpoints:
+// /healthz, /livez, /readyz and /metrics.
+func SkipProbes() Skipper {
+	return SkipPaths("/healthz", "/livez", "/readyz", "/metrics")
+}
+
+const skippedKey ctxKey = "auth_skipped"
+
+// AuthSkipped reports whether a Skipper let the request through without
+// authentication. The authorization middlewares (RequireRole, RequireScope,
+// RequirePermissionFn and RequireFeature) pass such requests on, so one
+// Skipper on the authenticating middleware covers them. Other authenticators
+// only follow their own Skipper: a path skipped by RequireAuth is still
+// checked by VerifyWebhook or APIKeyMiddleware wrapping it.
+func AuthSkipped(ctx context.Context) bool {
+	skipped, _ := ctx.Value(skippedKey).(bool)
+	return skipped
+}
+
+// skip returns r marked as skipped when s skips it. Authenticators call it
+// with their own Skipper only; an outer middleware having skipped r does not
+// skip them too.
+func skip(s Skipper, r *http.Request) (*http.Request, bool) {
+	if s == nil || !s(r) {
+		return r, false
+	}
+	return r.WithContext(context.WithValue(r.Context(), skippedKey, true)), true
+}
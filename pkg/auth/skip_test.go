@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSkippers(t *testing.T) {
+	paths := SkipPaths("/healthz", "/metrics/")
+	prefixes := SkipPrefixes("/webhooks/", "/public")
+	for _, tc := range []struct {
+		skipper Skipper
+		path    string
+		want    bool
+	}{
+		{paths, "/healthz", true},
+		{paths, "/healthz/", true},
+		{paths, "/metrics", true},
+		{paths, "//healthz", true},
+		{paths, "/x/../healthz", true},
+		{paths, "/healthz/../admin", false},
+		{paths, "/healthzx", false},
+		{paths, "/healthz/deep", false},
+
+		{prefixes, "/webhooks", true},
+		{prefixes, "/webhooks/stripe", true},
+		{prefixes, "/public/./img.png", true},
+		{prefixes, "/webhooksx", false},
+		{prefixes, "/webhooks/../admin", false},
+		{prefixes, "/webhooks/stripe/../../admin", false},
+		{prefixes, "/publicity", false},
+		{prefixes, "/", false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.Path = tc.path
+		if got := tc.skipper(req); got != tc.want {
+			t.Errorf("skip %q = %v, want %v", tc.path, got, tc.want)
+		}
+	}
+
+	all := SkipAny(nil, SkipProbes(), prefixes)
+	for path, want := range map[string]bool{"/readyz": true, "/webhooks/x": true, "/admin": false} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if got := all(req); got != want {
+			t.Errorf("SkipAny %q = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestSkipDoesNotCarryToOtherAuthenticators(t *testing.T) {
+	partnerKey, partnerHash, _ := GenerateAPIKey("cp_")
+	mux := http.NewServeMux()
+	mux.Handle("/webhooks/pay", VerifyWebhook(WebhookConfig{Secrets: [][]byte{[]byte("secret")}})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+	mux.Handle("/webhooks/partner", APIKeyMiddleware(APIKeyConfig{Store: NewMemoryAPIKeyStore(APIKey{ID: "p1", Hash: partnerHash, Owner: "partner"})})(
+		RequireScope("reviews:read")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))))
+	mux.Handle("/healthz", RequireRole("admin")(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+	h := RequireAuth(&JWTConfig{SecretKey: []byte("secret"), Skipper: SkipAny(SkipPrefixes("/webhooks"), SkipProbes())}, mux)
+
+	for _, tc := range []struct {
+		path, apiKey string
+		want         int
+	}{
+		{"/webhooks/pay", "", http.StatusUnauthorized},
+		{"/webhooks/partner", "", http.StatusUnauthorized},
+		// Once the key authenticates, RequireScope checks it despite
+		// RequireAuth having skipped the path.
+		{"/webhooks/partner", partnerKey, http.StatusForbidden},
+		{"/healthz", "", http.StatusOK},
+		{"/admin", "", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+		if tc.apiKey != "" {
+			req.Header.Set("X-API-Key", tc.apiKey)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s key=%t: status = %d, want %d", tc.path, tc.apiKey != "", rec.Code, tc.want)
+		}
+	}
+}
@@ -4,7 +4,10 @@ package auth
 
 import (
 	"context"
+	"crypto/ed25519"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,73 +23,192 @@ type TelegramUser struct {
 	IsBot     bool   `json:"is_bot,omitempty"`
 }
 
-type ctxKey string
-
-const (
-	userKey     ctxKey        = "telegram_user"
-	authTimeout time.Duration = 24 * time.Hour
-)
+const authTimeout time.Duration = 24 * time.Hour
 
+// GetUserFromContext returns the Telegram user of an identity authenticated
+// by TelegramAuthMiddleware.
 func GetUserFromContext(ctx context.Context) (*TelegramUser, bool) {
-	u, ok := ctx.Value(userKey).(*TelegramUser)
-	return u, ok
+	id, ok := IdentityFromContext(ctx)
+	if !ok || id.Telegram == nil {
+		return nil, false
+	}
+	return id.Telegram, true
+}
+
+// TelegramConfig configures TelegramAuth. Set either BotToken, to check the
+// init data hash, or BotID, to check Telegram's Ed25519 signature instead.
+// The latter lets services other than the bot itself authenticate users
+// without ever holding the bot token.
+type TelegramConfig struct {
+	BotToken string
+	// BotID is the numeric ID of the bot the Mini App belongs to, the part
+	// of its token before the colon. It is used when BotToken is empty.
+	BotID int64
+	// Bots are further bots whose init data is accepted, e.g. staging and
+	// regional bots served by the same backend. They are tried in order
+	// after BotToken or BotID.
+	Bots []TelegramBot
+	// ResolveBot, when set, picks the one bot to check a request against,
+	// e.g. by a path segment or header, instead of trying every bot. An
+	// error rejects the request.
+	ResolveBot func(r *http.Request) (TelegramBot, error)
+	// MaxAge is how old init data may be, going by its auth_date. Default
+	// 24 hours; a negative value disables the check, for local development
+	// only.
+	MaxAge time.Duration
+	// PublicKey verifies the Ed25519 signature. Default TelegramPublicKey;
+	// use TelegramTestPublicKey for Telegram's test environment.
+	PublicKey ed25519.PublicKey
+	// Skipper lets matching requests through unauthenticated.
+	Skipper Skipper
+}
+
+// TelegramBot is a bot whose Mini App init data TelegramAuth accepts. With
+// a Token the init data hash is checked, otherwise Telegram's signature for
+// the bot ID.
+type TelegramBot struct {
+	// ID defaults to the ID in Token.
+	ID    int64
+	Token string
+}
+
+// TelegramAuthMiddleware is TelegramAuth with only bot tokens. Init data
+// signed for any of them is accepted.
+func TelegramAuthMiddleware(botTokens ...string) func(http.Handler) http.Handler {
+	cfg := TelegramConfig{}
+	for _, token := range botTokens {
+		cfg.Bots = append(cfg.Bots, TelegramBot{Token: token})
+	}
+	return TelegramAuth(cfg)
+}
+
+// botID returns the ID of bot, taken from its token if not set.
+func (bot TelegramBot) botID() int64 {
+	if bot.ID != 0 || bot.Token == "" {
+		return bot.ID
+	}
+	prefix, _, _ := strings.Cut(bot.Token, ":")
+	id, _ := strconv.ParseInt(prefix, 10, 64)
+	return id
+}
+
+func (cfg TelegramConfig) bots() []TelegramBot {
+	var bots []TelegramBot
+	if cfg.BotToken != "" || cfg.BotID != 0 {
+		bots = append(bots, TelegramBot{ID: cfg.BotID, Token: cfg.BotToken})
+	}
+	return append(bots, cfg.Bots...)
+}
+
+func (cfg TelegramConfig) maxAge() time.Duration {
+	if cfg.MaxAge == 0 {
+		return authTimeout
+	}
+	return cfg.MaxAge
+}
+
+// validate checks raw init data against the configured bots and returns the
+// one it was signed for.
+func (cfg TelegramConfig) validate(r *http.Request, raw string) (TelegramBot, error) {
+	bots := cfg.bots()
+	if cfg.ResolveBot != nil {
+		bot, err := cfg.ResolveBot(r)
+		if err != nil {
+			return TelegramBot{}, err
+		}
+		bots = []TelegramBot{bot}
+	}
+
+	publicKey := cfg.PublicKey
+	if publicKey == nil {
+		publicKey = TelegramPublicKey
+	}
+	maxAge := cfg.maxAge()
+	err := initdata.ErrSignInvalid
+	for _, bot := range bots {
+		if bot.Token != "" {
+			err = validateInitData(raw, bot.Token, maxAge)
+		} else {
+			err = validateThirdPartyInitData(raw, bot.ID, publicKey, maxAge)
+		}
+		// Only a signature mismatch can depend on the bot.
+		if !errors.Is(err, initdata.ErrSignInvalid) {
+			return bot, err
+		}
+	}
+	return TelegramBot{}, err
 }
 
-func TelegramAuthMiddleware(botToken string) func(http.Handler) http.Handler {
+// TelegramAuth authenticates requests carrying Mini App init data in an
+// "Authorization: tma <init-data>" header.
+func TelegramAuth(cfg TelegramConfig) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			if r, skipped := skip(cfg.Skipper, r); skipped {
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			authParts := strings.Split(authHeader, " ")
-			if len(authParts) != 2 {
-				http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+			identity, aerr := cfg.authenticate(r)
+			if aerr != nil {
+				aerr.write(w, r)
 				return
 			}
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		})
+	}
+}
 
-			authType := authParts[0]
-			authData := authParts[1]
+// authenticate checks the init data in the Authorization header of r.
+func (cfg TelegramConfig) authenticate(r *http.Request) (*Identity, *authError) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, &authError{http.StatusUnauthorized, CodeMissingCredentials, "Authorization header required"}
+	}
 
-			if authType != "tma" {
-				http.Error(w, "Invalid authorization type", http.StatusUnauthorized)
-				return
-			}
+	authParts := strings.Split(authHeader, " ")
+	if len(authParts) != 2 {
+		return nil, &authError{http.StatusUnauthorized, CodeUnauthorized, "Invalid authorization header format"}
+	}
 
-			if err := initdata.Validate(authData, botToken, authTimeout); err != nil {
-				http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
-				return
-			}
+	authType := authParts[0]
+	authData := authParts[1]
 
-			parsedData, err := initdata.Parse(authData)
-			if err != nil {
-				http.Error(w, "Invalid init data format", http.StatusUnauthorized)
-				return
-			}
+	if authType != "tma" {
+		return nil, &authError{http.StatusUnauthorized, CodeUnauthorized, "Invalid authorization type"}
+	}
 
-			if parsedData.User.ID == 0 {
-				http.Error(w, "User data not found", http.StatusUnauthorized)
-				return
-			}
+	bot, err := cfg.validate(r, authData)
+	if err != nil {
+		return nil, initDataError(err)
+	}
 
-			user := TelegramUser{
-				ID:        parsedData.User.ID,
-				FirstName: parsedData.User.FirstName,
-				LastName:  parsedData.User.LastName,
-				Username:  parsedData.User.Username,
-				PhotoURL:  parsedData.User.PhotoURL,
-				IsBot:     parsedData.User.IsBot,
-			}
+	parsedData, err := initdata.Parse(authData)
+	if err != nil {
+		return nil, &authError{http.StatusUnauthorized, CodeInvalidInitData, "Invalid init data format"}
+	}
 
-			if user.IsBot {
-				http.Error(w, "Forbidden: bots are not allowed", http.StatusForbidden)
-				return
-			}
+	if parsedData.User.ID == 0 {
+		return nil, &authError{http.StatusUnauthorized, CodeInvalidInitData, "User data not found"}
+	}
 
-			ctx := context.WithValue(r.Context(), userKey, &user)
-			next.ServeHTTP(w, r.WithContext(ctx))
-		})
+	user := TelegramUser{
+		ID:        parsedData.User.ID,
+		FirstName: parsedData.User.FirstName,
+		LastName:  parsedData.User.LastName,
+		Username:  parsedData.User.Username,
+		PhotoURL:  parsedData.User.PhotoURL,
+		IsBot:     parsedData.User.IsBot,
 	}
+
+	if user.IsBot {
+		return nil, &authError{http.StatusForbidden, CodeBotNotAllowed, "Bots are not allowed"}
+	}
+
+	return &Identity{
+		UserID:        strconv.FormatInt(user.ID, 10),
+		Source:        SourceTelegram,
+		Telegram:      &user,
+		TelegramBotID: bot.botID(),
+	}, nil
 }
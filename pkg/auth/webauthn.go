@@ -0,0 +1,817 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrWebAuthnChallenge is returned when a response answers a challenge
+	// that was never issued, was already used or has expired.
+	ErrWebAuthnChallenge = errors.New("webauthn: unknown or expired challenge")
+	// ErrWebAuthnInvalid is returned when a credential response fails
+	// verification; the wrapped message says why.
+	ErrWebAuthnInvalid    = errors.New("webauthn: invalid credential response")
+	ErrCredentialNotFound = errors.New("webauthn: credential not found")
+	ErrCredentialExists   = errors.New("webauthn: credential already registered")
+)
+
+// COSE algorithm identifiers of the supported passkey signatures.
+const (
+	COSEAlgES256 int64 = -7
+	COSEAlgEdDSA int64 = -8
+	COSEAlgRS256 int64 = -257
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	defaultChallengeTTL     = 5 * time.Minute
+	webAuthnChallengeLength = 32
+	maxWebAuthnBody         = 64 << 10
+	maxUserHandleLength     = 64
+)
+
+// Authenticator data flags.
+const (
+	flagUserPresent       = 0x01
+	flagUserVerified      = 0x04
+	flagAttestedCredData  = 0x40
+	flagExtensionDataIncl = 0x80
+)
+
+// URLBytes is binary data encoded as unpadded base64url in JSON, matching
+// PublicKeyCredential.toJSON() and parseCreationOptionsFromJSON() in the
+// browser.
+type URLBytes []byte
+
+func (b URLBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// WebAuthnCredential is a registered passkey.
+type WebAuthnCredential struct {
+	ID     []byte
+	UserID string
+	// PublicKey is the COSE_Key the authenticator returned at registration.
+	PublicKey []byte
+	Algorithm int64
+	// SignCount is the authenticator's signature counter at the last login.
+	// Authenticators that do not count always report 0.
+	SignCount  uint32
+	AAGUID     []byte
+	Transports []string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// CredentialStore persists passkeys. Implementations must be safe for
+// concurrent use.
+type CredentialStore interface {
+	// Create returns ErrCredentialExists if the credential ID is taken.
+	Create(ctx context.Context, cred WebAuthnCredential) error
+	// Get returns ErrCredentialNotFound for unknown IDs.
+	Get(ctx context.Context, id []byte) (WebAuthnCredential, error)
+	ListByUser(ctx context.Context, userID string) ([]WebAuthnCredential, error)
+	UpdateSignCount(ctx context.Context, id []byte, signCount uint32, usedAt time.Time) error
+}
+
+// WebAuthnSession is the server side of a pending registration or login,
+// stored under its challenge between the two requests of a ceremony.
+type WebAuthnSession struct {
+	// Challenge is the base64url encoded challenge sent to the browser.
+	Challenge string
+	// Ceremony is "webauthn.create" or "webauthn.get".
+	Ceremony string
+	// UserID is the account registering a passkey; empty for logins.
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// ChallengeStore keeps pending WebAuthn sessions. Implementations must be
+// safe for concurrent use; share one store between replicas behind a load
+// balancer.
+type ChallengeStore interface {
+	Put(ctx context.Context, s WebAuthnSession) error
+	// Take returns and deletes the session for challenge, so a challenge is
+	// answered at most once. It returns ErrWebAuthnChallenge for unknown or
+	// expired challenges.
+	Take(ctx context.Context, challenge string) (WebAuthnSession, error)
+}
+
+type WebAuthnConfig struct {
+	// RPID is the relying party ID: the domain of the admin console, e.g.
+	// "admin.example.com", or a registrable suffix of it.
+	RPID string
+	// RPName is shown by the browser during registration. Defaults to RPID.
+	RPName string
+	// Origins are the accepted origins of the console, e.g.
+	// "https://admin.example.com".
+	Origins []string
+
+	// Credentials defaults to an in-memory store.
+	Credentials CredentialStore
+	// Challenges defaults to an in-memory store.
+	Challenges ChallengeStore
+	// ChallengeTTL bounds how long a ceremony may take. Default 5m.
+	ChallengeTTL time.Duration
+
+	// AllowPresenceOnly accepts logins where the authenticator only saw a
+	// touch. By default user verification (PIN or biometrics) is required.
+	AllowPresenceOnly bool
+}
+
+// WebAuthn registers passkeys for signed-in accounts and logs accounts in
+// with them. Attestation is not requested or verified: the account that
+// registers a passkey is trusted, not the authenticator model.
+type WebAuthn struct {
+	cfg      WebAuthnConfig
+	rpIDHash [32]byte
+	now      func() time.Time
+}
+
+func NewWebAuthn(cfg WebAuthnConfig) (*WebAuthn, error) {
+	if cfg.RPID == "" {
+		return nil, errors.New("webauthn: RPID is required")
+	}
+	if len(cfg.Origins) == 0 {
+		return nil, errors.New("webauthn: at least one origin is required")
+	}
+	if cfg.RPName == "" {
+		cfg.RPName = cfg.RPID
+	}
+	if cfg.Credentials == nil {
+		cfg.Credentials = NewMemoryCredentialStore()
+	}
+	if cfg.Challenges == nil {
+		cfg.Challenges = NewMemoryChallengeStore()
+	}
+	if cfg.ChallengeTTL <= 0 {
+		cfg.ChallengeTTL = defaultChallengeTTL
+	}
+	return &WebAuthn{cfg: cfg, rpIDHash: sha256.Sum256([]byte(cfg.RPID)), now: time.Now}, nil
+}
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type WebAuthnUser struct {
+	ID          URLBytes `json:"id"`
+	Name        string   `json:"name"`
+	DisplayName string   `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         URLBytes `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CredentialCreationOptions is passed to
+// PublicKeyCredential.parseCreationOptionsFromJSON() in the browser.
+type CredentialCreationOptions struct {
+	RP                     RelyingParty           `json:"rp"`
+	User                   WebAuthnUser           `json:"user"`
+	Challenge              URLBytes               `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// CredentialRequestOptions is passed to
+// PublicKeyCredential.parseRequestOptionsFromJSON() in the browser.
+type CredentialRequestOptions struct {
+	Challenge        URLBytes `json:"challenge"`
+	Timeout          int64    `json:"timeout"`
+	RPID             string   `json:"rpId"`
+	UserVerification string   `json:"userVerification"`
+}
+
+type AttestationResponse struct {
+	ClientDataJSON    URLBytes `json:"clientDataJSON"`
+	AttestationObject URLBytes `json:"attestationObject"`
+	Transports        []string `json:"transports,omitempty"`
+}
+
+// RegistrationCredential is the JSON form (toJSON()) of the credential
+// returned by navigator.credentials.create().
+type RegistrationCredential struct {
+	ID       string              `json:"id"`
+	RawID    URLBytes            `json:"rawId"`
+	Type     string              `json:"type"`
+	Response AttestationResponse `json:"response"`
+}
+
+type AssertionResponse struct {
+	ClientDataJSON    URLBytes `json:"clientDataJSON"`
+	AuthenticatorData URLBytes `json:"authenticatorData"`
+	Signature         URLBytes `json:"signature"`
+	UserHandle        URLBytes `json:"userHandle,omitempty"`
+}
+
+// LoginCredential is the JSON form (toJSON()) of the credential returned by
+// navigator.credentials.get().
+type LoginCredential struct {
+	ID       string            `json:"id"`
+	RawID    URLBytes          `json:"rawId"`
+	Type     string            `json:"type"`
+	Response AssertionResponse `json:"response"`
+}
+
+func (wa *WebAuthn) userVerification() string {
+	if wa.cfg.AllowPresenceOnly {
+		return "preferred"
+	}
+	return "required"
+}
+
+// BeginRegistration starts adding a passkey to user's account. The passkey
+// is discoverable, so later logins need no username.
+func (wa *WebAuthn) BeginRegistration(ctx context.Context, user UserIdentity) (*CredentialCreationOptions, error) {
+	if user.UserID == "" || len(user.UserID) > maxUserHandleLength {
+		return nil, errors.New("webauthn: user ID must be 1 to 64 bytes")
+	}
+	existing, err := wa.cfg.Credentials.ListByUser(ctx, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := wa.newSession(ctx, ceremonyCreate, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &CredentialCreationOptions{
+		RP:        RelyingParty{ID: wa.cfg.RPID, Name: wa.cfg.RPName},
+		User:      WebAuthnUser{ID: URLBytes(user.UserID), Name: user.UserID, DisplayName: user.UserID},
+		Challenge: challenge,
+		PubKeyCredParams: []CredentialParameter{
+			{Type: "public-key", Alg: COSEAlgES256},
+			{Type: "public-key", Alg: COSEAlgEdDSA},
+			{Type: "public-key", Alg: COSEAlgRS256},
+		},
+		Timeout: wa.cfg.ChallengeTTL.Milliseconds(),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: wa.userVerification(),
+		},
+		Attestation: "none",
+	}
+	for _, c := range existing {
+		opts.ExcludeCredentials = append(opts.ExcludeCredentials, CredentialDescriptor{Type: "public-key", ID: c.ID, Transports: c.Transports})
+	}
+	return opts, nil
+}
+
+// FinishRegistration verifies the browser's answer to BeginRegistration
+// and stores the new passkey for userID.
+func (wa *WebAuthn) FinishRegistration(ctx context.Context, userID string, cred *RegistrationCredential) (WebAuthnCredential, error) {
+	session, err := wa.takeSession(ctx, cred.Response.ClientDataJSON, ceremonyCreate)
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+	if session.UserID != userID {
+		return WebAuthnCredential{}, ErrWebAuthnChallenge
+	}
+
+	obj, n, err := decodeCBOR(cred.Response.AttestationObject)
+	if err != nil || n != len(cred.Response.AttestationObject) {
+		return WebAuthnCredential{}, invalidWebAuthn("malformed attestation object")
+	}
+	m, _ := obj.(map[any]any)
+	rawAuthData, ok := m["authData"].([]byte)
+	if !ok {
+		return WebAuthnCredential{}, invalidWebAuthn("attestation object has no authData")
+	}
+	ad, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+	if err := wa.checkAuthenticatorData(ad); err != nil {
+		return WebAuthnCredential{}, err
+	}
+	if ad.credentialID == nil {
+		return WebAuthnCredential{}, invalidWebAuthn("no attested credential data")
+	}
+	if !bytes.Equal(ad.credentialID, cred.RawID) {
+		return WebAuthnCredential{}, invalidWebAuthn("credential ID mismatch")
+	}
+	_, alg, err := parseCOSEKey(ad.publicKey)
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+
+	now := wa.now()
+	stored := WebAuthnCredential{
+		ID:         ad.credentialID,
+		UserID:     userID,
+		PublicKey:  ad.publicKey,
+		Algorithm:  alg,
+		SignCount:  ad.signCount,
+		AAGUID:     ad.aaguid,
+		Transports: cred.Response.Transports,
+		CreatedAt:  now,
+	}
+	if err := wa.cfg.Credentials.Create(ctx, stored); err != nil {
+		return WebAuthnCredential{}, err
+	}
+	return stored, nil
+}
+
+// BeginLogin starts a passkey login. The browser offers every passkey it
+// holds for the RP ID; the account is known once the user picks one.
+func (wa *WebAuthn) BeginLogin(ctx context.Context) (*CredentialRequestOptions, error) {
+	challenge, err := wa.newSession(ctx, ceremonyGet, "")
+	if err != nil {
+		return nil, err
+	}
+	return &CredentialRequestOptions{
+		Challenge:        challenge,
+		Timeout:          wa.cfg.ChallengeTTL.Milliseconds(),
+		RPID:             wa.cfg.RPID,
+		UserVerification: wa.userVerification(),
+	}, nil
+}
+
+// FinishLogin verifies the browser's answer to BeginLogin and returns the
+// passkey used, whose UserID is the account that logged in.
+func (wa *WebAuthn) FinishLogin(ctx context.Context, cred *LoginCredential) (WebAuthnCredential, error) {
+	if _, err := wa.takeSession(ctx, cred.Response.ClientDataJSON, ceremonyGet); err != nil {
+		return WebAuthnCredential{}, err
+	}
+	stored, err := wa.cfg.Credentials.Get(ctx, cred.RawID)
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+	if len(cred.Response.UserHandle) > 0 && string(cred.Response.UserHandle) != stored.UserID {
+		return WebAuthnCredential{}, invalidWebAuthn("user handle does not match credential")
+	}
+
+	ad, err := parseAuthenticatorData(cred.Response.AuthenticatorData)
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+	if err := wa.checkAuthenticatorData(ad); err != nil {
+		return WebAuthnCredential{}, err
+	}
+	key, alg, err := parseCOSEKey(stored.PublicKey)
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+	clientDataHash := sha256.Sum256(cred.Response.ClientDataJSON)
+	signed := append(slices.Clip([]byte(cred.Response.AuthenticatorData)), clientDataHash[:]...)
+	if !verifyCOSESignature(key, alg, signed, cred.Response.Signature) {
+		return WebAuthnCredential{}, invalidWebAuthn("bad signature")
+	}
+	// A counter that does not grow means two copies of the key exist.
+	if (ad.signCount != 0 || stored.SignCount != 0) && ad.signCount <= stored.SignCount {
+		return WebAuthnCredential{}, invalidWebAuthn("signature counter did not increase")
+	}
+
+	now := wa.now()
+	if err := wa.cfg.Credentials.UpdateSignCount(ctx, stored.ID, ad.signCount, now); err != nil {
+		return WebAuthnCredential{}, err
+	}
+	stored.SignCount = ad.signCount
+	stored.LastUsedAt = now
+	return stored, nil
+}
+
+func (wa *WebAuthn) newSession(ctx context.Context, ceremony, userID string) (URLBytes, error) {
+	challenge := make([]byte, webAuthnChallengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	err := wa.cfg.Challenges.Put(ctx, WebAuthnSession{
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Ceremony:  ceremony,
+		UserID:    userID,
+		ExpiresAt: wa.now().Add(wa.cfg.ChallengeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+type collectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// takeSession checks the client data of a response and consumes the
+// session of the challenge it answers.
+func (wa *WebAuthn) takeSession(ctx context.Context, raw []byte, ceremony string) (WebAuthnSession, error) {
+	var cd collectedClientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return WebAuthnSession{}, invalidWebAuthn("malformed client data")
+	}
+	if cd.Type != ceremony {
+		return WebAuthnSession{}, invalidWebAuthn("unexpected client data type " + cd.Type)
+	}
+	if cd.CrossOrigin || !slices.Contains(wa.cfg.Origins, cd.Origin) {
+		return WebAuthnSession{}, invalidWebAuthn("origin not allowed: " + cd.Origin)
+	}
+	session, err := wa.cfg.Challenges.Take(ctx, strings.TrimRight(cd.Challenge, "="))
+	if err != nil {
+		return WebAuthnSession{}, err
+	}
+	if session.Ceremony != ceremony || wa.now().After(session.ExpiresAt) {
+		return WebAuthnSession{}, ErrWebAuthnChallenge
+	}
+	return session, nil
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+func parseAuthenticatorData(b []byte) (authenticatorData, error) {
+	if len(b) < 37 {
+		return authenticatorData{}, invalidWebAuthn("authenticator data too short")
+	}
+	ad := authenticatorData{
+		rpIDHash:  b[:32],
+		flags:     b[32],
+		signCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+	rest := b[37:]
+	if ad.flags&flagAttestedCredData != 0 {
+		if len(rest) < 18 {
+			return authenticatorData{}, invalidWebAuthn("attested credential data too short")
+		}
+		ad.aaguid = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLen {
+			return authenticatorData{}, invalidWebAuthn("credential ID truncated")
+		}
+		ad.credentialID, rest = rest[:idLen], rest[idLen:]
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return authenticatorData{}, invalidWebAuthn("malformed credential public key")
+		}
+		ad.publicKey, rest = rest[:n], rest[n:]
+	}
+	if ad.flags&flagExtensionDataIncl != 0 {
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return authenticatorData{}, invalidWebAuthn("malformed extension data")
+		}
+		rest = rest[n:]
+	}
+	if len(rest) != 0 {
+		return authenticatorData{}, invalidWebAuthn("trailing bytes in authenticator data")
+	}
+	return ad, nil
+}
+
+func (wa *WebAuthn) checkAuthenticatorData(ad authenticatorData) error {
+	if subtle.ConstantTimeCompare(ad.rpIDHash, wa.rpIDHash[:]) != 1 {
+		return invalidWebAuthn("RP ID hash mismatch")
+	}
+	if ad.flags&flagUserPresent == 0 {
+		return invalidWebAuthn("user not present")
+	}
+	if !wa.cfg.AllowPresenceOnly && ad.flags&flagUserVerified == 0 {
+		return invalidWebAuthn("user not verified")
+	}
+	return nil
+}
+
+// parseCOSEKey decodes a COSE_Key holding an ES256, EdDSA (Ed25519) or
+// RS256 public key.
+func parseCOSEKey(b []byte) (crypto.PublicKey, int64, error) {
+	v, n, err := decodeCBOR(b)
+	if err != nil || n != len(b) {
+		return nil, 0, invalidWebAuthn("malformed COSE key")
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, 0, invalidWebAuthn("malformed COSE key")
+	}
+	kty, _ := m[int64(1)].(int64)
+	alg, _ := m[int64(3)].(int64)
+	crv, _ := m[int64(-1)].(int64)
+
+	switch {
+	case alg == COSEAlgES256 && kty == 2 && crv == 1:
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if len(x) != 32 || len(y) != 32 {
+			return nil, 0, invalidWebAuthn("bad P-256 coordinates")
+		}
+		// ecdh rejects points that are not on the curve.
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, 0, invalidWebAuthn("bad P-256 point")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, alg, nil
+	case alg == COSEAlgEdDSA && kty == 1 && crv == 6:
+		x, _ := m[int64(-2)].([]byte)
+		if len(x) != ed25519.PublicKeySize {
+			return nil, 0, invalidWebAuthn("bad Ed25519 key")
+		}
+		return ed25519.PublicKey(x), alg, nil
+	case alg == COSEAlgRS256 && kty == 3:
+		nb, _ := m[int64(-1)].([]byte)
+		eb, _ := m[int64(-2)].([]byte)
+		if len(eb) == 0 || len(eb) > 4 {
+			return nil, 0, invalidWebAuthn("bad RSA exponent")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: int(new(big.Int).SetBytes(eb).Int64())}
+		if key.N.BitLen() < 2048 {
+			return nil, 0, invalidWebAuthn("RSA key shorter than 2048 bits")
+		}
+		return key, alg, nil
+	}
+	return nil, 0, invalidWebAuthn(fmt.Sprintf("unsupported COSE key (kty %d, alg %d)", kty, alg))
+}
+
+func verifyCOSESignature(key crypto.PublicKey, alg int64, data, sig []byte) bool {
+	digest := sha256.Sum256(data)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return alg == COSEAlgES256 && ecdsa.VerifyASN1(k, digest[:], sig)
+	case ed25519.PublicKey:
+		return alg == COSEAlgEdDSA && ed25519.Verify(k, data, sig)
+	case *rsa.PublicKey:
+		return alg == COSEAlgRS256 && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	}
+	return false
+}
+
+func invalidWebAuthn(reason string) error {
+	return fmt.Errorf("%w: %s", ErrWebAuthnInvalid, reason)
+}
+
+// WebAuthnIdentityResolver maps the account that logged in with a passkey to
+// the identity its access token is issued for.
+type WebAuthnIdentityResolver interface {
+	ResolveWebAuthnUser(ctx context.Context, userID string) (UserIdentity, error)
+}
+
+type WebAuthnIdentityResolverFunc func(ctx context.Context, userID string) (UserIdentity, error)
+
+func (f WebAuthnIdentityResolverFunc) ResolveWebAuthnUser(ctx context.Context, userID string) (UserIdentity, error) {
+	return f(ctx, userID)
+}
+
+// RegistrationOptionsHandler answers a POST with the options for adding a
+// passkey to the caller's account. It must run behind an auth middleware,
+// e.g. RequireAuth, so passkeys are only added to signed-in accounts.
+func (wa *WebAuthn) RegistrationOptionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowPost(w, r) {
+			return
+		}
+		id, ok := IdentityFromContext(r.Context())
+		if !ok {
+			writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "")
+			return
+		}
+		opts, err := wa.BeginRegistration(r.Context(), UserIdentity{UserID: id.UserID})
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "")
+			return
+		}
+		writeWebAuthnJSON(w, http.StatusOK, opts)
+	})
+}
+
+// RegistrationHandler stores the passkey posted as the toJSON() form of the
+// navigator.credentials.create() result. Like RegistrationOptionsHandler it
+// must run behind an auth middleware.
+func (wa *WebAuthn) RegistrationHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowPost(w, r) {
+			return
+		}
+		id, ok := IdentityFromContext(r.Context())
+		if !ok {
+			writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "")
+			return
+		}
+		var cred RegistrationCredential
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxWebAuthnBody)).Decode(&cred); err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeBadRequest, "")
+			return
+		}
+		stored, err := wa.FinishRegistration(r.Context(), id.UserID, &cred)
+		switch {
+		case errors.Is(err, ErrWebAuthnInvalid), errors.Is(err, ErrWebAuthnChallenge), errors.Is(err, ErrCredentialExists):
+			writeError(w, r, http.StatusBadRequest, CodeBadRequest, "")
+			return
+		case err != nil:
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "")
+			return
+		}
+		writeWebAuthnJSON(w, http.StatusCreated, map[string]URLBytes{"id": stored.ID})
+	})
+}
+
+// LoginOptionsHandler answers a POST with the options for a passkey login.
+func (wa *WebAuthn) LoginOptionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowPost(w, r) {
+			return
+		}
+		opts, err := wa.BeginLogin(r.Context())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "")
+			return
+		}
+		writeWebAuthnJSON(w, http.StatusOK, opts)
+	})
+}
+
+// LoginHandler verifies the passkey assertion posted as the toJSON() form of
+// the navigator.credentials.get() result and issues the same TokenResponse
+// as TelegramExchangeHandler, including a refresh token if
+// cfg.RefreshTokens is set.
+func (wa *WebAuthn) LoginHandler(resolver WebAuthnIdentityResolver, cfg *JWTConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowPost(w, r) {
+			return
+		}
+		var cred LoginCredential
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxWebAuthnBody)).Decode(&cred); err != nil {
+			writeError(w, r, http.StatusBadRequest, CodeBadRequest, "")
+			return
+		}
+		stored, err := wa.FinishLogin(r.Context(), &cred)
+		switch {
+		case errors.Is(err, ErrWebAuthnInvalid), errors.Is(err, ErrWebAuthnChallenge), errors.Is(err, ErrCredentialNotFound):
+			writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "")
+			return
+		case err != nil:
+			writeError(w, r, http.StatusInternalServerError, CodeInternal, "")
+			return
+		}
+
+		identity, err := resolver.ResolveWebAuthnUser(r.Context(), stored.UserID)
+		respondWithTokens(w, r, identity, err, cfg)
+	})
+}
+
+func writeWebAuthnJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// MemoryCredentialStore keeps passkeys in process memory. It is the default
+// CredentialStore and only suits tests and development; passkeys do not
+// survive a restart.
+type MemoryCredentialStore struct {
+	mu    sync.Mutex
+	creds map[string]WebAuthnCredential
+}
+
+func NewMemoryCredentialStore() *MemoryCredentialStore {
+	return &MemoryCredentialStore{creds: make(map[string]WebAuthnCredential)}
+}
+
+func (s *MemoryCredentialStore) Create(_ context.Context, cred WebAuthnCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.creds[string(cred.ID)]; ok {
+		return ErrCredentialExists
+	}
+	s.creds[string(cred.ID)] = cred
+	return nil
+}
+
+func (s *MemoryCredentialStore) Get(_ context.Context, id []byte) (WebAuthnCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cred, ok := s.creds[string(id)]
+	if !ok {
+		return WebAuthnCredential{}, ErrCredentialNotFound
+	}
+	return cred, nil
+}
+
+func (s *MemoryCredentialStore) ListByUser(_ context.Context, userID string) ([]WebAuthnCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var creds []WebAuthnCredential
+	for _, c := range s.creds {
+		if c.UserID == userID {
+			creds = append(creds, c)
+		}
+	}
+	slices.SortFunc(creds, func(a, b WebAuthnCredential) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return creds, nil
+}
+
+func (s *MemoryCredentialStore) UpdateSignCount(_ context.Context, id []byte, signCount uint32, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cred, ok := s.creds[string(id)]
+	if !ok {
+		return ErrCredentialNotFound
+	}
+	cred.SignCount = signCount
+	cred.LastUsedAt = usedAt
+	s.creds[string(id)] = cred
+	return nil
+}
+
+// MemoryChallengeStore keeps pending ceremonies in process memory. It is the
+// default ChallengeStore and suits single-instance services.
+type MemoryChallengeStore struct {
+	mu       sync.Mutex
+	sessions map[string]WebAuthnSession
+	ops      int
+}
+
+func NewMemoryChallengeStore() *MemoryChallengeStore {
+	return &MemoryChallengeStore{sessions: make(map[string]WebAuthnSession)}
+}
+
+func (s *MemoryChallengeStore) Put(_ context.Context, session WebAuthnSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ops++; s.ops%256 == 0 {
+		now := time.Now()
+		for c, sess := range s.sessions {
+			if now.After(sess.ExpiresAt) {
+				delete(s.sessions, c)
+			}
+		}
+	}
+	s.sessions[session.Challenge] = session
+	return nil
+}
+
+func (s *MemoryChallengeStore) Take(_ context.Context, challenge string) (WebAuthnSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[challenge]
+	if !ok {
+		return WebAuthnSession{}, ErrWebAuthnChallenge
+	}
+	delete(s.sessions, challenge)
+	if time.Now().After(session.ExpiresAt) {
+		return WebAuthnSession{}, ErrWebAuthnChallenge
+	}
+	return session, nil
+}
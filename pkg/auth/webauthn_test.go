@@ -0,0 +1,242 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// cborPairs is a CBOR map with a fixed key order.
+type cborPairs [][2]any
+
+// encodeCBOR covers what a test authenticator needs to emit.
+func encodeCBOR(v any) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n <= 0xff:
+			return []byte{major<<5 | 24, byte(n)}
+		default:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		}
+	}
+	switch v := v.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case cborPairs:
+		out := head(5, uint64(len(v)))
+		for _, kv := range v {
+			out = append(out, encodeCBOR(kv[0])...)
+			out = append(out, encodeCBOR(kv[1])...)
+		}
+		return out
+	}
+	panic("unsupported CBOR value")
+}
+
+// testAuthenticator is a software passkey holding one P-256 credential.
+type testAuthenticator struct {
+	t      *testing.T
+	key    *ecdsa.PrivateKey
+	id     []byte
+	rpID   string
+	origin string
+	count  uint32
+}
+
+func newTestAuthenticator(t *testing.T, rpID, origin string) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &testAuthenticator{t: t, key: key, id: id, rpID: rpID, origin: origin}
+}
+
+func (a *testAuthenticator) clientData(typ string, challenge []byte) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"type":      typ,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    a.origin,
+	})
+	return b
+}
+
+func (a *testAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	out := append(rpIDHash[:], flags)
+	out = binary.BigEndian.AppendUint32(out, a.count)
+	return append(out, attested...)
+}
+
+func (a *testAuthenticator) create(challenge []byte) RegistrationCredential {
+	coseKey := encodeCBOR(cborPairs{
+		{1, 2}, {3, -7}, {-1, 1},
+		{-2, a.key.X.FillBytes(make([]byte, 32))},
+		{-3, a.key.Y.FillBytes(make([]byte, 32))},
+	})
+	attested := make([]byte, 16) // zero AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.id)))
+	attested = append(append(attested, a.id...), coseKey...)
+	attObj := encodeCBOR(cborPairs{
+		{"fmt", "none"},
+		{"attStmt", cborPairs{}},
+		{"authData", a.authData(flagUserPresent|flagUserVerified|flagAttestedCredData, attested)},
+	})
+	return RegistrationCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(a.id),
+		RawID: a.id,
+		Type:  "public-key",
+		Response: AttestationResponse{
+			ClientDataJSON:    a.clientData(ceremonyCreate, challenge),
+			AttestationObject: attObj,
+			Transports:        []string{"internal"},
+		},
+	}
+}
+
+func (a *testAuthenticator) get(challenge []byte, userID string) LoginCredential {
+	a.count++
+	authData := a.authData(flagUserPresent|flagUserVerified, nil)
+	clientData := a.clientData(ceremonyGet, challenge)
+	hash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(bytes.Clone(authData), hash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return LoginCredential{
+		ID:    base64.RawURLEncoding.EncodeToString(a.id),
+		RawID: a.id,
+		Type:  "public-key",
+		Response: AssertionResponse{
+			ClientDataJSON:    clientData,
+			AuthenticatorData: authData,
+			Signature:         sig,
+			UserHandle:        URLBytes(userID),
+		},
+	}
+}
+
+func postJSON(t *testing.T, ctx context.Context, h http.Handler, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWebAuthnRegisterAndLogin(t *testing.T) {
+	wa, err := NewWebAuthn(WebAuthnConfig{RPID: "admin.example.com", Origins: []string{"https://admin.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &JWTConfig{SecretKey: []byte("secret"), AccessTTL: time.Minute}
+	resolver := WebAuthnIdentityResolverFunc(func(_ context.Context, userID string) (UserIdentity, error) {
+		return UserIdentity{UserID: userID, Roles: []string{"admin"}}, nil
+	})
+	authn := newTestAuthenticator(t, "admin.example.com", "https://admin.example.com")
+	signedIn := WithIdentity(context.Background(), &Identity{UserID: "admin-1", Source: SourceJWT})
+
+	rec := postJSON(t, signedIn, wa.RegistrationOptionsHandler(), nil)
+	var creation CredentialCreationOptions
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &creation) != nil {
+		t.Fatalf("registration options: %d %s", rec.Code, rec.Body)
+	}
+	if string(creation.User.ID) != "admin-1" || creation.RP.ID != "admin.example.com" {
+		t.Fatalf("creation options = %+v", creation)
+	}
+	if rec := postJSON(t, signedIn, wa.RegistrationHandler(), authn.create(creation.Challenge)); rec.Code != http.StatusCreated {
+		t.Fatalf("registration: %d %s", rec.Code, rec.Body)
+	}
+
+	login := func() (LoginCredential, *httptest.ResponseRecorder) {
+		rec := postJSON(t, context.Background(), wa.LoginOptionsHandler(), nil)
+		var request CredentialRequestOptions
+		if err := json.Unmarshal(rec.Body.Bytes(), &request); err != nil {
+			t.Fatal(err)
+		}
+		cred := authn.get(request.Challenge, "admin-1")
+		return cred, postJSON(t, context.Background(), wa.LoginHandler(resolver, cfg), cred)
+	}
+
+	cred, rec := login()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", rec.Code, rec.Body)
+	}
+	var resp TokenResponse
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	claims, err := ParseAccessJWT(resp.AccessToken, cfg)
+	if err != nil || claims.Subject != "admin-1" || len(claims.Roles) != 1 {
+		t.Fatalf("claims = %+v, err = %v", claims, err)
+	}
+
+	// The challenge was spent.
+	if rec := postJSON(t, context.Background(), wa.LoginHandler(resolver, cfg), cred); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("replayed login = %d, want 401", rec.Code)
+	}
+	// A cloned authenticator reuses an old counter.
+	authn.count = 0
+	if _, rec := login(); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("stale counter login = %d, want 401", rec.Code)
+	}
+}
+
+func TestWebAuthnRejects(t *testing.T) {
+	ctx := context.Background()
+	wa, _ := NewWebAuthn(WebAuthnConfig{RPID: "admin.example.com", Origins: []string{"https://admin.example.com"}})
+	authn := newTestAuthenticator(t, "admin.example.com", "https://admin.example.com")
+
+	opts, _ := wa.BeginRegistration(ctx, UserIdentity{UserID: "admin-1"})
+	if _, err := wa.FinishRegistration(ctx, "admin-2", ptr(authn.create(opts.Challenge))); !errors.Is(err, ErrWebAuthnChallenge) {
+		t.Fatalf("other user err = %v, want ErrWebAuthnChallenge", err)
+	}
+
+	phishing := newTestAuthenticator(t, "admin.example.com", "https://admin.example.co")
+	opts, _ = wa.BeginRegistration(ctx, UserIdentity{UserID: "admin-1"})
+	if _, err := wa.FinishRegistration(ctx, "admin-1", ptr(phishing.create(opts.Challenge))); !errors.Is(err, ErrWebAuthnInvalid) {
+		t.Fatalf("foreign origin err = %v, want ErrWebAuthnInvalid", err)
+	}
+
+	otherRP := newTestAuthenticator(t, "evil.example.com", "https://admin.example.com")
+	opts, _ = wa.BeginRegistration(ctx, UserIdentity{UserID: "admin-1"})
+	if _, err := wa.FinishRegistration(ctx, "admin-1", ptr(otherRP.create(opts.Challenge))); !errors.Is(err, ErrWebAuthnInvalid) {
+		t.Fatalf("foreign RP ID err = %v, want ErrWebAuthnInvalid", err)
+	}
+
+	opts, _ = wa.BeginRegistration(ctx, UserIdentity{UserID: "admin-1"})
+	if _, err := wa.FinishRegistration(ctx, "admin-1", ptr(authn.create(opts.Challenge))); err != nil {
+		t.Fatal(err)
+	}
+	request, _ := wa.BeginLogin(ctx)
+	cred := authn.get(request.Challenge, "admin-1")
+	cred.Response.Signature[len(cred.Response.Signature)-1] ^= 1
+	if _, err := wa.FinishLogin(ctx, &cred); !errors.Is(err, ErrWebAuthnInvalid) {
+		t.Fatalf("bad signature err = %v, want ErrWebAuthnInvalid", err)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
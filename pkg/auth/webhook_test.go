@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	oldSecret, newSecret := []byte("old"), []byte("new")
+	var got string
+	h := VerifyWebhook(WebhookConfig{
+		Secrets:      [][]byte{newSecret, oldSecret},
+		MaxBodyBytes: 64,
+		Now:          func() time.Time { return now },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	}))
+
+	send := func(body, sig string, ts int64) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/payments", strings.NewReader(body))
+		if sig != "" {
+			req.Header.Set("X-Signature", sig)
+		}
+		req.Header.Set("X-Timestamp", strconv.FormatInt(ts, 10))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	body := `{"event":"payment.succeeded"}`
+	ts := now.Unix()
+	if code := send(body, SignWebhook(newSecret, ts, []byte(body)), ts); code != http.StatusOK || got != body {
+		t.Fatalf("valid webhook: %d, body %q", code, got)
+	}
+	if code := send(body, "sha256="+SignWebhook(oldSecret, ts, []byte(body)), ts); code != http.StatusOK {
+		t.Fatalf("previous secret with prefix: %d", code)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		sig  string
+		ts   int64
+		want int
+	}{
+		{"missing signature", body, "", ts, http.StatusUnauthorized},
+		{"wrong secret", body, SignWebhook([]byte("other"), ts, []byte(body)), ts, http.StatusUnauthorized},
+		{"tampered body", body + " ", SignWebhook(newSecret, ts, []byte(body)), ts, http.StatusUnauthorized},
+		{"moved timestamp", body, SignWebhook(newSecret, ts, []byte(body)), ts + 1, http.StatusUnauthorized},
+		{"stale", body, SignWebhook(newSecret, ts-600, []byte(body)), ts - 600, http.StatusUnauthorized},
+		{"future", body, SignWebhook(newSecret, ts+600, []byte(body)), ts + 600, http.StatusUnauthorized},
+		{"too large", strings.Repeat("x", 65), SignWebhook(newSecret, ts, []byte(strings.Repeat("x", 65))), ts, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		if code := send(tt.body, tt.sig, tt.ts); code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, code, tt.want)
+		}
+	}
+}
+
+func TestVerifyWebhookNoTimestamp(t *testing.T) {
+	secret := []byte("secret")
+	h := VerifyWebhook(WebhookConfig{Secrets: [][]byte{secret}, SignatureHeader: "X-Hub-Signature-256", NoTimestamp: true})(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("payload"))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+SignWebhook(secret, 0, []byte("payload")))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// maxClockSkew is how far in the future occurred_at may be before the consumer
+// logs it as producer clock skew.
+const maxClockSkew = time.Second
+
+var (
+	envelopeAgeOnce      sync.Once
+	envelopeAgeHistogram metric.Float64Histogram
+)
+
+func envelopeAge() metric.Float64Histogram {
+	envelopeAgeOnce.Do(func() {
+		envelopeAgeHistogram, _ = otel.Meter(meterName).Float64Histogram("events_envelope_age_seconds",
+			metric.WithDescription("Time between an event's occurred_at and its consumption"),
+			metric.WithUnit("s"),
+			metric.WithExplicitBucketBoundaries(0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600),
+		)
+	})
+	return envelopeAgeHistogram
+}
+
+// SetAgeWarningThreshold makes the consumer log events older than d when they
+// are consumed. Zero disables the warning; the age histogram is always
+// recorded.
+func (kc *KafkaConsumer) SetAgeWarningThreshold(d time.Duration) {
+	kc.ageWarn = d
+}
+
+// recordAge records now - occurred_at for the event. Events stamped in the
+// future count as age 0 and are logged, as that points at clock skew.
+func (kc *KafkaConsumer) recordAge(ctx context.Context, rawEnvelope map[string]json.RawMessage, sagaID, eventType string, now time.Time) {
+	raw, ok := rawEnvelope["occurred_at"]
+	if !ok {
+		return
+	}
+	var occurredAt time.Time
+	if err := json.Unmarshal(raw, &occurredAt); err != nil || occurredAt.IsZero() {
+		return
+	}
+
+	age := now.Sub(occurredAt)
+	if age < -maxClockSkew {
+		log.Printf("event occurred_at is %s in the future (clock skew?) - SagaID: %s, Type: %s", -age, sagaID, eventType)
+	}
+	if age < 0 {
+		age = 0
+	}
+	if kc.ageWarn > 0 && age > kc.ageWarn {
+		log.Printf("event consumed %s after it occurred (threshold %s) - SagaID: %s, Type: %s", age, kc.ageWarn, sagaID, eventType)
+	}
+
+	if h := envelopeAge(); h != nil {
+		h.Record(ctx, age.Seconds(), metric.WithAttributes(attribute.String("event_type", eventType)))
+	}
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestKafkaConsumer_RecordAge(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	prev := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(prev) })
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	raw := func(occurredAt time.Time) map[string]json.RawMessage {
+		b, _ := json.Marshal(occurredAt)
+		return map[string]json.RawMessage{"occurred_at": b}
+	}
+
+	kc := &KafkaConsumer{}
+	kc.SetAgeWarningThreshold(time.Minute)
+	kc.recordAge(context.Background(), raw(now.Add(-2*time.Second)), "s1", PipelineExtractRequest, now)
+	kc.recordAge(context.Background(), raw(now.Add(-2*time.Hour)), "s2", PipelineExtractRequest, now)
+	kc.recordAge(context.Background(), raw(now.Add(time.Hour)), "s3", PipelinePrepareRequest, now)
+	kc.recordAge(context.Background(), map[string]json.RawMessage{}, "s4", PipelinePrepareRequest, now)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	var hist metricdata.Histogram[float64]
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "events_envelope_age_seconds" {
+				hist = m.Data.(metricdata.Histogram[float64])
+			}
+		}
+	}
+	require.Len(t, hist.DataPoints, 2)
+
+	byType := map[string]metricdata.HistogramDataPoint[float64]{}
+	for _, dp := range hist.DataPoints {
+		v, _ := dp.Attributes.Value("event_type")
+		byType[v.AsString()] = dp
+	}
+	extract := byType[PipelineExtractRequest]
+	assert.Equal(t, uint64(2), extract.Count)
+	assert.InDelta(t, 7202, extract.Sum, 0.001)
+
+	// Events from the future are clamped to zero age.
+	prepare := byType[PipelinePrepareRequest]
+	assert.Equal(t, uint64(1), prepare.Count)
+	assert.Zero(t, prepare.Sum)
+}
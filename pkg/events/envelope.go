@@ -20,6 +20,12 @@ type Meta struct {
 	Initiator     Initiator `json:"initiator"`
 	Retries       int       `json:"retries"`
 	SchemaVersion string    `json:"schema_version"`
+	// Sequence is an optional per-saga step counter starting at 1, used by
+	// consumers to detect lost or reordered events. Zero means unsequenced.
+	Sequence uint64 `json:"sequence,omitempty"`
+	// TenantID is the tenant the event belongs to, typically copied from the
+	// caller's auth identity. Tenant-scoped consumers skip other tenants.
+	TenantID string `json:"tenant_id,omitempty"`
 }
 
 // Envelope defines the standard message envelope used for all events.
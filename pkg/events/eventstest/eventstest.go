@@ -0,0 +1,337 @@
+// Package eventstest replays captured Kafka traffic into an in-memory bus,
+// so orchestrator and service changes can be checked against the shape of
+// real production traffic without a broker:
+//
+//	msgs, _ := eventstest.LoadCaptured("testdata/saga_burst.jsonl")
+//	bus := eventstest.NewBus()
+//	bus.SubscribeProcessor(events.PipelineExtractCompleted, orchestrator)
+//	report, err := eventstest.Replay(ctx, bus, msgs, eventstest.ReplayOptions{Speed: 60})
+//
+// Messages of one partition are delivered in offset order, like a consumer
+// would see them, and the partitions are interleaved by message time.
+package eventstest
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/quiby-ai/common/pkg/events"
+	"github.com/quiby-ai/common/pkg/obs"
+)
+
+// Captured is one message as read from Kafka. LoadCaptured reads them as
+// JSON lines; FromPeeked converts the output of events.PeekTopic.
+type Captured struct {
+	Topic     string          `json:"topic"`
+	Partition int             `json:"partition"`
+	Offset    int64           `json:"offset"`
+	Key       string          `json:"key,omitempty"`
+	Time      time.Time       `json:"time"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// FromPeeked converts messages read from topic with events.PeekTopic.
+func FromPeeked(topic string, msgs []events.PeekedMessage) []Captured {
+	out := make([]Captured, len(msgs))
+	for i, m := range msgs {
+		out[i] = Captured{Topic: topic, Partition: m.Partition, Offset: m.Offset, Key: m.Key, Time: m.Time, Value: m.Raw}
+	}
+	return out
+}
+
+// LoadCaptured reads a file of Captured JSON lines, e.g. written by
+// WriteCaptured. Blank lines are skipped.
+func LoadCaptured(path string) ([]Captured, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Captured
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var c Captured
+		if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		out = append(out, c)
+	}
+	return out, sc.Err()
+}
+
+// WriteCaptured writes msgs as JSON lines.
+func WriteCaptured(w io.Writer, msgs []Captured) error {
+	enc := json.NewEncoder(w)
+	for _, m := range msgs {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Message is a message delivered by a Bus. Envelope is decoded from Value
+// with a raw payload; EnvelopeErr is set if Value is not an envelope.
+type Message struct {
+	Topic       string
+	Partition   int
+	Offset      int64
+	Key         []byte
+	Value       []byte
+	Time        time.Time
+	Envelope    events.Envelope[json.RawMessage]
+	EnvelopeErr error
+}
+
+type Handler func(ctx context.Context, m Message) error
+
+// Bus is an in-memory stand-in for Kafka. It implements
+// events.EventPublisher: published envelopes are recorded and delivered to
+// the subscribers of the topic named by their type, so a chain of services
+// can be run in one test. Delivery is synchronous.
+type Bus struct {
+	mu        sync.Mutex
+	handlers  map[string][]Handler
+	published []Message
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers h for messages on topic.
+func (b *Bus) Subscribe(topic string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], h)
+}
+
+// SubscribeProcessor hands messages on topic to p the way a KafkaConsumer
+// does: with the payload decoded into its registered struct and validated.
+func (b *Bus) SubscribeProcessor(topic string, p events.SagaMessageProcessor) {
+	b.Subscribe(topic, func(ctx context.Context, m Message) error {
+		if m.EnvelopeErr != nil {
+			return m.EnvelopeErr
+		}
+		env, err := events.DecodeTypedEnvelope(m.Envelope)
+		if err != nil {
+			return err
+		}
+		if verrs := events.ValidatePayload(env.Payload); len(verrs) > 0 {
+			return fmt.Errorf("%s payload validation failed: %v", env.Type, verrs)
+		}
+		return p.Handle(obs.WithSagaID(ctx, env.SagaID), env.Payload, env.SagaID)
+	})
+}
+
+// PublishEvent records envelope and delivers it to the subscribers of
+// envelope.Type. Their errors are returned joined.
+func (b *Bus) PublishEvent(ctx context.Context, key []byte, envelope events.Envelope[any]) error {
+	value, err := events.MarshalEnvelope(envelope)
+	if err != nil {
+		return err
+	}
+	m := newMessage(Captured{Topic: envelope.Type, Key: string(key), Time: time.Now(), Value: value})
+	b.mu.Lock()
+	m.Offset = int64(len(b.published))
+	b.published = append(b.published, m)
+	b.mu.Unlock()
+	return b.deliver(ctx, m)
+}
+
+// Published returns the messages published on the bus so far, in order.
+// Replayed messages are not included.
+func (b *Bus) Published() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.published)
+}
+
+// PublishedTo returns the messages published to topic so far.
+func (b *Bus) PublishedTo(topic string) []Message {
+	var out []Message
+	for _, m := range b.Published() {
+		if m.Topic == topic {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (b *Bus) deliver(ctx context.Context, m Message) error {
+	b.mu.Lock()
+	handlers := slices.Clone(b.handlers[m.Topic])
+	b.mu.Unlock()
+
+	var errs []error
+	for _, h := range handlers {
+		if err := h(ctx, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func newMessage(c Captured) Message {
+	m := Message{
+		Topic:     c.Topic,
+		Partition: c.Partition,
+		Offset:    c.Offset,
+		Value:     c.Value,
+		Time:      c.Time,
+	}
+	if c.Key != "" {
+		m.Key = []byte(c.Key)
+	}
+	m.Envelope, m.EnvelopeErr = events.UnmarshalEnvelope[json.RawMessage](c.Value)
+	return m
+}
+
+type ReplayOptions struct {
+	// Speed scales the captured time between messages: 1 replays in real
+	// time, 60 plays a minute of traffic per second. Zero delivers without
+	// pausing, keeping only the order.
+	Speed float64
+	// MaxPause caps a single pause after scaling, so quiet stretches of a
+	// capture do not stall the test. Zero means no cap.
+	MaxPause time.Duration
+}
+
+// DeliveryError is a replayed message whose handlers failed.
+type DeliveryError struct {
+	Message Message
+	Err     error
+}
+
+func (e DeliveryError) Error() string {
+	return fmt.Sprintf("%s/%d@%d: %v", e.Message.Topic, e.Message.Partition, e.Message.Offset, e.Err)
+}
+
+// ReplayReport summarizes Replay.
+type ReplayReport struct {
+	Delivered int
+	Failed    []DeliveryError
+}
+
+// Replay delivers msgs to the subscribers on bus, one at a time. Each
+// topic partition keeps its offset order, which also keeps the order of
+// each key; partitions are merged by message time, and with a Speed the
+// gaps between message times are reproduced. Handler errors are collected
+// in the report; Replay only fails if ctx is done.
+func Replay(ctx context.Context, bus *Bus, msgs []Captured, opts ReplayOptions) (ReplayReport, error) {
+	lanes := partitionLanes(msgs)
+	var report ReplayReport
+	var prev time.Time
+	var at time.Duration
+	start := time.Now()
+
+	for {
+		lane := nextLane(lanes)
+		if lane == nil {
+			return report, nil
+		}
+		c := (*lane)[0]
+		*lane = (*lane)[1:]
+
+		if opts.Speed > 0 {
+			// A message stamped earlier than its predecessor is delivered
+			// right away.
+			if !prev.IsZero() && c.Time.After(prev) {
+				gap := time.Duration(float64(c.Time.Sub(prev)) / opts.Speed)
+				if opts.MaxPause > 0 {
+					gap = min(gap, opts.MaxPause)
+				}
+				at += gap
+			}
+			if prev.IsZero() || c.Time.After(prev) {
+				prev = c.Time
+			}
+			if err := sleepUntil(ctx, start.Add(at)); err != nil {
+				return report, err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		m := newMessage(c)
+		report.Delivered++
+		if err := bus.deliver(ctx, m); err != nil {
+			report.Failed = append(report.Failed, DeliveryError{Message: m, Err: err})
+		}
+	}
+}
+
+// partitionLanes groups msgs by topic and partition, each sorted by offset,
+// in a fixed order so ties between lanes resolve the same way every run.
+func partitionLanes(msgs []Captured) []*[]Captured {
+	type laneKey struct {
+		topic     string
+		partition int
+	}
+	byKey := make(map[laneKey]*[]Captured)
+	var keys []laneKey
+	for _, m := range msgs {
+		k := laneKey{m.Topic, m.Partition}
+		lane, ok := byKey[k]
+		if !ok {
+			lane = new([]Captured)
+			byKey[k] = lane
+			keys = append(keys, k)
+		}
+		*lane = append(*lane, m)
+	}
+	slices.SortFunc(keys, func(a, b laneKey) int {
+		return cmp.Or(cmp.Compare(a.topic, b.topic), cmp.Compare(a.partition, b.partition))
+	})
+	lanes := make([]*[]Captured, len(keys))
+	for i, k := range keys {
+		lane := byKey[k]
+		slices.SortStableFunc(*lane, func(a, b Captured) int { return cmp.Compare(a.Offset, b.Offset) })
+		lanes[i] = lane
+	}
+	return lanes
+}
+
+// nextLane returns the lane whose next message is the earliest, or nil when
+// all are drained.
+func nextLane(lanes []*[]Captured) *[]Captured {
+	var next *[]Captured
+	for _, lane := range lanes {
+		if len(*lane) == 0 {
+			continue
+		}
+		if next == nil || (*lane)[0].Time.Before((*next)[0].Time) {
+			next = lane
+		}
+	}
+	return next
+}
+
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,128 @@
+package eventstest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/quiby-ai/common/pkg/events"
+)
+
+func extractRequest(t *testing.T, sagaID string) []byte {
+	t.Helper()
+	env := events.NewEnvelope(sagaID, events.PipelineExtractRequest, events.ExtractRequest{
+		AppID:     "123",
+		AppName:   "Example",
+		Countries: []string{"us"},
+		DateFrom:  "2025-01-01",
+		DateTo:    "2025-01-31",
+	}, events.NewMeta("123", events.InitiatorUser))
+	b, err := events.MarshalEnvelope(env)
+	require.NoError(t, err)
+	return b
+}
+
+type sagaRecorder struct {
+	sagas []string
+	fail  string
+}
+
+func (r *sagaRecorder) Handle(_ context.Context, payload any, sagaID string) error {
+	if _, ok := payload.(events.ExtractRequest); !ok {
+		return errors.New("unexpected payload type")
+	}
+	if sagaID == r.fail {
+		return errors.New("boom")
+	}
+	r.sagas = append(r.sagas, sagaID)
+	return nil
+}
+
+func TestReplayKeepsPartitionOrder(t *testing.T) {
+	topic := events.PipelineExtractRequest
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	msgs := []Captured{
+		// Partition 1 is listed out of order and has a skewed clock on
+		// offset 11; offsets still decide.
+		{Topic: topic, Partition: 1, Offset: 11, Key: "b", Time: t0.Add(1 * time.Second), Value: extractRequest(t, "b2")},
+		{Topic: topic, Partition: 1, Offset: 10, Key: "b", Time: t0.Add(3 * time.Second), Value: extractRequest(t, "b1")},
+		{Topic: topic, Partition: 0, Offset: 5, Key: "a", Time: t0, Value: extractRequest(t, "a1")},
+		{Topic: topic, Partition: 0, Offset: 6, Key: "a", Time: t0.Add(2 * time.Second), Value: extractRequest(t, "a2")},
+		{Topic: topic, Partition: 0, Offset: 7, Key: "a", Time: t0.Add(4 * time.Second), Value: []byte("not json")},
+	}
+
+	bus := NewBus()
+	rec := &sagaRecorder{fail: "a2"}
+	bus.SubscribeProcessor(topic, rec)
+
+	start := time.Now()
+	report, err := Replay(context.Background(), bus, msgs, ReplayOptions{Speed: 100})
+	require.NoError(t, err)
+
+	// 4s of capture at 100x.
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+	assert.Equal(t, []string{"a1", "b1", "b2"}, rec.sagas)
+	assert.Equal(t, 5, report.Delivered)
+	require.Len(t, report.Failed, 2)
+	assert.Equal(t, int64(6), report.Failed[0].Message.Offset)
+	assert.Equal(t, int64(7), report.Failed[1].Message.Offset)
+}
+
+func TestReplayMaxPauseAndCancel(t *testing.T) {
+	t0 := time.Now()
+	msgs := []Captured{
+		{Topic: "t", Offset: 0, Time: t0, Value: []byte("{}")},
+		{Topic: "t", Offset: 1, Time: t0.Add(time.Hour), Value: []byte("{}")},
+	}
+	bus := NewBus()
+
+	start := time.Now()
+	report, err := Replay(context.Background(), bus, msgs, ReplayOptions{Speed: 1, MaxPause: 10 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Delivered)
+	assert.Less(t, time.Since(start), time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report, err = Replay(ctx, bus, msgs, ReplayOptions{Speed: 1})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, report.Delivered)
+}
+
+func TestBusPublishChainsSubscribers(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	bus.Subscribe(events.PipelineExtractRequest, func(ctx context.Context, m Message) error {
+		got = append(got, m.Envelope.SagaID)
+		return nil
+	})
+
+	env, err := events.UnmarshalEnvelope[any](extractRequest(t, "s1"))
+	require.NoError(t, err)
+	require.NoError(t, bus.PublishEvent(context.Background(), []byte("s1"), env))
+
+	assert.Equal(t, []string{"s1"}, got)
+	require.Len(t, bus.PublishedTo(events.PipelineExtractRequest), 1)
+	assert.Empty(t, bus.PublishedTo(events.PipelineFailed))
+}
+
+func TestCapturedRoundTrip(t *testing.T) {
+	msgs := []Captured{{Topic: "t", Partition: 2, Offset: 9, Key: "k", Time: time.Unix(100, 0).UTC(), Value: extractRequest(t, "s1")}}
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, WriteCaptured(f, msgs))
+	require.NoError(t, f.Close())
+
+	loaded, err := LoadCaptured(path)
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	assert.Equal(t, msgs[0].Offset, loaded[0].Offset)
+	assert.JSONEq(t, string(msgs[0].Value), string(loaded[0].Value))
+}
@@ -0,0 +1,34 @@
+// Command schemagen writes the JSON Schema of every event payload to
+// <out>/<event type>.json. It is run by go generate in pkg/events.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/quiby-ai/common/pkg/events"
+)
+
+func main() {
+	out := flag.String("out", "schema/v1/generated", "output directory")
+	flag.Parse()
+
+	schemas, err := events.GeneratePayloadSchemas()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	for eventType, schema := range schemas {
+		data, err := events.MarshalJSONSchema(schema)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(*out, eventType+".json"), data, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
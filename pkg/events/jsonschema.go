@@ -0,0 +1,296 @@
+package events
+
+//go:generate go run ./internal/schemagen -out schema/v1/generated
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schemas holds the JSON Schemas shipped with the package: the hand-written
+// envelope and payload schemas under schema/v1 and the payload schemas
+// generated from the Go structs under schema/v1/generated, one file per
+// event type (e.g. schema/v1/generated/pipeline.failed.json).
+//
+//go:embed schema
+var Schemas embed.FS
+
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// PayloadTypes maps every event type to a zero value of its payload struct.
+var PayloadTypes = map[string]any{
+	PipelineExtractRequest:     ExtractRequest{},
+	PipelineExtractCompleted:   ExtractCompleted{},
+	PipelinePrepareRequest:     PrepareRequest{},
+	PipelinePrepareCompleted:   PrepareCompleted{},
+	PipelineVectorizeRequest:   VectorizeRequest{},
+	PipelineVectorizeCompleted: VectorizeCompleted{},
+	PipelineFailed:             Failed{},
+	SagaStateChanged:           StateChanged{},
+}
+
+// JSONSchema is the draft-07 subset produced by GenerateJSONSchema.
+type JSONSchema struct {
+	Schema           string                 `json:"$schema,omitempty"`
+	Title            string                 `json:"title,omitempty"`
+	Type             string                 `json:"type,omitempty"`
+	Format           string                 `json:"format,omitempty"`
+	Enum             []any                  `json:"enum,omitempty"`
+	Properties       map[string]*JSONSchema `json:"properties,omitempty"`
+	Required         []string               `json:"required,omitempty"`
+	Items            *JSONSchema            `json:"items,omitempty"`
+	Additional       *JSONSchema            `json:"additionalProperties,omitempty"`
+	MinLength        *int                   `json:"minLength,omitempty"`
+	MaxLength        *int                   `json:"maxLength,omitempty"`
+	MinItems         *int                   `json:"minItems,omitempty"`
+	MaxItems         *int                   `json:"maxItems,omitempty"`
+	Minimum          *float64               `json:"minimum,omitempty"`
+	Maximum          *float64               `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64               `json:"exclusiveMaximum,omitempty"`
+}
+
+// GenerateJSONSchema builds a schema for the struct v from its json tags and
+// go-playground validate tags. Embedded structs are flattened, as
+// encoding/json does. Supported rules: required, oneof, min, max, len, gt,
+// gte, lt, lte, dive, datetime, email, url and uuid; others are ignored.
+func GenerateJSONSchema(v any) (*JSONSchema, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("events: schema for %T: not a struct", v)
+	}
+	s, err := schemaForType(t)
+	if err != nil {
+		return nil, fmt.Errorf("events: schema for %s: %w", t.Name(), err)
+	}
+	s.Schema = jsonSchemaDraft
+	s.Title = t.Name()
+	return s, nil
+}
+
+// GeneratePayloadSchemas returns GenerateJSONSchema for every entry of
+// PayloadTypes, keyed by event type.
+func GeneratePayloadSchemas() (map[string]*JSONSchema, error) {
+	out := make(map[string]*JSONSchema, len(PayloadTypes))
+	for eventType, payload := range PayloadTypes {
+		s, err := GenerateJSONSchema(payload)
+		if err != nil {
+			return nil, err
+		}
+		out[eventType] = s
+	}
+	return out, nil
+}
+
+// MarshalJSONSchema encodes s the way the files under schema/v1/generated
+// are written: indented with two spaces and newline-terminated.
+func MarshalJSONSchema(s *JSONSchema) ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaForType(t reflect.Type) (*JSONSchema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &JSONSchema{Type: "string", Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}, nil
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &JSONSchema{Type: "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &JSONSchema{Type: "integer", Minimum: &zero}, nil
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}, nil
+	case reflect.Interface:
+		return &JSONSchema{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key %s is not a string", t.Key())
+		}
+		values, err := schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "object", Additional: values}, nil
+	case reflect.Struct:
+		s := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+		if err := addStructFields(s, t); err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unsupported kind %s", t.Kind())
+	}
+}
+
+func addStructFields(s *JSONSchema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := addStructFields(s, ft); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fs, err := schemaForType(f.Type)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		required, err := applyValidateTag(fs, f.Tag.Get("validate"))
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		s.Properties[name] = fs
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
+
+// applyValidateTag maps validate rules onto s. Rules after "dive" apply to
+// the items of an array. It reports whether the field is required.
+func applyValidateTag(s *JSONSchema, tag string) (bool, error) {
+	if tag == "" || tag == "-" {
+		return false, nil
+	}
+	required := false
+	target := s
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if target == s {
+				required = true
+			}
+		case "dive":
+			if target.Items == nil {
+				return false, fmt.Errorf("dive on non-array")
+			}
+			target = target.Items
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				target.Enum = append(target.Enum, enumValue(target.Type, v))
+			}
+		case "min", "max", "len":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				return false, fmt.Errorf("%s=%q: %w", name, param, err)
+			}
+			applyBound(target, name, n)
+		case "gt", "gte", "lt", "lte":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return false, fmt.Errorf("%s=%q: %w", name, param, err)
+			}
+			switch name {
+			case "gt":
+				target.ExclusiveMinimum = &n
+			case "gte":
+				target.Minimum = &n
+			case "lt":
+				target.ExclusiveMaximum = &n
+			case "lte":
+				target.Maximum = &n
+			}
+		case "datetime":
+			if param == time.DateOnly {
+				target.Format = "date"
+			} else {
+				target.Format = "date-time"
+			}
+		case "email":
+			target.Format = "email"
+		case "url":
+			target.Format = "uri"
+		case "uuid", "uuid4":
+			target.Format = "uuid"
+		}
+	}
+	return required, nil
+}
+
+func applyBound(s *JSONSchema, rule string, n int) {
+	switch s.Type {
+	case "string":
+		if rule != "max" {
+			s.MinLength = &n
+		}
+		if rule != "min" {
+			s.MaxLength = &n
+		}
+	case "array":
+		if rule != "max" {
+			s.MinItems = &n
+		}
+		if rule != "min" {
+			s.MaxItems = &n
+		}
+	case "integer", "number":
+		f := float64(n)
+		if rule != "max" {
+			s.Minimum = &f
+		}
+		if rule != "min" {
+			s.Maximum = &f
+		}
+	}
+}
+
+func enumValue(typ, v string) any {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateJSONSchemaFromValidateTags(t *testing.T) {
+	s, err := GenerateJSONSchema(ExtractCompleted{})
+	require.NoError(t, err)
+
+	assert.Equal(t, jsonSchemaDraft, s.Schema)
+	assert.Equal(t, "ExtractCompleted", s.Title)
+	assert.Equal(t, []string{"app_id", "app_name", "countries", "date_from", "date_to", "count"}, s.Required,
+		"embedded ExtractRequest fields are flattened")
+
+	countries := s.Properties["countries"]
+	require.NotNil(t, countries)
+	assert.Equal(t, "array", countries.Type)
+	assert.Equal(t, 1, *countries.MinItems)
+	assert.Nil(t, countries.MaxItems)
+	assert.Equal(t, 2, *countries.Items.MinLength, "rules after dive apply to items")
+	assert.Equal(t, 2, *countries.Items.MaxLength)
+
+	assert.Equal(t, "date", s.Properties["date_from"].Format)
+	assert.Equal(t, 0.0, *s.Properties["count"].Minimum)
+}
+
+func TestGenerateJSONSchemaEnumsAndOptionalFields(t *testing.T) {
+	s, err := GenerateJSONSchema(&StateChanged{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []any{"running", "failed", "completed"}, s.Properties["status"].Enum)
+	assert.NotContains(t, s.Required, "error")
+	assert.Equal(t, []string{"code"}, s.Properties["error"].Required)
+
+	_, err = GenerateJSONSchema("not a struct")
+	assert.Error(t, err)
+}
+
+func TestGeneratedSchemasUpToDate(t *testing.T) {
+	schemas, err := GeneratePayloadSchemas()
+	require.NoError(t, err)
+	require.Len(t, schemas, len(PayloadTypes))
+
+	for eventType, s := range schemas {
+		want, err := MarshalJSONSchema(s)
+		require.NoError(t, err)
+		got, err := Schemas.ReadFile("schema/v1/generated/" + eventType + ".json")
+		require.NoError(t, err, "missing schema file; run go generate ./pkg/events")
+		assert.Equal(t, string(want), string(got), "%s is stale; run go generate ./pkg/events", eventType)
+	}
+}
@@ -21,6 +21,10 @@ func (e Envelope[T]) KafkaHeaders() []KafkaHeader {
 		headers = append(headers, KafkaHeader{Key: "message_id", Value: []byte(e.MessageID)})
 	}
 
+	if e.Meta.Sequence > 0 {
+		headers = append(headers, KafkaHeader{Key: "sequence", Value: []byte(fmt.Sprintf("%d", e.Meta.Sequence))})
+	}
+
 	if e.TraceID != "" {
 		headers = append(headers, KafkaHeader{Key: "trace_id", Value: []byte(e.TraceID)})
 	}
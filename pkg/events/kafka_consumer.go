@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/quiby-ai/common/pkg/obs"
 	"github.com/segmentio/kafka-go"
 )
 
@@ -16,15 +18,122 @@ type SagaMessageProcessor interface {
 type KafkaConsumer struct {
 	reader    *kafka.Reader
 	processor any
+	sequences *SequenceTracker
+	onGap     func(SequenceCheck)
+	dedup     *messageDeduper
+	ageWarn   time.Duration
+
+	tenantID      string
+	allowNoTenant bool
+	onTenant      func(TenantMismatch)
+
+	retry *retryPolicy
+}
+
+// ConsumerConfig holds optional consumer settings.
+type ConsumerConfig struct {
+	// StartOffset is where a group without committed offsets starts reading.
+	// Defaults to StartFromEarliest. Groups that already committed resume
+	// from their offsets; use ResetGroupOffsets to move them.
+	StartOffset StartOffset
+	// AgeWarningThreshold logs events consumed more than this long after
+	// their occurred_at. Zero disables the warning.
+	AgeWarningThreshold time.Duration
+	// TenantID, when set, skips events whose Meta.TenantID differs; see
+	// SetTenant. AllowMissingTenant lets events without a tenant through.
+	TenantID           string
+	AllowMissingTenant bool
+	OnTenantMismatch   func(TenantMismatch)
+	// MaxRetries enables retries: a message whose handler fails is
+	// republished with Meta.Retries incremented until it has been retried
+	// MaxRetries times. After that it goes to DLQTopic and, for request
+	// events, a pipeline.failed event with Recoverable=false is published.
+	// Zero only logs handler errors.
+	MaxRetries int
+	// RetryTopic receives retried messages. Defaults to the topic the
+	// message was read from.
+	RetryTopic string
+	// DLQTopic receives messages that exhausted their retries. Defaults to
+	// the source topic with DLQSuffix appended.
+	DLQTopic string
+	// RetryProducer writes retries, dead letters and failed events. If nil
+	// the consumer creates one on its brokers and closes it in Close.
+	RetryProducer *KafkaProducer
+	// FailedAppID overrides Meta.AppID of emitted failed events, which
+	// otherwise copy the failed message's.
+	FailedAppID string
+	// OnRetryExhausted is called after a message was dead lettered.
+	OnRetryExhausted func(RetryExhausted)
+
+	// MinBytes and MaxBytes bound the size of a fetch, MaxWait is how long
+	// the broker may wait to fill MinBytes, and QueueCapacity is the number
+	// of messages buffered ahead of Run. Zero keeps the kafka-go defaults
+	// (1 byte, 1 MB, 10s, 100). High-volume topics want larger fetches,
+	// latency-sensitive ones a short MaxWait.
+	MinBytes      int
+	MaxBytes      int
+	MaxWait       time.Duration
+	QueueCapacity int
+	// CommitInterval commits offsets in the background at this interval
+	// instead of after every message. Messages handled since the last
+	// commit are redelivered after a crash.
+	CommitInterval time.Duration
+	// IsolationLevel selects whether records of aborted or open
+	// transactions are read. Defaults to ReadUncommitted.
+	IsolationLevel IsolationLevel
+}
+
+// defaultConsumerMaxBytes is kafka-go's default MaxBytes.
+const defaultConsumerMaxBytes = 1e6
+
+// IsolationLevel selects which records of transactional producers a consumer
+// reads.
+type IsolationLevel int
+
+const (
+	ReadUncommitted IsolationLevel = iota
+	ReadCommitted
+)
+
+// readerConfig builds the kafka-go reader settings for topic. kafka-go
+// panics on inconsistent values such as MinBytes above MaxBytes.
+func (cfg ConsumerConfig) readerConfig(brokers []string, topic, groupID string) kafka.ReaderConfig {
+	isolation := kafka.ReadUncommitted
+	if cfg.IsolationLevel == ReadCommitted {
+		isolation = kafka.ReadCommitted
+	}
+	// kafka-go checks MinBytes against MaxBytes before defaulting MaxBytes.
+	if cfg.MaxBytes == 0 && cfg.MinBytes > 0 {
+		cfg.MaxBytes = max(defaultConsumerMaxBytes, cfg.MinBytes)
+	}
+	return kafka.ReaderConfig{
+		Brokers:        brokers,
+		Topic:          topic,
+		GroupID:        groupID,
+		StartOffset:    cfg.StartOffset.kafkaOffset(),
+		MinBytes:       cfg.MinBytes,
+		MaxBytes:       cfg.MaxBytes,
+		MaxWait:        cfg.MaxWait,
+		QueueCapacity:  cfg.QueueCapacity,
+		CommitInterval: cfg.CommitInterval,
+		IsolationLevel: isolation,
+	}
 }
 
 func NewKafkaConsumer(brokers []string, topic string, groupID string) *KafkaConsumer {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: brokers,
-		Topic:   topic,
-		GroupID: groupID,
-	})
-	return &KafkaConsumer{reader: reader}
+	return NewKafkaConsumerWithConfig(brokers, topic, groupID, ConsumerConfig{})
+}
+
+// NewKafkaConsumerWithConfig creates a consumer with optional settings such as
+// the start offset of a new group or fetch tuning for its topic.
+func NewKafkaConsumerWithConfig(brokers []string, topic string, groupID string, cfg ConsumerConfig) *KafkaConsumer {
+	reader := kafka.NewReader(cfg.readerConfig(brokers, topic, groupID))
+	kc := &KafkaConsumer{reader: reader, sequences: NewSequenceTracker(), ageWarn: cfg.AgeWarningThreshold}
+	kc.SetTenant(cfg.TenantID, cfg.AllowMissingTenant, cfg.OnTenantMismatch)
+	if cfg.MaxRetries > 0 {
+		kc.retry = newRetryPolicy(brokers, cfg)
+	}
+	return kc
 }
 
 // NewTypedKafkaConsumer creates a consumer that can handle specific event types with proper validation
@@ -34,13 +143,20 @@ func NewTypedKafkaConsumer(brokers []string, topic string, groupID string) *Kafk
 		Topic:   topic,
 		GroupID: groupID,
 	})
-	return &KafkaConsumer{reader: reader}
+	return &KafkaConsumer{reader: reader, sequences: NewSequenceTracker()}
 }
 
 func (kc *KafkaConsumer) SetProcessor(processor any) {
 	kc.processor = processor
 }
 
+// SetSequenceHandler registers a callback for sequenced events that arrive
+// with a gap, late or duplicated. Such events are logged either way and are
+// still passed to the processor.
+func (kc *KafkaConsumer) SetSequenceHandler(fn func(SequenceCheck)) {
+	kc.onGap = fn
+}
+
 func (kc *KafkaConsumer) Run(ctx context.Context) error {
 	for {
 		m, err := kc.reader.ReadMessage(ctx)
@@ -80,6 +196,20 @@ func (kc *KafkaConsumer) Run(ctx context.Context) error {
 				continue
 			}
 
+			if !kc.tenantAllowed(rawEnvelope, sagaID, eventType) {
+				continue
+			}
+
+			if kc.duplicate(rawEnvelope) {
+				continue
+			}
+
+			kc.recordAge(ctx, rawEnvelope, sagaID, eventType, time.Now())
+
+			if seq := sequenceFromRaw(rawEnvelope); seq > 0 {
+				kc.checkSequence(sagaID, eventType, seq)
+			}
+
 			// Extract and validate payload based on event type
 			payload, err := kc.extractAndValidatePayload(rawEnvelope, eventType)
 			if err != nil {
@@ -90,9 +220,15 @@ func (kc *KafkaConsumer) Run(ctx context.Context) error {
 			// Log message info for debugging
 			kc.LogMessageInfo(sagaID, eventType, payload)
 
-			// Process the message
-			if err = p.Handle(ctx, payload, sagaID); err != nil {
+			// Process the message with the saga ID in the context, so the
+			// processor's logs, spans and outgoing calls carry it
+			if err = p.Handle(obs.WithSagaID(ctx, sagaID), payload, sagaID); err != nil {
 				log.Printf("handle error: %v", err)
+				if kc.retry != nil {
+					if rerr := kc.retry.handleFailure(ctx, m, rawEnvelope, sagaID, eventType, err); rerr != nil {
+						log.Printf("retry routing failed - SagaID: %s, Type: %s, Error: %v", sagaID, eventType, rerr)
+					}
+				}
 			}
 		default:
 			log.Printf("no processor set for consumer")
@@ -100,6 +236,21 @@ func (kc *KafkaConsumer) Run(ctx context.Context) error {
 	}
 }
 
+func (kc *KafkaConsumer) checkSequence(sagaID, eventType string, seq uint64) {
+	if kc.sequences == nil {
+		return
+	}
+	check := kc.sequences.Observe(sagaID, eventType, seq)
+	if check.Status == SequenceInOrder {
+		return
+	}
+	log.Printf("saga sequence %s - SagaID: %s, Type: %s, Sequence: %d, Expected: %d, Missing: %d %v",
+		check.Status, sagaID, eventType, seq, check.Expected, check.MissingCount, check.Missing)
+	if kc.onGap != nil {
+		kc.onGap(check)
+	}
+}
+
 // ValidateMessage validates the entire message envelope before processing
 func (kc *KafkaConsumer) ValidateMessage(data []byte) (ValidationResult, error) {
 	var envelope Envelope[any]
@@ -213,8 +364,14 @@ func (kc *KafkaConsumer) extractAndValidatePayload(rawEnvelope map[string]json.R
 }
 
 func (kc *KafkaConsumer) Close() error {
+	var err error
 	if kc.reader != nil {
-		return kc.reader.Close()
+		err = kc.reader.Close()
+	}
+	if kc.retry != nil && kc.retry.owned {
+		if perr := kc.retry.producer.Close(); err == nil {
+			err = perr
+		}
 	}
-	return nil
+	return err
 }
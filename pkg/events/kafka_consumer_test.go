@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/segmentio/kafka-go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -367,3 +368,38 @@ func mustMarshal(v any) json.RawMessage {
 	}
 	return data
 }
+
+func TestConsumerConfig_ReaderConfig(t *testing.T) {
+	brokers := []string{"localhost:9092"}
+
+	rc := ConsumerConfig{}.readerConfig(brokers, PipelinePrepareRequest, "prepare")
+	assert.Zero(t, rc.MinBytes)
+	assert.Zero(t, rc.MaxBytes)
+	assert.Zero(t, rc.CommitInterval)
+	assert.Equal(t, kafka.ReadUncommitted, rc.IsolationLevel)
+	assert.Equal(t, kafka.FirstOffset, rc.StartOffset)
+
+	rc = ConsumerConfig{
+		MinBytes:       64 << 10,
+		MaxBytes:       10 << 20,
+		MaxWait:        500 * time.Millisecond,
+		QueueCapacity:  1000,
+		CommitInterval: time.Second,
+		IsolationLevel: ReadCommitted,
+		StartOffset:    StartFromLatest,
+	}.readerConfig(brokers, PipelinePrepareRequest, "prepare")
+	assert.Equal(t, 64<<10, rc.MinBytes)
+	assert.Equal(t, 10<<20, rc.MaxBytes)
+	assert.Equal(t, 500*time.Millisecond, rc.MaxWait)
+	assert.Equal(t, 1000, rc.QueueCapacity)
+	assert.Equal(t, time.Second, rc.CommitInterval)
+	assert.Equal(t, kafka.ReadCommitted, rc.IsolationLevel)
+	assert.Equal(t, kafka.LastOffset, rc.StartOffset)
+	assert.NoError(t, rc.Validate())
+
+	// MinBytes alone must not trip kafka-go's check against the unset
+	// MaxBytes.
+	rc = ConsumerConfig{MinBytes: 2e6}.readerConfig(brokers, SagaStateChanged, "orchestrator")
+	assert.NoError(t, rc.Validate())
+	assert.Equal(t, int(2e6), rc.MaxBytes)
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/quiby-ai/common/pkg/obs"
 	"github.com/segmentio/kafka-go"
 )
 
@@ -13,28 +14,136 @@ type EventBuilder[T any] interface {
 	BuildEnvelope(event T, sagaID string) Envelope[any]
 }
 
+// ProducerConfig holds optional producer settings. The zero value keeps the
+// producer synchronous: PublishEvent returns only after Kafka acknowledged the
+// write.
+type ProducerConfig struct {
+	// QueueSize enables an in-memory queue of at most QueueSize messages that
+	// are written by background workers. PublishEvent then returns as soon as
+	// the message is queued.
+	QueueSize int
+	// QueuePolicy applies when the queue is full. Defaults to QueuePolicyBlock.
+	QueuePolicy QueuePolicy
+	// QueueWorkers is the number of goroutines draining the queue. Defaults to 1.
+	QueueWorkers int
+	// OnDeliveryError is invoked when a queued message could not be written.
+	OnDeliveryError func(msg kafka.Message, err error)
+	// SkipValidation disables envelope and payload validation in PublishEvent.
+	SkipValidation bool
+	// SagaSequences makes the producer number events per saga in
+	// Meta.Sequence, unless the envelope already carries a sequence. Use it
+	// only when this producer publishes every step of a saga.
+	SagaSequences bool
+}
+
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
 type KafkaProducer struct {
-	w *kafka.Writer
+	w         messageWriter
+	queue     *producerQueue
+	sequencer *sagaSequencer
+	cfg       ProducerConfig
 }
 
 func NewKafkaProducer(brokers []string) *KafkaProducer {
+	return NewKafkaProducerWithConfig(brokers, ProducerConfig{})
+}
+
+// NewKafkaProducerWithConfig creates a producer with optional settings such as
+// a bounded publish queue.
+func NewKafkaProducerWithConfig(brokers []string, cfg ProducerConfig) *KafkaProducer {
 	w := kafka.NewWriter(kafka.WriterConfig{
 		Brokers:      brokers,
 		Balancer:     &kafka.Hash{},
 		RequiredAcks: int(kafka.RequireAll),
 		Async:        false,
 	})
-	return &KafkaProducer{w: w}
+	return newKafkaProducer(w, cfg)
+}
+
+func newKafkaProducer(w messageWriter, cfg ProducerConfig) *KafkaProducer {
+	p := &KafkaProducer{w: w, cfg: cfg}
+	if cfg.QueueSize > 0 {
+		p.queue = newProducerQueue(w, cfg)
+	}
+	if cfg.SagaSequences {
+		p.sequencer = newSagaSequencer()
+	}
+	return p
 }
 
+// Close drains the publish queue, if any, and closes the underlying writer.
 func (p *KafkaProducer) Close() error {
+	if p.queue != nil {
+		p.queue.close()
+	}
 	return p.w.Close()
 }
 
+// QueueDepth returns the number of messages waiting to be written. It is
+// always zero for synchronous producers.
+func (p *KafkaProducer) QueueDepth() int {
+	if p.queue == nil {
+		return 0
+	}
+	return p.queue.depth()
+}
+
+// PublishEvent validates the envelope and its payload and writes it to the
+// topic named by envelope.Type. Invalid envelopes are rejected with an
+// *EnvelopeValidationError before anything reaches Kafka. An envelope
+// without a saga ID takes the one in ctx, see obs.SagaIDFromContext.
 func (p *KafkaProducer) PublishEvent(ctx context.Context, key []byte, envelope Envelope[any]) error {
+	return p.publish(ctx, key, envelope, envelope.Type)
+}
+
+// publish validates and sequences envelope once and writes it to each topic.
+func (p *KafkaProducer) publish(ctx context.Context, key []byte, envelope Envelope[any], topics ...string) error {
+	if envelope.SagaID == "" {
+		envelope.SagaID = obs.SagaIDFromContext(ctx)
+	}
+	if !p.cfg.SkipValidation {
+		if err := validateForPublish(envelope); err != nil {
+			return err
+		}
+	}
+
+	if p.sequencer != nil && envelope.Meta.Sequence == 0 {
+		envelope.Meta.Sequence = p.sequencer.next(envelope.SagaID)
+	}
+
+	msgs := make([]kafka.Message, 0, len(topics))
+	for _, topic := range topics {
+		msg, err := newMessage(topic, key, envelope)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, msg)
+	}
+	return p.write(ctx, msgs...)
+}
+
+// write hands msgs to the queue, if any, or writes them synchronously in one
+// batch.
+func (p *KafkaProducer) write(ctx context.Context, msgs ...kafka.Message) error {
+	if p.queue != nil {
+		for _, msg := range msgs {
+			if err := p.queue.enqueue(ctx, msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return p.w.WriteMessages(ctx, msgs...)
+}
+
+func newMessage(topic string, key []byte, envelope Envelope[any]) (kafka.Message, error) {
 	value, err := MarshalEnvelope(envelope)
 	if err != nil {
-		return fmt.Errorf("marshal envelope: %w", err)
+		return kafka.Message{}, fmt.Errorf("marshal envelope: %w", err)
 	}
 
 	kafkaHeaders := make([]kafka.Header, 0, len(envelope.KafkaHeaders()))
@@ -45,14 +154,13 @@ func (p *KafkaProducer) PublishEvent(ctx context.Context, key []byte, envelope E
 		})
 	}
 
-	msg := kafka.Message{
-		Topic:   envelope.Type,
+	return kafka.Message{
+		Topic:   topic,
 		Key:     key,
 		Value:   value,
 		Headers: kafkaHeaders,
 		Time:    time.Now(),
-	}
-	return p.w.WriteMessages(ctx, msg)
+	}, nil
 }
 
 func BuildEnvelope[T any](event T, eventType string, sagaID string) Envelope[any] {
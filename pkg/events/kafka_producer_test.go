@@ -1,8 +1,13 @@
 package events
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/quiby-ai/common/pkg/obs"
 )
 
 func TestNewKafkaProducer(t *testing.T) {
@@ -55,3 +60,60 @@ func TestProducerClose(t *testing.T) {
 		t.Errorf("Close should not return error: %v", err)
 	}
 }
+
+func TestPublishEventRejectsInvalidEnvelope(t *testing.T) {
+	w := &fakeWriter{}
+	producer := newKafkaProducer(w, ProducerConfig{})
+
+	envelope := BuildEnvelope(ExtractRequest{AppID: "123", AppName: "App", Countries: []string{"usa"}}, PipelineExtractRequest, "")
+	err := producer.PublishEvent(context.Background(), nil, envelope)
+
+	var verr *EnvelopeValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *EnvelopeValidationError, got %v", err)
+	}
+	if !errors.Is(err, ErrInvalidEnvelope) {
+		t.Error("expected error to match ErrInvalidEnvelope")
+	}
+
+	fields := map[string]bool{}
+	for _, ve := range verr.Errors {
+		fields[ve.Field] = true
+	}
+	for _, want := range []string{"saga_id", "payload.Countries[0]", "payload.DateFrom", "payload.DateTo"} {
+		if !fields[want] {
+			t.Errorf("expected validation error for %s, got %+v", want, verr.Errors)
+		}
+	}
+	if w.count() != 0 {
+		t.Error("invalid envelope must not be written")
+	}
+}
+
+func TestPublishEventSkipValidation(t *testing.T) {
+	w := &fakeWriter{}
+	producer := newKafkaProducer(w, ProducerConfig{SkipValidation: true})
+
+	envelope := BuildEnvelope(ExtractRequest{}, PipelineExtractRequest, "")
+	if err := producer.PublishEvent(context.Background(), nil, envelope); err != nil {
+		t.Fatalf("PublishEvent() error = %v", err)
+	}
+	if w.count() != 1 {
+		t.Errorf("expected 1 written message, got %d", w.count())
+	}
+}
+
+func TestPublishEventSagaIDFromContext(t *testing.T) {
+	w := &fakeWriter{}
+	producer := newKafkaProducer(w, ProducerConfig{SkipValidation: true})
+
+	ctx := obs.WithSagaID(context.Background(), "saga-1")
+	envelope := BuildEnvelope(ExtractRequest{}, PipelineExtractRequest, "")
+	if err := producer.PublishEvent(ctx, nil, envelope); err != nil {
+		t.Fatalf("PublishEvent() error = %v", err)
+	}
+	got, err := UnmarshalEnvelope[json.RawMessage](w.written[0].Value)
+	if err != nil || got.SagaID != "saga-1" {
+		t.Errorf("saga_id = %q, err = %v", got.SagaID, err)
+	}
+}
@@ -0,0 +1,338 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+// OpenLineage run event types.
+const (
+	LineageStart    = "START"
+	LineageComplete = "COMPLETE"
+	LineageFail     = "FAIL"
+)
+
+const (
+	// DefaultLineageProducer identifies this package as the producer of
+	// lineage events.
+	DefaultLineageProducer = "https://github.com/quiby-ai/common/pkg/events"
+	lineageSchemaURL       = "https://openlineage.io/spec/2-0-2/OpenLineage.json#/$defs/RunEvent"
+	lineageFacetSchemaURL  = "https://openlineage.io/spec/facets/1-0-0/"
+)
+
+// lineageRunNamespace derives stable run IDs from saga IDs, so the START and
+// COMPLETE events of a step share a run.
+var lineageRunNamespace = uuid.MustParse("6f1c0a52-2d1e-4b4c-9a57-3e0f8c1d7b21")
+
+// Dataset names a dataset in lineage events, e.g. a table or an index.
+type Dataset struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// LineageStep is the datasets a saga step reads and writes.
+type LineageStep struct {
+	Job     string
+	Inputs  []Dataset
+	Outputs []Dataset
+}
+
+// DefaultLineageSteps maps the review pipeline: App Store reviews are
+// extracted into raw reviews, cleaned, and vectorized.
+var DefaultLineageSteps = map[SagaStep]LineageStep{
+	SagaStepExtract: {
+		Job:     "extract_reviews",
+		Inputs:  []Dataset{{Namespace: "https://apps.apple.com", Name: "customer-reviews"}},
+		Outputs: []Dataset{{Namespace: "clientpulse", Name: "raw_reviews"}},
+	},
+	SagaStepPrepare: {
+		Job:     "prepare_reviews",
+		Inputs:  []Dataset{{Namespace: "clientpulse", Name: "raw_reviews"}},
+		Outputs: []Dataset{{Namespace: "clientpulse", Name: "clean_reviews"}},
+	},
+	SagaStepVectorize: {
+		Job:     "vectorize_reviews",
+		Inputs:  []Dataset{{Namespace: "clientpulse", Name: "clean_reviews"}},
+		Outputs: []Dataset{{Namespace: "clientpulse", Name: "review_vectors"}},
+	},
+}
+
+// LineageEvent is an OpenLineage RunEvent.
+type LineageEvent struct {
+	EventType string           `json:"eventType"`
+	EventTime time.Time        `json:"eventTime"`
+	Producer  string           `json:"producer"`
+	SchemaURL string           `json:"schemaURL"`
+	Run       LineageRun       `json:"run"`
+	Job       LineageJob       `json:"job"`
+	Inputs    []LineageDataset `json:"inputs"`
+	Outputs   []LineageDataset `json:"outputs"`
+}
+
+type LineageRun struct {
+	RunID  string         `json:"runId"`
+	Facets map[string]any `json:"facets,omitempty"`
+}
+
+type LineageJob struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type LineageDataset struct {
+	Dataset
+	Facets map[string]any `json:"outputFacets,omitempty"`
+}
+
+// LineageSink delivers lineage events, e.g. to an OpenLineage endpoint or a
+// Kafka topic.
+type LineageSink interface {
+	SendLineage(ctx context.Context, ev LineageEvent) error
+}
+
+type LineageSinkFunc func(ctx context.Context, ev LineageEvent) error
+
+func (f LineageSinkFunc) SendLineage(ctx context.Context, ev LineageEvent) error {
+	return f(ctx, ev)
+}
+
+type LineageConfig struct {
+	// Namespace is the job namespace, e.g. the environment. Defaults to
+	// "clientpulse".
+	Namespace string
+	// Producer is set as the producer URI of events. Defaults to
+	// DefaultLineageProducer.
+	Producer string
+	// Steps overrides DefaultLineageSteps.
+	Steps map[SagaStep]LineageStep
+	Sink  LineageSink
+}
+
+// LineageEmitter turns pipeline events into OpenLineage run events: a step's
+// request starts a run, its completed event completes it, and
+// pipeline.failed fails it. Each step run has the saga as its parent run.
+type LineageEmitter struct {
+	cfg LineageConfig
+	now func() time.Time
+}
+
+func NewLineageEmitter(cfg LineageConfig) *LineageEmitter {
+	if cfg.Namespace == "" {
+		cfg.Namespace = "clientpulse"
+	}
+	if cfg.Producer == "" {
+		cfg.Producer = DefaultLineageProducer
+	}
+	if cfg.Steps == nil {
+		cfg.Steps = DefaultLineageSteps
+	}
+	return &LineageEmitter{cfg: cfg, now: time.Now}
+}
+
+// lineageTopics maps pipeline event types to the step and run event type
+// they report.
+var lineageTopics = map[string]struct {
+	step      SagaStep
+	eventType string
+}{
+	PipelineExtractRequest:     {SagaStepExtract, LineageStart},
+	PipelineExtractCompleted:   {SagaStepExtract, LineageComplete},
+	PipelinePrepareRequest:     {SagaStepPrepare, LineageStart},
+	PipelinePrepareCompleted:   {SagaStepPrepare, LineageComplete},
+	PipelineVectorizeRequest:   {SagaStepVectorize, LineageStart},
+	PipelineVectorizeCompleted: {SagaStepVectorize, LineageComplete},
+}
+
+// Event builds the lineage event for env. ok is false for envelopes that do
+// not report a step, or a step with no mapping.
+func (e *LineageEmitter) Event(env Envelope[any]) (ev LineageEvent, ok bool) {
+	var step SagaStep
+	var eventType string
+	if t, known := lineageTopics[env.Type]; known {
+		step, eventType = t.step, t.eventType
+	} else if f, isFailed := failedPayload(env.Payload); isFailed && env.Type == PipelineFailed {
+		step, eventType = f.Step, LineageFail
+	} else {
+		return LineageEvent{}, false
+	}
+	mapping, ok := e.cfg.Steps[step]
+	if !ok {
+		return LineageEvent{}, false
+	}
+	job := mapping.Job
+	if job == "" {
+		job = string(step)
+	}
+
+	at := env.OccurredAt
+	if at.IsZero() {
+		at = e.now()
+	}
+	ev = LineageEvent{
+		EventType: eventType,
+		EventTime: at.UTC(),
+		Producer:  e.cfg.Producer,
+		SchemaURL: lineageSchemaURL,
+		Run: LineageRun{
+			RunID: lineageRunID(env.SagaID, step),
+			Facets: map[string]any{
+				"parent": e.facet("ParentRunFacet.json", map[string]any{
+					"run": map[string]string{"runId": lineageRunID(env.SagaID, "")},
+					"job": LineageJob{Namespace: e.cfg.Namespace, Name: "review_pipeline"},
+				}),
+			},
+		},
+		Job:     LineageJob{Namespace: e.cfg.Namespace, Name: job},
+		Inputs:  lineageDatasets(mapping.Inputs),
+		Outputs: lineageDatasets(mapping.Outputs),
+	}
+	if eventType == LineageFail {
+		f, _ := failedPayload(env.Payload)
+		ev.Run.Facets["errorMessage"] = e.facet("ErrorMessageRunFacet.json", map[string]any{
+			"message":             string(f.Code),
+			"programmingLanguage": "go",
+		})
+	}
+	if rows, ok := outputRows(env.Payload); ok && eventType == LineageComplete {
+		for i := range ev.Outputs {
+			ev.Outputs[i].Facets = map[string]any{
+				"outputStatistics": e.facet("OutputStatisticsOutputDatasetFacet.json", map[string]any{"rowCount": rows}),
+			}
+		}
+	}
+	return ev, true
+}
+
+// Observe sends the lineage event for env, if it has one.
+func (e *LineageEmitter) Observe(ctx context.Context, env Envelope[any]) error {
+	ev, ok := e.Event(env)
+	if !ok || e.cfg.Sink == nil {
+		return nil
+	}
+	return e.cfg.Sink.SendLineage(ctx, ev)
+}
+
+func (e *LineageEmitter) facet(schema string, fields map[string]any) map[string]any {
+	fields["_producer"] = e.cfg.Producer
+	fields["_schemaURL"] = lineageFacetSchemaURL + schema
+	return fields
+}
+
+// LineagePublisher wraps pub so every pipeline event it publishes is also
+// reported to e. Lineage failures are logged and do not fail the publish.
+func LineagePublisher(pub EventPublisher, e *LineageEmitter) EventPublisher {
+	return lineagePublisher{pub: pub, lineage: e}
+}
+
+type lineagePublisher struct {
+	pub     EventPublisher
+	lineage *LineageEmitter
+}
+
+func (p lineagePublisher) PublishEvent(ctx context.Context, key []byte, envelope Envelope[any]) error {
+	if err := p.pub.PublishEvent(ctx, key, envelope); err != nil {
+		return err
+	}
+	if err := p.lineage.Observe(ctx, envelope); err != nil {
+		log.Printf("lineage event failed - SagaID: %s, Type: %s, Error: %v", envelope.SagaID, envelope.Type, err)
+	}
+	return nil
+}
+
+func lineageRunID(sagaID string, step SagaStep) string {
+	name := sagaID
+	if step != "" {
+		name += "/" + string(step)
+	}
+	return uuid.NewSHA1(lineageRunNamespace, []byte(name)).String()
+}
+
+func lineageDatasets(ds []Dataset) []LineageDataset {
+	out := make([]LineageDataset, len(ds))
+	for i, d := range ds {
+		out[i] = LineageDataset{Dataset: d}
+	}
+	return out
+}
+
+func failedPayload(payload any) (Failed, bool) {
+	switch p := payload.(type) {
+	case Failed:
+		return p, true
+	case *Failed:
+		return *p, p != nil
+	}
+	return Failed{}, false
+}
+
+// outputRows returns the number of records a completed step wrote, when its
+// payload reports one.
+func outputRows(payload any) (int, bool) {
+	switch p := payload.(type) {
+	case ExtractCompleted:
+		return p.Count, true
+	case *ExtractCompleted:
+		return p.Count, true
+	case PrepareCompleted:
+		return p.CleanCount, true
+	case *PrepareCompleted:
+		return p.CleanCount, true
+	}
+	return 0, false
+}
+
+// NewHTTPLineageSink posts events as JSON to an OpenLineage HTTP endpoint,
+// e.g. Marquez's http://marquez:5000/api/v1/lineage. client defaults to
+// http.DefaultClient.
+func NewHTTPLineageSink(url string, client *http.Client) LineageSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return LineageSinkFunc(func(ctx context.Context, ev LineageEvent) error {
+		body, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("lineage endpoint returned %s", resp.Status)
+		}
+		return nil
+	})
+}
+
+// LineageSink writes events as JSON to topic, keyed by job like the
+// OpenLineage Kafka transport. Queued producers queue them as well.
+func (p *KafkaProducer) LineageSink(topic string) LineageSink {
+	return LineageSinkFunc(func(ctx context.Context, ev LineageEvent) error {
+		value, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		return p.write(ctx, kafka.Message{
+			Topic: topic,
+			Key:   []byte("run:" + ev.Job.Namespace + "/" + ev.Job.Name),
+			Value: value,
+			Time:  time.Now(),
+		})
+	})
+}
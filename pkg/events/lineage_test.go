@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineageEmitterMapsSteps(t *testing.T) {
+	var sent []LineageEvent
+	e := NewLineageEmitter(LineageConfig{Namespace: "prod", Sink: LineageSinkFunc(func(_ context.Context, ev LineageEvent) error {
+		sent = append(sent, ev)
+		return nil
+	})})
+	pub := &recordingPublisher{}
+	lp := LineagePublisher(pub, e)
+	ctx := context.Background()
+
+	req := ExtractRequest{AppID: "1", AppName: "App", Countries: []string{"us"}, DateFrom: "2025-01-01", DateTo: "2025-01-31"}
+	for _, env := range []Envelope[any]{
+		BuildEnvelope(req, PipelineExtractRequest, "saga-1"),
+		BuildEnvelope(ExtractCompleted{ExtractRequest: req, Count: 120}, PipelineExtractCompleted, "saga-1"),
+		BuildEnvelope(PrepareRequest{ExtractRequest: req}, PipelinePrepareRequest, "saga-1"),
+		BuildEnvelope(Failed{Step: SagaStepPrepare, Code: FailedCodeWriteFailed}, PipelineFailed, "saga-1"),
+		BuildEnvelope(StateChanged{}, SagaStateChanged, "saga-1"),
+	} {
+		require.NoError(t, lp.PublishEvent(ctx, nil, env))
+	}
+	assert.Len(t, pub.envelopes, 5)
+	require.Len(t, sent, 4, "saga state events carry no lineage")
+
+	start, complete, prepare, failed := sent[0], sent[1], sent[2], sent[3]
+	assert.Equal(t, LineageStart, start.EventType)
+	assert.Equal(t, LineageJob{Namespace: "prod", Name: "extract_reviews"}, start.Job)
+	assert.Equal(t, "raw_reviews", start.Outputs[0].Name)
+	assert.Equal(t, start.Run.RunID, complete.Run.RunID, "start and complete share a run")
+	assert.NotEqual(t, start.Run.RunID, prepare.Run.RunID)
+	assert.Equal(t, start.Run.Facets["parent"], prepare.Run.Facets["parent"], "steps share the saga parent run")
+
+	assert.Equal(t, LineageComplete, complete.EventType)
+	stats := complete.Outputs[0].Facets["outputStatistics"].(map[string]any)
+	assert.Equal(t, 120, stats["rowCount"])
+
+	assert.Equal(t, LineageFail, failed.EventType)
+	assert.Equal(t, prepare.Run.RunID, failed.Run.RunID)
+	assert.Equal(t, "clean_reviews", failed.Outputs[0].Name)
+	assert.Equal(t, "WRITE_FAILED", failed.Run.Facets["errorMessage"].(map[string]any)["message"])
+}
+
+func TestLineagePublisherIgnoresSinkErrors(t *testing.T) {
+	e := NewLineageEmitter(LineageConfig{Sink: LineageSinkFunc(func(context.Context, LineageEvent) error {
+		return errors.New("down")
+	})})
+	pub := &recordingPublisher{}
+	env := BuildEnvelope(ExtractRequest{}, PipelineExtractRequest, "saga-1")
+
+	require.NoError(t, LineagePublisher(pub, e).PublishEvent(context.Background(), nil, env))
+	assert.Len(t, pub.envelopes, 1)
+
+	pub.err = errors.New("kafka down")
+	assert.ErrorIs(t, LineagePublisher(pub, e).PublishEvent(context.Background(), nil, env), pub.err)
+}
+
+func TestLineageSinks(t *testing.T) {
+	e := NewLineageEmitter(LineageConfig{})
+	ev, ok := e.Event(BuildEnvelope(ExtractRequest{}, PipelineExtractRequest, "saga-1"))
+	require.True(t, ok)
+
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/lineage" {
+			http.NotFound(w, r)
+			return
+		}
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	require.NoError(t, NewHTTPLineageSink(server.URL+"/api/v1/lineage", nil).SendLineage(context.Background(), ev))
+	assert.Equal(t, "START", got["eventType"])
+	assert.Equal(t, DefaultLineageProducer, got["producer"])
+	assert.Equal(t, "clientpulse", got["job"].(map[string]any)["namespace"])
+
+	assert.Error(t, NewHTTPLineageSink(server.URL+"/missing", server.Client()).SendLineage(context.Background(), ev))
+
+	w := &fakeWriter{}
+	producer := newKafkaProducer(w, ProducerConfig{})
+	require.NoError(t, producer.LineageSink("openlineage").SendLineage(context.Background(), ev))
+	require.Len(t, w.written, 1)
+	assert.Equal(t, "openlineage", w.written[0].Topic)
+	assert.Equal(t, "run:clientpulse/extract_reviews", string(w.written[0].Key))
+}
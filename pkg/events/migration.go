@@ -0,0 +1,182 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+// TopicMigration describes a topic rename. During the cutover window events
+// for Old are always written to Old, and DualWritePercent of them are also
+// written to New. At 100 every event goes to both topics; once all consumers
+// read New, set NewOnly to stop writing Old.
+type TopicMigration struct {
+	Old              string
+	New              string
+	DualWritePercent int
+	NewOnly          bool
+}
+
+// MigratingProducer wraps a KafkaProducer and routes events for migrated
+// topics according to their TopicMigration. Dual-written events share the
+// same message_id so consumers of both topics can deduplicate them.
+type MigratingProducer struct {
+	*KafkaProducer
+
+	mu         sync.RWMutex
+	migrations map[string]TopicMigration
+}
+
+func NewMigratingProducer(p *KafkaProducer, migrations ...TopicMigration) (*MigratingProducer, error) {
+	mp := &MigratingProducer{KafkaProducer: p, migrations: make(map[string]TopicMigration)}
+	for _, m := range migrations {
+		if err := mp.SetMigration(m); err != nil {
+			return nil, err
+		}
+	}
+	return mp, nil
+}
+
+// SetMigration adds or updates a migration, e.g. to ramp up the dual-write
+// percentage without restarting.
+func (mp *MigratingProducer) SetMigration(m TopicMigration) error {
+	if m.Old == "" || m.New == "" || m.Old == m.New {
+		return fmt.Errorf("invalid topic migration %q -> %q", m.Old, m.New)
+	}
+	if m.DualWritePercent < 0 || m.DualWritePercent > 100 {
+		return fmt.Errorf("dual write percent for %q must be between 0 and 100, got %d", m.Old, m.DualWritePercent)
+	}
+	mp.mu.Lock()
+	mp.migrations[m.Old] = m
+	mp.mu.Unlock()
+	return nil
+}
+
+// PublishEvent writes the envelope to the topics selected by the migration
+// for envelope.Type, or to envelope.Type alone when it is not being migrated.
+func (mp *MigratingProducer) PublishEvent(ctx context.Context, key []byte, envelope Envelope[any]) error {
+	mp.mu.RLock()
+	m, ok := mp.migrations[envelope.Type]
+	mp.mu.RUnlock()
+	if !ok {
+		return mp.publish(ctx, key, envelope, envelope.Type)
+	}
+
+	if envelope.MessageID == "" {
+		envelope.MessageID = uuid.NewString()
+	}
+	return mp.publish(ctx, key, envelope, m.topics(envelope.MessageID)...)
+}
+
+func (m TopicMigration) topics(messageID string) []string {
+	switch {
+	case m.NewOnly:
+		return []string{m.New}
+	case dualWriteBucket(messageID) < m.DualWritePercent:
+		return []string{m.Old, m.New}
+	default:
+		return []string{m.Old}
+	}
+}
+
+// dualWriteBucket maps a message ID to [0, 100) so the same event is routed
+// the same way if it is published again.
+func dualWriteBucket(messageID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(messageID))
+	return int(h.Sum32() % 100)
+}
+
+// NewMigratingKafkaConsumer reads the old and new topic of a migration in one
+// consumer group and drops events whose message_id was already seen within
+// dedupWindow, so dual-written events are processed once.
+func NewMigratingKafkaConsumer(brokers []string, m TopicMigration, groupID string, dedupWindow time.Duration) *KafkaConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		GroupTopics: []string{m.Old, m.New},
+		GroupID:     groupID,
+	})
+	return &KafkaConsumer{
+		reader:    reader,
+		sequences: NewSequenceTracker(),
+		dedup:     newMessageDeduper(dedupWindow, defaultDedupCapacity),
+	}
+}
+
+const defaultDedupCapacity = 100_000
+
+// messageDeduper remembers message IDs for a time window, bounded by
+// capacity (oldest IDs are forgotten first).
+type messageDeduper struct {
+	mu     sync.Mutex
+	window time.Duration
+	cap    int
+	now    func() time.Time
+	seen   map[string]time.Time
+	order  []string
+}
+
+func newMessageDeduper(window time.Duration, capacity int) *messageDeduper {
+	if window <= 0 {
+		window = time.Hour
+	}
+	return &messageDeduper{
+		window: window,
+		cap:    capacity,
+		now:    time.Now,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// seenBefore records id and reports whether it was already recorded within
+// the window.
+func (d *messageDeduper) seenBefore(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	for len(d.order) > 0 {
+		oldest := d.order[0]
+		if len(d.order) < d.cap && now.Sub(d.seen[oldest]) <= d.window {
+			break
+		}
+		delete(d.seen, oldest)
+		d.order = d.order[1:]
+	}
+
+	if at, ok := d.seen[id]; ok && now.Sub(at) <= d.window {
+		return true
+	}
+	d.seen[id] = now
+	d.order = append(d.order, id)
+	return false
+}
+
+// duplicate reports whether the raw envelope's message_id was already
+// processed by this consumer. Retries republish the same message_id, so
+// each retry attempt is deduplicated separately.
+func (kc *KafkaConsumer) duplicate(rawEnvelope map[string]json.RawMessage) bool {
+	if kc.dedup == nil {
+		return false
+	}
+	var id string
+	if raw, ok := rawEnvelope["message_id"]; !ok || json.Unmarshal(raw, &id) != nil || id == "" {
+		return false
+	}
+	if retries := retriesFromRaw(rawEnvelope); retries > 0 {
+		id += "#" + strconv.Itoa(retries)
+	}
+	if kc.dedup.seenBefore(id) {
+		log.Printf("skipping duplicate message - MessageID: %s", id)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigratingProducerRouting(t *testing.T) {
+	w := &fakeWriter{}
+	mp, err := NewMigratingProducer(newKafkaProducer(w, ProducerConfig{SkipValidation: true}),
+		TopicMigration{Old: "legacy.topic", New: "pipeline.topic", DualWritePercent: 100})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, mp.PublishEvent(ctx, nil, BuildEnvelope("a", "legacy.topic", "saga-1")))
+	require.NoError(t, mp.PublishEvent(ctx, nil, BuildEnvelope("b", PipelineFailed, "saga-1")))
+
+	require.Len(t, w.written, 3)
+	assert.Equal(t, "legacy.topic", w.written[0].Topic)
+	assert.Equal(t, "pipeline.topic", w.written[1].Topic)
+	assert.Equal(t, w.written[0].Value, w.written[1].Value, "dual writes carry the same envelope")
+	assert.Equal(t, PipelineFailed, w.written[2].Topic)
+
+	require.NoError(t, mp.SetMigration(TopicMigration{Old: "legacy.topic", New: "pipeline.topic", NewOnly: true}))
+	require.NoError(t, mp.PublishEvent(ctx, nil, BuildEnvelope("c", "legacy.topic", "saga-2")))
+	assert.Equal(t, "pipeline.topic", w.written[3].Topic)
+}
+
+func TestMigratingProducerPercentage(t *testing.T) {
+	w := &fakeWriter{}
+	mp, err := NewMigratingProducer(newKafkaProducer(w, ProducerConfig{SkipValidation: true}),
+		TopicMigration{Old: "old", New: "new", DualWritePercent: 30})
+	require.NoError(t, err)
+
+	const n = 2000
+	for i := 0; i < n; i++ {
+		require.NoError(t, mp.PublishEvent(context.Background(), nil, BuildEnvelope(i, "old", "saga")))
+	}
+	dual := len(w.written) - n
+	assert.InDelta(t, 0.3, float64(dual)/n, 0.05)
+
+	env := BuildEnvelope(1, "old", "saga")
+	m := mp.migrations["old"]
+	assert.Equal(t, m.topics(env.MessageID), m.topics(env.MessageID), "routing is stable per message")
+}
+
+func TestMigratingProducerRejectsInvalidMigration(t *testing.T) {
+	p := newKafkaProducer(&fakeWriter{}, ProducerConfig{})
+	_, err := NewMigratingProducer(p, TopicMigration{Old: "a", New: "a"})
+	assert.Error(t, err)
+	_, err = NewMigratingProducer(p, TopicMigration{Old: "a", New: "b", DualWritePercent: 101})
+	assert.Error(t, err)
+}
+
+func TestMessageDeduper(t *testing.T) {
+	now := time.Unix(0, 0)
+	d := newMessageDeduper(time.Minute, 2)
+	d.now = func() time.Time { return now }
+
+	assert.False(t, d.seenBefore("a"))
+	assert.True(t, d.seenBefore("a"))
+
+	now = now.Add(2 * time.Minute)
+	assert.False(t, d.seenBefore("a"), "expired after the window")
+
+	assert.False(t, d.seenBefore("b"))
+	assert.False(t, d.seenBefore("c"))
+	assert.False(t, d.seenBefore("a"), "oldest id evicted at capacity")
+}
+
+func TestConsumerSkipsDuplicateMessageIDs(t *testing.T) {
+	kc := &KafkaConsumer{dedup: newMessageDeduper(time.Minute, 10)}
+	raw := map[string]json.RawMessage{"message_id": mustMarshal("msg-1")}
+
+	assert.False(t, kc.duplicate(raw))
+	assert.True(t, kc.duplicate(raw))
+	assert.False(t, (&KafkaConsumer{}).duplicate(raw), "dedup is off by default")
+}
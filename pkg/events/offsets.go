@@ -0,0 +1,198 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// StartOffset selects where a consumer group with no committed offsets
+// begins reading.
+type StartOffset int
+
+const (
+	StartFromEarliest StartOffset = iota
+	StartFromLatest
+)
+
+func (s StartOffset) kafkaOffset() int64 {
+	if s == StartFromLatest {
+		return kafka.LastOffset
+	}
+	return kafka.FirstOffset
+}
+
+var (
+	// ErrResetNotConfirmed is returned by ResetGroupOffsets when called
+	// without confirmation. The planned offsets are still returned.
+	ErrResetNotConfirmed = errors.New("offset reset not confirmed")
+	// ErrGroupActive is returned when the consumer group still has members.
+	ErrGroupActive = errors.New("consumer group has active members")
+)
+
+// OffsetReset describes the target of ResetGroupOffsets. When At is set the
+// group is moved to the first offset at or after that time (partitions with
+// nothing newer go to the end); otherwise To selects earliest or latest.
+type OffsetReset struct {
+	To StartOffset
+	At time.Time
+}
+
+// offsetAdmin is the subset of Kafka admin calls needed to reset a group.
+type offsetAdmin interface {
+	partitions(ctx context.Context, topic string) ([]int, error)
+	offset(ctx context.Context, topic string, partition int, reset OffsetReset) (int64, error)
+	groupState(ctx context.Context, groupID string) (state string, members int, err error)
+	commit(ctx context.Context, groupID, topic string, offsets map[int]int64) error
+}
+
+// ResetGroupOffsets moves the committed offsets of groupID on topic, e.g. to
+// reprocess events after a bug fix. The group must have no running
+// consumers. Unless confirm is true nothing is committed: the planned offsets
+// (partition -> offset) are returned together with ErrResetNotConfirmed, so a
+// dry run shows what would happen.
+func ResetGroupOffsets(ctx context.Context, brokers []string, groupID, topic string, reset OffsetReset, confirm bool) (map[int]int64, error) {
+	return resetGroupOffsets(ctx, newKafkaOffsetAdmin(brokers), groupID, topic, reset, confirm)
+}
+
+func resetGroupOffsets(ctx context.Context, admin offsetAdmin, groupID, topic string, reset OffsetReset, confirm bool) (map[int]int64, error) {
+	if groupID == "" || topic == "" {
+		return nil, errors.New("group ID and topic are required")
+	}
+
+	state, members, err := admin.groupState(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("describe group %s: %w", groupID, err)
+	}
+	if members > 0 {
+		return nil, fmt.Errorf("%w: %s is %s with %d members", ErrGroupActive, groupID, state, members)
+	}
+
+	partitions, err := admin.partitions(ctx, topic)
+	if err != nil {
+		return nil, fmt.Errorf("list partitions of %s: %w", topic, err)
+	}
+
+	offsets := make(map[int]int64, len(partitions))
+	for _, p := range partitions {
+		off, err := admin.offset(ctx, topic, p, reset)
+		if err != nil {
+			return nil, fmt.Errorf("look up offset of %s/%d: %w", topic, p, err)
+		}
+		offsets[p] = off
+	}
+
+	if !confirm {
+		return offsets, ErrResetNotConfirmed
+	}
+	if err := admin.commit(ctx, groupID, topic, offsets); err != nil {
+		return offsets, fmt.Errorf("commit offsets for %s: %w", groupID, err)
+	}
+	return offsets, nil
+}
+
+type kafkaOffsetAdmin struct {
+	brokers []string
+	client  *kafka.Client
+}
+
+func newKafkaOffsetAdmin(brokers []string) *kafkaOffsetAdmin {
+	return &kafkaOffsetAdmin{
+		brokers: brokers,
+		client:  &kafka.Client{Addr: kafka.TCP(brokers...), Timeout: 10 * time.Second},
+	}
+}
+
+func (a *kafkaOffsetAdmin) partitions(ctx context.Context, topic string) ([]int, error) {
+	resp, err := a.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range resp.Topics {
+		if t.Name != topic {
+			continue
+		}
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		out := make([]int, 0, len(t.Partitions))
+		for _, p := range t.Partitions {
+			out = append(out, p.ID)
+		}
+		return out, nil
+	}
+	return nil, kafka.UnknownTopicOrPartition
+}
+
+func (a *kafkaOffsetAdmin) offset(ctx context.Context, topic string, partition int, reset OffsetReset) (int64, error) {
+	var (
+		conn *kafka.Conn
+		err  error
+	)
+	for _, broker := range a.brokers {
+		if conn, err = kafka.DialLeader(ctx, "tcp", broker, topic, partition); err == nil {
+			break
+		}
+	}
+	if conn == nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if reset.At.IsZero() {
+		if reset.To == StartFromLatest {
+			return conn.ReadLastOffset()
+		}
+		return conn.ReadFirstOffset()
+	}
+	off, err := conn.ReadOffset(reset.At)
+	if err != nil {
+		return 0, err
+	}
+	if off < 0 {
+		return conn.ReadLastOffset()
+	}
+	return off, nil
+}
+
+func (a *kafkaOffsetAdmin) groupState(ctx context.Context, groupID string) (string, int, error) {
+	resp, err := a.client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{groupID}})
+	if err != nil {
+		return "", 0, err
+	}
+	for _, g := range resp.Groups {
+		if g.GroupID == groupID {
+			if g.Error != nil {
+				return "", 0, g.Error
+			}
+			return g.GroupState, len(g.Members), nil
+		}
+	}
+	return "", 0, nil
+}
+
+func (a *kafkaOffsetAdmin) commit(ctx context.Context, groupID, topic string, offsets map[int]int64) error {
+	commits := make([]kafka.OffsetCommit, 0, len(offsets))
+	for p, off := range offsets {
+		commits = append(commits, kafka.OffsetCommit{Partition: p, Offset: off})
+	}
+	// Generation -1 with no member ID is how Kafka accepts commits for a
+	// group with no active members.
+	resp, err := a.client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      groupID,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{topic: commits},
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return fmt.Errorf("partition %d: %w", p.Partition, p.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOffsetAdmin struct {
+	members   int
+	first     map[int]int64
+	last      map[int]int64
+	byTime    map[int]int64
+	committed map[int]int64
+}
+
+func (f *fakeOffsetAdmin) partitions(ctx context.Context, topic string) ([]int, error) {
+	return []int{0, 1}, nil
+}
+
+func (f *fakeOffsetAdmin) offset(ctx context.Context, topic string, partition int, reset OffsetReset) (int64, error) {
+	switch {
+	case !reset.At.IsZero():
+		return f.byTime[partition], nil
+	case reset.To == StartFromLatest:
+		return f.last[partition], nil
+	default:
+		return f.first[partition], nil
+	}
+}
+
+func (f *fakeOffsetAdmin) groupState(ctx context.Context, groupID string) (string, int, error) {
+	if f.members > 0 {
+		return "Stable", f.members, nil
+	}
+	return "Empty", 0, nil
+}
+
+func (f *fakeOffsetAdmin) commit(ctx context.Context, groupID, topic string, offsets map[int]int64) error {
+	f.committed = offsets
+	return nil
+}
+
+func newFakeOffsetAdmin() *fakeOffsetAdmin {
+	return &fakeOffsetAdmin{
+		first:  map[int]int64{0: 3, 1: 5},
+		last:   map[int]int64{0: 100, 1: 200},
+		byTime: map[int]int64{0: 40, 1: 80},
+	}
+}
+
+func TestResetGroupOffsets_RequiresConfirmation(t *testing.T) {
+	admin := newFakeOffsetAdmin()
+
+	planned, err := resetGroupOffsets(context.Background(), admin, "g", "t", OffsetReset{To: StartFromEarliest}, false)
+	assert.ErrorIs(t, err, ErrResetNotConfirmed)
+	assert.Equal(t, map[int]int64{0: 3, 1: 5}, planned)
+	assert.Nil(t, admin.committed)
+}
+
+func TestResetGroupOffsets_Targets(t *testing.T) {
+	tests := []struct {
+		name  string
+		reset OffsetReset
+		want  map[int]int64
+	}{
+		{"earliest", OffsetReset{To: StartFromEarliest}, map[int]int64{0: 3, 1: 5}},
+		{"latest", OffsetReset{To: StartFromLatest}, map[int]int64{0: 100, 1: 200}},
+		{"timestamp", OffsetReset{At: time.Now().Add(-time.Hour)}, map[int]int64{0: 40, 1: 80}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			admin := newFakeOffsetAdmin()
+			got, err := resetGroupOffsets(context.Background(), admin, "g", "t", tt.reset, true)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want, admin.committed)
+		})
+	}
+}
+
+func TestResetGroupOffsets_ActiveGroup(t *testing.T) {
+	admin := newFakeOffsetAdmin()
+	admin.members = 2
+
+	_, err := resetGroupOffsets(context.Background(), admin, "g", "t", OffsetReset{}, true)
+	assert.ErrorIs(t, err, ErrGroupActive)
+	assert.Nil(t, admin.committed)
+}
+
+func TestStartOffset_KafkaOffset(t *testing.T) {
+	assert.Equal(t, kafka.FirstOffset, StartOffset(0).kafkaOffset())
+	assert.Equal(t, kafka.FirstOffset, StartFromEarliest.kafkaOffset())
+	assert.Equal(t, kafka.LastOffset, StartFromLatest.kafkaOffset())
+}
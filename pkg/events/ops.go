@@ -0,0 +1,262 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+// The functions in this file back ops tooling: peeking at a topic, checking
+// a topic against the payload schemas and publishing a hand-written
+// envelope. They share the envelope handling of the producer and consumer.
+
+// PeekedMessage is a message read by PeekTopic. Err is set when the value is
+// not a valid envelope; Raw always holds the value as read.
+type PeekedMessage struct {
+	Partition int
+	Offset    int64
+	Key       string
+	Time      time.Time
+	Envelope  Envelope[json.RawMessage]
+	Raw       []byte
+	Err       error
+}
+
+// messageSource reads messages; *kafka.Reader implements it.
+type messageSource interface {
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+}
+
+// PeekTopic reads up to n messages from one partition of topic without
+// joining a consumer group, so no offsets are committed. StartFromEarliest
+// returns the first n messages, StartFromLatest the last n. It returns
+// early when the end of the partition is reached.
+func PeekTopic(ctx context.Context, brokers []string, topic string, partition int, from StartOffset, n int) ([]PeekedMessage, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	first, last, err := partitionBounds(ctx, brokers, topic, partition)
+	if err != nil {
+		return nil, fmt.Errorf("read offsets of %s/%d: %w", topic, partition, err)
+	}
+	start := first
+	if from == StartFromLatest {
+		start = max(first, last-int64(n))
+	}
+	if start >= last {
+		return nil, nil
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   brokers,
+		Topic:     topic,
+		Partition: partition,
+	})
+	defer reader.Close()
+	if err := reader.SetOffset(start); err != nil {
+		return nil, err
+	}
+	return peek(ctx, reader, int(min(int64(n), last-start)), last)
+}
+
+func partitionBounds(ctx context.Context, brokers []string, topic string, partition int) (first, last int64, err error) {
+	var conn *kafka.Conn
+	for _, broker := range brokers {
+		if conn, err = kafka.DialLeader(ctx, "tcp", broker, topic, partition); err == nil {
+			break
+		}
+	}
+	if conn == nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+	return conn.ReadOffsets()
+}
+
+// peek reads up to n messages from src, stopping at the last one before the
+// high watermark last. Compaction and transaction markers leave holes in the
+// offsets, so fewer than last-start messages may exist, and reading on would
+// block until new ones are produced.
+func peek(ctx context.Context, src messageSource, n int, last int64) ([]PeekedMessage, error) {
+	out := make([]PeekedMessage, 0, n)
+	for len(out) < n {
+		m, err := src.ReadMessage(ctx)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, decodePeeked(m))
+		if m.Offset >= last-1 {
+			break
+		}
+	}
+	return out, nil
+}
+
+func decodePeeked(m kafka.Message) PeekedMessage {
+	p := PeekedMessage{
+		Partition: m.Partition,
+		Offset:    m.Offset,
+		Key:       string(m.Key),
+		Time:      m.Time,
+		Raw:       m.Value,
+	}
+	p.Envelope, p.Err = UnmarshalEnvelope[json.RawMessage](m.Value)
+	return p
+}
+
+// FormatMessage writes m for a terminal: a header line with its position,
+// key, type and saga, followed by the value as indented JSON. Values that are
+// not JSON are written as they are.
+func FormatMessage(w io.Writer, m PeekedMessage) error {
+	header := fmt.Sprintf("--- partition %d offset %d key %q", m.Partition, m.Offset, m.Key)
+	if !m.Time.IsZero() {
+		header += " at " + m.Time.UTC().Format(time.RFC3339)
+	}
+	if m.Err == nil {
+		header += fmt.Sprintf(" | %s saga %s", m.Envelope.Type, m.Envelope.SagaID)
+	} else {
+		header += " | not an envelope: " + m.Err.Error()
+	}
+
+	var body bytes.Buffer
+	if err := json.Indent(&body, m.Raw, "", "  "); err != nil {
+		body.Reset()
+		body.Write(m.Raw)
+	}
+	_, err := fmt.Fprintf(w, "%s\n%s\n", header, body.Bytes())
+	return err
+}
+
+// InvalidMessage is a message rejected by ValidateMessages.
+type InvalidMessage struct {
+	Partition int
+	Offset    int64
+	SagaID    string
+	Type      string
+	Errors    []ValidationError
+}
+
+// TopicReport summarizes ValidateMessages.
+type TopicReport struct {
+	Checked int
+	Invalid []InvalidMessage
+}
+
+// Valid reports whether every checked message passed.
+func (r TopicReport) Valid() bool {
+	return len(r.Invalid) == 0
+}
+
+// ValidateTopic peeks at up to n messages like PeekTopic and checks them with
+// ValidateMessages.
+func ValidateTopic(ctx context.Context, brokers []string, topic string, partition int, from StartOffset, n int) (TopicReport, error) {
+	msgs, err := PeekTopic(ctx, brokers, topic, partition, from, n)
+	if err != nil {
+		return TopicReport{}, err
+	}
+	return ValidateMessages(msgs), nil
+}
+
+// ValidateMessages applies the checks the producer runs before publishing:
+// the envelope fields and the payload of its event type. Unknown event types
+// are reported as invalid.
+func ValidateMessages(msgs []PeekedMessage) TopicReport {
+	report := TopicReport{Checked: len(msgs)}
+	for _, m := range msgs {
+		inv := InvalidMessage{
+			Partition: m.Partition,
+			Offset:    m.Offset,
+			SagaID:    m.Envelope.SagaID,
+			Type:      m.Envelope.Type,
+		}
+		if m.Err != nil {
+			inv.Errors = []ValidationError{{Field: "envelope", Message: m.Err.Error()}}
+			report.Invalid = append(report.Invalid, inv)
+			continue
+		}
+		env, err := DecodeTypedEnvelope(m.Envelope)
+		if err != nil {
+			inv.Errors = []ValidationError{{Field: "payload", Message: err.Error()}}
+			report.Invalid = append(report.Invalid, inv)
+			continue
+		}
+		var verr *EnvelopeValidationError
+		if errors.As(validateForPublish(env), &verr) {
+			inv.Errors = verr.Errors
+			report.Invalid = append(report.Invalid, inv)
+		}
+	}
+	return report
+}
+
+// DecodeTypedEnvelope decodes the payload of e into the struct registered in
+// PayloadTypes for e.Type, the form a SagaMessageProcessor receives.
+func DecodeTypedEnvelope(e Envelope[json.RawMessage]) (Envelope[any], error) {
+	zero, ok := PayloadTypes[e.Type]
+	if !ok {
+		return Envelope[any]{}, fmt.Errorf("unknown event type %q", e.Type)
+	}
+	ptr := reflect.New(reflect.TypeOf(zero))
+	if err := json.Unmarshal(e.Payload, ptr.Interface()); err != nil {
+		return Envelope[any]{}, fmt.Errorf("decode %s payload: %w", e.Type, err)
+	}
+	return Envelope[any]{
+		MessageID:  e.MessageID,
+		TraceID:    e.TraceID,
+		SagaID:     e.SagaID,
+		Type:       e.Type,
+		OccurredAt: e.OccurredAt,
+		Payload:    ptr.Elem().Interface(),
+		Meta:       e.Meta,
+	}, nil
+}
+
+// LoadEnvelope reads a hand-written envelope from a JSON file. The payload is
+// decoded into the struct registered for its type. A missing message_id,
+// occurred_at or meta.schema_version is filled in.
+func LoadEnvelope(path string) (Envelope[any], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Envelope[any]{}, err
+	}
+	raw, err := UnmarshalEnvelope[json.RawMessage](data)
+	if err != nil {
+		return Envelope[any]{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	env, err := DecodeTypedEnvelope(raw)
+	if err != nil {
+		return Envelope[any]{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if env.MessageID == "" {
+		env.MessageID = uuid.NewString()
+	}
+	if env.OccurredAt.IsZero() {
+		env.OccurredAt = time.Now().UTC()
+	}
+	if env.Meta.SchemaVersion == "" {
+		env.Meta.SchemaVersion = SchemaVersionV1
+	}
+	return env, nil
+}
+
+// PublishEnvelopeFile publishes the envelope in path with pub, keyed by its
+// saga ID, and returns what was sent. *KafkaProducer validates it first.
+func PublishEnvelopeFile(ctx context.Context, pub EventPublisher, path string) (Envelope[any], error) {
+	env, err := LoadEnvelope(path)
+	if err != nil {
+		return Envelope[any]{}, err
+	}
+	if err := pub.PublishEvent(ctx, []byte(env.SagaID), env); err != nil {
+		return env, err
+	}
+	return env, nil
+}
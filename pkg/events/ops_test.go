@@ -0,0 +1,118 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sliceSource struct{ msgs []kafka.Message }
+
+func (s *sliceSource) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	if len(s.msgs) == 0 {
+		return kafka.Message{}, io.EOF
+	}
+	m := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return m, nil
+}
+
+var opsExtractRequest = ExtractRequest{
+	AppID:     "test-app",
+	AppName:   "Test App",
+	Countries: []string{"US"},
+	DateFrom:  "2024-01-01",
+	DateTo:    "2024-01-31",
+}
+
+func opsMessage(t *testing.T, offset int64, env Envelope[any]) kafka.Message {
+	t.Helper()
+	value, err := MarshalEnvelope(env)
+	require.NoError(t, err)
+	return kafka.Message{Offset: offset, Key: []byte(env.SagaID), Value: value}
+}
+
+func TestPeekStopsAtHighWatermark(t *testing.T) {
+	// Offsets 11 and 13 were compacted away; reading past 14 would block.
+	src := &sliceSource{msgs: []kafka.Message{{Offset: 10}, {Offset: 12}, {Offset: 14}}}
+	msgs, err := peek(context.Background(), src, 5, 15)
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, int64(14), msgs[2].Offset)
+}
+
+func TestPeekAndValidateMessages(t *testing.T) {
+	valid := BuildEnvelopeWithMeta(opsExtractRequest, PipelineExtractRequest, "saga-1", "ops", InitiatorUser)
+	badPayload := BuildEnvelopeWithMeta(ExtractRequest{AppID: "test-app"}, PipelineExtractRequest, "saga-2", "ops", InitiatorUser)
+	unknown := BuildEnvelopeWithMeta(opsExtractRequest, "pipeline.unknown", "saga-3", "ops", InitiatorUser)
+
+	src := &sliceSource{msgs: []kafka.Message{
+		opsMessage(t, 10, valid),
+		opsMessage(t, 11, badPayload),
+		opsMessage(t, 12, unknown),
+		{Offset: 13, Value: []byte("not json")},
+		opsMessage(t, 14, valid),
+	}}
+
+	msgs, err := peek(context.Background(), src, 4, 15)
+	require.NoError(t, err)
+	require.Len(t, msgs, 4)
+	assert.Equal(t, "saga-1", msgs[0].Envelope.SagaID)
+	assert.Error(t, msgs[3].Err)
+
+	report := ValidateMessages(msgs)
+	assert.Equal(t, 4, report.Checked)
+	assert.False(t, report.Valid())
+	require.Len(t, report.Invalid, 3)
+	assert.Equal(t, int64(11), report.Invalid[0].Offset)
+	assert.Contains(t, report.Invalid[0].Errors, ValidationError{Field: "payload.AppName", Message: "failed on the 'required' validation"})
+	assert.Equal(t, "payload", report.Invalid[1].Errors[0].Field)
+	assert.Equal(t, "envelope", report.Invalid[2].Errors[0].Field)
+
+	var out bytes.Buffer
+	require.NoError(t, FormatMessage(&out, msgs[0]))
+	assert.Contains(t, out.String(), `--- partition 0 offset 10 key "saga-1" | pipeline.extract_reviews.request saga saga-1`)
+	assert.Contains(t, out.String(), "\n  \"saga_id\": \"saga-1\"")
+
+	out.Reset()
+	require.NoError(t, FormatMessage(&out, msgs[3]))
+	assert.Contains(t, out.String(), "not an envelope")
+	assert.Contains(t, out.String(), "\nnot json\n")
+}
+
+func TestPublishEnvelopeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.json")
+	payload, err := json.Marshal(opsExtractRequest)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"saga_id": "saga-1",
+		"type": "`+PipelineExtractRequest+`",
+		"payload": `+string(payload)+`,
+		"meta": {"app_id": "ops-cli", "initiator": "user"}
+	}`), 0o600))
+
+	pub := &recordingPublisher{}
+	env, err := PublishEnvelopeFile(context.Background(), pub, path)
+	require.NoError(t, err)
+
+	require.Len(t, pub.envelopes, 1)
+	assert.Equal(t, env, pub.envelopes[0])
+	assert.Equal(t, opsExtractRequest, env.Payload)
+	assert.NotEmpty(t, env.MessageID)
+	assert.False(t, env.OccurredAt.IsZero())
+	assert.Equal(t, SchemaVersionV1, env.Meta.SchemaVersion)
+	assert.Nil(t, validateForPublish(env))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"saga_id": "s", "type": "nope", "payload": {}}`), 0o600))
+	_, err = PublishEnvelopeFile(context.Background(), pub, path)
+	assert.ErrorContains(t, err, `unknown event type "nope"`)
+	assert.Len(t, pub.envelopes, 1)
+}
@@ -86,7 +86,7 @@ const (
 type Failed struct {
 	Step        SagaStep   `json:"step" validate:"required,oneof=extract prepare vectorize"`
 	Code        FailedCode `json:"code" validate:"required,oneof=SOURCE_UNAVAILABLE RATE_LIMIT AUTH_FAILED TEMP_STORAGE_UNAVAILABLE WRITE_FAILED VALIDATION_ERROR SCHEMA_MISMATCH UNKNOWN"`
-	Recoverable bool       `json:"recoverable" validate:"required"`
+	Recoverable bool       `json:"recoverable"`
 	// Details     string     `json:"details" validate:"omitempty"`
 	// Context     any        `json:"context" validate:"omitempty"`
 }
@@ -116,6 +116,19 @@ const (
 
 type StateChangedContext struct {
 	Message string `json:"message" validate:"required"`
+	// TraceID and SpanID identify the trace of the step, so dashboards can
+	// link to it. StateChangedEmitter fills them from the context.
+	TraceID string `json:"trace_id,omitempty" validate:"omitempty,len=32,hexadecimal"`
+	SpanID  string `json:"span_id,omitempty" validate:"omitempty,len=16,hexadecimal"`
+	// Metrics is an optional snapshot of the step's counters and timings.
+	Metrics *StepMetrics `json:"metrics,omitempty"`
+}
+
+// StepMetrics is a snapshot of what a saga step did, e.g. reviews fetched
+// and time spent per phase. Durations are in milliseconds.
+type StepMetrics struct {
+	Counts      map[string]int64 `json:"counts,omitempty"`
+	DurationsMs map[string]int64 `json:"durations_ms,omitempty"`
 }
 
 // StateChanged represents the payload for saga.orchestrator.state.changed events.
@@ -0,0 +1,161 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const meterName = "github.com/quiby-ai/common/pkg/events"
+
+// QueuePolicy decides what PublishEvent does when the producer queue is full.
+type QueuePolicy string
+
+const (
+	// QueuePolicyBlock makes PublishEvent wait for free space or ctx cancellation.
+	QueuePolicyBlock QueuePolicy = "block"
+	// QueuePolicyReject makes PublishEvent fail fast with ErrQueueFull.
+	QueuePolicyReject QueuePolicy = "reject"
+)
+
+var (
+	ErrQueueFull      = errors.New("events: producer queue is full")
+	ErrProducerClosed = errors.New("events: producer is closed")
+)
+
+type queuedMessage struct {
+	ctx context.Context
+	msg kafka.Message
+}
+
+type producerQueue struct {
+	items   chan queuedMessage
+	done    chan struct{}
+	policy  QueuePolicy
+	onError func(msg kafka.Message, err error)
+
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	rejected     metric.Int64Counter
+	registration metric.Registration
+}
+
+func newProducerQueue(w messageWriter, cfg ProducerConfig) *producerQueue {
+	if cfg.QueuePolicy == "" {
+		cfg.QueuePolicy = QueuePolicyBlock
+	}
+	if cfg.QueueWorkers <= 0 {
+		cfg.QueueWorkers = 1
+	}
+
+	q := &producerQueue{
+		items:   make(chan queuedMessage, cfg.QueueSize),
+		done:    make(chan struct{}),
+		policy:  cfg.QueuePolicy,
+		onError: cfg.OnDeliveryError,
+	}
+	q.registerMetrics(cfg.QueueSize)
+
+	for i := 0; i < cfg.QueueWorkers; i++ {
+		q.wg.Add(1)
+		go q.run(w)
+	}
+	return q
+}
+
+func (q *producerQueue) registerMetrics(capacity int) {
+	meter := otel.Meter(meterName)
+
+	depth, err := meter.Int64ObservableGauge("events_producer_queue_depth",
+		metric.WithDescription("Messages waiting in the producer queue"),
+	)
+	if err != nil {
+		return
+	}
+	capacityGauge, err := meter.Int64ObservableGauge("events_producer_queue_capacity",
+		metric.WithDescription("Maximum number of messages the producer queue can hold"),
+	)
+	if err != nil {
+		return
+	}
+	q.registration, _ = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(depth, int64(len(q.items)))
+		o.ObserveInt64(capacityGauge, int64(capacity))
+		return nil
+	}, depth, capacityGauge)
+
+	q.rejected, _ = meter.Int64Counter("events_producer_queue_rejected_total",
+		metric.WithDescription("Messages rejected because the producer queue was full"),
+	)
+}
+
+func (q *producerQueue) enqueue(ctx context.Context, msg kafka.Message) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrProducerClosed
+	}
+
+	item := queuedMessage{ctx: context.WithoutCancel(ctx), msg: msg}
+
+	if q.policy == QueuePolicyReject {
+		select {
+		case q.items <- item:
+			return nil
+		default:
+			if q.rejected != nil {
+				q.rejected.Add(ctx, 1)
+			}
+			return ErrQueueFull
+		}
+	}
+
+	select {
+	case q.items <- item:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-q.done:
+		return ErrProducerClosed
+	}
+}
+
+func (q *producerQueue) depth() int {
+	return len(q.items)
+}
+
+func (q *producerQueue) run(w messageWriter) {
+	defer q.wg.Done()
+	for item := range q.items {
+		if err := w.WriteMessages(item.ctx, item.msg); err != nil && q.onError != nil {
+			q.onError(item.msg, err)
+		}
+	}
+}
+
+// close stops accepting messages and waits until everything already queued
+// has been handed to the writer.
+func (q *producerQueue) close() {
+	q.closeOnce.Do(func() {
+		// Wake up blocked publishers first; they hold the read lock.
+		close(q.done)
+
+		q.mu.Lock()
+		q.closed = true
+		close(q.items)
+		q.mu.Unlock()
+
+		q.wg.Wait()
+		if q.registration != nil {
+			q.registration.Unregister()
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWriter struct {
+	mu      sync.Mutex
+	written []kafka.Message
+	release chan struct{}
+	err     error
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if w.release != nil {
+		<-w.release
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = append(w.written, msgs...)
+	return w.err
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+func (w *fakeWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.written)
+}
+
+func testEnvelope() Envelope[any] {
+	return BuildEnvelope("payload", PipelineExtractRequest, "saga-1")
+}
+
+func TestProducerQueueReject(t *testing.T) {
+	w := &fakeWriter{release: make(chan struct{})}
+	p := newKafkaProducer(w, ProducerConfig{QueueSize: 1, QueuePolicy: QueuePolicyReject})
+
+	ctx := context.Background()
+	// The first message is picked up by the worker, the second fills the queue.
+	require.NoError(t, p.PublishEvent(ctx, nil, testEnvelope()))
+	require.Eventually(t, func() bool { return p.QueueDepth() == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, p.PublishEvent(ctx, nil, testEnvelope()))
+
+	err := p.PublishEvent(ctx, nil, testEnvelope())
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.Equal(t, 1, p.QueueDepth())
+
+	close(w.release)
+	require.NoError(t, p.Close())
+	assert.Equal(t, 2, w.count())
+}
+
+func TestProducerQueueBlockRespectsContext(t *testing.T) {
+	w := &fakeWriter{release: make(chan struct{})}
+	p := newKafkaProducer(w, ProducerConfig{QueueSize: 1})
+
+	require.NoError(t, p.PublishEvent(context.Background(), nil, testEnvelope()))
+	require.Eventually(t, func() bool { return p.QueueDepth() == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, p.PublishEvent(context.Background(), nil, testEnvelope()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := p.PublishEvent(ctx, nil, testEnvelope())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(w.release)
+	require.NoError(t, p.Close())
+	assert.ErrorIs(t, p.PublishEvent(context.Background(), nil, testEnvelope()), ErrProducerClosed)
+}
+
+func TestProducerQueueDeliveryError(t *testing.T) {
+	w := &fakeWriter{err: errors.New("broker down")}
+	var (
+		mu     sync.Mutex
+		failed int
+	)
+	p := newKafkaProducer(w, ProducerConfig{
+		QueueSize: 4,
+		OnDeliveryError: func(msg kafka.Message, err error) {
+			mu.Lock()
+			failed++
+			mu.Unlock()
+		},
+	})
+
+	require.NoError(t, p.PublishEvent(context.Background(), nil, testEnvelope()))
+	require.NoError(t, p.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, failed)
+}
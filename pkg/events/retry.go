@@ -0,0 +1,184 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// DLQSuffix is appended to a topic to name its dead letter topic when
+// ConsumerConfig.DLQTopic is not set.
+const DLQSuffix = ".dlq"
+
+// Headers set on messages routed to the dead letter topic.
+const (
+	HeaderDLQError       = "dlq_error"
+	HeaderDLQSourceTopic = "dlq_source_topic"
+)
+
+// RetryExhausted describes a message that failed on its last allowed attempt
+// and was routed to the dead letter topic.
+type RetryExhausted struct {
+	SagaID   string
+	Type     string
+	Retries  int
+	DLQTopic string
+	Err      error
+}
+
+// retryPolicy republishes messages whose handler failed.
+type retryPolicy struct {
+	max       int
+	topic     string // empty: the topic the message was read from
+	dlq       string // empty: source topic + DLQSuffix
+	producer  *KafkaProducer
+	owned     bool
+	appID     string
+	onExhaust func(RetryExhausted)
+}
+
+// retryStep maps request types to the saga step reported in Failed events.
+// Other types have no step of their own, so exhausting them only dead
+// letters the message.
+var retryStep = map[string]SagaStep{
+	PipelineExtractRequest:   SagaStepExtract,
+	PipelinePrepareRequest:   SagaStepPrepare,
+	PipelineVectorizeRequest: SagaStepVectorize,
+}
+
+// handleFailure republishes m with meta.retries incremented while retries
+// remain, and otherwise routes it to the dead letter topic and emits a
+// pipeline.failed event with Recoverable=false.
+func (r *retryPolicy) handleFailure(ctx context.Context, m kafka.Message, rawEnvelope map[string]json.RawMessage, sagaID, eventType string, handleErr error) error {
+	retries := retriesFromRaw(rawEnvelope)
+	if retries < r.max {
+		msg, err := withRetries(m, rawEnvelope, retries+1)
+		if err != nil {
+			return err
+		}
+		msg.Topic = r.topic
+		if msg.Topic == "" {
+			msg.Topic = m.Topic
+		}
+		log.Printf("retrying message - SagaID: %s, Type: %s, Retry: %d/%d, Error: %v",
+			sagaID, eventType, retries+1, r.max, handleErr)
+		return r.producer.write(ctx, msg)
+	}
+
+	dlq := r.dlq
+	if dlq == "" {
+		dlq = m.Topic + DLQSuffix
+	}
+	msg := kafka.Message{
+		Topic: dlq,
+		Key:   m.Key,
+		Value: m.Value,
+		Headers: append(append([]kafka.Header(nil), m.Headers...),
+			kafka.Header{Key: HeaderDLQError, Value: []byte(handleErr.Error())},
+			kafka.Header{Key: HeaderDLQSourceTopic, Value: []byte(m.Topic)},
+		),
+	}
+	log.Printf("retries exhausted, dead lettering message - SagaID: %s, Type: %s, Retries: %d, DLQ: %s, Error: %v",
+		sagaID, eventType, retries, dlq, handleErr)
+	if err := r.producer.write(ctx, msg); err != nil {
+		return fmt.Errorf("write to %s: %w", dlq, err)
+	}
+	if r.onExhaust != nil {
+		r.onExhaust(RetryExhausted{SagaID: sagaID, Type: eventType, Retries: retries, DLQTopic: dlq, Err: handleErr})
+	}
+
+	step, ok := retryStep[eventType]
+	if !ok {
+		return nil
+	}
+	meta := metaFromRaw(rawEnvelope)
+	if r.appID != "" {
+		meta.AppID = r.appID
+	}
+	if meta.Initiator == "" {
+		meta.Initiator = InitiatorSystem
+	}
+	failed := BuildEnvelopeWithMeta(Failed{Step: step, Code: FailedCodeUnknown, Recoverable: false},
+		PipelineFailed, sagaID, meta.AppID, meta.Initiator)
+	failed.Meta.Retries = retries
+	failed.Meta.TenantID = meta.TenantID
+	failed.TraceID = traceIDFromRaw(rawEnvelope)
+	return r.producer.PublishEvent(ctx, m.Key, failed)
+}
+
+// withRetries returns a copy of m whose envelope and retries header carry
+// retries. Fields the consumer does not know are kept as they are.
+func withRetries(m kafka.Message, rawEnvelope map[string]json.RawMessage, retries int) (kafka.Message, error) {
+	var meta map[string]json.RawMessage
+	if raw, ok := rawEnvelope["meta"]; ok {
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return kafka.Message{}, fmt.Errorf("decode meta: %w", err)
+		}
+	}
+	if meta == nil {
+		meta = map[string]json.RawMessage{}
+	}
+	meta["retries"] = json.RawMessage(strconv.Itoa(retries))
+
+	envelope := make(map[string]json.RawMessage, len(rawEnvelope))
+	for k, v := range rawEnvelope {
+		envelope[k] = v
+	}
+	var err error
+	if envelope["meta"], err = json.Marshal(meta); err != nil {
+		return kafka.Message{}, err
+	}
+	value, err := json.Marshal(envelope)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	headers := make([]kafka.Header, 0, len(m.Headers)+1)
+	for _, h := range m.Headers {
+		if h.Key != "retries" {
+			headers = append(headers, h)
+		}
+	}
+	headers = append(headers, kafka.Header{Key: "retries", Value: []byte(strconv.Itoa(retries))})
+	return kafka.Message{Key: m.Key, Value: value, Headers: headers}, nil
+}
+
+func metaFromRaw(rawEnvelope map[string]json.RawMessage) Meta {
+	var meta Meta
+	if raw, ok := rawEnvelope["meta"]; ok {
+		_ = json.Unmarshal(raw, &meta)
+	}
+	return meta
+}
+
+func retriesFromRaw(rawEnvelope map[string]json.RawMessage) int {
+	return metaFromRaw(rawEnvelope).Retries
+}
+
+func traceIDFromRaw(rawEnvelope map[string]json.RawMessage) string {
+	var id string
+	if raw, ok := rawEnvelope["trace_id"]; ok {
+		_ = json.Unmarshal(raw, &id)
+	}
+	return id
+}
+
+func newRetryPolicy(brokers []string, cfg ConsumerConfig) *retryPolicy {
+	r := &retryPolicy{
+		max:       cfg.MaxRetries,
+		topic:     cfg.RetryTopic,
+		dlq:       cfg.DLQTopic,
+		producer:  cfg.RetryProducer,
+		appID:     cfg.FailedAppID,
+		onExhaust: cfg.OnRetryExhausted,
+	}
+	if r.producer == nil {
+		r.producer = NewKafkaProducer(brokers)
+		r.owned = true
+	}
+	return r
+}
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func retryMessage(t *testing.T, retries int) (kafka.Message, map[string]json.RawMessage) {
+	t.Helper()
+	envelope := BuildEnvelopeWithMeta(ExtractRequest{AppID: "123"}, PipelineExtractRequest, "saga-1", "gateway", InitiatorUser)
+	envelope.Meta.Retries = retries
+	envelope.Meta.TenantID = "acme"
+	envelope.TraceID = "trace-1"
+	msg, err := newMessage(PipelineExtractRequest, []byte("saga-1"), envelope)
+	require.NoError(t, err)
+
+	var raw map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(msg.Value, &raw))
+	// Unknown envelope fields must survive a retry.
+	raw["x_custom"] = json.RawMessage(`"kept"`)
+	msg.Value, err = json.Marshal(raw)
+	require.NoError(t, err)
+	return msg, raw
+}
+
+func headerValue(m kafka.Message, key string) string {
+	for _, h := range m.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func TestRetryRepublishesWithIncrementedRetries(t *testing.T) {
+	w := &fakeWriter{}
+	r := &retryPolicy{max: 3, producer: newKafkaProducer(w, ProducerConfig{})}
+
+	msg, raw := retryMessage(t, 1)
+	require.NoError(t, r.handleFailure(context.Background(), msg, raw, "saga-1", PipelineExtractRequest, assert.AnError))
+
+	require.Len(t, w.written, 1)
+	out := w.written[0]
+	assert.Equal(t, PipelineExtractRequest, out.Topic)
+	assert.Equal(t, "2", headerValue(out, "retries"))
+
+	var got Envelope[json.RawMessage]
+	require.NoError(t, json.Unmarshal(out.Value, &got))
+	assert.Equal(t, 2, got.Meta.Retries)
+	assert.Equal(t, "acme", got.Meta.TenantID)
+	assert.Contains(t, string(out.Value), `"x_custom":"kept"`)
+}
+
+func TestRetryExhaustedRoutesToDLQ(t *testing.T) {
+	w := &fakeWriter{}
+	var exhausted []RetryExhausted
+	r := &retryPolicy{
+		max:       3,
+		producer:  newKafkaProducer(w, ProducerConfig{}),
+		onExhaust: func(e RetryExhausted) { exhausted = append(exhausted, e) },
+	}
+
+	msg, raw := retryMessage(t, 3)
+	require.NoError(t, r.handleFailure(context.Background(), msg, raw, "saga-1", PipelineExtractRequest, assert.AnError))
+
+	require.Len(t, w.written, 2)
+	dead := w.written[0]
+	assert.Equal(t, PipelineExtractRequest+DLQSuffix, dead.Topic)
+	assert.Equal(t, msg.Value, dead.Value)
+	assert.Equal(t, assert.AnError.Error(), headerValue(dead, HeaderDLQError))
+	assert.Equal(t, PipelineExtractRequest, headerValue(dead, HeaderDLQSourceTopic))
+
+	failed := w.written[1]
+	assert.Equal(t, PipelineFailed, failed.Topic)
+	var got Envelope[Failed]
+	require.NoError(t, json.Unmarshal(failed.Value, &got))
+	assert.Equal(t, Failed{Step: SagaStepExtract, Code: FailedCodeUnknown, Recoverable: false}, got.Payload)
+	assert.Equal(t, 3, got.Meta.Retries)
+	assert.Equal(t, "acme", got.Meta.TenantID)
+	assert.Equal(t, "trace-1", got.TraceID)
+
+	require.Len(t, exhausted, 1)
+	assert.Equal(t, 3, exhausted[0].Retries)
+}
+
+func TestRetryDedupKeepsRetryAttempts(t *testing.T) {
+	kc := &KafkaConsumer{dedup: newMessageDeduper(0, 10)}
+	_, first := retryMessage(t, 0)
+	retried, err := withRetries(kafka.Message{}, first, 1)
+	require.NoError(t, err)
+	var second map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(retried.Value, &second))
+
+	assert.False(t, kc.duplicate(first))
+	assert.False(t, kc.duplicate(second), "a retry is not a duplicate of the original")
+	assert.True(t, kc.duplicate(second))
+}
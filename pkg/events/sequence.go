@@ -0,0 +1,199 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// sequenceIdleTTL is how long per-saga sequence state is kept after the saga
+// was last seen.
+const sequenceIdleTTL = 24 * time.Hour
+
+const (
+	sequencePruneEvery = 1024
+	maxMissingPerSaga  = 1024
+	// maxSequenceJump bounds how far one event may move a saga's sequence
+	// ahead. Sagas have a handful of steps; a larger jump is a corrupt or
+	// forged number.
+	maxSequenceJump = 1 << 16
+)
+
+// sagaSequencer assigns increasing Meta.Sequence numbers per saga. Numbers are
+// only consistent when a single producer publishes all steps of a saga; other
+// setups should set Meta.Sequence themselves.
+type sagaSequencer struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	ops     int
+	entries map[string]*sequencerEntry
+}
+
+type sequencerEntry struct {
+	last    uint64
+	touched time.Time
+}
+
+func newSagaSequencer() *sagaSequencer {
+	return &sagaSequencer{now: time.Now, entries: make(map[string]*sequencerEntry)}
+}
+
+func (s *sagaSequencer) next(sagaID string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.ops++; s.ops%sequencePruneEvery == 0 {
+		for id, e := range s.entries {
+			if now.Sub(e.touched) > sequenceIdleTTL {
+				delete(s.entries, id)
+			}
+		}
+	}
+
+	e, ok := s.entries[sagaID]
+	if !ok {
+		e = &sequencerEntry{}
+		s.entries[sagaID] = e
+	}
+	e.last++
+	e.touched = now
+	return e.last
+}
+
+type SequenceStatus int
+
+const (
+	// SequenceInOrder is the next expected number, or the first one seen for a
+	// saga.
+	SequenceInOrder SequenceStatus = iota
+	// SequenceGap means one or more numbers before this one were skipped.
+	SequenceGap
+	// SequenceLate fills a previously reported gap.
+	SequenceLate
+	// SequenceDuplicate was already seen.
+	SequenceDuplicate
+	// SequenceInvalid jumps implausibly far ahead and is ignored, so it
+	// does not move the saga's expected number.
+	SequenceInvalid
+)
+
+func (s SequenceStatus) String() string {
+	switch s {
+	case SequenceInOrder:
+		return "in_order"
+	case SequenceGap:
+		return "gap"
+	case SequenceLate:
+		return "late"
+	case SequenceDuplicate:
+		return "duplicate"
+	case SequenceInvalid:
+		return "invalid"
+	default:
+		return "unknown"
+	}
+}
+
+// SequenceCheck is the result of observing one sequenced event.
+type SequenceCheck struct {
+	SagaID   string
+	Type     string
+	Sequence uint64
+	Expected uint64
+	Status   SequenceStatus
+	// Missing lists the numbers skipped by a SequenceGap, at most the
+	// first 1024 of them; MissingCount is how many were skipped.
+	Missing      []uint64
+	MissingCount uint64
+}
+
+// SequenceTracker detects gaps, late arrivals and duplicates in per-saga
+// sequence numbers on the consumer side. The first number seen for a saga is
+// taken as the baseline, so a restarted consumer does not report the whole
+// history as missing.
+type SequenceTracker struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	ops     int
+	entries map[string]*trackerEntry
+}
+
+type trackerEntry struct {
+	highest uint64
+	missing map[uint64]struct{}
+	touched time.Time
+}
+
+func NewSequenceTracker() *SequenceTracker {
+	return &SequenceTracker{now: time.Now, entries: make(map[string]*trackerEntry)}
+}
+
+// Observe records seq for sagaID and classifies it.
+func (t *SequenceTracker) Observe(sagaID, eventType string, seq uint64) SequenceCheck {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if t.ops++; t.ops%sequencePruneEvery == 0 {
+		for id, e := range t.entries {
+			if now.Sub(e.touched) > sequenceIdleTTL {
+				delete(t.entries, id)
+			}
+		}
+	}
+
+	check := SequenceCheck{SagaID: sagaID, Type: eventType, Sequence: seq}
+	e, ok := t.entries[sagaID]
+	if !ok {
+		t.entries[sagaID] = &trackerEntry{highest: seq, touched: now}
+		check.Expected = seq
+		return check
+	}
+	e.touched = now
+	check.Expected = e.highest + 1
+
+	switch {
+	case seq == e.highest+1:
+		e.highest = seq
+	case seq > e.highest+1 && seq-e.highest > maxSequenceJump:
+		check.Status = SequenceInvalid
+	case seq > e.highest+1:
+		check.Status = SequenceGap
+		check.MissingCount = seq - e.highest - 1
+		if e.missing == nil {
+			e.missing = make(map[uint64]struct{})
+		}
+		for n := e.highest + 1; n < seq && len(check.Missing) < maxMissingPerSaga; n++ {
+			check.Missing = append(check.Missing, n)
+			if len(e.missing) < maxMissingPerSaga {
+				e.missing[n] = struct{}{}
+			}
+		}
+		e.highest = seq
+	default:
+		if _, ok := e.missing[seq]; ok {
+			delete(e.missing, seq)
+			check.Status = SequenceLate
+		} else {
+			check.Status = SequenceDuplicate
+		}
+	}
+	return check
+}
+
+// sequenceFromRaw reads meta.sequence from a raw envelope; zero means the
+// event is not sequenced.
+func sequenceFromRaw(rawEnvelope map[string]json.RawMessage) uint64 {
+	metaRaw, ok := rawEnvelope["meta"]
+	if !ok {
+		return 0
+	}
+	var meta struct {
+		Sequence uint64 `json:"sequence"`
+	}
+	if err := json.Unmarshal(metaRaw, &meta); err != nil {
+		return 0
+	}
+	return meta.Sequence
+}
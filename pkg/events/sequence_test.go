@@ -0,0 +1,107 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceTrackerClassifiesEvents(t *testing.T) {
+	tr := NewSequenceTracker()
+
+	first := tr.Observe("saga-1", PipelineExtractRequest, 3)
+	assert.Equal(t, SequenceInOrder, first.Status, "first number is the baseline")
+
+	assert.Equal(t, SequenceInOrder, tr.Observe("saga-1", PipelineExtractCompleted, 4).Status)
+
+	gap := tr.Observe("saga-1", PipelinePrepareCompleted, 7)
+	assert.Equal(t, SequenceGap, gap.Status)
+	assert.Equal(t, uint64(5), gap.Expected)
+	assert.Equal(t, []uint64{5, 6}, gap.Missing)
+
+	assert.Equal(t, SequenceLate, tr.Observe("saga-1", PipelinePrepareRequest, 5).Status)
+	assert.Equal(t, SequenceDuplicate, tr.Observe("saga-1", PipelinePrepareRequest, 5).Status)
+	assert.Equal(t, SequenceDuplicate, tr.Observe("saga-1", PipelineExtractCompleted, 4).Status)
+
+	assert.Equal(t, SequenceInOrder, tr.Observe("saga-2", PipelineExtractRequest, 1).Status, "sagas are tracked independently")
+}
+
+func TestSequenceTrackerBoundsHugeGaps(t *testing.T) {
+	tr := NewSequenceTracker()
+	tr.Observe("saga-1", PipelineExtractRequest, 1)
+
+	forged := tr.Observe("saga-1", PipelineExtractCompleted, 1+1<<40)
+	assert.Equal(t, SequenceInvalid, forged.Status)
+	assert.Empty(t, forged.Missing)
+	assert.Equal(t, SequenceInOrder, tr.Observe("saga-1", PipelineExtractCompleted, 2).Status, "an invalid number does not move the saga")
+
+	gap := tr.Observe("saga-1", PipelinePrepareRequest, 2+maxSequenceJump)
+	assert.Equal(t, SequenceGap, gap.Status)
+	assert.Len(t, gap.Missing, maxMissingPerSaga)
+	assert.Equal(t, uint64(3), gap.Missing[0])
+	assert.Equal(t, uint64(maxSequenceJump-1), gap.MissingCount)
+}
+
+func TestSequenceTrackerForgetsIdleSagas(t *testing.T) {
+	now := time.Unix(0, 0)
+	tr := NewSequenceTracker()
+	tr.now = func() time.Time { return now }
+
+	tr.Observe("old", PipelineExtractRequest, 1)
+	now = now.Add(sequenceIdleTTL + time.Minute)
+	for i := 0; i < sequencePruneEvery; i++ {
+		tr.Observe("busy", PipelineExtractRequest, uint64(i+1))
+	}
+
+	_, ok := tr.entries["old"]
+	assert.False(t, ok)
+}
+
+func TestProducerAssignsSagaSequences(t *testing.T) {
+	w := &fakeWriter{}
+	p := newKafkaProducer(w, ProducerConfig{SkipValidation: true, SagaSequences: true})
+	ctx := context.Background()
+
+	require.NoError(t, p.PublishEvent(ctx, nil, BuildEnvelope("a", PipelineExtractRequest, "saga-1")))
+	require.NoError(t, p.PublishEvent(ctx, nil, BuildEnvelope("b", PipelineExtractRequest, "saga-2")))
+	require.NoError(t, p.PublishEvent(ctx, nil, BuildEnvelope("c", PipelineExtractCompleted, "saga-1")))
+
+	explicit := BuildEnvelope("d", PipelinePrepareRequest, "saga-1")
+	explicit.Meta.Sequence = 10
+	require.NoError(t, p.PublishEvent(ctx, nil, explicit))
+
+	var got []uint64
+	for _, m := range w.written {
+		var env Envelope[json.RawMessage]
+		require.NoError(t, json.Unmarshal(m.Value, &env))
+		got = append(got, env.Meta.Sequence)
+	}
+	assert.Equal(t, []uint64{1, 1, 2, 10}, got)
+
+	var header string
+	for _, h := range w.written[2].Headers {
+		if h.Key == "sequence" {
+			header = string(h.Value)
+		}
+	}
+	assert.Equal(t, "2", header)
+}
+
+func TestConsumerReportsSequenceGaps(t *testing.T) {
+	var reported []SequenceCheck
+	kc := &KafkaConsumer{sequences: NewSequenceTracker()}
+	kc.SetSequenceHandler(func(c SequenceCheck) { reported = append(reported, c) })
+
+	for _, seq := range []uint64{1, 2, 4} {
+		raw := map[string]json.RawMessage{"meta": mustMarshal(Meta{Sequence: seq})}
+		kc.checkSequence("saga-1", PipelineExtractCompleted, sequenceFromRaw(raw))
+	}
+
+	require.Len(t, reported, 1)
+	assert.Equal(t, SequenceGap, reported[0].Status)
+	assert.Equal(t, []uint64{3}, reported[0].Missing)
+}
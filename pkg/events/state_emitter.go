@@ -0,0 +1,285 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrIllegalTransition is matched by errors.Is for every *TransitionError.
+var ErrIllegalTransition = errors.New("events: illegal saga state transition")
+
+// Reasons a transition is rejected. Each is matched by errors.Is on the
+// *TransitionError as well.
+var (
+	ErrSagaNotStarted = errors.New("events: saga must start running at the first step")
+	ErrSagaFinished   = errors.New("events: saga already completed")
+	ErrStepRegressed  = errors.New("events: saga step moved backwards")
+	ErrStepSkipped    = errors.New("events: saga step skipped")
+	ErrStatusNotFrom  = errors.New("events: status change not allowed from current status")
+)
+
+// sagaStepOrder is the order steps must run in.
+var sagaStepOrder = map[SagaStep]int{
+	SagaStepExtract:   0,
+	SagaStepPrepare:   1,
+	SagaStepVectorize: 2,
+}
+
+// SagaState is the last status and step emitted for a saga.
+type SagaState struct {
+	Status SagaStatus
+	Step   SagaStep
+}
+
+// TransitionError reports a StateChanged event rejected by
+// StateChangedEmitter. From is the zero value for a saga the emitter has not
+// seen yet.
+type TransitionError struct {
+	SagaID string
+	From   SagaState
+	To     SagaState
+	Reason error
+}
+
+func (e *TransitionError) Error() string {
+	from := "none"
+	if e.From != (SagaState{}) {
+		from = string(e.From.Status) + "@" + string(e.From.Step)
+	}
+	return fmt.Sprintf("events: saga %s: %s -> %s@%s: %v", e.SagaID, from, e.To.Status, e.To.Step, e.Reason)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrIllegalTransition
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Reason
+}
+
+// EventPublisher publishes envelopes; *KafkaProducer implements it.
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, key []byte, envelope Envelope[any]) error
+}
+
+// StateChangedEmitter publishes saga.orchestrator.state.changed events after
+// checking that they follow the saga lifecycle:
+//
+//   - a saga starts running at the extract step;
+//   - while running it may report running again, move to the next step,
+//     complete or fail; steps never go back or get skipped;
+//   - a failed saga may only be retried by running the failed step again;
+//   - a completed saga emits nothing further.
+//
+// State is kept in memory per process, so one emitter should own a saga.
+// After a restart, Resume restores the last known state.
+type StateChangedEmitter struct {
+	pub       EventPublisher
+	appID     string
+	initiator Initiator
+	now       func() time.Time
+
+	mu    sync.Mutex
+	ops   int
+	sagas map[string]*emitterEntry
+}
+
+// emitterEntry is the state of one saga. Its fields other than emit are
+// guarded by StateChangedEmitter.mu.
+type emitterEntry struct {
+	state SagaState
+	// known is false while the first Emit for the saga is still publishing.
+	known   bool
+	touched time.Time
+	// users counts Emit calls holding or waiting for emit; the entry is not
+	// pruned while any are.
+	users int
+	// emit serializes Emit for the saga, held across the publish so other
+	// sagas are not held up by a slow broker.
+	emit sync.Mutex
+}
+
+// NewStateChangedEmitter creates an emitter publishing with appID and a
+// system initiator in the envelope meta.
+func NewStateChangedEmitter(pub EventPublisher, appID string) *StateChangedEmitter {
+	return &StateChangedEmitter{
+		pub:       pub,
+		appID:     appID,
+		initiator: InitiatorSystem,
+		now:       time.Now,
+		sagas:     make(map[string]*emitterEntry),
+	}
+}
+
+// Resume sets the current state of a saga without publishing, e.g. from the
+// orchestrator's store after a restart.
+func (e *StateChangedEmitter) Resume(sagaID string, state SagaState) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	entry, ok := e.sagas[sagaID]
+	if !ok {
+		entry = &emitterEntry{}
+		e.sagas[sagaID] = entry
+	}
+	entry.state, entry.known, entry.touched = state, true, e.now()
+}
+
+// State returns the last state emitted or resumed for sagaID.
+func (e *StateChangedEmitter) State(sagaID string) (SagaState, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	entry, ok := e.sagas[sagaID]
+	if !ok || !entry.known {
+		return SagaState{}, false
+	}
+	return entry.state, true
+}
+
+// Emit validates the transition to sc and publishes it, keyed by sagaID.
+// Illegal transitions return a *TransitionError and nothing is published.
+// The trace and span IDs of ctx's span are filled in unless sc.Context
+// already carries a trace ID.
+// The saga's state only advances once the publish succeeded.
+//
+// Concurrent Emit calls for the same saga are serialized; different sagas
+// publish in parallel.
+func (e *StateChangedEmitter) Emit(ctx context.Context, sagaID string, sc StateChanged) error {
+	entry := e.acquire(sagaID)
+	defer e.release(sagaID, entry)
+	entry.emit.Lock()
+	defer entry.emit.Unlock()
+
+	e.mu.Lock()
+	from, known := entry.state, entry.known
+	e.mu.Unlock()
+
+	to := SagaState{Status: sc.Status, Step: sc.Step}
+	if err := checkTransition(from, to, known); err != nil {
+		return &TransitionError{SagaID: sagaID, From: from, To: to, Reason: err}
+	}
+
+	sc.Context = sc.Context.WithTrace(ctx)
+	env := BuildEnvelopeWithMeta(sc, SagaStateChanged, sagaID, e.appID, e.initiator)
+	env.TraceID = sc.Context.TraceID
+	if err := e.pub.PublishEvent(ctx, []byte(sagaID), env); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	entry.state, entry.known, entry.touched = to, true, e.now()
+	e.mu.Unlock()
+	return nil
+}
+
+// acquire returns the entry for sagaID, creating it if needed, and pins it
+// until release. Idle entries are pruned every sequencePruneEvery calls.
+func (e *StateChangedEmitter) acquire(sagaID string) *emitterEntry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	if e.ops++; e.ops%sequencePruneEvery == 0 {
+		for id, entry := range e.sagas {
+			if entry.users == 0 && now.Sub(entry.touched) > sequenceIdleTTL {
+				delete(e.sagas, id)
+			}
+		}
+	}
+
+	entry, ok := e.sagas[sagaID]
+	if !ok {
+		entry = &emitterEntry{touched: now}
+		e.sagas[sagaID] = entry
+	}
+	entry.users++
+	return entry
+}
+
+// release unpins entry, dropping it if no Emit for the saga ever published.
+func (e *StateChangedEmitter) release(sagaID string, entry *emitterEntry) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if entry.users--; entry.users == 0 && !entry.known {
+		delete(e.sagas, sagaID)
+	}
+}
+
+func checkTransition(from, to SagaState, known bool) error {
+	toStep, ok := sagaStepOrder[to.Step]
+	if !ok {
+		return fmt.Errorf("unknown step %q", to.Step)
+	}
+	if !known {
+		if to.Status != SagaStatusRunning || toStep != 0 {
+			return ErrSagaNotStarted
+		}
+		return nil
+	}
+
+	fromStep := sagaStepOrder[from.Step]
+	switch {
+	case from.Status == SagaStatusCompleted:
+		return ErrSagaFinished
+	case toStep < fromStep:
+		return ErrStepRegressed
+	case toStep > fromStep+1:
+		return ErrStepSkipped
+	}
+
+	switch from.Status {
+	case SagaStatusRunning:
+		if toStep != fromStep && to.Status != SagaStatusRunning {
+			// A new step has to start running before it can finish.
+			return ErrStatusNotFrom
+		}
+	case SagaStatusFailed:
+		if to.Status != SagaStatusRunning || toStep != fromStep {
+			return ErrStatusNotFrom
+		}
+	}
+	return nil
+}
+
+// WithTrace returns c with TraceID and SpanID taken from the span in ctx. It
+// leaves c unchanged if c already has a trace ID or ctx has no valid span.
+func (c StateChangedContext) WithTrace(ctx context.Context) StateChangedContext {
+	if c.TraceID != "" {
+		return c
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return c
+	}
+	c.TraceID = sc.TraceID().String()
+	c.SpanID = sc.SpanID().String()
+	return c
+}
+
+// Add adds n to the counter name.
+func (m *StepMetrics) Add(name string, n int64) {
+	if m.Counts == nil {
+		m.Counts = make(map[string]int64)
+	}
+	m.Counts[name] += n
+}
+
+// Observe adds d to the duration name.
+func (m *StepMetrics) Observe(name string, d time.Duration) {
+	if m.DurationsMs == nil {
+		m.DurationsMs = make(map[string]int64)
+	}
+	m.DurationsMs[name] += d.Milliseconds()
+}
+
+// Time starts timing name; call the returned func when the phase ends.
+//
+//	defer metrics.Time("fetch")()
+func (m *StepMetrics) Time(name string) func() {
+	start := time.Now()
+	return func() { m.Observe(name, time.Since(start)) }
+}
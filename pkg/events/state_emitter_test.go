@@ -0,0 +1,191 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type recordingPublisher struct {
+	envelopes []Envelope[any]
+	err       error
+}
+
+func (p *recordingPublisher) PublishEvent(ctx context.Context, key []byte, envelope Envelope[any]) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.envelopes = append(p.envelopes, envelope)
+	return nil
+}
+
+func stateChanged(status SagaStatus, step SagaStep) StateChanged {
+	return StateChanged{Status: status, Step: step, Context: StateChangedContext{Message: string(status)}}
+}
+
+func TestStateChangedEmitterHappyPath(t *testing.T) {
+	pub := &recordingPublisher{}
+	e := NewStateChangedEmitter(pub, "orchestrator")
+	ctx := context.Background()
+
+	for _, sc := range []StateChanged{
+		stateChanged(SagaStatusRunning, SagaStepExtract),
+		stateChanged(SagaStatusRunning, SagaStepExtract),
+		stateChanged(SagaStatusRunning, SagaStepPrepare),
+		stateChanged(SagaStatusFailed, SagaStepPrepare),
+		stateChanged(SagaStatusRunning, SagaStepPrepare),
+		stateChanged(SagaStatusRunning, SagaStepVectorize),
+		stateChanged(SagaStatusCompleted, SagaStepVectorize),
+	} {
+		require.NoError(t, e.Emit(ctx, "saga-1", sc), "%s@%s", sc.Status, sc.Step)
+	}
+
+	require.Len(t, pub.envelopes, 7)
+	assert.Equal(t, SagaStateChanged, pub.envelopes[0].Type)
+	assert.Equal(t, "saga-1", pub.envelopes[0].SagaID)
+	assert.Equal(t, "orchestrator", pub.envelopes[0].Meta.AppID)
+
+	state, ok := e.State("saga-1")
+	assert.True(t, ok)
+	assert.Equal(t, SagaState{Status: SagaStatusCompleted, Step: SagaStepVectorize}, state)
+}
+
+func TestStateChangedEmitterRejectsIllegalTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		from *SagaState
+		to   StateChanged
+		want error
+	}{
+		{"start at later step", nil, stateChanged(SagaStatusRunning, SagaStepPrepare), ErrSagaNotStarted},
+		{"start completed", nil, stateChanged(SagaStatusCompleted, SagaStepExtract), ErrSagaNotStarted},
+		{"after completion", &SagaState{SagaStatusCompleted, SagaStepVectorize}, stateChanged(SagaStatusRunning, SagaStepVectorize), ErrSagaFinished},
+		{"step backwards", &SagaState{SagaStatusRunning, SagaStepPrepare}, stateChanged(SagaStatusRunning, SagaStepExtract), ErrStepRegressed},
+		{"step skipped", &SagaState{SagaStatusRunning, SagaStepExtract}, stateChanged(SagaStatusRunning, SagaStepVectorize), ErrStepSkipped},
+		{"complete next step without running it", &SagaState{SagaStatusRunning, SagaStepExtract}, stateChanged(SagaStatusCompleted, SagaStepPrepare), ErrStatusNotFrom},
+		{"failed to completed", &SagaState{SagaStatusFailed, SagaStepPrepare}, stateChanged(SagaStatusCompleted, SagaStepPrepare), ErrStatusNotFrom},
+		{"failed to next step", &SagaState{SagaStatusFailed, SagaStepPrepare}, stateChanged(SagaStatusRunning, SagaStepVectorize), ErrStatusNotFrom},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := &recordingPublisher{}
+			e := NewStateChangedEmitter(pub, "orchestrator")
+			if tt.from != nil {
+				e.Resume("saga-1", *tt.from)
+			}
+
+			err := e.Emit(context.Background(), "saga-1", tt.to)
+
+			var terr *TransitionError
+			require.ErrorAs(t, err, &terr)
+			assert.ErrorIs(t, err, ErrIllegalTransition)
+			assert.ErrorIs(t, err, tt.want)
+			assert.Empty(t, pub.envelopes)
+		})
+	}
+}
+
+func TestStateChangedEmitterKeepsStateOnPublishError(t *testing.T) {
+	pub := &recordingPublisher{err: errors.New("broker down")}
+	e := NewStateChangedEmitter(pub, "orchestrator")
+
+	err := e.Emit(context.Background(), "saga-1", stateChanged(SagaStatusRunning, SagaStepExtract))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrIllegalTransition)
+
+	_, ok := e.State("saga-1")
+	assert.False(t, ok)
+}
+
+// blockingPublisher holds publishes for the saga keyed block until release
+// is closed.
+type blockingPublisher struct {
+	block   string
+	started chan struct{}
+	release chan struct{}
+
+	mu   sync.Mutex
+	keys []string
+}
+
+func (p *blockingPublisher) PublishEvent(ctx context.Context, key []byte, envelope Envelope[any]) error {
+	if string(key) == p.block {
+		p.started <- struct{}{}
+		<-p.release
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append(p.keys, string(key))
+	return nil
+}
+
+func TestStateChangedEmitterPublishesSagasInParallel(t *testing.T) {
+	pub := &blockingPublisher{block: "saga-1", started: make(chan struct{}, 1), release: make(chan struct{})}
+	e := NewStateChangedEmitter(pub, "orchestrator")
+	ctx := context.Background()
+
+	first := make(chan error, 1)
+	go func() { first <- e.Emit(ctx, "saga-1", stateChanged(SagaStatusRunning, SagaStepExtract)) }()
+	<-pub.started
+
+	// Another saga is not held up by the blocked publish.
+	require.NoError(t, e.Emit(ctx, "saga-2", stateChanged(SagaStatusRunning, SagaStepExtract)))
+	_, ok := e.State("saga-1")
+	assert.False(t, ok, "state advanced before the publish returned")
+
+	// The same saga waits for it, then sees its state.
+	second := make(chan error, 1)
+	go func() { second <- e.Emit(ctx, "saga-1", stateChanged(SagaStatusRunning, SagaStepPrepare)) }()
+	select {
+	case <-second:
+		t.Fatal("second Emit for saga-1 did not wait for the first")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(pub.release)
+	require.NoError(t, <-first)
+	<-pub.started
+	require.NoError(t, <-second)
+	assert.Equal(t, []string{"saga-2", "saga-1", "saga-1"}, pub.keys)
+	state, _ := e.State("saga-1")
+	assert.Equal(t, SagaState{Status: SagaStatusRunning, Step: SagaStepPrepare}, state)
+}
+
+func TestStateChangedEmitterAddsTraceAndMetrics(t *testing.T) {
+	pub := &recordingPublisher{}
+	e := NewStateChangedEmitter(pub, "orchestrator")
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+	}))
+
+	sc := stateChanged(SagaStatusRunning, SagaStepExtract)
+	sc.Context.Metrics = &StepMetrics{}
+	sc.Context.Metrics.Add("reviews_fetched", 120)
+	sc.Context.Metrics.Add("reviews_fetched", 30)
+	sc.Context.Metrics.Observe("fetch", 1500*time.Millisecond)
+	require.NoError(t, sc.Validate())
+	require.NoError(t, e.Emit(ctx, "saga-1", sc))
+
+	require.Len(t, pub.envelopes, 1)
+	got := pub.envelopes[0].Payload.(StateChanged)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", got.Context.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", got.Context.SpanID)
+	assert.Equal(t, got.Context.TraceID, pub.envelopes[0].TraceID)
+	assert.Equal(t, int64(150), got.Context.Metrics.Counts["reviews_fetched"])
+	assert.Equal(t, int64(1500), got.Context.Metrics.DurationsMs["fetch"])
+
+	// An explicit trace ID is kept.
+	sc = stateChanged(SagaStatusRunning, SagaStepPrepare)
+	sc.Context.TraceID = "0af7651916cd43dd8448eb211c80319c"
+	require.NoError(t, e.Emit(ctx, "saga-1", sc))
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", pub.envelopes[1].Payload.(StateChanged).Context.TraceID)
+}
@@ -0,0 +1,56 @@
+package events
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// TenantMismatch describes an event skipped by a tenant-scoped consumer.
+// Got is empty when the event carries no tenant.
+type TenantMismatch struct {
+	SagaID string
+	Type   string
+	Want   string
+	Got    string
+}
+
+// SetTenant scopes the consumer to tenantID: events whose Meta.TenantID
+// differs are logged, passed to onMismatch if set, and not processed. Events
+// without a tenant are skipped too unless allowMissing is set, e.g. while
+// producers are being upgraded. An empty tenantID disables the check.
+func (kc *KafkaConsumer) SetTenant(tenantID string, allowMissing bool, onMismatch func(TenantMismatch)) {
+	kc.tenantID = tenantID
+	kc.allowNoTenant = allowMissing
+	kc.onTenant = onMismatch
+}
+
+// tenantAllowed reports whether the event belongs to the consumer's tenant.
+func (kc *KafkaConsumer) tenantAllowed(rawEnvelope map[string]json.RawMessage, sagaID, eventType string) bool {
+	if kc.tenantID == "" {
+		return true
+	}
+	got := tenantFromRaw(rawEnvelope)
+	if got == kc.tenantID || (got == "" && kc.allowNoTenant) {
+		return true
+	}
+	log.Printf("skipping event for another tenant - SagaID: %s, Type: %s, Tenant: %q, Expected: %q",
+		sagaID, eventType, got, kc.tenantID)
+	if kc.onTenant != nil {
+		kc.onTenant(TenantMismatch{SagaID: sagaID, Type: eventType, Want: kc.tenantID, Got: got})
+	}
+	return false
+}
+
+func tenantFromRaw(rawEnvelope map[string]json.RawMessage) string {
+	metaRaw, ok := rawEnvelope["meta"]
+	if !ok {
+		return ""
+	}
+	var meta struct {
+		TenantID string `json:"tenant_id"`
+	}
+	if err := json.Unmarshal(metaRaw, &meta); err != nil {
+		return ""
+	}
+	return meta.TenantID
+}
@@ -0,0 +1,32 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumerTenantScoping(t *testing.T) {
+	raw := func(tenant string) map[string]json.RawMessage {
+		return map[string]json.RawMessage{"meta": mustMarshal(Meta{TenantID: tenant})}
+	}
+
+	var flagged []TenantMismatch
+	kc := &KafkaConsumer{}
+	assert.True(t, kc.tenantAllowed(raw("acme"), "saga-1", PipelineExtractRequest), "unscoped consumer")
+
+	kc.SetTenant("acme", false, func(m TenantMismatch) { flagged = append(flagged, m) })
+	assert.True(t, kc.tenantAllowed(raw("acme"), "saga-1", PipelineExtractRequest))
+	assert.False(t, kc.tenantAllowed(raw("globex"), "saga-2", PipelineExtractRequest))
+	assert.False(t, kc.tenantAllowed(raw(""), "saga-3", PipelinePrepareRequest))
+
+	assert.Equal(t, []TenantMismatch{
+		{SagaID: "saga-2", Type: PipelineExtractRequest, Want: "acme", Got: "globex"},
+		{SagaID: "saga-3", Type: PipelinePrepareRequest, Want: "acme"},
+	}, flagged)
+
+	kc.SetTenant("acme", true, nil)
+	assert.True(t, kc.tenantAllowed(raw(""), "saga-3", PipelinePrepareRequest))
+	assert.False(t, kc.tenantAllowed(raw("globex"), "saga-2", PipelineExtractRequest))
+}
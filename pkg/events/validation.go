@@ -1,5 +1,22 @@
 package events
 
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrInvalidEnvelope is matched by errors.Is for every *EnvelopeValidationError.
+var ErrInvalidEnvelope = errors.New("events: invalid envelope")
+
+// PayloadValidator is implemented by payloads that can validate themselves.
+type PayloadValidator interface {
+	Validate() error
+}
+
 // ValidationError represents a validation error with field path and message.
 type ValidationError struct {
 	Field   string `json:"field"`
@@ -72,3 +89,85 @@ func ValidateEnvelope[T any](envelope Envelope[T]) ValidationResult {
 
 	return result
 }
+
+// EnvelopeValidationError is returned by KafkaProducer.PublishEvent when the
+// envelope or its payload fails validation.
+type EnvelopeValidationError struct {
+	Type   string
+	Errors []ValidationError
+}
+
+func (e *EnvelopeValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, ve := range e.Errors {
+		msgs = append(msgs, ve.Error())
+	}
+	return fmt.Sprintf("events: invalid %s envelope: %s", e.Type, strings.Join(msgs, "; "))
+}
+
+func (e *EnvelopeValidationError) Unwrap() error {
+	return ErrInvalidEnvelope
+}
+
+// ValidatePayload runs the payload's Validate method, if it has one, and maps
+// the result to field-level validation errors prefixed with "payload.".
+// Payloads passed by value are still validated when Validate has a pointer
+// receiver.
+func ValidatePayload(payload any) []ValidationError {
+	v := payloadValidator(payload)
+	if v == nil {
+		return nil
+	}
+
+	err := v.Validate()
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return []ValidationError{{Field: "payload", Message: err.Error()}}
+	}
+
+	out := make([]ValidationError, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		field := fe.Namespace()
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		out = append(out, ValidationError{
+			Field:   "payload." + field,
+			Message: fmt.Sprintf("failed on the '%s' validation", fe.Tag()),
+		})
+	}
+	return out
+}
+
+func payloadValidator(payload any) PayloadValidator {
+	if payload == nil {
+		return nil
+	}
+	if v, ok := payload.(PayloadValidator); ok {
+		return v
+	}
+
+	rv := reflect.ValueOf(payload)
+	if rv.Kind() == reflect.Pointer {
+		return nil
+	}
+	ptr := reflect.New(rv.Type())
+	ptr.Elem().Set(rv)
+	if v, ok := ptr.Interface().(PayloadValidator); ok {
+		return v
+	}
+	return nil
+}
+
+func validateForPublish[T any](envelope Envelope[T]) error {
+	result := ValidateEnvelope(envelope)
+	errs := append(result.Errors, ValidatePayload(envelope.Payload)...)
+	if len(errs) == 0 {
+		return nil
+	}
+	return &EnvelopeValidationError{Type: envelope.Type, Errors: errs}
+}
@@ -0,0 +1,167 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/quiby-ai/common/pkg/obs"
+)
+
+// AttemptInfo describes one request sent by the client, including retries.
+type AttemptInfo struct {
+	Method  string
+	URL     string
+	Attempt int // 1 for the first request
+	Status  int // 0 when no response was received
+	// Latency runs until the response headers arrived or the request failed.
+	Latency time.Duration
+	Phases  AttemptPhases
+	Err     error
+}
+
+// AttemptPhases splits an attempt's latency. Phases that did not happen,
+// e.g. DNS and TLS on a reused connection, are zero.
+type AttemptPhases struct {
+	DNS             time.Duration
+	Connect         time.Duration
+	TLS             time.Duration
+	TimeToFirstByte time.Duration
+	ReusedConn      bool
+}
+
+func (p AttemptPhases) logAttrs() []any {
+	return []any{
+		"dns_ms", p.DNS.Milliseconds(),
+		"connect_ms", p.Connect.Milliseconds(),
+		"tls_ms", p.TLS.Milliseconds(),
+		"ttfb_ms", p.TimeToFirstByte.Milliseconds(),
+		"reused_conn", p.ReusedConn,
+	}
+}
+
+// LogAttempt logs a at debug level through pkg/obs, with credentials removed
+// from the URL. Use it as Config.OnAttempt.
+func LogAttempt(ctx context.Context, a AttemptInfo) {
+	obs.Debug(ctx, "httpx attempt", attemptAttrs(a)...)
+}
+
+// LogSlowAttempt logs a at warn level with its phase timings. It is the
+// default Config.OnSlowRequest.
+func LogSlowAttempt(ctx context.Context, a AttemptInfo) {
+	obs.Warn(ctx, "httpx slow request", append(attemptAttrs(a), a.Phases.logAttrs()...)...)
+}
+
+func attemptAttrs(a AttemptInfo) []any {
+	host := ""
+	if u, err := url.Parse(a.URL); err == nil {
+		host = u.Host
+	}
+	attrs := []any{
+		"method", a.Method,
+		"host", host,
+		"url", obs.ScrubURL(a.URL),
+		"attempt", a.Attempt,
+		"status", a.Status,
+		"latency_ms", a.Latency.Milliseconds(),
+	}
+	if a.Err != nil {
+		attrs = append(attrs, "error", obs.ScrubText(a.Err.Error()))
+	}
+	return attrs
+}
+
+// attemptTimer measures one attempt for the OnAttempt and OnSlowRequest
+// hooks. It is nil when neither is configured.
+type attemptTimer struct {
+	now   func() time.Time
+	start time.Time
+
+	mu                     sync.Mutex
+	dnsStart, connectStart time.Time
+	tlsStart               time.Time
+	phases                 AttemptPhases
+}
+
+// startAttempt returns req with phase tracing attached when a hook needs it.
+func (c *realClient) startAttempt(req *http.Request) (*http.Request, *attemptTimer) {
+	if c.cfg.OnAttempt == nil && c.cfg.SlowRequestThreshold <= 0 {
+		return req, nil
+	}
+	t := &attemptTimer{now: c.clock().Now}
+	t.start = t.now()
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.phases.ReusedConn = info.Reused
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.since(t.dnsStart, &t.phases.DNS) },
+		ConnectStart: func(string, string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(string, string, error) {
+			t.since(t.connectStart, &t.phases.Connect)
+		},
+		TLSHandshakeStart: func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.since(t.tlsStart, &t.phases.TLS)
+		},
+		GotFirstResponseByte: func() { t.since(t.start, &t.phases.TimeToFirstByte) },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+func (t *attemptTimer) mark(at *time.Time) {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at.IsZero() {
+		*at = now
+	}
+}
+
+func (t *attemptTimer) since(from time.Time, d *time.Duration) {
+	now := t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if *d == 0 && !from.IsZero() {
+		*d = now.Sub(from)
+	}
+}
+
+func (c *realClient) finishAttempt(t *attemptTimer, req *http.Request, attempt int, resp *http.Response, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	a := AttemptInfo{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Attempt: attempt,
+		Latency: t.now().Sub(t.start),
+		Phases:  t.phases,
+		Err:     err,
+	}
+	t.mu.Unlock()
+	if resp != nil {
+		a.Status = resp.StatusCode
+	}
+
+	ctx := req.Context()
+	if c.cfg.OnAttempt != nil {
+		c.cfg.OnAttempt(ctx, a)
+	}
+	if c.cfg.SlowRequestThreshold > 0 && a.Latency >= c.cfg.SlowRequestThreshold {
+		if c.cfg.OnSlowRequest != nil {
+			c.cfg.OnSlowRequest(ctx, a)
+		} else {
+			LogSlowAttempt(ctx, a)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOnAttemptReportsEveryAttempt(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var (
+		mu       sync.Mutex
+		attempts []AttemptInfo
+	)
+	client := New(Config{
+		MaxRetries: 2,
+		Backoff:    ConstantBackoff{Interval: time.Millisecond},
+		OnAttempt: func(ctx context.Context, a AttemptInfo) {
+			mu.Lock()
+			attempts = append(attempts, a)
+			mu.Unlock()
+		},
+	})
+	if _, err := client.DoGET(context.Background(), server.URL+"/apps", map[string]string{"token": "s3cr3t"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attempts) != 2 {
+		t.Fatalf("attempts = %+v, want 2", attempts)
+	}
+	if attempts[0].Attempt != 1 || attempts[0].Status != http.StatusServiceUnavailable {
+		t.Errorf("first attempt = %+v", attempts[0])
+	}
+	if attempts[1].Attempt != 2 || attempts[1].Status != http.StatusOK || attempts[1].Method != http.MethodGet {
+		t.Errorf("second attempt = %+v", attempts[1])
+	}
+}
+
+func TestOnAttemptReportsTransportErrors(t *testing.T) {
+	var got AttemptInfo
+	client := New(Config{OnAttempt: func(ctx context.Context, a AttemptInfo) { got = a }})
+	client.DoGET(context.Background(), "http://127.0.0.1:1/", nil, nil)
+
+	if got.Err == nil || got.Status != 0 || got.Attempt != 1 {
+		t.Errorf("attempt = %+v", got)
+	}
+	LogAttempt(context.Background(), got) // must not panic without obs.Init
+}
+
+func TestSlowRequestThreshold(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var slow []AttemptInfo
+	client := New(Config{
+		SlowRequestThreshold: 30 * time.Millisecond,
+		OnSlowRequest:        func(ctx context.Context, a AttemptInfo) { slow = append(slow, a) },
+	})
+	ctx := context.Background()
+	if _, err := client.DoGET(ctx, server.URL+"/fast", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DoGET(ctx, server.URL+"/slow", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(slow) != 1 {
+		t.Fatalf("slow attempts = %+v, want 1", slow)
+	}
+	a := slow[0]
+	if a.Latency < 30*time.Millisecond || a.Phases.TimeToFirstByte < 30*time.Millisecond {
+		t.Errorf("latency = %v, ttfb = %v", a.Latency, a.Phases.TimeToFirstByte)
+	}
+	if !a.Phases.ReusedConn || a.Phases.Connect != 0 {
+		t.Errorf("second request should reuse the connection: %+v", a.Phases)
+	}
+	LogSlowAttempt(ctx, a) // must not panic without obs.Init
+}
+
+func TestAttemptPhasesOnNewConnection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var got AttemptInfo
+	client := New(Config{OnAttempt: func(ctx context.Context, a AttemptInfo) { got = a }})
+	if _, err := client.DoGET(context.Background(), server.URL, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.Phases.ReusedConn || got.Phases.Connect <= 0 || got.Phases.TimeToFirstByte <= 0 {
+		t.Errorf("phases = %+v", got.Phases)
+	}
+}
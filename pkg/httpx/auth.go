@@ -0,0 +1,52 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var ErrEmptyToken = errors.New("httpx: token provider returned empty token")
+
+// Auth sets the Authorization header on every attempt. The first configured
+// option wins, in the order TokenProvider, BearerToken, Username/Password.
+// A request that sets its own Authorization header is left untouched.
+type Auth struct {
+	Username string
+	Password string
+
+	BearerToken string
+
+	// TokenProvider is called before every attempt, so tokens that expire
+	// mid-run can be refreshed transparently.
+	TokenProvider func(ctx context.Context) (string, error)
+}
+
+func (c *realClient) applyAuth(req *http.Request, customHeaders map[string]string) error {
+	if _, ok := headerLookup(customHeaders, "Authorization"); ok {
+		return nil
+	}
+	return c.cfg.Auth.apply(req)
+}
+
+func (a *Auth) apply(req *http.Request) error {
+	switch {
+	case a == nil:
+		return nil
+	case a.TokenProvider != nil:
+		token, err := a.TokenProvider(req.Context())
+		if err != nil {
+			return fmt.Errorf("httpx: auth token: %w", err)
+		}
+		if token == "" {
+			return ErrEmptyToken
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case a.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+a.BearerToken)
+	case a.Username != "" || a.Password != "":
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthApplied(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		auth    *Auth
+		headers map[string]string
+		want    string
+	}{
+		{name: "none", want: ""},
+		{name: "basic", auth: &Auth{Username: "user", Password: "pass"}, want: "Basic dXNlcjpwYXNz"},
+		{name: "bearer", auth: &Auth{BearerToken: "static"}, want: "Bearer static"},
+		{
+			name: "provider wins",
+			auth: &Auth{BearerToken: "static", TokenProvider: func(context.Context) (string, error) { return "dynamic", nil }},
+			want: "Bearer dynamic",
+		},
+		{
+			name:    "request header wins",
+			auth:    &Auth{BearerToken: "static"},
+			headers: map[string]string{"authorization": "Custom abc"},
+			want:    "Custom abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := New(Config{Timeout: 5 * time.Second, Auth: tt.auth})
+			resp, err := client.DoGET(context.Background(), server.URL, nil, tt.headers)
+			if err != nil {
+				t.Fatalf("DoGET() error = %v", err)
+			}
+			if got := string(resp.Body); got != tt.want {
+				t.Fatalf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenProviderCalledPerAttempt(t *testing.T) {
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		if len(seen) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	calls := 0
+	client := New(Config{
+		Timeout:        5 * time.Second,
+		MaxRetries:     1,
+		BackoffInitial: time.Millisecond,
+		BackoffMax:     time.Millisecond,
+		Auth: &Auth{TokenProvider: func(context.Context) (string, error) {
+			calls++
+			return fmt.Sprintf("token-%d", calls), nil
+		}},
+	})
+	if _, err := client.DoGET(context.Background(), server.URL, nil, nil); err != nil {
+		t.Fatalf("DoGET() error = %v", err)
+	}
+	if len(seen) != 2 || seen[0] != "Bearer token-1" || seen[1] != "Bearer token-2" {
+		t.Fatalf("seen = %v", seen)
+	}
+}
+
+func TestTokenProviderError(t *testing.T) {
+	providerErr := errors.New("vault unavailable")
+	client := New(Config{Auth: &Auth{TokenProvider: func(context.Context) (string, error) { return "", providerErr }}})
+
+	_, err := client.DoGET(context.Background(), "http://127.0.0.1:1", nil, nil)
+	if !errors.Is(err, providerErr) {
+		t.Fatalf("err = %v, want provider error", err)
+	}
+
+	client = New(Config{Auth: &Auth{TokenProvider: func(context.Context) (string, error) { return "", nil }}})
+	if _, err := client.DoGET(context.Background(), "http://127.0.0.1:1", nil, nil); !errors.Is(err, ErrEmptyToken) {
+		t.Fatalf("err = %v, want ErrEmptyToken", err)
+	}
+}
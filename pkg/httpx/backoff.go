@@ -0,0 +1,88 @@
+package httpx
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff decides how long to wait before retry number attempt+1. prev is the
+// delay used before the previous retry (zero for the first one).
+type Backoff interface {
+	Delay(attempt int, prev time.Duration) time.Duration
+}
+
+// ExponentialBackoff doubles Initial per attempt and adds up to 250ms of
+// jitter, capped at Max. It is the default, built from BackoffInitial and
+// BackoffMax.
+type ExponentialBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+func (b ExponentialBackoff) Delay(attempt int, _ time.Duration) time.Duration {
+	backoff := float64(b.Initial) * math.Pow(2, float64(attempt))
+	backoff += float64(time.Duration(rand.Intn(250)) * time.Millisecond)
+	return capDelay(time.Duration(backoff), b.Max)
+}
+
+// FullJitterBackoff waits a random duration in [0, min(Max, Initial*2^attempt)].
+// It spreads retries of many clients hitting the same upstream.
+type FullJitterBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+func (b FullJitterBackoff) Delay(attempt int, _ time.Duration) time.Duration {
+	ceiling := capDelay(time.Duration(float64(b.Initial)*math.Pow(2, float64(attempt))), b.Max)
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// DecorrelatedJitterBackoff waits a random duration in [Initial, prev*3],
+// capped at Max.
+type DecorrelatedJitterBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+func (b DecorrelatedJitterBackoff) Delay(_ int, prev time.Duration) time.Duration {
+	if prev < b.Initial {
+		prev = b.Initial
+	}
+	upper := prev * 3
+	if upper <= b.Initial {
+		return capDelay(b.Initial, b.Max)
+	}
+	return capDelay(b.Initial+time.Duration(rand.Int63n(int64(upper-b.Initial))), b.Max)
+}
+
+// ConstantBackoff always waits Interval.
+type ConstantBackoff struct {
+	Interval time.Duration
+}
+
+func (b ConstantBackoff) Delay(int, time.Duration) time.Duration {
+	return b.Interval
+}
+
+// LinearBackoff waits Initial + attempt*Step, capped at Max.
+type LinearBackoff struct {
+	Initial time.Duration
+	Step    time.Duration
+	Max     time.Duration
+}
+
+func (b LinearBackoff) Delay(attempt int, _ time.Duration) time.Duration {
+	return capDelay(b.Initial+time.Duration(attempt)*b.Step, b.Max)
+}
+
+// capDelay limits d to max; a non-positive max means no limit.
+func capDelay(d, max time.Duration) time.Duration {
+	if max > 0 && (d > max || d < 0) {
+		return max
+	}
+	return d
+}
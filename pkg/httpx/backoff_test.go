@@ -0,0 +1,81 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBackoffStrategies(t *testing.T) {
+	const (
+		initial = 100 * time.Millisecond
+		max     = time.Second
+	)
+
+	for attempt := 0; attempt < 8; attempt++ {
+		if d := (ExponentialBackoff{Initial: initial, Max: max}).Delay(attempt, 0); d < initial || d > max {
+			t.Errorf("exponential attempt %d: %v out of range", attempt, d)
+		}
+
+		ceiling := capDelay(initial<<attempt, max)
+		if d := (FullJitterBackoff{Initial: initial, Max: max}).Delay(attempt, 0); d < 0 || d > ceiling {
+			t.Errorf("full jitter attempt %d: %v not in [0, %v]", attempt, d, ceiling)
+		}
+	}
+
+	prev := time.Duration(0)
+	for i := 0; i < 20; i++ {
+		d := (DecorrelatedJitterBackoff{Initial: initial, Max: max}).Delay(i, prev)
+		upper := max
+		if p := prev * 3; prev > 0 && p < upper {
+			upper = p
+		}
+		if d < initial || d > upper {
+			t.Fatalf("decorrelated step %d: %v not in [%v, %v]", i, d, initial, upper)
+		}
+		prev = d
+	}
+
+	if d := (ConstantBackoff{Interval: 42 * time.Millisecond}).Delay(5, time.Second); d != 42*time.Millisecond {
+		t.Errorf("constant = %v", d)
+	}
+
+	linear := LinearBackoff{Initial: initial, Step: 200 * time.Millisecond, Max: max}
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 500 * time.Millisecond, 700 * time.Millisecond, 900 * time.Millisecond, max} {
+		if d := linear.Delay(attempt, 0); d != want {
+			t.Errorf("linear attempt %d = %v, want %v", attempt, d, want)
+		}
+	}
+}
+
+type recordingBackoff struct {
+	calls [][2]time.Duration
+}
+
+func (b *recordingBackoff) Delay(attempt int, prev time.Duration) time.Duration {
+	b.calls = append(b.calls, [2]time.Duration{time.Duration(attempt), prev})
+	return time.Duration(attempt+1) * time.Millisecond
+}
+
+func TestClientUsesConfiguredBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	b := &recordingBackoff{}
+	client := New(Config{Timeout: 5 * time.Second, MaxRetries: 3, Backoff: b})
+	client.DoGET(context.Background(), server.URL, nil, nil)
+
+	want := [][2]time.Duration{{0, 0}, {1, time.Millisecond}, {2, 2 * time.Millisecond}}
+	if len(b.calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", b.calls, want)
+	}
+	for i := range want {
+		if b.calls[i] != want[i] {
+			t.Fatalf("calls = %v, want %v", b.calls, want)
+		}
+	}
+}
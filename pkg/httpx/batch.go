@@ -0,0 +1,73 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Result is the outcome of one request of a DoBatch call.
+type Result struct {
+	Response Response
+	Err      error
+}
+
+// DoBatch sends reqs with at most concurrency requests in flight and returns
+// one Result per request, in the same order. Requests not yet started when
+// ctx is cancelled fail with the context error. A concurrency below 1 means 1.
+func (c *realClient) DoBatch(ctx context.Context, reqs []Request, concurrency int) []Result {
+	results := make([]Result, len(reqs))
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(reqs) {
+		concurrency = len(reqs)
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				resp, err := c.Do(ctx, reqs[i])
+				results[i] = Result{Response: resp, Err: err}
+			}
+		}()
+	}
+
+	i := 0
+feed:
+	for ; i < len(reqs); i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	for ; i < len(reqs); i++ {
+		results[i] = Result{Err: ctx.Err()}
+	}
+	wg.Wait()
+	return results
+}
+
+// BatchErr joins the errors of a DoBatch call, each prefixed with the index
+// and URL of its request. It returns nil when every request succeeded.
+func BatchErr(reqs []Request, results []Result) error {
+	var errs []error
+	for i, r := range results {
+		if r.Err == nil {
+			continue
+		}
+		url := ""
+		if i < len(reqs) {
+			url = reqs[i].URL
+		}
+		errs = append(errs, fmt.Errorf("request %d (%s): %w", i, url, r.Err))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,92 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoBatchPreservesOrderAndBoundsConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if r.URL.Query().Get("i") == "3" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		w.Write([]byte(r.URL.Query().Get("i")))
+	}))
+	defer server.Close()
+
+	reqs := make([]Request, 10)
+	for i := range reqs {
+		reqs[i] = Request{URL: server.URL, Params: map[string]string{"i": fmt.Sprint(i)}}
+	}
+	reqs[5].URL = "://bad"
+
+	client := New(Config{})
+	results := client.DoBatch(context.Background(), reqs, 3)
+
+	if len(results) != len(reqs) {
+		t.Fatalf("got %d results, want %d", len(results), len(reqs))
+	}
+	for i, r := range results {
+		if i == 5 {
+			if !errors.Is(r.Err, ErrInvalidURL) {
+				t.Fatalf("result 5 err = %v, want ErrInvalidURL", r.Err)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Fatalf("result %d err = %v", i, r.Err)
+		}
+		if string(r.Response.Body) != fmt.Sprint(i) {
+			t.Fatalf("result %d body = %q", i, r.Response.Body)
+		}
+	}
+	if results[3].Response.Status != http.StatusBadRequest {
+		t.Fatalf("result 3 status = %d", results[3].Response.Status)
+	}
+	if m := atomic.LoadInt32(&maxInFlight); m > 3 {
+		t.Fatalf("max in flight = %d, want <= 3", m)
+	}
+
+	err := BatchErr(reqs, results)
+	if !errors.Is(err, ErrInvalidURL) || !strings.Contains(err.Error(), "request 5") {
+		t.Fatalf("BatchErr() = %v", err)
+	}
+}
+
+func TestDoBatchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+	}))
+	defer server.Close()
+
+	reqs := make([]Request, 20)
+	for i := range reqs {
+		reqs[i] = Request{URL: server.URL}
+	}
+
+	results := New(Config{}).DoBatch(ctx, reqs, 1)
+	if !errors.Is(results[len(results)-1].Err, context.Canceled) {
+		t.Fatalf("last result err = %v, want context.Canceled", results[len(results)-1].Err)
+	}
+	if err := BatchErr(reqs[:0], nil); err != nil {
+		t.Fatalf("BatchErr() = %v, want nil", err)
+	}
+}
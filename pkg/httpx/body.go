@@ -0,0 +1,232 @@
+package httpx
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var ErrBodyNotReplayable = errors.New("httpx: request body cannot be replayed for retry")
+
+// errBodySuperseded stops the writer of a multipart body whose attempt was
+// abandoned for a retry.
+var errBodySuperseded = errors.New("httpx: request body superseded by retry")
+
+type Multipart struct {
+	Fields map[string]string
+	Files  []MultipartFile
+}
+
+// MultipartFile is a file part of a multipart body. Readers that implement
+// io.Seeker are rewound between retries; other readers only allow a single
+// attempt.
+type MultipartFile struct {
+	FieldName   string
+	FileName    string
+	ContentType string
+	Reader      io.Reader
+}
+
+// requestBody produces a fresh body reader for every attempt so retries never
+// send a drained reader.
+type requestBody struct {
+	contentType string
+	size        int64
+	reader      func(attempt int) (io.Reader, error)
+	buf         []byte // the whole body when it is held in memory
+
+	// getBody, when set, lets the transport resend the body within a single
+	// attempt (see http.Request.GetBody).
+	getBody func() (io.ReadCloser, error)
+}
+
+func newRequestBody(r Request, replay bool) (*requestBody, error) {
+	switch {
+	case r.Multipart != nil:
+		return newMultipartBody(r.Multipart)
+	case r.Form != nil:
+		return newBytesBody([]byte(r.Form.Encode()), "application/x-www-form-urlencoded"), nil
+	case r.Body == nil:
+		return &requestBody{reader: func(int) (io.Reader, error) { return nil, nil }}, nil
+	}
+
+	if s, ok := r.Body.(io.ReadSeeker); ok {
+		start, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, fmt.Errorf("httpx: seek body: %w", err)
+		}
+		end, err := s.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, fmt.Errorf("httpx: seek body: %w", err)
+		}
+		if _, err := s.Seek(start, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("httpx: seek body: %w", err)
+		}
+		// The transport closes request bodies; wrapping keeps a caller's
+		// file open so it can be rewound for the next attempt.
+		return &requestBody{
+			size: end - start,
+			reader: func(attempt int) (io.Reader, error) {
+				if attempt > 0 {
+					if _, err := s.Seek(start, io.SeekStart); err != nil {
+						return nil, err
+					}
+				}
+				return io.NopCloser(s), nil
+			},
+			getBody: func() (io.ReadCloser, error) {
+				if _, err := s.Seek(start, io.SeekStart); err != nil {
+					return nil, err
+				}
+				return io.NopCloser(s), nil
+			},
+		}, nil
+	}
+
+	if !replay {
+		return &requestBody{size: -1, reader: func(attempt int) (io.Reader, error) {
+			if attempt > 0 {
+				return nil, ErrBodyNotReplayable
+			}
+			return r.Body, nil
+		}}, nil
+	}
+
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("httpx: read body: %w", err)
+	}
+	return newBytesBody(buf, ""), nil
+}
+
+func newBytesBody(buf []byte, contentType string) *requestBody {
+	return &requestBody{
+		contentType: contentType,
+		size:        int64(len(buf)),
+		buf:         buf,
+		reader: func(int) (io.Reader, error) {
+			return bytes.NewReader(buf), nil
+		},
+		getBody: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(buf)), nil
+		},
+	}
+}
+
+func newMultipartBody(m *Multipart) (*requestBody, error) {
+	boundary, err := randomBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	starts := make([]int64, len(m.Files))
+	for i, f := range m.Files {
+		if s, ok := f.Reader.(io.Seeker); ok {
+			if starts[i], err = s.Seek(0, io.SeekCurrent); err != nil {
+				return nil, fmt.Errorf("httpx: seek multipart file %q: %w", f.FileName, err)
+			}
+		}
+	}
+
+	// The previous attempt's writer may still be reading the files when the
+	// transport gives up on it; it must be stopped before they are rewound.
+	var (
+		mu         sync.Mutex
+		prevReader *io.PipeReader
+		prevDone   chan struct{}
+	)
+	body := &requestBody{contentType: "multipart/form-data; boundary=" + boundary, size: -1}
+	body.reader = func(attempt int) (io.Reader, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if prevReader != nil {
+			prevReader.CloseWithError(errBodySuperseded)
+		}
+		if attempt > 0 {
+			for _, f := range m.Files {
+				if _, ok := f.Reader.(io.Seeker); !ok {
+					return nil, ErrBodyNotReplayable
+				}
+			}
+		}
+		if prevDone != nil {
+			<-prevDone
+		}
+		if attempt > 0 {
+			for i, f := range m.Files {
+				if _, err := f.Reader.(io.Seeker).Seek(starts[i], io.SeekStart); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		pr, pw := io.Pipe()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			pw.CloseWithError(writeMultipart(pw, boundary, m))
+		}()
+		prevReader, prevDone = pr, done
+		return pr, nil
+	}
+	return body, nil
+}
+
+func writeMultipart(w io.Writer, boundary string, m *Multipart) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(m.Fields))
+	for k := range m.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := mw.WriteField(k, m.Fields[k]); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range m.Files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(f.FieldName), escapeQuotes(f.FileName)))
+		contentType := f.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h.Set("Content-Type", contentType)
+
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, f.Reader); err != nil {
+			return fmt.Errorf("copy multipart file %q: %w", f.FileName, err)
+		}
+	}
+	return mw.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+func randomBoundary() (string, error) {
+	var buf [30]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", fmt.Errorf("httpx: multipart boundary: %w", err)
+	}
+	return fmt.Sprintf("%x", buf[:]), nil
+}
@@ -0,0 +1,209 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoMultipartWithRetry(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart: %v", err)
+			return
+		}
+		if got := r.FormValue("kind"); got != "export" {
+			t.Errorf("expected field kind=export, got %q", got)
+		}
+		f, hdr, err := r.FormFile("artifact")
+		if err != nil {
+			t.Errorf("form file: %v", err)
+			return
+		}
+		data, _ := io.ReadAll(f)
+		if string(data) != "artifact-bytes" {
+			t.Errorf("attempt %d: expected file contents, got %q", attempts, string(data))
+		}
+		if hdr.Filename != "reviews.ndjson" {
+			t.Errorf("expected filename reviews.ndjson, got %q", hdr.Filename)
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := New(Config{
+		Timeout:        5 * time.Second,
+		MaxRetries:     2,
+		BackoffInitial: time.Millisecond,
+		BackoffMax:     5 * time.Millisecond,
+	})
+
+	resp, err := client.Do(context.Background(), Request{
+		Method: http.MethodPost,
+		URL:    server.URL,
+		Multipart: &Multipart{
+			Fields: map[string]string{"kind": "export"},
+			Files: []MultipartFile{{
+				FieldName: "artifact",
+				FileName:  "reviews.ndjson",
+				Reader:    strings.NewReader("artifact-bytes"),
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.Status != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", resp.Status)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestDoMultipartRetryWaitsForWriter(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 1<<18) // 4 MiB
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt is refused before its body is read, leaving the
+		// writer mid-file.
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		f, _, err := r.FormFile("artifact")
+		if err != nil {
+			t.Errorf("form file: %v", err)
+			return
+		}
+		defer f.Close()
+		if data, _ := io.ReadAll(f); !bytes.Equal(data, payload) {
+			t.Errorf("file corrupted on retry: %d bytes", len(data))
+		}
+	}))
+	defer server.Close()
+
+	client := New(Config{Timeout: 5 * time.Second, MaxRetries: 2, BackoffInitial: time.Millisecond, BackoffMax: 5 * time.Millisecond})
+	resp, err := client.Do(context.Background(), Request{
+		Method: http.MethodPost,
+		URL:    server.URL,
+		Multipart: &Multipart{Files: []MultipartFile{{
+			FieldName: "artifact",
+			FileName:  "reviews.ndjson",
+			Reader:    bytes.NewReader(payload),
+		}}},
+	})
+	if err != nil || resp.Status != http.StatusOK {
+		t.Fatalf("Do() = %d, %v", resp.Status, err)
+	}
+	if n := attempts.Load(); n != 2 {
+		t.Errorf("attempts = %d, want 2", n)
+	}
+}
+
+func TestDoMultipartNotReplayable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := New(Config{
+		Timeout:        5 * time.Second,
+		MaxRetries:     1,
+		BackoffInitial: time.Millisecond,
+		BackoffMax:     5 * time.Millisecond,
+	})
+
+	_, err := client.Do(context.Background(), Request{
+		Method: http.MethodPost,
+		URL:    server.URL,
+		Multipart: &Multipart{
+			Files: []MultipartFile{{FieldName: "f", FileName: "f.bin", Reader: io.LimitReader(strings.NewReader("data"), 4)}},
+		},
+	})
+	if !errors.Is(err, ErrBodyNotReplayable) {
+		t.Errorf("expected ErrBodyNotReplayable, got %v", err)
+	}
+}
+
+func TestDoReplaysBodyOnRetry(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("attempt %d: expected body 'payload', got %q", attempts, string(body))
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := New(Config{
+		Timeout:        5 * time.Second,
+		MaxRetries:     1,
+		BackoffInitial: time.Millisecond,
+		BackoffMax:     5 * time.Millisecond,
+	})
+
+	for _, body := range []io.Reader{strings.NewReader("payload"), io.LimitReader(strings.NewReader("payload"), 7)} {
+		attempts = 0
+		if _, err := client.Do(context.Background(), Request{Method: http.MethodPost, URL: server.URL, Body: body}); err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		if attempts != 2 {
+			t.Errorf("expected 2 attempts, got %d", attempts)
+		}
+	}
+}
+
+func TestDoFormWithRetry(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			t.Errorf("attempt %d: Content-Type = %q", attempts, ct)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("attempt %d: ParseForm() error = %v", attempts, err)
+		}
+		if r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("scope") != "a b&c" {
+			t.Errorf("attempt %d: form = %v", attempts, r.PostForm)
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := New(Config{MaxRetries: 1, BackoffInitial: time.Millisecond, BackoffMax: time.Millisecond})
+	_, err := client.Do(context.Background(), Request{
+		Method: http.MethodPost,
+		URL:    server.URL,
+		Form:   url.Values{"grant_type": {"refresh_token"}, "scope": {"a b&c"}},
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
@@ -0,0 +1,103 @@
+package httpx
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrRetryBudgetExhausted = errors.New("httpx: retry budget exhausted")
+
+// RetryBudget caps retries client-wide: over the sliding Window, retries may
+// make up at most Ratio of the requests, plus MinRetries so that low-traffic
+// clients can still retry. When an upstream melts down the client stops
+// retrying instead of multiplying its load.
+type RetryBudget struct {
+	Ratio      float64       // default 0.2
+	Window     time.Duration // default 10s
+	MinRetries int           // retries always allowed per window, default 10
+}
+
+const budgetBuckets = 10
+
+type budgetBucket struct {
+	start    time.Time
+	requests int
+	retries  int
+}
+
+type retryBudget struct {
+	ratio      float64
+	minRetries int
+	width      time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	buckets [budgetBuckets]budgetBucket
+}
+
+func newRetryBudget(cfg *RetryBudget) *retryBudget {
+	if cfg == nil {
+		return nil
+	}
+	b := &retryBudget{ratio: cfg.Ratio, minRetries: cfg.MinRetries, width: cfg.Window / budgetBuckets, now: time.Now}
+	if b.ratio <= 0 {
+		b.ratio = 0.2
+	}
+	if b.width <= 0 {
+		b.width = 10 * time.Second / budgetBuckets
+	}
+	if b.minRetries <= 0 {
+		b.minRetries = 10
+	}
+	return b
+}
+
+// current returns the bucket for now, resetting it if it belongs to an
+// earlier window. Callers hold mu.
+func (b *retryBudget) current() *budgetBucket {
+	now := b.now()
+	slot := now.Truncate(b.width)
+	bucket := &b.buckets[(slot.UnixNano()/int64(b.width))%budgetBuckets]
+	if !bucket.start.Equal(slot) {
+		*bucket = budgetBucket{start: slot}
+	}
+	return bucket
+}
+
+func (b *retryBudget) totals() (requests, retries int) {
+	cutoff := b.now().Add(-b.width * budgetBuckets)
+	for _, bucket := range b.buckets {
+		if bucket.start.After(cutoff) {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+	return requests, retries
+}
+
+func (b *retryBudget) recordRequest() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.current().requests++
+	b.mu.Unlock()
+}
+
+// allowRetry reports whether a retry fits the budget and, if so, spends it.
+func (b *retryBudget) allowRetry() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bucket := b.current()
+	requests, retries := b.totals()
+	if float64(retries+1) > float64(b.minRetries)+b.ratio*float64(requests) {
+		return false
+	}
+	bucket.retries++
+	return true
+}
@@ -0,0 +1,67 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudgetRatio(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newRetryBudget(&RetryBudget{Ratio: 0.5, Window: 10 * time.Second, MinRetries: 1})
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		b.recordRequest()
+	}
+	// 1 + 0.5*4 = 3 retries allowed.
+	for i := 0; i < 3; i++ {
+		if !b.allowRetry() {
+			t.Fatalf("retry %d denied", i)
+		}
+	}
+	if b.allowRetry() {
+		t.Fatal("expected budget to be exhausted")
+	}
+
+	now = now.Add(11 * time.Second)
+	if !b.allowRetry() {
+		t.Fatal("expected budget to recover after the window")
+	}
+}
+
+func TestRetryBudgetNilAllows(t *testing.T) {
+	var b *retryBudget
+	b.recordRequest()
+	if !b.allowRetry() {
+		t.Fatal("nil budget must not limit retries")
+	}
+}
+
+func TestClientShedsRetriesWhenBudgetExhausted(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := New(Config{
+		Timeout:     5 * time.Second,
+		MaxRetries:  5,
+		Backoff:     ConstantBackoff{},
+		RetryBudget: &RetryBudget{Ratio: 0.1, MinRetries: 2},
+	})
+
+	_, err := client.DoGET(context.Background(), server.URL, nil, nil)
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("err = %v, want ErrRetryBudgetExhausted", err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 3 {
+		t.Fatalf("hits = %d, want 3 (1 request + 2 budgeted retries)", got)
+	}
+}
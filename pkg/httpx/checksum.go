@@ -0,0 +1,172 @@
+package httpx
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrChecksumMismatch    = errors.New("httpx: checksum mismatch")
+	ErrUnsupportedChecksum = errors.New("httpx: unsupported checksum algorithm")
+	ErrMissingDigest       = errors.New("httpx: response has no verifiable digest")
+)
+
+// Checksum is an expected digest of a payload. Algorithm is one of sha256
+// (default), sha512, sha1 or md5; Value is hex encoded.
+type Checksum struct {
+	Algorithm string
+	Value     string
+}
+
+type ChecksumError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("httpx: %s checksum mismatch: expected %s, got %s", e.Algorithm, e.Expected, e.Actual)
+}
+
+func (e *ChecksumError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+func (c Checksum) algorithm() string {
+	if c.Algorithm == "" {
+		return "sha256"
+	}
+	return strings.ToLower(c.Algorithm)
+}
+
+func (c Checksum) newHash() (hash.Hash, error) {
+	switch c.algorithm() {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedChecksum, c.Algorithm)
+	}
+}
+
+func (c Checksum) verify(h hash.Hash) error {
+	actual := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(actual, c.Value) {
+		return &ChecksumError{Algorithm: c.algorithm(), Expected: strings.ToLower(c.Value), Actual: actual}
+	}
+	return nil
+}
+
+func (c Checksum) verifyBytes(b []byte) error {
+	h, err := c.newHash()
+	if err != nil {
+		return err
+	}
+	h.Write(b)
+	return c.verify(h)
+}
+
+// DigestPolicy controls whether digests announced by the server are checked.
+type DigestPolicy int
+
+const (
+	// DigestIgnore does not look at digest headers.
+	DigestIgnore DigestPolicy = iota
+	// DigestVerifyIfPresent checks digest headers when the server sends one.
+	DigestVerifyIfPresent
+	// DigestRequire fails with ErrMissingDigest unless a digest header could
+	// be checked.
+	DigestRequire
+)
+
+// digestAlgorithms maps digest header algorithm names to Checksum algorithms.
+var digestAlgorithms = map[string]string{
+	"sha-512": "sha512",
+	"sha-256": "sha256",
+	"sha":     "sha1",
+	"md5":     "md5",
+}
+
+// responseDigests extracts the digests announced in Content-Digest,
+// Repr-Digest (RFC 9530), Digest (RFC 3230) and Content-MD5.
+func responseDigests(h http.Header) []Checksum {
+	var sums []Checksum
+	add := func(alg, b64 string) {
+		algorithm, ok := digestAlgorithms[strings.ToLower(strings.TrimSpace(alg))]
+		if !ok {
+			return
+		}
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(b64))
+		if err != nil {
+			return
+		}
+		sums = append(sums, Checksum{Algorithm: algorithm, Value: hex.EncodeToString(raw)})
+	}
+
+	for _, name := range []string{"Content-Digest", "Repr-Digest"} {
+		for _, v := range h.Values(name) {
+			for _, member := range strings.Split(v, ",") {
+				alg, value, ok := strings.Cut(member, "=")
+				if !ok {
+					continue
+				}
+				value = strings.TrimSpace(value)
+				add(alg, strings.TrimSuffix(strings.TrimPrefix(value, ":"), ":"))
+			}
+		}
+	}
+	for _, v := range h.Values("Digest") {
+		for _, member := range strings.Split(v, ",") {
+			if alg, value, ok := strings.Cut(member, "="); ok {
+				add(alg, value)
+			}
+		}
+	}
+	if v := h.Get("Content-MD5"); v != "" {
+		add("md5", v)
+	}
+	return sums
+}
+
+// verifyResponse checks body against the expected checksum and, depending on
+// policy, against the digests the server announced. Digests refer to the
+// bytes on the wire, so they are only checked for bodies that were not
+// content-decoded.
+func verifyResponse(res Response, expected *Checksum, policy DigestPolicy) error {
+	if expected != nil {
+		if err := expected.verifyBytes(res.Body); err != nil {
+			return err
+		}
+	}
+	if policy == DigestIgnore {
+		return nil
+	}
+
+	checked := false
+	if res.ContentEncoding == "" || strings.EqualFold(res.ContentEncoding, "identity") {
+		for _, sum := range responseDigests(res.Headers) {
+			if err := sum.verifyBytes(res.Body); err != nil {
+				return err
+			}
+			checked = true
+		}
+	}
+	if !checked && policy == DigestRequire {
+		return ErrMissingDigest
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package httpx
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponseDigests(t *testing.T) {
+	body := []byte("token page")
+	sha := sha256.Sum256(body)
+	md := md5.Sum(body)
+	b64 := base64.StdEncoding.EncodeToString(sha[:])
+
+	h := http.Header{}
+	h.Set("Content-Digest", "sha-256=:"+b64+":, unknown=:abc:")
+	h.Set("Digest", "MD5="+base64.StdEncoding.EncodeToString(md[:]))
+
+	sums := responseDigests(h)
+	if len(sums) != 2 {
+		t.Fatalf("sums = %+v, want 2", sums)
+	}
+	if sums[0].Algorithm != "sha256" || sums[0].Value != hex.EncodeToString(sha[:]) {
+		t.Fatalf("content-digest parsed as %+v", sums[0])
+	}
+	if sums[1].Algorithm != "md5" {
+		t.Fatalf("digest parsed as %+v", sums[1])
+	}
+}
+
+func TestDoVerifiesExpectedChecksum(t *testing.T) {
+	body := []byte("export data")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256(body)
+	client := New(Config{Timeout: 5 * time.Second})
+
+	resp, err := client.Do(context.Background(), Request{URL: server.URL, Checksum: &Checksum{Value: hex.EncodeToString(sum[:])}})
+	if err != nil || string(resp.Body) != string(body) {
+		t.Fatalf("Do() = %q, %v", resp.Body, err)
+	}
+
+	_, err = client.Do(context.Background(), Request{URL: server.URL, Checksum: &Checksum{Value: "deadbeef"}})
+	var cerr *ChecksumError
+	if !errors.Is(err, ErrChecksumMismatch) || !errors.As(err, &cerr) {
+		t.Fatalf("err = %v, want *ChecksumError", err)
+	}
+	if cerr.Actual != hex.EncodeToString(sum[:]) || cerr.Expected != "deadbeef" {
+		t.Fatalf("unexpected details: %+v", cerr)
+	}
+}
+
+func TestDoDigestPolicy(t *testing.T) {
+	body := []byte("artifact")
+	sum := sha256.Sum256(body)
+	good := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+
+	var digest string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "identity" {
+			t.Errorf("Accept-Encoding = %q, want identity", r.Header.Get("Accept-Encoding"))
+		}
+		if digest != "" {
+			w.Header().Set("Content-Digest", digest)
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := New(Config{Timeout: 5 * time.Second})
+	do := func(policy DigestPolicy) error {
+		_, err := client.Do(context.Background(), Request{URL: server.URL, DigestPolicy: policy})
+		return err
+	}
+
+	digest = good
+	if err := do(DigestRequire); err != nil {
+		t.Fatalf("valid digest: %v", err)
+	}
+
+	digest = "sha-256=:" + base64.StdEncoding.EncodeToString(make([]byte, 32)) + ":"
+	if err := do(DigestVerifyIfPresent); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("bad digest: err = %v", err)
+	}
+
+	digest = ""
+	if err := do(DigestVerifyIfPresent); err != nil {
+		t.Fatalf("missing digest with VerifyIfPresent: %v", err)
+	}
+	if err := do(DigestRequire); !errors.Is(err, ErrMissingDigest) {
+		t.Fatalf("missing digest with Require: err = %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package httpx
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the time for retry budgets, throttling, DNS caching and
+// deadlines. Config.Clock defaults to the system clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// Sleeper waits between retries, reconnects and throttled requests. Sleep
+// returns ctx.Err() if ctx ends first. Config.Sleeper defaults to real
+// timers; tests can record or skip the waits instead.
+type Sleeper interface {
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *realClient) clock() Clock {
+	if c.cfg.Clock != nil {
+		return c.cfg.Clock
+	}
+	return systemClock{}
+}
+
+// sleep waits with the configured Sleeper. Close ends the wait early with
+// ErrClientClosed.
+func (c *realClient) sleep(ctx context.Context, d time.Duration) error {
+	var sleeper Sleeper = systemClock{}
+	if c.cfg.Sleeper != nil {
+		sleeper = c.cfg.Sleeper
+	}
+	if c.lifecycle == nil || c.closing == nil {
+		return sleeper.Sleep(ctx, d)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := context.AfterFunc(c.closing, func() { cancel(ErrClientClosed) })
+	defer stop()
+
+	err := sleeper.Sleep(ctx, d)
+	if err != nil && context.Cause(ctx) == ErrClientClosed {
+		return ErrClientClosed
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock advances instantly on Sleep and records the requested waits.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+func (c *fakeClock) Sleeps() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.sleeps...)
+}
+
+func TestDoRetriesUseSleeper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	clock := newFakeClock()
+	client := New(Config{
+		MaxRetries: 3,
+		Backoff:    ConstantBackoff{Interval: time.Hour},
+		Clock:      clock,
+		Sleeper:    clock,
+	})
+
+	start := time.Now()
+	client.Do(context.Background(), Request{URL: server.URL})
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("retries slept on the real clock")
+	}
+	if sleeps := clock.Sleeps(); len(sleeps) != 3 || sleeps[0] != time.Hour {
+		t.Errorf("sleeps = %v, want 3 x 1h", sleeps)
+	}
+}
+
+func TestDoStopsBackoffWhenContextEnds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := New(Config{MaxRetries: 3, Backoff: ConstantBackoff{Interval: time.Hour}})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Do(ctx, Request{URL: server.URL})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
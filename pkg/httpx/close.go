@@ -0,0 +1,80 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrClientClosed = errors.New("httpx: client closed")
+
+// acquire registers an in-flight request, failing once Close was called.
+func (c *realClient) acquire() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClientClosed
+	}
+	c.inflight.Add(1)
+	return nil
+}
+
+func (c *realClient) release() {
+	c.inflight.Done()
+}
+
+// trackStream registers an open SSE stream so Close can end it.
+func (c *realClient) trackStream(s *SSEStream) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	if c.streams == nil {
+		c.streams = make(map[*SSEStream]struct{})
+	}
+	c.streams[s] = struct{}{}
+	return true
+}
+
+func (c *realClient) untrackStream(s *SSEStream) {
+	c.mu.Lock()
+	delete(c.streams, s)
+	c.mu.Unlock()
+}
+
+// Close stops accepting new requests, ends open SSE streams, cuts short
+// requests waiting for a retry or a rate limit, waits for in-flight requests
+// until ctx is done and then closes idle connections and drops the DNS
+// cache. It returns ctx's error if requests were still running at the
+// deadline. Clients built per job should be closed when the job ends.
+func (c *realClient) Close(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	for s := range c.streams {
+		s.cancel()
+	}
+	c.mu.Unlock()
+	c.stopWaiting()
+
+	drained := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = fmt.Errorf("httpx: close: in-flight requests not drained: %w", ctx.Err())
+	}
+
+	c.http.CloseIdleConnections()
+	c.closeHosts()
+	if h3, ok := c.http.Transport.(*http3Transport); ok {
+		h3.close()
+	}
+	c.dns.close()
+	return err
+}
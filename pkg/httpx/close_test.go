@@ -0,0 +1,102 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCloseDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	}))
+	defer server.Close()
+
+	client := New(Config{Timeout: 5 * time.Second})
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.DoGET(context.Background(), server.URL, nil, nil)
+		result <- err
+	}()
+	<-started
+
+	closed := make(chan error, 1)
+	go func() { closed <- client.Close(context.Background()) }()
+
+	// New requests are refused while draining.
+	time.Sleep(10 * time.Millisecond)
+	if _, err := client.DoGET(context.Background(), server.URL, nil, nil); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("err = %v, want ErrClientClosed", err)
+	}
+	select {
+	case <-closed:
+		t.Fatal("Close returned before in-flight request finished")
+	default:
+	}
+
+	close(release)
+	if err := <-result; err != nil {
+		t.Fatalf("in-flight request failed: %v", err)
+	}
+	if err := <-closed; err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestCloseDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := New(Config{Timeout: 5 * time.Second})
+	go client.DoGET(context.Background(), server.URL, nil, nil)
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close() error = %v, want deadline exceeded", err)
+	}
+	if _, err := client.DownloadToFile(context.Background(), server.URL, t.TempDir()+"/f", DownloadOptions{}); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("DownloadToFile() error = %v, want ErrClientClosed", err)
+	}
+}
+
+func TestCloseInterruptsRetryBackoff(t *testing.T) {
+	hit := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case hit <- struct{}{}:
+		default:
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := New(Config{Timeout: 5 * time.Second, MaxRetries: 3, BackoffInitial: time.Hour, BackoffMax: time.Hour})
+	result := make(chan error, 1)
+	go func() {
+		_, err := client.DoGET(context.Background(), server.URL, nil, nil)
+		result <- err
+	}()
+	<-hit
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := <-result; !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("err = %v, want ErrClientClosed", err)
+	}
+}
@@ -0,0 +1,83 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+)
+
+// Validators are the cache validators of a response. Sent back with a
+// conditional request they let the server answer 304 Not Modified instead of
+// the whole page when it has not changed.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Validators returns the ETag and Last-Modified headers of r.
+func (r Response) Validators() Validators {
+	if r.Headers == nil {
+		return Validators{}
+	}
+	return Validators{
+		ETag:         r.Headers.Get("ETag"),
+		LastModified: r.Headers.Get("Last-Modified"),
+	}
+}
+
+// IsZero reports whether v holds no validator, so a request cannot be made
+// conditional.
+func (v Validators) IsZero() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// Headers returns the If-None-Match and If-Modified-Since headers that make a
+// request conditional on v.
+func (v Validators) Headers() map[string]string {
+	h := make(map[string]string, 2)
+	if v.ETag != "" {
+		h["If-None-Match"] = v.ETag
+	}
+	if v.LastModified != "" {
+		h["If-Modified-Since"] = v.LastModified
+	}
+	return h
+}
+
+// merge returns v with the validators set in newer replacing its own. A 304
+// may carry an updated ETag but need not repeat the others.
+func (v Validators) merge(newer Validators) Validators {
+	if newer.ETag != "" {
+		v.ETag = newer.ETag
+	}
+	if newer.LastModified != "" {
+		v.LastModified = newer.LastModified
+	}
+	return v
+}
+
+// ConditionalResponse is the result of DoConditional.
+type ConditionalResponse struct {
+	Response
+	// NotModified is set when the server answered 304: the page is unchanged
+	// since prev was fetched and Body is empty.
+	NotModified bool
+	// Validators are the ones to send with the next request for the page:
+	// those of the new response, or prev updated by the 304.
+	Validators Validators
+}
+
+// DoConditional sends req with If-None-Match and If-Modified-Since taken from
+// prev, typically the Validators of the last response for the same URL, so
+// unchanged pages come back as NotModified without a body. Conditional
+// headers already in req.Headers win over prev. A zero prev sends req as is.
+func (c *realClient) DoConditional(ctx context.Context, req Request, prev Validators) (ConditionalResponse, error) {
+	req.Headers = mergeHeaders(prev.Headers(), req.Headers)
+	res, err := c.Do(ctx, req)
+	if err != nil {
+		return ConditionalResponse{Response: res}, err
+	}
+	if res.Status == http.StatusNotModified {
+		return ConditionalResponse{Response: res, NotModified: true, Validators: prev.merge(res.Validators())}, nil
+	}
+	return ConditionalResponse{Response: res, Validators: res.Validators()}, nil
+}
@@ -0,0 +1,72 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDoConditional(t *testing.T) {
+	const lastModified = "Mon, 02 Jun 2025 10:00:00 GMT"
+	etag := `"v1"`
+	var gotINM, gotIMS string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotINM, gotIMS = r.Header.Get("If-None-Match"), r.Header.Get("If-Modified-Since")
+		if gotINM == etag {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte("page " + etag))
+	}))
+	defer server.Close()
+
+	client := New(Config{})
+	ctx := context.Background()
+	req := Request{URL: server.URL, Transforms: []Transformer{TransformerFunc(func(b []byte, _ http.Header) ([]byte, error) {
+		if len(b) == 0 {
+			t.Error("transform ran on an empty body")
+		}
+		return b, nil
+	})}}
+
+	first, err := client.DoConditional(ctx, req, Validators{})
+	if err != nil || first.NotModified || string(first.Body) != `page "v1"` || gotINM != "" || gotIMS != "" {
+		t.Fatalf("first = %+v, %v (If-None-Match %q)", first, err, gotINM)
+	}
+	if first.Validators != (Validators{ETag: etag, LastModified: lastModified}) {
+		t.Fatalf("validators = %+v", first.Validators)
+	}
+
+	second, err := client.DoConditional(ctx, req, first.Validators)
+	if err != nil || !second.NotModified || len(second.Body) != 0 || second.Status != http.StatusNotModified {
+		t.Fatalf("second = %+v, %v", second, err)
+	}
+	if gotINM != etag || gotIMS != lastModified || second.Validators != first.Validators {
+		t.Errorf("sent %q / %q, validators %+v", gotINM, gotIMS, second.Validators)
+	}
+
+	etag = `"v2"`
+	third, err := client.DoConditional(ctx, req, second.Validators)
+	if err != nil || third.NotModified || third.Validators.ETag != `"v2"` {
+		t.Fatalf("changed page = %+v, %v", third, err)
+	}
+
+	req.Headers = map[string]string{"if-none-match": "*"}
+	if _, err := client.DoConditional(ctx, req, third.Validators); err != nil || gotINM != "*" {
+		t.Errorf("explicit If-None-Match replaced: %q, %v", gotINM, err)
+	}
+}
+
+func TestValidators(t *testing.T) {
+	if !(Response{}).Validators().IsZero() || len(Validators{}.Headers()) != 0 {
+		t.Error("empty response has validators")
+	}
+	v := Response{Headers: http.Header{"Etag": {`W/"abc"`}}}.Validators()
+	if v.IsZero() || v.Headers()["If-None-Match"] != `W/"abc"` || v.LastModified != "" {
+		t.Errorf("validators = %+v", v)
+	}
+}
@@ -0,0 +1,40 @@
+package httpx
+
+import (
+	"net/http"
+
+	"github.com/quiby-ai/common/pkg/obs"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	HeaderRequestID = "X-Request-ID"
+	HeaderSagaID    = "X-Saga-ID"
+)
+
+// setContextHeaders adds X-Request-ID and X-Saga-ID from the obs IDs in the
+// request context, and the OTel baggage, which carries the saga ID too.
+// X-Request-ID falls back to the trace ID. Headers given by the caller win.
+func (c *realClient) setContextHeaders(req *http.Request, customHeaders map[string]string) {
+	if c.cfg.DisableContextHeaders {
+		return
+	}
+	ctx := req.Context()
+	if _, ok := headerLookup(customHeaders, HeaderRequestID); !ok {
+		id := obs.RequestIDFromContext(ctx)
+		if id == "" {
+			id = obs.TraceIDFromContext(ctx)
+		}
+		if id != "" {
+			req.Header.Set(HeaderRequestID, id)
+		}
+	}
+	if _, ok := headerLookup(customHeaders, HeaderSagaID); !ok {
+		if id := obs.SagaIDFromContext(ctx); id != "" {
+			req.Header.Set(HeaderSagaID, id)
+		}
+	}
+	if _, ok := headerLookup(customHeaders, "Baggage"); !ok {
+		propagation.Baggage{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+}
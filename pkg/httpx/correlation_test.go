@@ -0,0 +1,46 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quiby-ai/common/pkg/obs"
+)
+
+func TestDoPropagatesContextIDs(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	ctx := obs.WithSagaID(obs.WithRequestID(context.Background(), "req-1"), "saga-1")
+
+	client := New(Config{})
+	if _, err := client.DoGET(ctx, server.URL, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get(HeaderRequestID) != "req-1" || got.Get(HeaderSagaID) != "saga-1" {
+		t.Errorf("headers = %v", got)
+	}
+	if got.Get("Baggage") != "saga_id=saga-1" {
+		t.Errorf("baggage = %q", got.Get("Baggage"))
+	}
+
+	if _, err := client.DoGET(ctx, server.URL, nil, map[string]string{"x-request-id": "mine"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get(HeaderRequestID) != "mine" {
+		t.Errorf("caller header overridden: %q", got.Get(HeaderRequestID))
+	}
+
+	client = New(Config{DisableContextHeaders: true})
+	if _, err := client.DoGET(ctx, server.URL, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get(HeaderRequestID) != "" || got.Get(HeaderSagaID) != "" || got.Get("Baggage") != "" {
+		t.Errorf("headers sent while disabled: %v", got)
+	}
+}
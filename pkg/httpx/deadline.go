@@ -0,0 +1,160 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/quiby-ai/common/pkg/obs"
+)
+
+// ErrDeadlineBudget is returned when a retry was skipped because the ctx
+// deadline would leave it less than DeadlineBudget.MinAttempt.
+var ErrDeadlineBudget = errors.New("httpx: not enough time left before deadline to retry")
+
+// DeadlineBudget fits retries into the ctx deadline instead of sending an
+// attempt that has no time left to succeed.
+type DeadlineBudget struct {
+	// MinAttempt is the time an attempt needs at least after its backoff.
+	// Retries that would start with less left are skipped. Defaults to
+	// 100ms.
+	MinAttempt time.Duration
+	// Shrink shortens a backoff that would eat into MinAttempt instead of
+	// giving up, as long as some wait remains.
+	Shrink bool
+}
+
+// DeadlineAction says what DeadlineBudget did.
+type DeadlineAction string
+
+const (
+	// DeadlineShort: the deadline is shorter than Timeout per attempt plus
+	// the projected backoffs. Reported once per request.
+	DeadlineShort DeadlineAction = "short"
+	// DeadlineShrunk: a backoff was shortened to leave MinAttempt.
+	DeadlineShrunk DeadlineAction = "shrunk"
+	// DeadlineGaveUp: a retry was skipped.
+	DeadlineGaveUp DeadlineAction = "gave_up"
+)
+
+// DeadlineEvent is passed to Config.OnDeadlineBudget.
+type DeadlineEvent struct {
+	Action    DeadlineAction
+	Method    string
+	URL       string
+	Attempt   int           // attempts sent so far
+	Remaining time.Duration // until the ctx deadline
+	// Needed is the projected time for all attempts (DeadlineShort) or the
+	// planned backoff plus MinAttempt.
+	Needed time.Duration
+}
+
+// LogDeadlineBudget logs e through pkg/obs, at debug level for
+// DeadlineShort and warn level otherwise. It is the default
+// Config.OnDeadlineBudget.
+func LogDeadlineBudget(ctx context.Context, e DeadlineEvent) {
+	attrs := []any{
+		"action", string(e.Action),
+		"method", e.Method,
+		"url", obs.ScrubURL(e.URL),
+		"attempt", e.Attempt,
+		"remaining_ms", e.Remaining.Milliseconds(),
+		"needed_ms", e.Needed.Milliseconds(),
+	}
+	if e.Action == DeadlineShort {
+		obs.Debug(ctx, "httpx deadline shorter than retry schedule", attrs...)
+		return
+	}
+	obs.Warn(ctx, "httpx retry limited by deadline", attrs...)
+}
+
+func (c *realClient) reportDeadline(ctx context.Context, e DeadlineEvent) {
+	if c.cfg.OnDeadlineBudget != nil {
+		c.cfg.OnDeadlineBudget(ctx, e)
+		return
+	}
+	LogDeadlineBudget(ctx, e)
+}
+
+func (c *realClient) timeLeft(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return deadline.Sub(c.clock().Now()), true
+}
+
+// checkDeadline reports DeadlineShort when ctx cannot fit the whole retry
+// schedule.
+func (c *realClient) checkDeadline(ctx context.Context, method, u string) {
+	if c.cfg.DeadlineBudget == nil || c.cfg.MaxRetries == 0 || c.cfg.Timeout <= 0 {
+		return
+	}
+	remaining, ok := c.timeLeft(ctx)
+	if !ok {
+		return
+	}
+	needed := time.Duration(c.cfg.MaxRetries+1) * c.cfg.Timeout
+	var prev time.Duration
+	for attempt := 0; attempt < c.cfg.MaxRetries; attempt++ {
+		prev = c.backoff().Delay(attempt, prev)
+		needed += prev
+	}
+	if remaining < needed {
+		c.reportDeadline(ctx, DeadlineEvent{
+			Action:    DeadlineShort,
+			Method:    method,
+			URL:       u,
+			Remaining: remaining,
+			Needed:    needed,
+		})
+	}
+}
+
+// fitBackoff returns the delay to wait before the next retry, shortened if
+// DeadlineBudget.Shrink allows, or an error wrapping ErrDeadlineBudget and
+// lastErr when no useful attempt fits before the deadline.
+func (c *realClient) fitBackoff(ctx context.Context, req *http.Request, sent int, delay time.Duration, lastErr error) (time.Duration, error) {
+	b := c.cfg.DeadlineBudget
+	if b == nil {
+		return delay, nil
+	}
+	remaining, ok := c.timeLeft(ctx)
+	if !ok {
+		return delay, nil
+	}
+	minAttempt := b.MinAttempt
+	if minAttempt <= 0 {
+		minAttempt = 100 * time.Millisecond
+	}
+	if remaining-delay >= minAttempt {
+		return delay, nil
+	}
+
+	e := DeadlineEvent{
+		Action:    DeadlineGaveUp,
+		Method:    req.Method,
+		URL:       req.URL.String(),
+		Attempt:   sent,
+		Remaining: remaining,
+		Needed:    delay + minAttempt,
+	}
+	if b.Shrink && remaining > minAttempt {
+		e.Action = DeadlineShrunk
+		c.reportDeadline(ctx, e)
+		return remaining - minAttempt, nil
+	}
+	c.reportDeadline(ctx, e)
+	return 0, fmt.Errorf("%w (%s left, last error: %v)", ErrDeadlineBudget, remaining, lastErr)
+}
+
+// retryBackoff is sleepBackoff fitted into the ctx deadline, for Do.
+func (c *realClient) retryBackoff(ctx context.Context, req *http.Request, attempt, sent int, prev time.Duration, lastErr error) (time.Duration, error) {
+	delay, err := c.fitBackoff(ctx, req, sent, c.backoff().Delay(attempt, prev), lastErr)
+	if err != nil {
+		return prev, err
+	}
+	return delay, c.sleep(ctx, delay)
+}
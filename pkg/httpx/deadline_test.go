@@ -0,0 +1,71 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type constBackoff time.Duration
+
+func (b constBackoff) Delay(int, time.Duration) time.Duration { return time.Duration(b) }
+
+func TestDeadlineBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		shrink  bool
+		sleeps  []time.Duration
+		actions []DeadlineAction
+	}{
+		{false, []time.Duration{4 * time.Second, 4 * time.Second}, []DeadlineAction{DeadlineShort, DeadlineGaveUp}},
+		{true, []time.Duration{4 * time.Second, 4 * time.Second, time.Second}, []DeadlineAction{DeadlineShort, DeadlineShrunk, DeadlineGaveUp}},
+	} {
+		// The fake clock starts at the real time so the ctx deadline stays
+		// in the future while sleeps are skipped.
+		clock := &fakeClock{now: time.Now()}
+		var actions []DeadlineAction
+		client := New(Config{
+			Timeout:        5 * time.Second,
+			MaxRetries:     5,
+			RetryStatus:    []int{http.StatusServiceUnavailable},
+			Backoff:        constBackoff(4 * time.Second),
+			DeadlineBudget: &DeadlineBudget{MinAttempt: time.Second, Shrink: tt.shrink},
+			OnDeadlineBudget: func(ctx context.Context, e DeadlineEvent) {
+				actions = append(actions, e.Action)
+			},
+			Clock:   clock,
+			Sleeper: clock,
+		})
+
+		ctx, cancel := context.WithDeadline(context.Background(), clock.Now().Add(10*time.Second))
+		_, err := client.DoGET(ctx, server.URL, nil, nil)
+		cancel()
+
+		if !errors.Is(err, ErrDeadlineBudget) {
+			t.Fatalf("shrink=%v: err = %v, want ErrDeadlineBudget", tt.shrink, err)
+		}
+		if got := clock.Sleeps(); !reflect.DeepEqual(got, tt.sleeps) {
+			t.Errorf("shrink=%v: sleeps = %v, want %v", tt.shrink, got, tt.sleeps)
+		}
+		if !reflect.DeepEqual(actions, tt.actions) {
+			t.Errorf("shrink=%v: actions = %v, want %v", tt.shrink, actions, tt.actions)
+		}
+	}
+}
+
+func TestDeadlineBudgetWithoutDeadline(t *testing.T) {
+	clock := newFakeClock()
+	c := New(Config{DeadlineBudget: &DeadlineBudget{}, Clock: clock}).(*realClient)
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if d, err := c.fitBackoff(context.Background(), req, 1, time.Hour, nil); err != nil || d != time.Hour {
+		t.Errorf("fitBackoff() = %v, %v", d, err)
+	}
+}
@@ -0,0 +1,139 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// maxDumpBody bounds the request and response bodies kept in a DebugDump.
+const maxDumpBody = 64 << 10
+
+// debugRedactHeaders are always replaced in dumps, next to
+// Config.DebugRedactHeaders.
+var debugRedactHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+}
+
+// DebugDump is the wire form of one attempt, captured when Config.Debug is
+// set. Credentials in headers are redacted and bodies are cut at 64 KiB.
+type DebugDump struct {
+	Attempt  int
+	Request  string
+	Response string // empty when no response was received
+}
+
+func (d *DebugDump) String() string {
+	if d.Response == "" {
+		return d.Request
+	}
+	return d.Request + "\n\n" + d.Response
+}
+
+// dumpRequestBody is the body to show for b. In-memory bodies are shown, as
+// are small seekable ones, which are read and rewound before they are sent.
+// Other streamed bodies are not read a second time.
+func dumpRequestBody(b *requestBody) string {
+	switch {
+	case b.buf != nil:
+		return dumpBody(b.buf)
+	case b.size == 0:
+		return ""
+	case b.getBody != nil && b.size > 0 && b.size <= maxDumpBody:
+		rc, err := b.getBody()
+		if err != nil {
+			return "[body not captured: " + err.Error() + "]"
+		}
+		buf, err := io.ReadAll(rc)
+		if _, rerr := b.getBody(); err == nil {
+			err = rerr
+		}
+		if err != nil {
+			return "[body not captured: " + err.Error() + "]"
+		}
+		return string(buf)
+	default:
+		return "[streamed body not captured]"
+	}
+}
+
+func (c *realClient) newDebugDump(req *http.Request, reqBody string, attempt int) *DebugDump {
+	if !c.cfg.Debug {
+		return nil
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), host)
+	c.writeDumpHeaders(&b, req.Header)
+	if reqBody != "" {
+		b.WriteString("\r\n" + reqBody)
+	}
+	return &DebugDump{Attempt: attempt, Request: b.String()}
+}
+
+// finishDebugDump adds the response, if any, and passes d to the hook.
+func (c *realClient) finishDebugDump(ctx context.Context, d *DebugDump, resp *http.Response, body []byte) {
+	if d == nil {
+		return
+	}
+	if resp != nil {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s %s\r\n", resp.Proto, resp.Status)
+		c.writeDumpHeaders(&b, resp.Header)
+		if len(body) > 0 {
+			b.WriteString("\r\n" + dumpBody(body))
+		}
+		d.Response = b.String()
+	}
+	if c.cfg.OnDebugDump != nil {
+		c.cfg.OnDebugDump(ctx, *d)
+	}
+}
+
+func (c *realClient) writeDumpHeaders(b *strings.Builder, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		redact := c.redactInDump(k)
+		for _, v := range h[k] {
+			if redact {
+				v = "[REDACTED]"
+			}
+			fmt.Fprintf(b, "%s: %s\r\n", k, v)
+		}
+	}
+}
+
+func (c *realClient) redactInDump(header string) bool {
+	for _, h := range debugRedactHeaders {
+		if strings.EqualFold(h, header) {
+			return true
+		}
+	}
+	for _, h := range c.cfg.DebugRedactHeaders {
+		if strings.EqualFold(h, header) {
+			return true
+		}
+	}
+	return false
+}
+
+func dumpBody(body []byte) string {
+	if len(body) > maxDumpBody {
+		return fmt.Sprintf("%s\n[%d bytes truncated]", body[:maxDumpBody], len(body)-maxDumpBody)
+	}
+	return string(body)
+}
@@ -0,0 +1,93 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugDumpRedactsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "server-secret"})
+		w.Header().Set("X-Request-Id", "req-1")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":"forbidden"}`))
+	}))
+	defer server.Close()
+
+	var hooked []DebugDump
+	client := New(Config{
+		Debug:              true,
+		DebugRedactHeaders: []string{"X-Signature"},
+		OnDebugDump:        func(_ context.Context, d DebugDump) { hooked = append(hooked, d) },
+	})
+	res, err := client.Do(context.Background(), Request{
+		Method: http.MethodPost,
+		URL:    server.URL + "/apps",
+		Headers: map[string]string{
+			"Authorization": "Bearer client-secret",
+			"Cookie":        "session=client-secret",
+			"X-Signature":   "sig-secret",
+		},
+		Body: strings.NewReader(`{"name":"app"}`),
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if len(hooked) != 1 {
+		t.Fatalf("OnDebugDump called %d times, want 1", len(hooked))
+	}
+	if res.Dump == nil {
+		t.Fatal("Response.Dump is nil")
+	}
+
+	dump := res.Dump.String()
+	for _, secret := range []string{"client-secret", "server-secret", "sig-secret"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("dump leaks %q:\n%s", secret, dump)
+		}
+	}
+	for _, want := range []string{
+		"POST /apps HTTP/1.1",
+		"Authorization: [REDACTED]",
+		"Set-Cookie: [REDACTED]",
+		`{"name":"app"}`,
+		"403 Forbidden",
+		"X-Request-Id: req-1",
+		`{"error":"forbidden"}`,
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump missing %q:\n%s", want, dump)
+		}
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(res.Err(), &httpErr) || httpErr.Dump != res.Dump {
+		t.Fatalf("Err() = %v, want *HTTPError carrying the dump", res.Err())
+	}
+}
+
+func TestDebugDumpOffByDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	res, err := New(Config{}).DoGET(context.Background(), server.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("DoGET() error = %v", err)
+	}
+	if res.Dump != nil {
+		t.Fatalf("Dump = %+v, want nil", res.Dump)
+	}
+}
+
+func TestDumpBodyTruncates(t *testing.T) {
+	got := dumpBody([]byte(strings.Repeat("a", maxDumpBody+10)))
+	if !strings.HasSuffix(got, "[10 bytes truncated]") {
+		t.Fatalf("dumpBody() suffix = %q", got[len(got)-30:])
+	}
+}
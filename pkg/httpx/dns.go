@@ -0,0 +1,275 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+var ErrNoAddresses = errors.New("httpx: no addresses found for host")
+
+// DNSConfig enables an in-process DNS cache in front of the dialer. By default
+// lookups go to the system resolver; Servers or DoHURL replace it.
+type DNSConfig struct {
+	// TTL is how long successful lookups are cached. Defaults to one minute.
+	TTL time.Duration
+
+	// Servers are plain DNS servers ("host:port"). They are used round-robin:
+	// each query, including the resolver's retries after a timeout, goes to
+	// the next server in turn.
+	Servers []string
+
+	// DoHURL is a DNS-over-HTTPS endpoint accepting RFC 8484 POST queries,
+	// e.g. "https://cloudflare-dns.com/dns-query". Takes precedence over
+	// Servers.
+	DoHURL string
+}
+
+type lookupFunc func(ctx context.Context, host string) ([]net.IP, error)
+
+type dnsEntry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+type dnsCall struct {
+	done chan struct{}
+	ips  []net.IP
+	err  error
+}
+
+// dnsCache caches lookups per host and collapses concurrent lookups for the
+// same host into one query.
+type dnsCache struct {
+	ttl    time.Duration
+	lookup lookupFunc
+	now    func() time.Time
+	doh    *http.Client // nil unless DoHURL is set
+
+	mu       sync.Mutex
+	entries  map[string]dnsEntry
+	inflight map[string]*dnsCall
+}
+
+func newDNSCache(cfg *DNSConfig) *dnsCache {
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+
+	var lookup lookupFunc
+	var doh *http.Client
+	switch {
+	case cfg.DoHURL != "":
+		// A transport of its own, so Close does not touch connections of
+		// http.DefaultTransport.
+		doh = &http.Client{
+			Timeout:   5 * time.Second,
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+		}
+		lookup = dohLookup(doh, cfg.DoHURL)
+	case len(cfg.Servers) > 0:
+		lookup = serversLookup(cfg.Servers)
+	default:
+		lookup = resolverLookup(net.DefaultResolver)
+	}
+
+	return &dnsCache{
+		ttl:      ttl,
+		lookup:   lookup,
+		now:      time.Now,
+		doh:      doh,
+		entries:  make(map[string]dnsEntry),
+		inflight: make(map[string]*dnsCall),
+	}
+}
+
+func (c *dnsCache) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[host]; ok && c.now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.ips, nil
+	}
+	if call, ok := c.inflight[host]; ok {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.ips, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &dnsCall{done: make(chan struct{})}
+	c.inflight[host] = call
+	c.mu.Unlock()
+
+	// The lookup outlives a cancelled caller so waiters still get a result.
+	call.ips, call.err = c.lookup(context.WithoutCancel(ctx), host)
+	if call.err == nil && len(call.ips) == 0 {
+		call.err = fmt.Errorf("%w: %s", ErrNoAddresses, host)
+	}
+
+	c.mu.Lock()
+	delete(c.inflight, host)
+	if call.err == nil {
+		c.entries[host] = dnsEntry{ips: call.ips, expires: c.now().Add(c.ttl)}
+	} else {
+		delete(c.entries, host)
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.ips, call.err
+}
+
+// close drops cached entries and the DoH server's idle connections.
+func (c *dnsCache) close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	clear(c.entries)
+	c.mu.Unlock()
+	if c.doh != nil {
+		c.doh.CloseIdleConnections()
+	}
+}
+
+// dialContext resolves addr through the cache and tries each address in turn.
+func (c *dnsCache) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		ips, err := c.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, lastErr
+	}
+}
+
+func resolverLookup(r *net.Resolver) lookupFunc {
+	return func(ctx context.Context, host string) ([]net.IP, error) {
+		return r.LookupIP(ctx, "ip", host)
+	}
+}
+
+func serversLookup(servers []string) lookupFunc {
+	var next int
+	var mu sync.Mutex
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			mu.Lock()
+			server := servers[next%len(servers)]
+			next++
+			mu.Unlock()
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+	return resolverLookup(r)
+}
+
+func dohLookup(hc *http.Client, endpoint string) lookupFunc {
+	return func(ctx context.Context, host string) ([]net.IP, error) {
+		var ips []net.IP
+		var lastErr error
+		for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+			found, err := dohQuery(ctx, hc, endpoint, host, qtype)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			ips = append(ips, found...)
+		}
+		if len(ips) == 0 && lastErr != nil {
+			return nil, lastErr
+		}
+		return ips, nil
+	}
+}
+
+func dohQuery(ctx context.Context, hc *http.Client, endpoint, host string, qtype dnsmessage.Type) ([]net.IP, error) {
+	name, err := dnsmessage.NewName(dnsName(host))
+	if err != nil {
+		return nil, fmt.Errorf("httpx: dns name %q: %w", host, err)
+	}
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	query, err := msg.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("httpx: pack dns query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(query))
+	if err != nil {
+		return nil, fmt.Errorf("httpx: build doh request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("httpx: doh request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("httpx: doh status %d", resp.StatusCode)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("httpx: read doh response: %w", err)
+	}
+
+	var reply dnsmessage.Message
+	if err := reply.Unpack(raw); err != nil {
+		return nil, fmt.Errorf("httpx: unpack dns response: %w", err)
+	}
+	if reply.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("httpx: dns %s for %s", reply.RCode, host)
+	}
+
+	var ips []net.IP
+	for _, ans := range reply.Answers {
+		switch body := ans.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(body.AAAA[:]))
+		}
+	}
+	return ips, nil
+}
+
+func dnsName(host string) string {
+	if len(host) > 0 && host[len(host)-1] == '.' {
+		return host
+	}
+	return host + "."
+}
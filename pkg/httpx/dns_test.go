@@ -0,0 +1,128 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDNSCacheCachesUntilTTL(t *testing.T) {
+	var calls atomic.Int32
+	now := time.Unix(0, 0)
+	cache := newDNSCache(&DNSConfig{TTL: time.Minute})
+	cache.now = func() time.Time { return now }
+	cache.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		calls.Add(1)
+		return []net.IP{net.IPv4(127, 0, 0, 1)}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.resolve(context.Background(), "apps.apple.com"); err != nil {
+			t.Fatalf("resolve() error = %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("lookups = %d, want 1", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	cache.resolve(context.Background(), "apps.apple.com")
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("lookups after expiry = %d, want 2", got)
+	}
+}
+
+func TestDNSCacheCollapsesConcurrentLookups(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	cache := newDNSCache(&DNSConfig{})
+	cache.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		calls.Add(1)
+		<-release
+		return []net.IP{net.IPv4(127, 0, 0, 1)}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.resolve(context.Background(), "example.com")
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("lookups = %d, want 1", got)
+	}
+}
+
+func TestDNSCacheDoesNotCacheErrors(t *testing.T) {
+	var calls atomic.Int32
+	cache := newDNSCache(&DNSConfig{})
+	cache.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		calls.Add(1)
+		return nil, nil
+	}
+
+	_, err := cache.resolve(context.Background(), "example.com")
+	if !errors.Is(err, ErrNoAddresses) {
+		t.Fatalf("err = %v, want ErrNoAddresses", err)
+	}
+	cache.resolve(context.Background(), "example.com")
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("lookups = %d, want 2", got)
+	}
+}
+
+func TestClientDialsThroughDNSCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+
+	doh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var q dnsmessage.Message
+		if err := q.Unpack(raw); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reply := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: q.ID, Response: true},
+			Questions: q.Questions,
+		}
+		if q.Questions[0].Type == dnsmessage.TypeA {
+			reply.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: q.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+			}}
+		}
+		out, _ := reply.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(out)
+	}))
+	defer doh.Close()
+
+	client := New(Config{Timeout: 5 * time.Second, DNS: &DNSConfig{DoHURL: doh.URL}})
+	resp, err := client.DoGET(context.Background(), "http://reviews.internal.test:"+port, nil, nil)
+	if err != nil {
+		t.Fatalf("DoGET() error = %v", err)
+	}
+	if got := string(resp.Body); got != "reviews.internal.test:"+port {
+		t.Fatalf("host = %q", got)
+	}
+}
@@ -0,0 +1,341 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDownloadInterrupted  = errors.New("httpx: download interrupted")
+	ErrUnexpectedStatusCode = errors.New("httpx: unexpected status code")
+)
+
+type DownloadOptions struct {
+	Headers map[string]string
+	// MaxResumes is how many times an interrupted transfer is resumed with a
+	// Range request. Defaults to 3; negative disables resumption.
+	MaxResumes int
+	// Timeout bounds the whole download. Zero means only ctx applies; the
+	// client's Timeout is not used because it would cut off large files.
+	Timeout time.Duration
+	// Progress is called after every chunk written with the bytes on disk and
+	// the expected total (-1 if unknown).
+	Progress func(written, total int64)
+	// Checksum, when set, is verified before the file is moved into place.
+	Checksum *Checksum
+}
+
+type DownloadResult struct {
+	Path    string
+	Bytes   int64
+	Resumes int
+}
+
+// DownloadToFile streams rawURL into path. Data is written to path+".part"
+// and renamed once complete (and verified), so a leftover .part file from an
+// earlier run is resumed instead of downloaded again. A .part file that fails
+// the checksum is removed. Resumed requests carry If-Range with the ETag or
+// Last-Modified of the first response, kept in path+".part.etag" for later
+// runs, so a resource that changed meanwhile is downloaded again from the
+// start rather than spliced. A leftover .part file without a stored
+// validator is discarded.
+func (c *realClient) DownloadToFile(ctx context.Context, rawURL, path string, opts DownloadOptions) (DownloadResult, error) {
+	if rawURL == "" {
+		return DownloadResult{}, ErrEmptyURL
+	}
+	if hc := c.forHost(rawURL); hc != c {
+		return hc.DownloadToFile(ctx, rawURL, path, opts)
+	}
+	rawURL, err := c.normalizeURL(rawURL)
+	if err != nil {
+		return DownloadResult{}, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	if err := c.guard.checkURL(rawURL); err != nil {
+		return DownloadResult{}, err
+	}
+	if err := c.acquire(); err != nil {
+		return DownloadResult{}, err
+	}
+	defer c.release()
+	if opts.MaxResumes == 0 {
+		opts.MaxResumes = 3
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	partPath := path + ".part"
+	f, err := os.OpenFile(partPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return DownloadResult{}, fmt.Errorf("httpx: open %s: %w", partPath, err)
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return DownloadResult{}, fmt.Errorf("httpx: seek %s: %w", partPath, err)
+	}
+	validator := validatorStore{path: partPath + ".etag"}
+	if offset > 0 && validator.load() == "" {
+		// Without a validator the leftover cannot be told apart from a
+		// different version of the resource.
+		if err := truncate(f); err != nil {
+			return DownloadResult{}, err
+		}
+		offset = 0
+	}
+
+	hc := *c.http
+	hc.Timeout = 0
+
+	result := DownloadResult{Path: path}
+	var delay time.Duration
+	for {
+		done, n, err := c.downloadChunk(ctx, &hc, rawURL, f, offset, &validator, opts)
+		offset = n
+		if err == nil && done {
+			break
+		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if err != nil && !errors.Is(err, ErrDownloadInterrupted) {
+			return result, err
+		}
+		if result.Resumes >= opts.MaxResumes {
+			return result, err
+		}
+		if delay, err = c.sleepBackoff(ctx, result.Resumes, delay); err != nil {
+			return result, err
+		}
+		result.Resumes++
+	}
+	result.Bytes = offset
+
+	if opts.Checksum != nil {
+		if err := verifyFile(f, *opts.Checksum); err != nil {
+			// A complete but wrong .part file would be taken as done by
+			// every later call.
+			f.Close()
+			os.Remove(partPath)
+			validator.remove()
+			return result, err
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return result, fmt.Errorf("httpx: close %s: %w", partPath, err)
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return result, fmt.Errorf("httpx: rename %s: %w", partPath, err)
+	}
+	validator.remove()
+	return result, nil
+}
+
+// validatorStore keeps the If-Range validator of a .part file next to it.
+type validatorStore struct {
+	path  string
+	value string
+}
+
+func (v *validatorStore) load() string {
+	data, err := os.ReadFile(v.path)
+	if err != nil {
+		return ""
+	}
+	v.value = strings.TrimSpace(string(data))
+	return v.value
+}
+
+// set records value, the validator of a response starting the file anew.
+func (v *validatorStore) set(value string) error {
+	v.value = value
+	if value == "" {
+		v.remove()
+		return nil
+	}
+	if err := os.WriteFile(v.path, []byte(value), 0o644); err != nil {
+		return fmt.Errorf("httpx: write %s: %w", v.path, err)
+	}
+	return nil
+}
+
+func (v *validatorStore) remove() {
+	os.Remove(v.path)
+}
+
+// downloadChunk performs one request starting at offset and returns whether
+// the file is complete and the new offset. validator holds the If-Range
+// value for resumed requests and is updated from full responses.
+func (c *realClient) downloadChunk(ctx context.Context, hc *http.Client, rawURL string, f *os.File, offset int64, validator *validatorStore, opts DownloadOptions) (bool, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, offset, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	c.setRequestHeaders(req, opts.Headers)
+	if err := c.applyAuth(req, opts.Headers); err != nil {
+		return false, offset, err
+	}
+	if err := c.limiter.wait(ctx); err != nil {
+		return false, offset, err
+	}
+	// Ranges refer to the encoded representation, so ask for the raw bytes.
+	req.Header.Set("Accept-Encoding", "identity")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if validator.value != "" {
+			req.Header.Set("If-Range", validator.value)
+		}
+	}
+
+	host := throttleHost(rawURL)
+	if err := c.throttle.wait(ctx, host); err != nil {
+		return false, offset, err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return false, offset, fmt.Errorf("%w: %v", ErrDownloadInterrupted, err)
+	}
+	defer resp.Body.Close()
+	c.throttle.observe(host, resp.StatusCode)
+
+	total := int64(-1)
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if start := contentRangeStart(resp.Header.Get("Content-Range")); start != offset {
+			if err := truncate(f); err != nil {
+				return false, offset, err
+			}
+			return false, 0, fmt.Errorf("%w: range starts at %d, not %d", ErrDownloadInterrupted, start, offset)
+		}
+		total = contentRangeTotal(resp.Header.Get("Content-Range"))
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// Either the .part file is already complete or it is stale.
+		if contentRangeTotal(resp.Header.Get("Content-Range")) == offset {
+			return true, offset, nil
+		}
+		if err := truncate(f); err != nil {
+			return false, offset, err
+		}
+		return false, 0, fmt.Errorf("%w: stale partial file", ErrDownloadInterrupted)
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		// The server ignored the Range header; start over.
+		if offset > 0 {
+			if err := truncate(f); err != nil {
+				return false, offset, err
+			}
+			offset = 0
+		}
+		if resp.ContentLength >= 0 {
+			total = resp.ContentLength
+		}
+	default:
+		return false, offset, fmt.Errorf("%w: %d from %s", ErrUnexpectedStatusCode, resp.StatusCode, rawURL)
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		if err := validator.set(rangeValidator(resp.Header)); err != nil {
+			return false, offset, err
+		}
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := f.Write(buf[:n]); err != nil {
+				return false, offset, fmt.Errorf("httpx: write %s: %w", f.Name(), err)
+			}
+			offset += int64(n)
+			if opts.Progress != nil {
+				opts.Progress(offset, total)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return false, offset, fmt.Errorf("%w: %v", ErrDownloadInterrupted, readErr)
+		}
+	}
+
+	if total >= 0 && offset < total {
+		return false, offset, fmt.Errorf("%w: got %d of %d bytes", ErrDownloadInterrupted, offset, total)
+	}
+	return true, offset, nil
+}
+
+func verifyFile(f *os.File, sum Checksum) error {
+	h, err := sum.newHash()
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("httpx: seek %s: %w", f.Name(), err)
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("httpx: hash %s: %w", f.Name(), err)
+	}
+	return sum.verify(h)
+}
+
+// rangeValidator returns the If-Range value identifying the representation
+// of a response: its ETag unless weak (RFC 9110 section 13.1.5 allows only
+// strong ones), else its Last-Modified date.
+func rangeValidator(h http.Header) string {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return h.Get("Last-Modified")
+}
+
+func truncate(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("httpx: truncate %s: %w", f.Name(), err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("httpx: seek %s: %w", f.Name(), err)
+	}
+	return nil
+}
+
+// contentRangeStart parses the first byte position from "bytes 100-199/200".
+// It returns -1 when missing or unsatisfied.
+func contentRangeStart(v string) int64 {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(v), "bytes ")
+	if !ok {
+		return -1
+	}
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return -1
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(first), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
+
+// contentRangeTotal parses the complete length from "bytes 100-199/200" or
+// "bytes */200". It returns -1 when unknown.
+func contentRangeTotal(v string) int64 {
+	i := strings.LastIndex(v, "/")
+	if i < 0 {
+		return -1
+	}
+	total, err := strconv.ParseInt(strings.TrimSpace(v[i+1:]), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return total
+}
@@ -0,0 +1,311 @@
+package httpx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const downloadPayload = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// interruptingServer serves downloadPayload, cutting the first full response
+// off halfway and honouring Range requests afterwards.
+func interruptingServer(t *testing.T) (*httptest.Server, *[]string) {
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rng := r.Header.Get("Range")
+		ranges = append(ranges, rng)
+		if rng == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(len(downloadPayload)))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(downloadPayload[:10]))
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		var start int
+		fmt.Sscanf(rng, "bytes=%d-", &start)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(downloadPayload)-1, len(downloadPayload)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(downloadPayload[start:]))
+	}))
+	return server, &ranges
+}
+
+func TestDownloadToFileResumes(t *testing.T) {
+	server, ranges := interruptingServer(t)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "out.bin")
+	client := New(Config{BackoffInitial: time.Millisecond, BackoffMax: 5 * time.Millisecond})
+
+	var lastWritten, lastTotal int64
+	res, err := client.DownloadToFile(context.Background(), server.URL, path, DownloadOptions{
+		Progress: func(written, total int64) { lastWritten, lastTotal = written, total },
+		Checksum: &Checksum{Value: sha256Hex(downloadPayload)},
+	})
+	if err != nil {
+		t.Fatalf("DownloadToFile() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != downloadPayload {
+		t.Errorf("expected file contents %q, got %q", downloadPayload, string(data))
+	}
+	if res.Resumes != 1 || res.Bytes != int64(len(downloadPayload)) {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if got := strings.Join(*ranges, ","); got != ",bytes=10-" {
+		t.Errorf("unexpected Range headers %q", got)
+	}
+	if lastWritten != int64(len(downloadPayload)) || lastTotal != int64(len(downloadPayload)) {
+		t.Errorf("unexpected final progress %d/%d", lastWritten, lastTotal)
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Error("expected .part file to be renamed")
+	}
+}
+
+func TestDownloadToFileChecksumMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(downloadPayload))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "out.bin")
+	client := New(Config{})
+
+	_, err := client.DownloadToFile(context.Background(), server.URL, path, DownloadOptions{
+		Checksum: &Checksum{Algorithm: "sha256", Value: sha256Hex("something else")},
+	})
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	var cerr *ChecksumError
+	if !errors.As(err, &cerr) || cerr.Actual != sha256Hex(downloadPayload) {
+		t.Errorf("expected checksum details, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("file must not be moved into place when the checksum fails")
+	}
+}
+
+func TestDownloadToFileRetriesAfterChecksumMismatch(t *testing.T) {
+	var mu sync.Mutex
+	payload := "corrupted" + downloadPayload[9:]
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		body := payload
+		mu.Unlock()
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(body))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "out.bin")
+	client := New(Config{})
+	opts := DownloadOptions{Checksum: &Checksum{Value: sha256Hex(downloadPayload)}}
+
+	if _, err := client.DownloadToFile(context.Background(), server.URL, path, opts); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Fatal("corrupt .part file left behind")
+	}
+
+	mu.Lock()
+	payload = downloadPayload
+	mu.Unlock()
+	if _, err := client.DownloadToFile(context.Background(), server.URL, path, opts); err != nil {
+		t.Fatalf("retry after mismatch: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != downloadPayload {
+		t.Errorf("file = %q", data)
+	}
+}
+
+func TestDownloadToFileRestartsChangedResource(t *testing.T) {
+	var mu sync.Mutex
+	etag := `"v1"`
+	var ifRange []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Range") == "" {
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Content-Length", strconv.Itoa(len(downloadPayload)))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("OLD-VERSI"))
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			etag = `"v2"`
+			return
+		}
+		ifRange = append(ifRange, r.Header.Get("If-Range"))
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(downloadPayload))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "out.bin")
+	client := New(Config{BackoffInitial: time.Millisecond, BackoffMax: 5 * time.Millisecond})
+	if _, err := client.DownloadToFile(context.Background(), server.URL, path, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadToFile() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != downloadPayload {
+		t.Errorf("changed resource spliced: %q", data)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ifRange) != 1 || ifRange[0] != `"v1"` {
+		t.Errorf("If-Range = %q", ifRange)
+	}
+}
+
+func TestDownloadToFileLeftoverPart(t *testing.T) {
+	var mu sync.Mutex
+	var ranges, ifRange []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		ifRange = append(ifRange, r.Header.Get("If-Range"))
+		mu.Unlock()
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(downloadPayload))
+	}))
+	defer server.Close()
+
+	client := New(Config{})
+	tests := []struct {
+		name      string
+		part      string
+		etag      string
+		wantRange string
+		wantIf    string
+	}{
+		{name: "no validator", part: "STALE-DATA", wantRange: ""},
+		{name: "stored validator", part: downloadPayload[:10], etag: `"v1"`, wantRange: "bytes=10-", wantIf: `"v1"`},
+		{name: "changed resource", part: "STALE-DATA", etag: `"v0"`, wantRange: "bytes=10-", wantIf: `"v0"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			ranges, ifRange = nil, nil
+			mu.Unlock()
+			path := filepath.Join(t.TempDir(), "out.bin")
+			os.WriteFile(path+".part", []byte(tt.part), 0o644)
+			if tt.etag != "" {
+				os.WriteFile(path+".part.etag", []byte(tt.etag), 0o644)
+			}
+
+			if _, err := client.DownloadToFile(context.Background(), server.URL, path, DownloadOptions{}); err != nil {
+				t.Fatalf("DownloadToFile() error = %v", err)
+			}
+			if data, _ := os.ReadFile(path); string(data) != downloadPayload {
+				t.Errorf("file = %q", data)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(ranges) != 1 || ranges[0] != tt.wantRange || ifRange[0] != tt.wantIf {
+				t.Errorf("Range = %q, If-Range = %q", ranges, ifRange)
+			}
+			if _, err := os.Stat(path + ".part.etag"); !os.IsNotExist(err) {
+				t.Error("expected validator file to be removed")
+			}
+		})
+	}
+}
+
+func TestDownloadToFileRejectsMisplacedRange(t *testing.T) {
+	var mu sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("Range") != "" {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(downloadPayload)-1, len(downloadPayload)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(downloadPayload))
+			return
+		}
+		w.Write([]byte(downloadPayload))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "out.bin")
+	os.WriteFile(path+".part", []byte(downloadPayload[:10]), 0o644)
+	os.WriteFile(path+".part.etag", []byte(`"v1"`), 0o644)
+
+	client := New(Config{BackoffInitial: time.Millisecond, BackoffMax: 5 * time.Millisecond})
+	if _, err := client.DownloadToFile(context.Background(), server.URL, path, DownloadOptions{}); err != nil {
+		t.Fatalf("DownloadToFile() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != downloadPayload {
+		t.Errorf("misplaced range spliced: %q", data)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(ranges, ","); got != "bytes=10-," {
+		t.Errorf("unexpected Range headers %q", got)
+	}
+}
+
+func TestDownloadToFileUnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := New(Config{})
+	_, err := client.DownloadToFile(context.Background(), server.URL, filepath.Join(t.TempDir(), "out.bin"), DownloadOptions{})
+	if !errors.Is(err, ErrUnexpectedStatusCode) {
+		t.Errorf("expected ErrUnexpectedStatusCode, got %v", err)
+	}
+}
+
+func TestContentRangeStart(t *testing.T) {
+	tests := map[string]int64{
+		"bytes 10-35/36": 10,
+		"bytes 0-9/*":    0,
+		"bytes */36":     -1,
+		"":               -1,
+	}
+	for in, want := range tests {
+		if got := contentRangeStart(in); got != want {
+			t.Errorf("contentRangeStart(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestContentRangeTotal(t *testing.T) {
+	tests := map[string]int64{
+		"bytes 0-9/36": 36,
+		"bytes */36":   36,
+		"bytes 0-9/*":  -1,
+		"":             -1,
+	}
+	for in, want := range tests {
+		if got := contentRangeTotal(in); got != want {
+			t.Errorf("contentRangeTotal(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package httpx
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const acceptEncoding = "gzip, deflate, br"
+
+// readBody reads the response body, transparently decoding gzip, deflate and
+// brotli payloads. It returns the original Content-Encoding so callers can
+// still tell what the server sent, and the pooled buffer backing the body
+// when it was borrowed from Config.BodyPool.
+func (c *realClient) readBody(resp *http.Response) ([]byte, string, *pooledBody, error) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if c.cfg.DisableCompression || encoding == "" || encoding == "identity" {
+		body, pooled, err := c.readPooled(resp.Body, resp.ContentLength)
+		return body, encoding, pooled, err
+	}
+
+	r, err := newDecoder(encoding, resp.Body)
+	if err != nil {
+		return nil, encoding, nil, err
+	}
+	defer r.Close()
+
+	body, pooled, err := c.readPooled(r, 0)
+	if err != nil {
+		return nil, encoding, nil, fmt.Errorf("decode %s: %w", encoding, err)
+	}
+
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	return body, encoding, pooled, nil
+}
+
+func newDecoder(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("decode gzip: %w", err)
+		}
+		return zr, nil
+	case "deflate":
+		return newDeflateReader(r)
+	case "br":
+		return io.NopCloser(brotli.NewReader(r)), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// newDeflateReader decodes Content-Encoding: deflate, which is zlib-wrapped
+// DEFLATE (RFC 9110 section 8.4.1.2). Some servers send raw DEFLATE
+// instead, so bodies without a zlib header are read as that.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	if h, err := br.Peek(2); err == nil && isZlibHeader(h[0], h[1]) {
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("decode deflate: %w", err)
+		}
+		return zr, nil
+	}
+	return flate.NewReader(br), nil
+}
+
+// isZlibHeader reports whether cmf and flg start a zlib stream (RFC 1950):
+// compression method 8 and a header checksum that is a multiple of 31.
+func isZlibHeader(cmf, flg byte) bool {
+	return cmf&0x0f == 8 && cmf>>4 <= 7 && (uint16(cmf)<<8|uint16(flg))%31 == 0
+}
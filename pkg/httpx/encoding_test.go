@@ -0,0 +1,122 @@
+package httpx
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestDoDecodesCompressedBodies(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		encode   func([]byte) []byte
+	}{
+		{
+			name:     "gzip",
+			encoding: "gzip",
+			encode: func(b []byte) []byte {
+				var buf bytes.Buffer
+				zw := gzip.NewWriter(&buf)
+				zw.Write(b)
+				zw.Close()
+				return buf.Bytes()
+			},
+		},
+		{
+			name:     "deflate",
+			encoding: "deflate",
+			encode: func(b []byte) []byte {
+				var buf bytes.Buffer
+				zw := zlib.NewWriter(&buf)
+				zw.Write(b)
+				zw.Close()
+				return buf.Bytes()
+			},
+		},
+		{
+			name:     "raw deflate",
+			encoding: "deflate",
+			encode: func(b []byte) []byte {
+				var buf bytes.Buffer
+				fw, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+				fw.Write(b)
+				fw.Close()
+				return buf.Bytes()
+			},
+		},
+		{
+			name:     "brotli",
+			encoding: "br",
+			encode: func(b []byte) []byte {
+				var buf bytes.Buffer
+				bw := brotli.NewWriter(&buf)
+				bw.Write(b)
+				bw.Close()
+				return buf.Bytes()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Accept-Encoding") != acceptEncoding {
+					t.Errorf("expected Accept-Encoding %q, got %q", acceptEncoding, r.Header.Get("Accept-Encoding"))
+				}
+				w.Header().Set("Content-Encoding", tt.encoding)
+				w.Write(tt.encode([]byte("<html>hello</html>")))
+			}))
+			defer server.Close()
+
+			client := New(Config{Timeout: 5 * time.Second})
+			resp, err := client.DoGET(context.Background(), server.URL, nil, nil)
+			if err != nil {
+				t.Fatalf("DoGET() error = %v", err)
+			}
+			if string(resp.Body) != "<html>hello</html>" {
+				t.Errorf("expected decoded body, got %q", string(resp.Body))
+			}
+			if resp.ContentEncoding != tt.encoding {
+				t.Errorf("expected ContentEncoding %q, got %q", tt.encoding, resp.ContentEncoding)
+			}
+			if resp.Headers.Get("Content-Encoding") != "" {
+				t.Error("expected Content-Encoding header to be removed after decoding")
+			}
+		})
+	}
+}
+
+func TestDoDisableCompression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "" {
+			t.Errorf("expected no Accept-Encoding, got %q", r.Header.Get("Accept-Encoding"))
+		}
+		w.Write([]byte("plain"))
+	}))
+	defer server.Close()
+
+	tr := &http.Transport{DisableCompression: true}
+	client := NewWithHTTP(&http.Client{Transport: tr}, Config{DisableCompression: true})
+	resp, err := client.DoGET(context.Background(), server.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("DoGET() error = %v", err)
+	}
+	if string(resp.Body) != "plain" {
+		t.Errorf("expected body 'plain', got %q", string(resp.Body))
+	}
+}
+
+func TestNewDecoderUnsupported(t *testing.T) {
+	if _, err := newDecoder("zstd", bytes.NewReader(nil)); err == nil {
+		t.Error("expected error for unsupported encoding")
+	}
+}
@@ -0,0 +1,139 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// failoverURLs returns the endpoints to try for r in order, starting with
+// r.URL: r.FallbackURLs if set, otherwise r.URL moved to each
+// HostConfig.Fallbacks origin of its host. It returns nil when there is
+// nothing to fail over to.
+func (c *realClient) failoverURLs(r Request) []string {
+	if len(r.FallbackURLs) > 0 {
+		return append([]string{r.URL}, r.FallbackURLs...)
+	}
+	fallbacks := c.forHost(r.URL).fallbacks
+	if len(fallbacks) == 0 {
+		return nil
+	}
+	urls := []string{r.URL}
+	for _, origin := range fallbacks {
+		urls = append(urls, replaceOrigin(r.URL, origin))
+	}
+	return urls
+}
+
+// doFailover sends r to each endpoint in turn until one answers. An endpoint
+// is skipped when it fails with a network error, runs out of retries, or
+// answers with a retryable status. Other errors, such as a 4xx, a cancelled
+// ctx or an exhausted retry budget, are returned without trying further.
+func (c *realClient) doFailover(ctx context.Context, r Request, urls []string) (Response, error) {
+	rewind, err := replayableBody(&r)
+	if err != nil {
+		return Response{}, err
+	}
+	r.FallbackURLs = nil
+
+	var (
+		res      Response
+		attempts int
+	)
+	for i, u := range urls {
+		if i > 0 {
+			if rerr := rewind(); rerr != nil {
+				return res, fmt.Errorf("%w: %v", ErrBodyNotReplayable, rerr)
+			}
+		}
+		r.URL = u
+		res, err = c.send(ctx, r)
+		attempts += sentAttempts(res, err)
+		if res.Attempts > 0 {
+			res.Attempts = attempts
+		}
+		if !c.shouldFailover(res, err) {
+			return res, err
+		}
+	}
+	return res, err
+}
+
+// sentAttempts is the number of requests behind a result of send, when known.
+func sentAttempts(res Response, err error) int {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Attempts
+	}
+	return res.Attempts
+}
+
+func (c *realClient) shouldFailover(res Response, err error) bool {
+	if err == nil {
+		return c.shouldRetry(res.Status, nil)
+	}
+	var netErr *NetworkError
+	return errors.As(err, &netErr) || errors.Is(err, ErrMaxRetries)
+}
+
+// replayableBody makes r's body readable once per endpoint and returns a
+// func that rewinds it before the next one. Bodies that are neither seekable
+// nor already in memory are read into memory first.
+func replayableBody(r *Request) (rewind func() error, err error) {
+	var seekers []io.Seeker
+	switch {
+	case r.Multipart != nil:
+		for _, f := range r.Multipart.Files {
+			s, ok := f.Reader.(io.Seeker)
+			if !ok {
+				return func() error { return fmt.Errorf("multipart file %q is not seekable", f.FileName) }, nil
+			}
+			seekers = append(seekers, s)
+		}
+	case r.Form != nil || r.Body == nil:
+		return func() error { return nil }, nil
+	default:
+		s, ok := r.Body.(io.Seeker)
+		if !ok {
+			buf, err := io.ReadAll(r.Body)
+			if err != nil {
+				return nil, fmt.Errorf("httpx: read body: %w", err)
+			}
+			reader := bytes.NewReader(buf)
+			r.Body, s = reader, reader
+		}
+		seekers = append(seekers, s)
+	}
+
+	starts := make([]int64, len(seekers))
+	for i, s := range seekers {
+		if starts[i], err = s.Seek(0, io.SeekCurrent); err != nil {
+			return nil, fmt.Errorf("httpx: seek body: %w", err)
+		}
+	}
+	return func() error {
+		for i, s := range seekers {
+			if _, err := s.Seek(starts[i], io.SeekStart); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// replaceOrigin swaps the scheme and host of rawURL for origin. It works on
+// the string so {placeholders} in the path survive.
+func replaceOrigin(rawURL, origin string) string {
+	origin = strings.TrimSuffix(origin, "/")
+	rest := rawURL
+	if _, after, ok := strings.Cut(rest, "://"); ok {
+		rest = after
+	}
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		return origin + rest[i:]
+	}
+	return origin
+}
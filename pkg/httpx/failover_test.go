@@ -0,0 +1,98 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func echoServer(t *testing.T, status int) (*httptest.Server, *[]string) {
+	t.Helper()
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.URL.Path+" "+string(b))
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+func TestFailoverOnRetryableStatus(t *testing.T) {
+	primary, primaryBodies := echoServer(t, http.StatusServiceUnavailable)
+	fallback, fallbackBodies := echoServer(t, http.StatusOK)
+
+	client := New(Config{MaxRetries: 1, RetryStatus: []int{http.StatusServiceUnavailable}, Sleeper: newFakeClock()})
+	res, err := client.Do(context.Background(), Request{
+		Method:       http.MethodPost,
+		URL:          primary.URL + "/apps/{id}",
+		PathParams:   map[string]string{"id": "42"},
+		FallbackURLs: []string{fallback.URL + "/apps/{id}"},
+		Body:         io.NopCloser(strings.NewReader("payload")),
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if res.Status != http.StatusOK || res.Attempts != 3 {
+		t.Errorf("status = %d, attempts = %d", res.Status, res.Attempts)
+	}
+	if len(*primaryBodies) != 2 || (*fallbackBodies)[0] != "/apps/42 payload" {
+		t.Errorf("primary got %q, fallback got %q", *primaryBodies, *fallbackBodies)
+	}
+}
+
+func TestFailoverStopsOnClientError(t *testing.T) {
+	primary, _ := echoServer(t, http.StatusNotFound)
+	fallback, fallbackBodies := echoServer(t, http.StatusOK)
+
+	client := New(Config{})
+	res, err := client.Do(context.Background(), Request{URL: primary.URL, FallbackURLs: []string{fallback.URL}})
+	if err != nil || res.Status != http.StatusNotFound {
+		t.Fatalf("Do() = %d, %v", res.Status, err)
+	}
+	if len(*fallbackBodies) != 0 {
+		t.Error("a 404 failed over")
+	}
+}
+
+func TestFailoverFromHostConfig(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := strings.Replace(down.URL, "127.0.0.1", "localhost", 1)
+	down.Close()
+	fallback, fallbackBodies := echoServer(t, http.StatusOK)
+
+	client := New(Config{Hosts: map[string]HostConfig{
+		"localhost": {Fallbacks: []string{fallback.URL + "/"}},
+	}})
+	res, err := client.DoGET(context.Background(), downURL+"/reviews?page=2", nil, nil)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if res.Status != http.StatusOK || (*fallbackBodies)[0] != "/reviews " {
+		t.Errorf("status = %d, fallback got %q", res.Status, *fallbackBodies)
+	}
+
+	// Without replicas the network error is returned as before.
+	_, err = New(Config{}).DoGET(context.Background(), downURL, nil, nil)
+	var netErr *NetworkError
+	if !errors.As(err, &netErr) {
+		t.Errorf("err = %v, want *NetworkError", err)
+	}
+}
+
+func TestReplaceOrigin(t *testing.T) {
+	tests := []struct{ url, origin, want string }{
+		{"https://api.eu.internal/apps/{id}?x=1", "https://api.us.internal", "https://api.us.internal/apps/{id}?x=1"},
+		{"https://api.eu.internal", "http://10.0.0.1:8080/", "http://10.0.0.1:8080"},
+		{"https://api.eu.internal#frag", "https://b", "https://b#frag"},
+	}
+	for _, tt := range tests {
+		if got := replaceOrigin(tt.url, tt.origin); got != tt.want {
+			t.Errorf("replaceOrigin(%q, %q) = %q, want %q", tt.url, tt.origin, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrHostBlocked is returned, wrapped, when HostPolicy refuses a host or
+// address. Such errors are classified as ErrorKindBlocked and not retried.
+var ErrHostBlocked = errors.New("httpx: host blocked by policy")
+
+// HostPolicy restricts where the client connects, for services that request
+// user-influenced URLs. Host names are checked on the request URL and on
+// every redirect target. IP rules are checked on the address actually
+// dialed, after DNS, so a name cannot be pointed around them.
+//
+// With Proxies the dialed address is the proxy's, so IP rules apply to the
+// proxy and only host names are checked for the target. HTTP/3 is not used
+// while a HostPolicy is set.
+//
+// NewWithHTTP applies the policy to a copy of the caller's *http.Transport,
+// checking each connection once its dial func returns. A client whose
+// Transport is any other RoundTripper refuses every request with
+// ErrHostBlocked, since the addresses it dials cannot be checked.
+type HostPolicy struct {
+	// AllowHosts, when set, are the only hosts requests may go to: exact
+	// names, IP literals or wildcards such as "*.apple.com". DenyHosts are
+	// refused even when allowed.
+	AllowHosts []string
+	DenyHosts  []string
+
+	// AllowCIDRs, when set, are the only networks that may be dialed. They
+	// also lift the built-in blocks below for the ranges they cover.
+	// DenyCIDRs are refused in any case. Both take CIDRs or single IPs.
+	AllowCIDRs []string
+	DenyCIDRs  []string
+
+	// DenyPrivate also refuses loopback, RFC 1918, CGNAT and unique local
+	// addresses. Link-local ranges, which hold cloud metadata endpoints such
+	// as 169.254.169.254, and unspecified and multicast addresses are
+	// always refused unless allowed by AllowCIDRs.
+	DenyPrivate bool
+}
+
+var blockedPrefixes = mustPrefixes(
+	"0.0.0.0/8",
+	"169.254.0.0/16",
+	"224.0.0.0/4",
+	"255.255.255.255/32",
+	"100.100.100.200/32", // Alibaba Cloud metadata
+	"::/128",
+	"fe80::/10",
+	"ff00::/8",
+	"fd00:ec2::254/128", // AWS metadata over IPv6
+)
+
+var privatePrefixes = mustPrefixes(
+	"127.0.0.0/8",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"100.64.0.0/10",
+	"::1/128",
+	"fc00::/7",
+)
+
+func mustPrefixes(cidrs ...string) []netip.Prefix {
+	prefixes, err := parsePrefixes(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return prefixes
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, s := range cidrs {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP or CIDR %q", s)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP or CIDR %q", s)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// hostGuard is a compiled HostPolicy. A policy with invalid CIDRs compiles to
+// a guard that refuses everything rather than one that silently allows more.
+type hostGuard struct {
+	allowHosts, denyHosts []string
+	allowNets, denyNets   []netip.Prefix
+	denyPrivate           bool
+	invalid               error
+}
+
+func newHostGuard(p *HostPolicy) *hostGuard {
+	if p == nil {
+		return nil
+	}
+	g := &hostGuard{
+		allowHosts:  lowerAll(p.AllowHosts),
+		denyHosts:   lowerAll(p.DenyHosts),
+		denyPrivate: p.DenyPrivate,
+	}
+	var err error
+	if g.allowNets, err = parsePrefixes(p.AllowCIDRs); err != nil {
+		g.invalid = err
+	}
+	if g.denyNets, err = parsePrefixes(p.DenyCIDRs); err != nil {
+		g.invalid = err
+	}
+	return g
+}
+
+// lowerAll lower-cases hosts, converting internationalized names to
+// punycode as NormalizeURL does with request URLs.
+func lowerAll(hosts []string) []string {
+	out := make([]string, len(hosts))
+	for i, h := range hosts {
+		h = strings.TrimSpace(h)
+		if ascii, err := asciiHost(h); err == nil {
+			h = ascii
+		}
+		out[i] = strings.ToLower(h)
+	}
+	return out
+}
+
+// checkURL checks the host of rawURL, and its address if it is an IP
+// literal.
+func (g *hostGuard) checkURL(rawURL string) error {
+	if g == nil {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	return g.checkHost(u.Hostname())
+}
+
+func (g *hostGuard) checkHost(host string) error {
+	if g.invalid != nil {
+		return fmt.Errorf("%w: %v", ErrHostBlocked, g.invalid)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if matchHost(g.denyHosts, host) {
+		return fmt.Errorf("%w: %s is denied", ErrHostBlocked, host)
+	}
+	if len(g.allowHosts) > 0 && !matchHost(g.allowHosts, host) {
+		return fmt.Errorf("%w: %s is not allowed", ErrHostBlocked, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return g.checkAddr(addr)
+	}
+	return nil
+}
+
+func (g *hostGuard) checkAddr(addr netip.Addr) error {
+	if g.invalid != nil {
+		return fmt.Errorf("%w: %v", ErrHostBlocked, g.invalid)
+	}
+	addr = addr.Unmap().WithZone("")
+	if containsAddr(g.denyNets, addr) {
+		return fmt.Errorf("%w: %s is denied", ErrHostBlocked, addr)
+	}
+	if containsAddr(g.allowNets, addr) {
+		return nil
+	}
+	if len(g.allowNets) > 0 {
+		return fmt.Errorf("%w: %s is not allowed", ErrHostBlocked, addr)
+	}
+	if containsAddr(blockedPrefixes, addr) {
+		return fmt.Errorf("%w: %s is a link-local, metadata or reserved address", ErrHostBlocked, addr)
+	}
+	if g.denyPrivate && containsAddr(privatePrefixes, addr) {
+		return fmt.Errorf("%w: %s is a private address", ErrHostBlocked, addr)
+	}
+	return nil
+}
+
+func matchHost(patterns []string, host string) bool {
+	for _, p := range patterns {
+		if p == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(p, "*"); ok && strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// control is a net.Dialer Control func checking the address about to be
+// dialed, after name resolution.
+func (g *hostGuard) control(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unexpected dial address %q", ErrHostBlocked, address)
+	}
+	return g.checkAddr(ap.Addr())
+}
+
+// transport returns a copy of rt whose connections are checked by g, for
+// clients built by NewWithHTTP. Only an *http.Transport (or nil, meaning
+// http.DefaultTransport) exposes its dialer; any other RoundTripper is
+// replaced by one refusing every request, since its addresses cannot be
+// checked.
+func (g *hostGuard) transport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	tr, ok := rt.(*http.Transport)
+	if !ok {
+		return refuseTransport{fmt.Errorf("%w: cannot check addresses dialed by %T", ErrHostBlocked, rt)}
+	}
+	tr = tr.Clone()
+
+	dial := tr.DialContext
+	if dial == nil && tr.Dial != nil {
+		legacy := tr.Dial
+		dial = func(_ context.Context, network, addr string) (net.Conn, error) { return legacy(network, addr) }
+	}
+	if dial == nil {
+		// Like the default transport's dialer, checking before connecting.
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: g.control}
+		tr.DialContext = dialer.DialContext
+	} else {
+		tr.DialContext = g.checkConn(dial)
+	}
+	tr.Dial = nil
+	if tr.DialTLSContext != nil {
+		tr.DialTLSContext = g.checkConn(tr.DialTLSContext)
+	} else if tr.DialTLS != nil {
+		legacy := tr.DialTLS
+		tr.DialTLSContext = g.checkConn(func(_ context.Context, network, addr string) (net.Conn, error) { return legacy(network, addr) })
+	}
+	tr.DialTLS = nil
+	return tr
+}
+
+// checkConn wraps a dial func whose dialer the guard cannot control, checking
+// the remote address of every connection it makes. The connection is closed
+// before anything is written on it.
+func (g *hostGuard) checkConn(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.control(network, conn.RemoteAddr().String(), nil); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+type refuseTransport struct{ err error }
+
+func (t refuseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, t.err
+}
+
+// checkRedirect refuses redirects to blocked hosts before they are followed,
+// then defers to next or the default limit of 10 redirects.
+func (g *hostGuard) checkRedirect(next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if err := g.checkHost(req.URL.Hostname()); err != nil {
+			return fmt.Errorf("redirect to %s: %w", req.URL.Redacted(), err)
+		}
+		if next != nil {
+			return next(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
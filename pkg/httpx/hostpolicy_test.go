@@ -0,0 +1,136 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHostPolicyBlocksMetadataByDefault(t *testing.T) {
+	client := New(Config{HostPolicy: &HostPolicy{}})
+	for _, u := range []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[fe80::1]/",
+		"http://[::ffff:169.254.169.254]/",
+	} {
+		if _, err := client.DoGET(context.Background(), u, nil, nil); !errors.Is(err, ErrHostBlocked) {
+			t.Errorf("GET %s err = %v, want ErrHostBlocked", u, err)
+		}
+	}
+}
+
+func TestHostPolicyChecksResolvedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	// localhost passes the host name checks and is refused once resolved.
+	target := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	var attempts int
+	client := New(Config{
+		MaxRetries: 2,
+		Backoff:    ConstantBackoff{Interval: time.Millisecond},
+		HostPolicy: &HostPolicy{DenyPrivate: true},
+		OnAttempt:  func(context.Context, AttemptInfo) { attempts++ },
+	})
+	_, err := client.DoGET(context.Background(), target, nil, nil)
+	if !errors.Is(err, ErrHostBlocked) || ClassifyError(err) != ErrorKindBlocked {
+		t.Fatalf("err = %v (%s), want ErrHostBlocked", err, ClassifyError(err))
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1 (blocked dials are not retried)", attempts)
+	}
+
+	allowed := New(Config{HostPolicy: &HostPolicy{DenyPrivate: true, AllowCIDRs: []string{"127.0.0.1", "::1"}}})
+	if _, err := allowed.DoGET(context.Background(), target, nil, nil); err != nil {
+		t.Fatalf("AllowCIDRs: err = %v", err)
+	}
+}
+
+func TestHostPolicyChecksRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := New(Config{HostPolicy: &HostPolicy{AllowHosts: []string{"127.0.0.1"}}})
+	_, err := client.DoGET(context.Background(), server.URL, nil, nil)
+	if !errors.Is(err, ErrHostBlocked) {
+		t.Fatalf("err = %v, want ErrHostBlocked", err)
+	}
+}
+
+func TestHostGuardHosts(t *testing.T) {
+	g := newHostGuard(&HostPolicy{
+		AllowHosts: []string{"*.apple.com", "api.example.com"},
+		DenyHosts:  []string{"internal.apple.com"},
+	})
+	tests := map[string]bool{
+		"itunes.apple.com":   true,
+		"ITUNES.apple.com.":  true,
+		"api.example.com":    true,
+		"apple.com":          false,
+		"evilapple.com":      false,
+		"internal.apple.com": false,
+		"example.com":        false,
+	}
+	for host, want := range tests {
+		if got := g.checkHost(host) == nil; got != want {
+			t.Errorf("checkHost(%q) allowed = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestHostGuardInvalidCIDRFailsClosed(t *testing.T) {
+	g := newHostGuard(&HostPolicy{DenyCIDRs: []string{"10.0.0.0/33"}})
+	if err := g.checkAddr(netip.MustParseAddr("8.8.8.8")); !errors.Is(err, ErrHostBlocked) {
+		t.Fatalf("err = %v, want ErrHostBlocked", err)
+	}
+}
+
+func TestHostPolicyGuardsNewWithHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	target := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	policy := &HostPolicy{DenyPrivate: true}
+
+	var dialed int
+	custom := &http.Transport{DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed++
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}}
+	for name, hc := range map[string]*http.Client{
+		"default transport": {},
+		"custom dialer":     {Transport: custom},
+	} {
+		client := NewWithHTTP(hc, Config{HostPolicy: policy})
+		if _, err := client.DoGET(context.Background(), target, nil, nil); !errors.Is(err, ErrHostBlocked) {
+			t.Errorf("%s: err = %v, want ErrHostBlocked", name, err)
+		}
+	}
+	if dialed == 0 {
+		t.Error("custom dialer not used")
+	}
+
+	opaque := NewWithHTTP(&http.Client{Transport: opaqueTransport{}}, Config{HostPolicy: policy})
+	if _, err := opaque.DoGET(context.Background(), server.URL, nil, nil); !errors.Is(err, ErrHostBlocked) {
+		t.Errorf("opaque RoundTripper: err = %v, want ErrHostBlocked", err)
+	}
+
+	allowed := NewWithHTTP(&http.Client{Transport: custom}, Config{HostPolicy: &HostPolicy{DenyPrivate: true, AllowCIDRs: []string{"127.0.0.1", "::1"}}})
+	if _, err := allowed.DoGET(context.Background(), target, nil, nil); err != nil {
+		t.Fatalf("AllowCIDRs: err = %v", err)
+	}
+}
+
+// opaqueTransport is a RoundTripper whose dialer the guard cannot reach.
+type opaqueTransport struct{}
+
+func (opaqueTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -0,0 +1,204 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostConfig overrides the client configuration for requests to one host.
+// Zero fields keep the client-wide setting.
+type HostConfig struct {
+	Timeout time.Duration
+
+	// Retry, when set, replaces MaxRetries, Backoff, RetryStatus and RetryOn.
+	Retry *RetryPolicy
+
+	// RateLimit caps requests to the host per second across all callers of
+	// the client. Zero means unlimited.
+	RateLimit float64
+
+	// Headers are merged over Config.BaseHeaders.
+	Headers map[string]string
+
+	// Proxies replace Config.Proxies. QUIC cannot be tunnelled, so these
+	// hosts never use HTTP/3.
+	Proxies []string
+
+	// Fallbacks are origins ("https://api.us.internal") of replicas that
+	// Do moves a request to, in order, when this host is down. See
+	// Request.FallbackURLs.
+	Fallbacks []string
+}
+
+// RetryPolicy is the retry part of Config, for use in HostConfig.
+type RetryPolicy struct {
+	MaxRetries      int
+	Backoff         Backoff // default exponential from Config.BackoffInitial/BackoffMax
+	RetryStatus     []int
+	RetryOn         func(status int, err error) bool
+	RetryErrorKinds []ErrorKind
+}
+
+// lifecycle tracks in-flight work for Close. Per-host clients share their
+// parent's, so closing the client covers every host.
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
+	streams  map[*SSEStream]struct{}
+
+	// closing is cancelled by Close to cut short retry, reconnect and
+	// rate-limit waits.
+	closing     context.Context
+	stopWaiting context.CancelFunc
+}
+
+func newLifecycle() *lifecycle {
+	l := &lifecycle{}
+	l.closing, l.stopWaiting = context.WithCancel(context.Background())
+	return l
+}
+
+// initHosts builds a client per entry of cfg.Hosts. Keys are hostnames
+// ("api.internal") or wildcards matching any subdomain ("*.apple.com").
+func (c *realClient) initHosts() {
+	if len(c.cfg.Hosts) == 0 {
+		return
+	}
+	c.hosts = make(map[string]*realClient, len(c.cfg.Hosts))
+	for pattern, hc := range c.cfg.Hosts {
+		if ascii, err := asciiHost(pattern); err == nil {
+			pattern = ascii
+		}
+		c.hosts[strings.ToLower(pattern)] = c.newHostClient(hc)
+	}
+}
+
+func (c *realClient) newHostClient(h HostConfig) *realClient {
+	cfg := c.cfg
+	cfg.Hosts = nil
+	if h.Timeout > 0 {
+		cfg.Timeout = h.Timeout
+	}
+	if h.Retry != nil {
+		cfg.MaxRetries = h.Retry.MaxRetries
+		cfg.Backoff = h.Retry.Backoff
+		cfg.RetryStatus = h.Retry.RetryStatus
+		cfg.RetryOn = h.Retry.RetryOn
+		cfg.RetryErrorKinds = h.Retry.RetryErrorKinds
+		normalizeConfig(&cfg)
+	}
+	if len(h.Headers) > 0 {
+		cfg.BaseHeaders = mergeHeaders(c.cfg.BaseHeaders, h.Headers)
+	}
+
+	hc := *c.http
+	hc.Timeout = cfg.Timeout
+	if len(h.Proxies) > 0 {
+		cfg.Proxies = h.Proxies
+		hc.Transport = proxiedTransport(c.http.Transport, h.Proxies)
+	}
+
+	hostClient := &realClient{
+		http:      &hc,
+		cfg:       cfg,
+		budget:    c.budget,
+		throttle:  c.throttle,
+		lifecycle: c.lifecycle,
+		fallbacks: h.Fallbacks,
+		guard:     c.guard,
+	}
+	if h.RateLimit > 0 {
+		hostClient.limiter = &rateLimiter{
+			interval: time.Duration(float64(time.Second) / h.RateLimit),
+			now:      c.clock().Now,
+			sleep:    c.sleep,
+		}
+	}
+	return hostClient
+}
+
+// proxiedTransport copies the TCP transport behind rt with its proxy
+// replaced.
+func proxiedTransport(rt http.RoundTripper, proxies []string) http.RoundTripper {
+	var base *http.Transport
+	switch t := rt.(type) {
+	case *http.Transport:
+		base = t
+	case *http3Transport:
+		base = t.fallback
+	case nil:
+		base = http.DefaultTransport.(*http.Transport)
+	default:
+		return rt
+	}
+	t := base.Clone()
+	t.Proxy = newProxyRotator(proxies).proxy
+	return t
+}
+
+// forHost returns the client configured for the host of rawURL, or c.
+func (c *realClient) forHost(rawURL string) *realClient {
+	if c.hosts == nil {
+		return c
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return c
+	}
+	host, err := asciiHost(strings.TrimSuffix(u.Hostname(), "."))
+	if err != nil {
+		return c
+	}
+	if hc, ok := c.hosts[host]; ok {
+		return hc
+	}
+	for {
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			return c
+		}
+		if hc, ok := c.hosts["*."+parent]; ok {
+			return hc
+		}
+		host = parent
+	}
+}
+
+// closeHosts closes idle connections of per-host transports.
+func (c *realClient) closeHosts() {
+	for _, hc := range c.hosts {
+		if hc.http.Transport != c.http.Transport {
+			hc.http.CloseIdleConnections()
+		}
+	}
+}
+
+// rateLimiter spaces requests evenly at a fixed rate.
+type rateLimiter struct {
+	interval time.Duration
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := l.now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+	return l.sleep(ctx, at.Sub(now))
+}
@@ -0,0 +1,128 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostConfigOverridesRetriesAndHeaders(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("X-Env-Seen", r.Header.Get("X-Env"))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := New(Config{
+		BaseHeaders: map[string]string{"X-Env": "default"},
+		Hosts: map[string]HostConfig{
+			"127.0.0.1": {
+				Retry:   &RetryPolicy{MaxRetries: 2, Backoff: ConstantBackoff{Interval: time.Millisecond}},
+				Headers: map[string]string{"x-env": "internal"},
+			},
+		},
+	})
+
+	_, err := client.DoGET(context.Background(), server.URL, nil, nil)
+	var herr *HTTPError
+	if !errors.As(err, &herr) || herr.Attempts != 3 {
+		t.Fatalf("overridden host: err = %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("calls = %d, want 3", calls.Load())
+	}
+
+	calls.Store(0)
+	res, _ := client.DoGET(context.Background(), strings.Replace(server.URL, "127.0.0.1", "localhost", 1), nil, nil)
+	if calls.Load() != 1 {
+		t.Fatalf("default host calls = %d, want 1", calls.Load())
+	}
+	if got := res.Headers.Get("X-Env-Seen"); got != "default" {
+		t.Fatalf("default host X-Env = %q", got)
+	}
+}
+
+func TestHostConfigTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := New(Config{Hosts: map[string]HostConfig{"127.0.0.1": {Timeout: 20 * time.Millisecond}}})
+	if _, err := client.DoGET(context.Background(), server.URL, nil, nil); err == nil {
+		t.Fatal("expected host timeout")
+	}
+	if _, err := client.DoGET(context.Background(), strings.Replace(server.URL, "127.0.0.1", "localhost", 1), nil, nil); err != nil {
+		t.Fatalf("default timeout: %v", err)
+	}
+}
+
+func TestHostConfigRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	clock := newFakeClock()
+	client := New(Config{
+		Clock:   clock,
+		Sleeper: clock,
+		Hosts:   map[string]HostConfig{"127.0.0.1": {RateLimit: 10}},
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := client.DoGET(context.Background(), server.URL, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var waits []time.Duration
+	for _, d := range clock.Sleeps() {
+		if d > 0 {
+			waits = append(waits, d)
+		}
+	}
+	// The fake clock does not advance between requests, so each one waits
+	// for the next 100ms slot.
+	if len(waits) != 2 || waits[0] != 100*time.Millisecond || waits[1] != 100*time.Millisecond {
+		t.Fatalf("waits = %v", waits)
+	}
+}
+
+func TestForHostMatching(t *testing.T) {
+	client := New(Config{Hosts: map[string]HostConfig{
+		"*.apple.com":            {Timeout: time.Second},
+		"amp-api.apps.apple.com": {Timeout: 2 * time.Second},
+		"*.apps.apple.com":       {Timeout: 3 * time.Second},
+	}}).(*realClient)
+
+	tests := []struct {
+		url  string
+		want time.Duration
+	}{
+		{"https://itunes.apple.com/lookup", time.Second},
+		{"https://AMP-API.apps.apple.com:443/v1", 2 * time.Second},
+		{"https://x.apps.apple.com", 3 * time.Second},
+		{"https://apple.com", client.cfg.Timeout},
+		{"https://example.com", client.cfg.Timeout},
+	}
+	for _, tt := range tests {
+		if got := client.forHost(tt.url).cfg.Timeout; got != tt.want {
+			t.Errorf("forHost(%q) timeout = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestHostClientsShareClose(t *testing.T) {
+	client := New(Config{Hosts: map[string]HostConfig{"127.0.0.1": {Proxies: []string{"http://127.0.0.1:1"}}}})
+	if err := client.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DoGET(context.Background(), "http://127.0.0.1/", nil, nil); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("err = %v, want ErrClientClosed", err)
+	}
+}
@@ -0,0 +1,218 @@
+package httpx
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+var (
+	http3HandshakeTimeout = 3 * time.Second
+	http3BrokenFor        = 5 * time.Minute
+	// altSvcDefaultMaxAge is the Alt-Svc lifetime when no ma is given
+	// (RFC 7838).
+	altSvcDefaultMaxAge = 24 * time.Hour
+)
+
+// http3Transport sends https requests over HTTP/3 and falls back to the
+// regular HTTP/2 / HTTP/1.1 transport when QUIC fails, e.g. because UDP is
+// blocked. Hosts that failed are sent straight to the fallback for a while.
+// A request that failed after it was sent is only sent again over TCP when
+// it is idempotent, since the server may already have acted on it.
+//
+// When hosts is non-empty only those hosts start on QUIC; others are
+// upgraded once a response advertises h3 on the same port via Alt-Svc.
+type http3Transport struct {
+	h3       *http3.Transport
+	fallback *http.Transport
+	hosts    map[string]bool
+
+	now        func() time.Time
+	mu         sync.Mutex
+	broken     map[string]time.Time
+	advertised map[string]time.Time
+}
+
+func newHTTP3Transport(cfg Config, fallback *http.Transport) *http3Transport {
+	t := &http3Transport{
+		h3: &http3.Transport{
+			TLSClientConfig:    cfg.TLS.build(),
+			QUICConfig:         &quic.Config{HandshakeIdleTimeout: http3HandshakeTimeout},
+			DisableCompression: true,
+		},
+		fallback:   fallback,
+		now:        time.Now,
+		broken:     make(map[string]time.Time),
+		advertised: make(map[string]time.Time),
+	}
+	if cfg.Clock != nil {
+		t.now = cfg.Clock.Now
+	}
+	if len(cfg.HTTP3Hosts) > 0 {
+		t.hosts = make(map[string]bool, len(cfg.HTTP3Hosts))
+		for _, h := range cfg.HTTP3Hosts {
+			t.hosts[strings.ToLower(h)] = true
+		}
+	}
+	return t
+}
+
+func (t *http3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.useHTTP3(req.URL) {
+		resp, err := t.fallback.RoundTrip(req)
+		if err == nil && t.hosts != nil {
+			t.learnAltSvc(req.URL, resp.Header.Get("Alt-Svc"))
+		}
+		return resp, err
+	}
+
+	var sent atomic.Bool
+	trace := &httptrace.ClientTrace{WroteHeaders: func() { sent.Store(true) }}
+	resp, err := t.h3.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err == nil {
+		return resp, nil
+	}
+	if req.Context().Err() != nil {
+		return nil, err
+	}
+	if sent.Load() && !isIdempotent(req) {
+		return nil, err
+	}
+
+	retry, ok := rewindRequest(req)
+	if !ok {
+		return nil, err
+	}
+	t.markBroken(req.URL.Host)
+	return t.fallback.RoundTrip(retry)
+}
+
+func (t *http3Transport) CloseIdleConnections() {
+	t.h3.CloseIdleConnections()
+	t.fallback.CloseIdleConnections()
+}
+
+func (t *http3Transport) close() {
+	t.h3.Close()
+	t.fallback.CloseIdleConnections()
+}
+
+func (t *http3Transport) useHTTP3(u *url.URL) bool {
+	if u.Scheme != "https" || t.isBroken(u.Host) {
+		return false
+	}
+	if t.hosts == nil || t.hosts[strings.ToLower(u.Hostname())] {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until, ok := t.advertised[u.Host]
+	if ok && t.now().After(until) {
+		delete(t.advertised, u.Host)
+		return false
+	}
+	return ok
+}
+
+// learnAltSvc records whether the origin of u advertises HTTP/3 on its own
+// port. "clear" forgets a previous advertisement.
+func (t *http3Transport) learnAltSvc(u *url.URL, header string) {
+	if header == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if strings.TrimSpace(header) == "clear" {
+		delete(t.advertised, u.Host)
+		return
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	if maxAge, ok := parseAltSvcH3(header, port); ok {
+		t.advertised[u.Host] = t.now().Add(maxAge)
+	}
+}
+
+// parseAltSvcH3 looks for an h3 alternative served by the same host on port
+// and returns its max age.
+func parseAltSvcH3(header, port string) (time.Duration, bool) {
+	for _, alt := range strings.Split(header, ",") {
+		params := strings.Split(alt, ";")
+		proto, authority, ok := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if !ok || proto != "h3" {
+			continue
+		}
+		host, altPort, err := net.SplitHostPort(strings.Trim(authority, `"`))
+		if err != nil || host != "" || altPort != port {
+			continue
+		}
+		maxAge := altSvcDefaultMaxAge
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if k != "ma" {
+				continue
+			}
+			if secs, err := strconv.Atoi(strings.Trim(v, `"`)); err == nil {
+				maxAge = time.Duration(secs) * time.Second
+			}
+		}
+		return maxAge, maxAge > 0
+	}
+	return 0, false
+}
+
+func (t *http3Transport) isBroken(host string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until, ok := t.broken[host]
+	if ok && t.now().After(until) {
+		delete(t.broken, host)
+		return false
+	}
+	return ok
+}
+
+func (t *http3Transport) markBroken(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.broken[host] = t.now().Add(http3BrokenFor)
+}
+
+// isIdempotent reports whether req may be sent twice, by its method or an
+// Idempotency-Key header, as net/http decides which requests to retry.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// rewindRequest returns a copy of req that can be sent again, or false when
+// the body was already consumed and cannot be recreated.
+func rewindRequest(req *http.Request) (*http.Request, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, true
+}
@@ -0,0 +1,246 @@
+package httpx
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestHTTP3RoundTrip(t *testing.T) {
+	tlsServer := httptest.NewUnstartedServer(nil)
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("udp not available: %v", err)
+	}
+	server := &http3.Server{
+		TLSConfig: http3.ConfigureTLSConfig(tlsServer.TLS.Clone()),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}),
+	}
+	go server.Serve(conn)
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(tlsServer.Certificate())
+	client := New(Config{Timeout: 5 * time.Second, EnableHTTP3: true, TLS: &TLSConfig{RootCAs: pool}})
+
+	resp, err := client.DoGET(context.Background(), "https://"+conn.LocalAddr().String(), nil, nil)
+	if err != nil {
+		t.Fatalf("DoGET() error = %v", err)
+	}
+	if got := string(resp.Body); got != "HTTP/3.0" {
+		t.Fatalf("proto = %q, want HTTP/3.0", got)
+	}
+}
+
+func TestHTTP3FallsBackWhenQUICUnavailable(t *testing.T) {
+	old := http3HandshakeTimeout
+	http3HandshakeTimeout = 200 * time.Millisecond
+	defer func() { http3HandshakeTimeout = old }()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	client := New(Config{Timeout: 5 * time.Second, EnableHTTP3: true, TLS: &TLSConfig{RootCAs: pool}})
+
+	resp, err := client.Do(context.Background(), Request{
+		Method: http.MethodPost,
+		URL:    server.URL,
+		Body:   strings.NewReader("payload"),
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := string(resp.Body); got != "HTTP/2.0" {
+		t.Fatalf("proto = %q, want HTTP/2.0", got)
+	}
+
+	rt := client.(*realClient).http.Transport.(*http3Transport)
+	if !rt.isBroken(strings.TrimPrefix(server.URL, "https://")) {
+		t.Fatal("expected host to be marked as broken after fallback")
+	}
+}
+
+func TestHTTP3UpgradesAdvertisedHosts(t *testing.T) {
+	tlsServer := httptest.NewUnstartedServer(nil)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	addr := tlsServer.Listener.Addr().(*net.TCPAddr)
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: addr.IP, Port: addr.Port})
+	if err != nil {
+		t.Skipf("udp port not available: %v", err)
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", fmt.Sprintf(`h3=":%d"; ma=60`, addr.Port))
+		w.Write([]byte(r.Proto))
+	})
+	tlsServer.Config.Handler = handler
+	server := &http3.Server{TLSConfig: http3.ConfigureTLSConfig(tlsServer.TLS.Clone()), Handler: handler}
+	go server.Serve(conn)
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(tlsServer.Certificate())
+	client := New(Config{
+		Timeout:     5 * time.Second,
+		EnableHTTP3: true,
+		HTTP3Hosts:  []string{"api.example.com"},
+		TLS:         &TLSConfig{RootCAs: pool},
+	})
+
+	for i, want := range []string{"HTTP/2.0", "HTTP/3.0"} {
+		resp, err := client.DoGET(context.Background(), tlsServer.URL, nil, nil)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if got := string(resp.Body); got != want {
+			t.Fatalf("request %d: proto = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestParseAltSvcH3(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{`h3=":443"`, altSvcDefaultMaxAge, true},
+		{`h3-29=":443", h3=":443"; ma=3600`, time.Hour, true},
+		{`h3=":8443"`, 0, false},
+		{`h3="alt.example.com:443"`, 0, false},
+		{`h2=":443"`, 0, false},
+		{`h3=":443"; ma=0`, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseAltSvcH3(tt.header, "443")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseAltSvcH3(%q) = %v, %v; want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHTTP3AltSvcClear(t *testing.T) {
+	rt := newHTTP3Transport(Config{HTTP3Hosts: []string{"pinned.example.com"}}, &http.Transport{})
+	u, _ := url.Parse("https://api.example.com")
+	if rt.useHTTP3(u) {
+		t.Fatal("unlisted host should not use HTTP/3 before Alt-Svc")
+	}
+	rt.learnAltSvc(u, `h3=":443"`)
+	if !rt.useHTTP3(u) {
+		t.Fatal("advertised host should use HTTP/3")
+	}
+	rt.learnAltSvc(u, "clear")
+	if rt.useHTTP3(u) {
+		t.Fatal("clear should drop the advertisement")
+	}
+	if pinned, _ := url.Parse("https://Pinned.example.com"); !rt.useHTTP3(pinned) {
+		t.Fatal("listed host should use HTTP/3")
+	}
+}
+
+func TestHTTP3FallbackOnlyReplaysIdempotentRequests(t *testing.T) {
+	var tcpRequests atomic.Int32
+	tlsServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tcpRequests.Add(1)
+	}))
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	addr := tlsServer.Listener.Addr().(*net.TCPAddr)
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: addr.IP, Port: addr.Port})
+	if err != nil {
+		t.Skipf("udp port not available: %v", err)
+	}
+	// The request reaches the server over QUIC, then the stream is reset.
+	server := &http3.Server{
+		TLSConfig: http3.ConfigureTLSConfig(tlsServer.TLS.Clone()),
+		Handler: http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic(http.ErrAbortHandler)
+		}),
+	}
+	go server.Serve(conn)
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(tlsServer.Certificate())
+	cfg := Config{EnableHTTP3: true, TLS: &TLSConfig{RootCAs: pool}}
+	fallback, _ := newTransport(cfg)
+	rt := newHTTP3Transport(cfg, fallback)
+	defer rt.close()
+
+	post, _ := http.NewRequest(http.MethodPost, tlsServer.URL, strings.NewReader("payload"))
+	if _, err := rt.RoundTrip(post); err == nil {
+		t.Fatal("POST sent over QUIC was replayed over TCP")
+	}
+	if n := tcpRequests.Load(); n != 0 {
+		t.Fatalf("TCP requests = %d, want 0", n)
+	}
+
+	keyed, _ := http.NewRequest(http.MethodPost, tlsServer.URL, strings.NewReader("payload"))
+	keyed.Header.Set("Idempotency-Key", "k1")
+	resp, err := rt.RoundTrip(keyed)
+	if err != nil {
+		t.Fatalf("POST with Idempotency-Key: %v", err)
+	}
+	resp.Body.Close()
+	if n := tcpRequests.Load(); n != 1 {
+		t.Fatalf("TCP requests = %d, want 1", n)
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	for method, want := range map[string]bool{
+		http.MethodGet:    true,
+		http.MethodPut:    true,
+		http.MethodDelete: true,
+		http.MethodPost:   false,
+		http.MethodPatch:  false,
+	} {
+		req, _ := http.NewRequest(method, "https://example.com", nil)
+		if got := isIdempotent(req); got != want {
+			t.Errorf("isIdempotent(%s) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+func TestRewindRequestWithoutGetBody(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com", nil)
+	req.Body = http.NoBody
+	if _, ok := rewindRequest(req); !ok {
+		t.Fatal("empty body should be rewindable")
+	}
+
+	req.Body = nopReadCloser{strings.NewReader("x")}
+	req.GetBody = nil
+	if _, ok := rewindRequest(req); ok {
+		t.Fatal("body without GetBody should not be rewindable")
+	}
+}
+
+type nopReadCloser struct{ *strings.Reader }
+
+func (nopReadCloser) Close() error { return nil }
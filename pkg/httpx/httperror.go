@@ -0,0 +1,61 @@
+package httpx
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// maxErrorBody bounds the body excerpt kept in an HTTPError.
+const maxErrorBody = 512
+
+// HTTPError describes a failed final response. Do returns it when retries
+// on a retryable status run out (matching ErrMaxRetries) or the retry budget
+// stops them (matching ErrRetryBudgetExhausted). Other failures are returned
+// as a plain Response; use Response.Err to turn them into an HTTPError.
+type HTTPError struct {
+	Status   int
+	Body     []byte // at most the first 512 bytes of the response body
+	URL      string
+	Attempts int
+	// Dump is the failing attempt on the wire when Config.Debug is set.
+	Dump *DebugDump
+
+	cause error
+}
+
+func newHTTPError(res Response, attempts int, cause error) *HTTPError {
+	body := res.Body
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+	return &HTTPError{
+		Status:   res.Status,
+		Body:     append([]byte(nil), body...),
+		URL:      res.URL,
+		Attempts: attempts,
+		Dump:     res.Dump,
+		cause:    cause,
+	}
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("httpx: %s: status %d", e.URL, e.Status)
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" after %d attempts", e.Attempts)
+	}
+	if len(e.Body) > 0 && utf8.Valid(e.Body) {
+		msg += ": " + string(e.Body)
+	}
+	return msg
+}
+
+func (e *HTTPError) Unwrap() error { return e.cause }
+
+// Err returns an *HTTPError matching ErrNonRetryableResp when the status is
+// 400 or above, and nil otherwise.
+func (r Response) Err() error {
+	if r.Status < 400 {
+		return nil
+	}
+	return newHTTPError(r, r.Attempts, ErrNonRetryableResp)
+}
@@ -0,0 +1,69 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDoReturnsHTTPErrorAfterRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(strings.Repeat("x", 1000)))
+	}))
+	defer server.Close()
+
+	client := New(Config{MaxRetries: 2, BackoffInitial: time.Millisecond, BackoffMax: time.Millisecond})
+	_, err := client.Do(context.Background(), Request{URL: server.URL})
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected *HTTPError, got %v", err)
+	}
+	if !errors.Is(err, ErrMaxRetries) {
+		t.Errorf("expected ErrMaxRetries, got %v", err)
+	}
+	if httpErr.Status != http.StatusServiceUnavailable || httpErr.Attempts != 3 || httpErr.URL != server.URL+"/" {
+		t.Errorf("unexpected error fields: %+v", httpErr)
+	}
+	if len(httpErr.Body) != maxErrorBody {
+		t.Errorf("body excerpt length = %d, want %d", len(httpErr.Body), maxErrorBody)
+	}
+}
+
+func TestResponseErr(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.Error(w, "no such app", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := New(Config{})
+	resp, err := client.Do(context.Background(), Request{URL: server.URL + "/missing"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = resp.Err()
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || !errors.Is(err, ErrNonRetryableResp) {
+		t.Fatalf("expected *HTTPError matching ErrNonRetryableResp, got %v", err)
+	}
+	if httpErr.Status != http.StatusNotFound || httpErr.Attempts != 1 {
+		t.Errorf("unexpected error fields: %+v", httpErr)
+	}
+	if !strings.Contains(err.Error(), "status 404: no such app") {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+
+	resp, err = client.Do(context.Background(), Request{URL: server.URL})
+	if err != nil || resp.Err() != nil {
+		t.Fatalf("expected success, got %v / %v", err, resp.Err())
+	}
+}
@@ -1,11 +1,11 @@
 package httpx
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
-	"math"
 	"math/rand"
 	"net"
 	"net/http"
@@ -30,6 +30,118 @@ type Config struct {
 	BaseHeaders    map[string]string
 	RetryStatus    []int
 	RetryOn        func(status int, err error) bool
+
+	// RetryErrorKinds selects which transport errors are retried, see
+	// ClassifyError. Defaults to DefaultRetryErrorKinds. RetryOn, when set,
+	// decides instead.
+	RetryErrorKinds []ErrorKind
+
+	// Backoff overrides the default exponential backoff built from
+	// BackoffInitial and BackoffMax.
+	Backoff Backoff
+
+	// RetryBudget, when set, limits retries across all requests of the
+	// client. Requests that would exceed it fail with ErrRetryBudgetExhausted.
+	RetryBudget *RetryBudget
+
+	// DeadlineBudget, when set, skips or shortens retries that would not
+	// fit before the ctx deadline. OnDeadlineBudget is told when that
+	// happens and defaults to LogDeadlineBudget.
+	DeadlineBudget   *DeadlineBudget
+	OnDeadlineBudget func(ctx context.Context, e DeadlineEvent)
+
+	// Throttle, when set, paces hosts that keep answering 429 and lets them
+	// recover gradually. See ThrottleStats for the current rates.
+	Throttle *Throttle
+
+	// BodyPool, when set, reads response bodies into pooled buffers instead
+	// of growing a new slice per response. Bodies are copied out to an
+	// exactly sized slice unless BorrowBodies is set, in which case Body
+	// points into the pooled buffer and should be handed back with
+	// Response.Release once the caller is done with it. Bodies of requests
+	// with Transforms are never borrowed.
+	BodyPool     *BufferPool
+	BorrowBodies bool
+
+	// DisableCompression stops the client from advertising gzip/deflate/br
+	// and returns bodies exactly as received.
+	DisableCompression bool
+
+	TLS *TLSConfig
+
+	// Auth, when set, adds an Authorization header to every request.
+	Auth *Auth
+
+	// EnableHTTP3 sends https requests over HTTP/3 (QUIC), falling back to
+	// HTTP/2 or HTTP/1.1 for hosts where QUIC fails. It is ignored when
+	// Proxies are set, since QUIC cannot be tunnelled through them, and when
+	// HostPolicy is set, since QUIC dials are not address-checked.
+	EnableHTTP3 bool
+
+	// HTTP3Hosts limits EnableHTTP3 to these hostnames. Other hosts use
+	// HTTP/2 or HTTP/1.1 until a response advertises h3 on the same port in
+	// its Alt-Svc header. When empty every https host is tried over QUIC.
+	HTTP3Hosts []string
+
+	// Hosts overrides settings for specific hosts, keyed by hostname or by a
+	// wildcard such as "*.apple.com" matching any subdomain. An exact match
+	// wins over a wildcard, and a longer wildcard over a shorter one.
+	Hosts map[string]HostConfig
+
+	// DNS, when set, caches host lookups made by the dialer.
+	DNS *DNSConfig
+
+	// HostPolicy, when set, restricts the hosts and addresses requests may
+	// reach. See HostPolicy for how it applies to NewWithHTTP transports.
+	HostPolicy *HostPolicy
+
+	// Proxies are used round-robin, one per attempt. Supported schemes are
+	// http, https, socks5 and socks5h, with optional user:pass credentials.
+	// When empty the proxy is taken from the environment.
+	Proxies []string
+
+	// OnUnauthorized is called when a request gets a 401. If it returns
+	// retry=true the request is sent once more with newHeaders merged over the
+	// request headers. The extra attempt does not count against MaxRetries.
+	OnUnauthorized func(ctx context.Context, resp Response) (newHeaders map[string]string, retry bool)
+
+	// OnAttempt is called after every request sent, including retries, e.g.
+	// to feed a latency histogram. Set it to LogAttempt to log attempts
+	// through pkg/obs.
+	OnAttempt func(ctx context.Context, a AttemptInfo)
+
+	// OnBeforeAttempt is called right before every request Do sends,
+	// including retries, and may set per-attempt headers on req, e.g. with
+	// AttemptHeaders. An error aborts the request without sending it.
+	OnBeforeAttempt func(ctx context.Context, req *http.Request, s RetryState) error
+
+	// SlowRequestThreshold reports attempts whose headers took at least this
+	// long to OnSlowRequest, with DNS, connect, TLS and first-byte timings.
+	// OnSlowRequest defaults to LogSlowAttempt.
+	SlowRequestThreshold time.Duration
+	OnSlowRequest        func(ctx context.Context, a AttemptInfo)
+
+	// Debug captures every attempt as a DebugDump with credentials
+	// redacted. Dumps are passed to OnDebugDump and kept in Response.Dump
+	// and HTTPError.Dump. DebugRedactHeaders are redacted next to
+	// Authorization, Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key.
+	Debug              bool
+	DebugRedactHeaders []string
+	OnDebugDump        func(ctx context.Context, d DebugDump)
+
+	// DisableURLNormalization sends request URLs as given instead of
+	// normalizing them with NormalizeURL, e.g. for signed URLs whose
+	// signature covers the exact spelling.
+	DisableURLNormalization bool
+
+	// DisableContextHeaders stops the client from sending X-Request-ID,
+	// X-Saga-ID and the baggage header taken from the request context.
+	DisableContextHeaders bool
+
+	// Clock and Sleeper replace the system clock and real sleeps, so tests
+	// can check retry timing without waiting.
+	Clock   Clock
+	Sleeper Sleeper
 }
 
 type Request struct {
@@ -38,48 +150,161 @@ type Request struct {
 	Params  map[string]string
 	Headers map[string]string
 	Body    io.Reader
+
+	// PathParams fill {name} placeholders in URL, each escaped as a single
+	// path segment: "/apps/{id}/reviews" with {"id": "a/b"} requests
+	// "/apps/a%2Fb/reviews".
+	PathParams map[string]string
+
+	// FallbackURLs are tried in order when URL is down: on a network error,
+	// once retries are used up, or on a retryable status. They take the same
+	// Params and PathParams, and replace HostConfig.Fallbacks for this
+	// request. Only Do fails over.
+	FallbackURLs []string
+
+	// Multipart, when set, is encoded as a multipart/form-data body and takes
+	// precedence over Body.
+	Multipart *Multipart
+
+	// Form, when set, is sent as an application/x-www-form-urlencoded body
+	// and takes precedence over Body. Multipart wins over Form.
+	Form url.Values
+
+	// Transforms run in order on the body of the final response, unless it
+	// is a 304 Not Modified.
+	Transforms []Transformer
+
+	// Checksum, when set, is verified against the body of a 2xx response;
+	// a mismatch returns a *ChecksumError matching ErrChecksumMismatch.
+	Checksum *Checksum
+	// DigestPolicy checks digest headers sent by the server (Content-Digest,
+	// Repr-Digest, Digest, Content-MD5). Setting it asks for an unencoded
+	// body unless Accept-Encoding is given explicitly.
+	DigestPolicy DigestPolicy
+
+	retryKey string // RetryState.Key, shared by failover endpoints
 }
 
 type Response struct {
 	Status  int
 	Body    []byte
 	Headers http.Header
-	URL     string
+	// URL is the URL the request was sent to, with PathParams and Params
+	// filled in and normalized by NormalizeURL unless
+	// Config.DisableURLNormalization is set.
+	URL string
+
+	// ContentEncoding is the Content-Encoding the server replied with. Body is
+	// always decoded unless Config.DisableCompression is set.
+	ContentEncoding string
+
+	// Attempts is the number of requests sent, including retries.
+	Attempts int
+
+	// Dump is the last attempt on the wire when Config.Debug is set.
+	Dump *DebugDump
+
+	pooled *pooledBody // set when Body is borrowed, see Release
 }
 
 type Client interface {
 	Do(ctx context.Context, req Request) (Response, error)
 	DoGET(ctx context.Context, rawURL string, params, headers map[string]string) (Response, error)
+	DoConditional(ctx context.Context, req Request, prev Validators) (ConditionalResponse, error)
+	DoBatch(ctx context.Context, reqs []Request, concurrency int) []Result
+	DoSSE(ctx context.Context, req Request) (*SSEStream, error)
+	DialWebSocket(ctx context.Context, rawURL string, headers map[string]string) (*WebSocket, error)
+	DownloadToFile(ctx context.Context, rawURL, path string, opts DownloadOptions) (DownloadResult, error)
+	Close(ctx context.Context) error
+	ThrottleStats() map[string]ThrottleStat
 }
 
 type realClient struct {
-	http *http.Client
-	cfg  Config
+	http     *http.Client
+	cfg      Config
+	budget   *retryBudget
+	throttle *throttler
+	limiter  *rateLimiter
+	hosts    map[string]*realClient
+	dns      *dnsCache
+	guard    *hostGuard // nil unless Config.HostPolicy is set
+
+	// fallbacks are the HostConfig.Fallbacks of a per-host client.
+	fallbacks []string
+
+	*lifecycle
 }
 
 func New(cfg Config) Client {
 	normalizeConfig(&cfg)
 
-	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   5 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
+	tr, dns := newTransport(cfg)
+	var rt http.RoundTripper = tr
+	if cfg.EnableHTTP3 && len(cfg.Proxies) == 0 && cfg.HostPolicy == nil {
+		rt = newHTTP3Transport(cfg, tr)
+	}
+
+	c := newRealClient(&http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: rt,
+	}, cfg)
+	c.dns = dns
+	return c
+}
+
+func newRealClient(hc *http.Client, cfg Config) *realClient {
+	c := &realClient{http: hc, cfg: cfg, lifecycle: newLifecycle()}
+	if c.guard = newHostGuard(cfg.HostPolicy); c.guard != nil {
+		guarded := *hc
+		guarded.CheckRedirect = c.guard.checkRedirect(hc.CheckRedirect)
+		c.http = &guarded
+	}
+	if c.budget = newRetryBudget(cfg.RetryBudget); c.budget != nil {
+		c.budget.now = c.clock().Now
+	}
+	if c.throttle = newThrottler(cfg.Throttle); c.throttle != nil {
+		c.throttle.now = c.clock().Now
+		c.throttle.sleep = c.sleep
+	}
+	c.initHosts()
+	return c
+}
+
+// newTransport returns the default transport for cfg and its DNS cache, which
+// is nil unless cfg.DNS is set.
+func newTransport(cfg Config) (*http.Transport, *dnsCache) {
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if cfg.HostPolicy != nil {
+		dialer.Control = newHostGuard(cfg.HostPolicy).control
+	}
+	dial := dialer.DialContext
+	var cache *dnsCache
+	if cfg.DNS != nil {
+		cache = newDNSCache(cfg.DNS)
+		if cfg.Clock != nil {
+			cache.now = cfg.Clock.Now
+		}
+		dial = cache.dialContext(dialer)
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if len(cfg.Proxies) > 0 {
+		proxy = newProxyRotator(cfg.Proxies).proxy
+	}
+
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dial,
+		TLSClientConfig:       cfg.TLS.build(),
 		ForceAttemptHTTP2:     true,
 		MaxIdleConns:          100,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   5 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
-	}
-
-	return &realClient{
-		http: &http.Client{
-			Timeout:   cfg.Timeout,
-			Transport: tr,
-		},
-		cfg: cfg,
-	}
+	}, cache
 }
 
 func NewWithHTTP(hc *http.Client, cfg Config) Client {
@@ -87,7 +312,12 @@ func NewWithHTTP(hc *http.Client, cfg Config) Client {
 	if hc == nil {
 		return New(cfg)
 	}
-	return &realClient{http: hc, cfg: cfg}
+	if cfg.HostPolicy != nil {
+		guarded := *hc
+		guarded.Transport = newHostGuard(cfg.HostPolicy).transport(hc.Transport)
+		hc = &guarded
+	}
+	return newRealClient(hc, cfg)
 }
 
 func (c *realClient) DoGET(ctx context.Context, rawURL string, params, headers map[string]string) (Response, error) {
@@ -103,64 +333,188 @@ func (c *realClient) Do(ctx context.Context, r Request) (Response, error) {
 	if r.URL == "" {
 		return Response{}, ErrEmptyURL
 	}
+	r = c.withRetryKey(r)
+	if urls := c.failoverURLs(r); urls != nil {
+		return c.doFailover(ctx, r, urls)
+	}
+	return c.send(ctx, r)
+}
+
+// send performs r against r.URL only, with retries.
+func (c *realClient) send(ctx context.Context, r Request) (Response, error) {
+	if hc := c.forHost(r.URL); hc != c {
+		return hc.send(ctx, r)
+	}
+	if err := c.acquire(); err != nil {
+		return Response{}, err
+	}
+	defer c.release()
 	if r.Method == "" {
 		r.Method = http.MethodGet
 	}
+	r = c.withRetryKey(r)
 
-	u, err := buildURL(r.URL, r.Params)
+	u, err := c.requestURL(r)
 	if err != nil {
 		return Response{}, fmt.Errorf("%w: %v", ErrInvalidURL, err)
 	}
+	if err := c.guard.checkURL(u); err != nil {
+		return Response{}, err
+	}
+	c.checkDeadline(ctx, r.Method, u)
+
+	body, err := newRequestBody(r, c.cfg.MaxRetries > 0 || c.cfg.OnUnauthorized != nil)
+	if err != nil {
+		return Response{}, err
+	}
+
+	c.budget.recordRequest()
+	host := throttleHost(u)
 
-	var lastErr error
+	var (
+		lastErr    error
+		lastStatus int
+		delay      time.Duration
+		sent       int
+		refreshed  bool
+	)
 	for attempt := 0; attempt <= c.cfg.MaxRetries; attempt++ {
-		req, err := http.NewRequestWithContext(ctx, r.Method, u, r.Body)
+		reqBody, err := body.reader(sent)
+		if err != nil {
+			if errors.Is(err, ErrBodyNotReplayable) {
+				return Response{}, fmt.Errorf("%w (last error: %v)", err, lastErr)
+			}
+			return Response{}, fmt.Errorf("httpx: rewind body: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, r.Method, u, reqBody)
 		if err != nil {
 			return Response{}, fmt.Errorf("httpx: build request: %w", err)
 		}
+		if body.size > 0 {
+			req.ContentLength = body.size
+		}
+		if body.getBody != nil {
+			req.GetBody = body.getBody
+		}
 
 		c.setRequestHeaders(req, r.Headers)
+		if err := c.applyAuth(req, r.Headers); err != nil {
+			return Response{}, err
+		}
+		if _, ok := headerLookup(r.Headers, "Accept-Encoding"); !ok && r.DigestPolicy != DigestIgnore {
+			req.Header.Set("Accept-Encoding", "identity")
+		}
+		if _, ok := headerLookup(r.Headers, "Content-Type"); !ok && body.contentType != "" {
+			req.Header.Set("Content-Type", body.contentType)
+		}
 
+		if err := c.limiter.wait(ctx); err != nil {
+			return Response{}, err
+		}
+		if err := c.throttle.wait(ctx, host); err != nil {
+			return Response{}, err
+		}
+		if err := c.beforeAttempt(ctx, req, r, sent, lastStatus, lastErr); err != nil {
+			return Response{}, err
+		}
+		req, timer := c.startAttempt(req)
+		dump := c.newDebugDump(req, dumpRequestBody(body), sent+1)
 		resp, err := c.http.Do(req)
+		sent++
+		c.finishAttempt(timer, req, sent, resp, err)
 		if err != nil {
+			c.finishDebugDump(ctx, dump, nil, nil)
+			lastStatus = 0
 			if ctx.Err() != nil {
 				return Response{}, ctx.Err()
 			}
 			if c.shouldRetry(0, err) && attempt < c.cfg.MaxRetries {
-				c.sleepBackoff(attempt)
+				if !c.budget.allowRetry() {
+					return Response{}, fmt.Errorf("%w: %v", ErrRetryBudgetExhausted, err)
+				}
 				lastErr = err
+				if delay, err = c.retryBackoff(ctx, req, attempt, sent, delay, lastErr); err != nil {
+					return Response{}, err
+				}
 				continue
 			}
-			return Response{}, fmt.Errorf("httpx: request failed: %w", err)
+			return Response{}, fmt.Errorf("httpx: request failed: %w", &NetworkError{Kind: ClassifyError(err), Err: err})
 		}
 
-		body, readErr := io.ReadAll(resp.Body)
+		c.throttle.observe(host, resp.StatusCode)
+		body, encoding, pooled, readErr := c.readBody(resp)
 		resp.Body.Close()
+		c.finishDebugDump(ctx, dump, resp, body)
+		lastStatus = resp.StatusCode
 
 		res := Response{
-			Status:  resp.StatusCode,
-			Body:    body,
-			Headers: resp.Header.Clone(),
-			URL:     u,
+			Status:          resp.StatusCode,
+			Body:            body,
+			Headers:         resp.Header.Clone(),
+			URL:             u,
+			ContentEncoding: encoding,
+			Attempts:        sent,
+			Dump:            dump,
+			pooled:          pooled,
 		}
 
 		if readErr != nil {
 			if c.shouldRetry(resp.StatusCode, readErr) && attempt < c.cfg.MaxRetries {
-				c.sleepBackoff(attempt)
+				if !c.budget.allowRetry() {
+					return res, fmt.Errorf("%w: read body: %v", ErrRetryBudgetExhausted, readErr)
+				}
 				lastErr = readErr
+				if delay, err = c.retryBackoff(ctx, req, attempt, sent, delay, lastErr); err != nil {
+					return Response{}, err
+				}
 				continue
 			}
 			return res, fmt.Errorf("httpx: read body: %w", readErr)
 		}
 
+		if resp.StatusCode == http.StatusUnauthorized && c.cfg.OnUnauthorized != nil && !refreshed {
+			refreshed = true
+			if headers, retry := c.cfg.OnUnauthorized(ctx, res); retry {
+				r.Headers = mergeHeaders(r.Headers, headers)
+				attempt--
+				continue
+			}
+		}
+
 		if c.shouldRetry(resp.StatusCode, nil) && attempt < c.cfg.MaxRetries {
+			if !c.budget.allowRetry() {
+				return res, newHTTPError(res, sent, ErrRetryBudgetExhausted)
+			}
+			res.Release()
 			lastErr = fmt.Errorf("httpx: retryable status %d", resp.StatusCode)
-			c.sleepBackoff(attempt)
+			if delay, err = c.retryBackoff(ctx, req, attempt, sent, delay, lastErr); err != nil {
+				return Response{}, err
+			}
 			continue
 		}
 
 		if c.shouldRetry(resp.StatusCode, nil) && attempt > 0 && attempt >= c.cfg.MaxRetries {
-			return Response{}, fmt.Errorf("%w: retryable status %d", ErrMaxRetries, resp.StatusCode)
+			return Response{}, newHTTPError(res, sent, ErrMaxRetries)
+		}
+
+		if (r.Checksum != nil || r.DigestPolicy != DigestIgnore) && res.Status >= 200 && res.Status < 300 {
+			if err := verifyResponse(res, r.Checksum, r.DigestPolicy); err != nil {
+				return res, err
+			}
+		}
+
+		if len(r.Transforms) > 0 && res.Status != http.StatusNotModified {
+			// Transformed bodies may alias the input, so they are never
+			// borrowed.
+			if res.pooled != nil {
+				res.Body = bytes.Clone(res.Body)
+				res.Release()
+				res.pooled = nil
+			}
+			if res.Body, err = applyTransforms(res.Body, res.Headers, r.Transforms); err != nil {
+				return res, err
+			}
 		}
 
 		return res, nil
@@ -186,6 +540,12 @@ func (c *realClient) setRequestHeaders(req *http.Request, customHeaders map[stri
 		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 	}
 
+	if _, ok := headerLookup(customHeaders, "Accept-Encoding"); !ok && !c.cfg.DisableCompression && req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+
+	c.setContextHeaders(req, customHeaders)
+
 	for k, v := range customHeaders {
 		req.Header.Set(k, v)
 	}
@@ -196,7 +556,7 @@ func (c *realClient) shouldRetry(status int, err error) bool {
 		return c.cfg.RetryOn(status, err)
 	}
 	if err != nil {
-		return true
+		return c.retryErrorKind(err)
 	}
 	for _, s := range c.cfg.RetryStatus {
 		if status == s {
@@ -206,14 +566,18 @@ func (c *realClient) shouldRetry(status int, err error) bool {
 	return false
 }
 
-func (c *realClient) sleepBackoff(attempt int) {
-	backoff := float64(c.cfg.BackoffInitial) * math.Pow(2, float64(attempt))
-	backoff += float64(time.Duration(rand.Intn(250)) * time.Millisecond)
-	delay := time.Duration(backoff)
-	if delay > c.cfg.BackoffMax {
-		delay = c.cfg.BackoffMax
+// sleepBackoff waits before the next retry and returns the delay used, to be
+// passed back as prev on the following call. It stops early when ctx ends.
+func (c *realClient) sleepBackoff(ctx context.Context, attempt int, prev time.Duration) (time.Duration, error) {
+	delay := c.backoff().Delay(attempt, prev)
+	return delay, c.sleep(ctx, delay)
+}
+
+func (c *realClient) backoff() Backoff {
+	if c.cfg.Backoff != nil {
+		return c.cfg.Backoff
 	}
-	time.Sleep(delay)
+	return ExponentialBackoff{Initial: c.cfg.BackoffInitial, Max: c.cfg.BackoffMax}
 }
 
 func (c *realClient) pickUA() string {
@@ -259,6 +623,22 @@ func buildURL(raw string, params map[string]string) (string, error) {
 	return u.String(), nil
 }
 
+func mergeHeaders(base, override map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		for existing := range merged {
+			if strings.EqualFold(existing, k) {
+				delete(merged, existing)
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
 func headerLookup(h map[string]string, key string) (string, bool) {
 	for k, v := range h {
 		if strings.EqualFold(k, key) {
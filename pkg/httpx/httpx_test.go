@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -478,21 +479,89 @@ func TestShouldRetryWithCustomLogic(t *testing.T) {
 }
 
 func TestSleepBackoff(t *testing.T) {
+	clock := newFakeClock()
 	client := &realClient{
 		cfg: Config{
 			BackoffInitial: 10 * time.Millisecond,
 			BackoffMax:     100 * time.Millisecond,
+			Sleeper:        clock,
 		},
 	}
 
-	start := time.Now()
-	client.sleepBackoff(2)
-	duration := time.Since(start)
-
-	if duration < 10*time.Millisecond {
+	delay, err := client.sleepBackoff(context.Background(), 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if delay < 10*time.Millisecond {
 		t.Error("expected backoff to take at least initial duration")
 	}
-	if duration > 200*time.Millisecond {
-		t.Error("expected backoff to not exceed reasonable time")
+	if delay > 100*time.Millisecond {
+		t.Error("expected backoff to not exceed max")
+	}
+	if sleeps := clock.Sleeps(); len(sleeps) != 1 || sleeps[0] != delay {
+		t.Errorf("sleeps = %v, want [%v]", sleeps, delay)
+	}
+}
+
+func TestOnUnauthorizedRetriesOnceWithNewHeaders(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	var refreshes int32
+	client := New(Config{
+		Timeout: 5 * time.Second,
+		OnUnauthorized: func(ctx context.Context, resp Response) (map[string]string, bool) {
+			atomic.AddInt32(&refreshes, 1)
+			return map[string]string{"authorization": "Bearer fresh"}, true
+		},
+	})
+
+	resp, err := client.Do(context.Background(), Request{
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer stale"},
+		Body:    io.NopCloser(strings.NewReader("payload")),
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.Status != http.StatusOK || string(resp.Body) != "payload" {
+		t.Fatalf("unexpected response: %d %q", resp.Status, resp.Body)
+	}
+	if calls != 2 || refreshes != 1 {
+		t.Fatalf("calls = %d, refreshes = %d, want 2 and 1", calls, refreshes)
+	}
+}
+
+func TestOnUnauthorizedOnlyRefreshesOnce(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := New(Config{
+		Timeout: 5 * time.Second,
+		OnUnauthorized: func(ctx context.Context, resp Response) (map[string]string, bool) {
+			return map[string]string{"Authorization": "Bearer still-bad"}, true
+		},
+	})
+
+	resp, err := client.DoGET(context.Background(), server.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("DoGET() error = %v", err)
+	}
+	if resp.Status != http.StatusUnauthorized || calls != 2 {
+		t.Fatalf("status = %d, calls = %d, want 401 and 2", resp.Status, calls)
 	}
 }
@@ -14,6 +14,54 @@ type Client struct {
 	mock.Mock
 }
 
+// Close provides a mock function with given fields: ctx
+func (_m *Client) Close(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Close")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DialWebSocket provides a mock function with given fields: ctx, rawURL, headers
+func (_m *Client) DialWebSocket(ctx context.Context, rawURL string, headers map[string]string) (*httpx.WebSocket, error) {
+	ret := _m.Called(ctx, rawURL, headers)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DialWebSocket")
+	}
+
+	var r0 *httpx.WebSocket
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]string) (*httpx.WebSocket, error)); ok {
+		return rf(ctx, rawURL, headers)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]string) *httpx.WebSocket); ok {
+		r0 = rf(ctx, rawURL, headers)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*httpx.WebSocket)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, map[string]string) error); ok {
+		r1 = rf(ctx, rawURL, headers)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Do provides a mock function with given fields: ctx, req
 func (_m *Client) Do(ctx context.Context, req httpx.Request) (httpx.Response, error) {
 	ret := _m.Called(ctx, req)
@@ -42,6 +90,26 @@ func (_m *Client) Do(ctx context.Context, req httpx.Request) (httpx.Response, er
 	return r0, r1
 }
 
+// DoBatch provides a mock function with given fields: ctx, reqs, concurrency
+func (_m *Client) DoBatch(ctx context.Context, reqs []httpx.Request, concurrency int) []httpx.Result {
+	ret := _m.Called(ctx, reqs, concurrency)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DoBatch")
+	}
+
+	var r0 []httpx.Result
+	if rf, ok := ret.Get(0).(func(context.Context, []httpx.Request, int) []httpx.Result); ok {
+		r0 = rf(ctx, reqs, concurrency)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]httpx.Result)
+		}
+	}
+
+	return r0
+}
+
 // DoGET provides a mock function with given fields: ctx, rawURL, params, headers
 func (_m *Client) DoGET(ctx context.Context, rawURL string, params map[string]string, headers map[string]string) (httpx.Response, error) {
 	ret := _m.Called(ctx, rawURL, params, headers)
@@ -70,6 +138,84 @@ func (_m *Client) DoGET(ctx context.Context, rawURL string, params map[string]st
 	return r0, r1
 }
 
+// DoSSE provides a mock function with given fields: ctx, req
+func (_m *Client) DoSSE(ctx context.Context, req httpx.Request) (*httpx.SSEStream, error) {
+	ret := _m.Called(ctx, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DoSSE")
+	}
+
+	var r0 *httpx.SSEStream
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, httpx.Request) (*httpx.SSEStream, error)); ok {
+		return rf(ctx, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, httpx.Request) *httpx.SSEStream); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*httpx.SSEStream)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, httpx.Request) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DownloadToFile provides a mock function with given fields: ctx, rawURL, path, opts
+func (_m *Client) DownloadToFile(ctx context.Context, rawURL string, path string, opts httpx.DownloadOptions) (httpx.DownloadResult, error) {
+	ret := _m.Called(ctx, rawURL, path, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DownloadToFile")
+	}
+
+	var r0 httpx.DownloadResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, httpx.DownloadOptions) (httpx.DownloadResult, error)); ok {
+		return rf(ctx, rawURL, path, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, httpx.DownloadOptions) httpx.DownloadResult); ok {
+		r0 = rf(ctx, rawURL, path, opts)
+	} else {
+		r0 = ret.Get(0).(httpx.DownloadResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, httpx.DownloadOptions) error); ok {
+		r1 = rf(ctx, rawURL, path, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ThrottleStats provides a mock function with no fields
+func (_m *Client) ThrottleStats() map[string]httpx.ThrottleStat {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ThrottleStats")
+	}
+
+	var r0 map[string]httpx.ThrottleStat
+	if rf, ok := ret.Get(0).(func() map[string]httpx.ThrottleStat); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]httpx.ThrottleStat)
+		}
+	}
+
+	return r0
+}
+
 // NewClient creates a new instance of Client. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewClient(t interface {
@@ -0,0 +1,89 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"syscall"
+)
+
+// ErrorKind classifies transport errors for retry decisions.
+type ErrorKind string
+
+const (
+	ErrorKindDNS               ErrorKind = "dns"
+	ErrorKindConnectionRefused ErrorKind = "connection_refused"
+	ErrorKindConnectionReset   ErrorKind = "connection_reset"
+	ErrorKindTLS               ErrorKind = "tls"
+	ErrorKindTimeout           ErrorKind = "timeout"
+	ErrorKindBlocked           ErrorKind = "blocked" // refused by Config.HostPolicy
+	ErrorKindOther             ErrorKind = "other"
+)
+
+// DefaultRetryErrorKinds are retried when Config.RetryErrorKinds is empty.
+// TLS errors (bad certificates, handshake alerts) are permanent and left out.
+var DefaultRetryErrorKinds = []ErrorKind{
+	ErrorKindDNS,
+	ErrorKindConnectionRefused,
+	ErrorKindConnectionReset,
+	ErrorKindTimeout,
+	ErrorKindOther,
+}
+
+// NetworkError is returned, wrapped, when a request fails before a response
+// arrives.
+type NetworkError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *NetworkError) Error() string { return e.Err.Error() }
+
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// ClassifyError returns the kind of a transport error.
+func ClassifyError(err error) ErrorKind {
+	var (
+		netErr   *NetworkError
+		dnsErr   *net.DNSError
+		certErr  *tls.CertificateVerificationError
+		alertErr tls.AlertError
+		recErr   tls.RecordHeaderError
+		authErr  x509.UnknownAuthorityError
+		hostErr  x509.HostnameError
+		invErr   x509.CertificateInvalidError
+		timeout  interface{ Timeout() bool }
+	)
+	switch {
+	case errors.As(err, &netErr):
+		return netErr.Kind
+	case errors.Is(err, ErrHostBlocked):
+		return ErrorKindBlocked
+	case errors.As(err, &dnsErr):
+		return ErrorKindDNS
+	case errors.As(err, &certErr), errors.As(err, &alertErr), errors.As(err, &recErr),
+		errors.As(err, &authErr), errors.As(err, &hostErr), errors.As(err, &invErr):
+		return ErrorKindTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &timeout) && timeout.Timeout():
+		return ErrorKindTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorKindConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE),
+		errors.Is(err, syscall.ECONNABORTED), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return ErrorKindConnectionReset
+	default:
+		return ErrorKindOther
+	}
+}
+
+func (c *realClient) retryErrorKind(err error) bool {
+	kinds := c.cfg.RetryErrorKinds
+	if len(kinds) == 0 {
+		kinds = DefaultRetryErrorKinds
+	}
+	return slices.Contains(kinds, ClassifyError(err))
+}
@@ -0,0 +1,99 @@
+package httpx
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"dns", &net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}, ErrorKindDNS},
+		{"unknown authority", fmt.Errorf("tls: %w", x509.UnknownAuthorityError{}), ErrorKindTLS},
+		{"hostname", x509.HostnameError{Host: "example.com"}, ErrorKindTLS},
+		{"refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ErrorKindConnectionRefused},
+		{"reset", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, ErrorKindConnectionReset},
+		{"unexpected eof", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), ErrorKindConnectionReset},
+		{"deadline", context.DeadlineExceeded, ErrorKindTimeout},
+		{"net timeout", &net.OpError{Op: "dial", Err: timeoutErr{}}, ErrorKindTimeout},
+		{"other", errors.New("boom"), ErrorKindOther},
+		{"already classified", &NetworkError{Kind: ErrorKindDNS, Err: errors.New("x")}, ErrorKindDNS},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("%s: ClassifyError() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestCertificateErrorsAreNotRetried(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	attempts := 0
+	client := New(Config{
+		MaxRetries: 3,
+		Backoff:    ConstantBackoff{Interval: time.Millisecond},
+		OnAttempt:  func(ctx context.Context, a AttemptInfo) { attempts++ },
+	})
+	_, err := client.DoGET(context.Background(), server.URL, nil, nil)
+
+	var netErr *NetworkError
+	if !errors.As(err, &netErr) || netErr.Kind != ErrorKindTLS {
+		t.Fatalf("err = %v, want TLS NetworkError", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestRetryErrorKindsSelectsRetries(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	for _, tt := range []struct {
+		kinds []ErrorKind
+		want  int
+	}{
+		{nil, 3},
+		{[]ErrorKind{ErrorKindTimeout}, 1},
+	} {
+		attempts := 0
+		client := New(Config{
+			MaxRetries:      2,
+			Backoff:         ConstantBackoff{Interval: time.Millisecond},
+			RetryErrorKinds: tt.kinds,
+			OnAttempt:       func(ctx context.Context, a AttemptInfo) { attempts++ },
+		})
+		_, err := client.DoGET(context.Background(), "http://"+addr, nil, nil)
+		if ClassifyError(err) != ErrorKindConnectionRefused {
+			t.Fatalf("kinds %v: err = %v, want connection refused", tt.kinds, err)
+		}
+		if attempts != tt.want {
+			t.Errorf("kinds %v: attempts = %d, want %d", tt.kinds, attempts, tt.want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package httpx
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// NormalizeURL returns rawURL in a canonical form, so that different
+// spellings of one resource compare equal, e.g. as cache keys or in
+// allowlists:
+//
+//   - the scheme and host are lower-cased and internationalized host names
+//     converted to punycode ("Bücher.de" becomes "xn--bcher-kva.de"),
+//     without a trailing dot
+//   - default ports are dropped (":80" for http and ws, ":443" for https
+//     and wss)
+//   - "." and ".." path segments are resolved and an empty path becomes "/"
+//   - percent-encoded unreserved characters are decoded ("%7E" becomes
+//     "~"), other escapes use upper-case hex, and characters that must be
+//     escaped are
+//   - the fragment, which is never sent, is removed
+//
+// The order of query parameters is kept. The client sends every request to
+// its normalized URL unless Config.DisableURLNormalization is set.
+func NormalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Opaque != "" {
+		return "", fmt.Errorf("opaque URL %q", rawURL)
+	}
+
+	if u.Host != "" {
+		host, err := asciiHost(strings.TrimSuffix(u.Hostname(), "."))
+		if err != nil {
+			return "", fmt.Errorf("host %q: %w", u.Hostname(), err)
+		}
+		port := u.Port()
+		if port == defaultPorts[u.Scheme] {
+			port = ""
+		}
+		if port != "" || strings.Contains(host, ":") {
+			host = net.JoinHostPort(host, port)
+			host = strings.TrimSuffix(host, ":") // IPv6 without port
+		}
+		u.Host = host
+	}
+
+	path := removeDotSegments(normalizeEscapes(u.EscapedPath()))
+	if path == "" && u.Host != "" {
+		path = "/"
+	}
+	if u.Path, err = url.PathUnescape(path); err != nil {
+		return "", err
+	}
+	u.RawPath = path
+	u.RawQuery = normalizeEscapes(u.RawQuery)
+	u.Fragment, u.RawFragment = "", ""
+	return u.String(), nil
+}
+
+// requestURL is the URL r is sent to: r.URL with its path and query
+// parameters filled in, normalized unless disabled.
+func (c *realClient) requestURL(r Request) (string, error) {
+	u, err := buildRequestURL(r)
+	if err != nil {
+		return "", err
+	}
+	return c.normalizeURL(u)
+}
+
+func (c *realClient) normalizeURL(rawURL string) (string, error) {
+	if c.cfg.DisableURLNormalization {
+		return rawURL, nil
+	}
+	return NormalizeURL(rawURL)
+}
+
+// asciiHost lower-cases host and converts an internationalized name to
+// punycode. IP literals and ASCII names are only lower-cased, so names that
+// are not strictly valid DNS labels, such as "my_service", still work. A
+// leading "*." wildcard is kept.
+func asciiHost(host string) (string, error) {
+	ascii := true
+	for i := 0; i < len(host); i++ {
+		if host[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return strings.ToLower(host), nil
+	}
+	wildcard := strings.HasPrefix(host, "*.")
+	if wildcard {
+		host = host[2:]
+	}
+	host, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", err
+	}
+	if wildcard {
+		host = "*." + host
+	}
+	return host, nil
+}
+
+// normalizeEscapes decodes percent-encoded unreserved characters, upper-cases
+// the hex digits of the remaining escapes and escapes bytes that may not
+// appear in a URL. Malformed escapes are escaped themselves.
+func normalizeEscapes(s string) string {
+	const upperhex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			d := unhex(s[i+1])<<4 | unhex(s[i+2])
+			if isUnreserved(d) {
+				b.WriteByte(d)
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(upperhex[d>>4])
+				b.WriteByte(upperhex[d&15])
+			}
+			i += 2
+		case c == '%' || c <= ' ' || c >= 0x7f || strings.IndexByte(`"<>\^`+"`{|}", c) >= 0:
+			b.WriteByte('%')
+			b.WriteByte(upperhex[c>>4])
+			b.WriteByte(upperhex[c&15])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// removeDotSegments resolves "." and ".." segments as in RFC 3986, section
+// 5.2.4. Empty segments are kept: "/a//b" stays as it is.
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+	segs := strings.Split(path, "/")
+	out := make([]string, 0, len(segs))
+	for i, s := range segs {
+		if s != "." && s != ".." {
+			out = append(out, s)
+			continue
+		}
+		if s == ".." && len(out) > 1 {
+			out = out[:len(out)-1]
+		}
+		if i == len(segs)-1 {
+			out = append(out, "") // "/a/.." is "/", not ""
+		}
+	}
+	return strings.Join(out, "/")
+}
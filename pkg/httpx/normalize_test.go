@@ -0,0 +1,86 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"HTTPS://Apps.Apple.COM:443/us/app/id1?l=en", "https://apps.apple.com/us/app/id1?l=en"},
+		{"http://example.com:80", "http://example.com/"},
+		{"http://example.com.:8080/a", "http://example.com:8080/a"},
+		{"wss://example.com:443/ws", "wss://example.com/ws"},
+		{"https://Bücher.de/katalog", "https://xn--bcher-kva.de/katalog"},
+		{"https://xn--bcher-kva.de/katalog", "https://xn--bcher-kva.de/katalog"},
+		{"http://[::1]:80/x", "http://[::1]/x"},
+		{"http://[::1]:8080/x", "http://[::1]:8080/x"},
+		{"http://my_service:8080/x", "http://my_service:8080/x"},
+		{"https://example.com/a/./b/../c", "https://example.com/a/c"},
+		{"https://example.com/a/b/..", "https://example.com/a/"},
+		{"https://example.com/../../a", "https://example.com/a"},
+		{"https://example.com/%2e%2E/a", "https://example.com/a"},
+		{"https://example.com/a//b", "https://example.com/a//b"},
+		{"https://example.com/%7euser/%41%2f%3a", "https://example.com/~user/A%2F%3A"},
+		{"https://example.com/s?q=caf%c3%a9&x=%7E&y=a b", "https://example.com/s?q=caf%C3%A9&x=~&y=a%20b"},
+		{"https://example.com/s?b=2&a=1#top", "https://example.com/s?b=2&a=1"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeURL(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+		if again, _ := NormalizeURL(got); again != got {
+			t.Errorf("NormalizeURL(%q) = %q, not idempotent", got, again)
+		}
+	}
+
+	if _, err := NormalizeURL("mailto:someone@example.com"); err == nil {
+		t.Error("expected error for opaque URL")
+	}
+}
+
+func TestDoNormalizesURL(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.RawPath
+		if gotPath == "" {
+			gotPath = r.URL.Path
+		}
+	}))
+	defer server.Close()
+
+	resp, err := New(Config{}).Do(context.Background(), Request{URL: server.URL + "/apps/%7e/../%41/x#frag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/apps/A/x" || resp.URL != server.URL+"/apps/A/x" {
+		t.Fatalf("server saw %q, Response.URL = %q", gotPath, resp.URL)
+	}
+
+	resp, err = New(Config{DisableURLNormalization: true}).Do(context.Background(), Request{URL: server.URL + "/a/%7e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.URL != server.URL+"/a/%7e" {
+		t.Fatalf("Response.URL = %q with normalization disabled", resp.URL)
+	}
+}
+
+func TestHostPolicyMatchesPunycode(t *testing.T) {
+	client := New(Config{HostPolicy: &HostPolicy{AllowHosts: []string{"*.bücher.de"}}})
+	_, err := client.Do(context.Background(), Request{URL: "https://evil.example.com/"})
+	if !errors.Is(err, ErrHostBlocked) {
+		t.Fatalf("err = %v, want ErrHostBlocked", err)
+	}
+	guard := newHostGuard(&HostPolicy{AllowHosts: []string{"*.bücher.de"}})
+	u, _ := NormalizeURL("https://shop.BÜCHER.de/")
+	if err := guard.checkURL(u); err != nil {
+		t.Fatalf("checkURL(%q) = %v", u, err)
+	}
+}
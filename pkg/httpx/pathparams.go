@@ -0,0 +1,61 @@
+package httpx
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// buildRequestURL expands r.PathParams into r.URL and adds r.Params to the
+// query.
+func buildRequestURL(r Request) (string, error) {
+	raw, err := expandPath(r.URL, r.PathParams)
+	if err != nil {
+		return "", err
+	}
+	return buildURL(raw, r.Params)
+}
+
+// expandPath replaces {name} placeholders before the query string with the
+// path-escaped value of params[name]. A value cannot add path segments: "/"
+// is escaped and "." and ".." are rejected. URLs are left untouched when
+// params is empty.
+func expandPath(raw string, params map[string]string) (string, error) {
+	if len(params) == 0 {
+		return raw, nil
+	}
+	end := strings.IndexAny(raw, "?#")
+	if end < 0 {
+		end = len(raw)
+	}
+
+	var b strings.Builder
+	rest := raw[:end]
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			if strings.IndexByte(rest, '}') >= 0 {
+				return "", fmt.Errorf("unbalanced '}' in %q", raw)
+			}
+			b.WriteString(rest)
+			break
+		}
+		close := strings.IndexByte(rest[open:], '}')
+		if close < 0 {
+			return "", fmt.Errorf("unclosed '{' in %q", raw)
+		}
+		name := rest[open+1 : open+close]
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("missing path parameter %q", name)
+		}
+		if value == "" || value == "." || value == ".." {
+			return "", fmt.Errorf("invalid value %q for path parameter %q", value, name)
+		}
+		b.WriteString(rest[:open])
+		b.WriteString(url.PathEscape(value))
+		rest = rest[open+close+1:]
+	}
+	b.WriteString(raw[end:])
+	return b.String(), nil
+}
@@ -0,0 +1,69 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExpandPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		params  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"no params", "https://x/apps/{id}", nil, "https://x/apps/{id}", false},
+		{"simple", "https://x/apps/{id}/reviews", map[string]string{"id": "123"}, "https://x/apps/123/reviews", false},
+		{"escapes", "https://x/apps/{id}", map[string]string{"id": "a/b?c d"}, "https://x/apps/a%2Fb%3Fc%20d", false},
+		{"query untouched", "https://x/{country}/app?q={literal}", map[string]string{"country": "us"}, "https://x/us/app?q={literal}", false},
+		{"several", "/{a}/{b}/{a}", map[string]string{"a": "1", "b": "2"}, "/1/2/1", false},
+		{"missing", "/apps/{id}", map[string]string{"other": "1"}, "", true},
+		{"traversal", "/apps/{id}", map[string]string{"id": ".."}, "", true},
+		{"empty value", "/apps/{id}", map[string]string{"id": ""}, "", true},
+		{"unclosed", "/apps/{id", map[string]string{"id": "1"}, "", true},
+		{"stray close", "/apps/id}", map[string]string{"id": "1"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandPath(tt.raw, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("expandPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoWithPathParams(t *testing.T) {
+	var gotPath, gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.EscapedPath(), r.URL.RawQuery
+	}))
+	defer server.Close()
+
+	client := New(Config{})
+	_, err := client.Do(context.Background(), Request{
+		URL:        server.URL + "/apps/{id}/reviews",
+		PathParams: map[string]string{"id": "id/../1"},
+		Params:     map[string]string{"page": "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/apps/id%2F..%2F1/reviews" || gotQuery != "page=2" {
+		t.Fatalf("path = %q, query = %q", gotPath, gotQuery)
+	}
+
+	_, err = client.Do(context.Background(), Request{URL: server.URL + "/apps/{id}", PathParams: map[string]string{}})
+	if err != nil {
+		t.Fatalf("empty PathParams should leave the URL alone: %v", err)
+	}
+	if _, err := client.Do(context.Background(), Request{URL: server.URL + "/apps/{id}", PathParams: map[string]string{"x": "1"}}); err == nil {
+		t.Fatal("expected error for missing path parameter")
+	}
+}
@@ -0,0 +1,113 @@
+package httpx
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBufferClasses are the buffer capacities NewBufferPool pools when
+// called without classes.
+var DefaultBufferClasses = []int{4 << 10, 32 << 10, 256 << 10, 1 << 20, 4 << 20}
+
+// BufferPool reuses buffers for reading response bodies. Buffers are kept
+// per size class so a small body does not pin a large buffer; buffers that
+// grew past the largest class are left to the garbage collector.
+type BufferPool struct {
+	classes []int
+	pools   []sync.Pool
+}
+
+// NewBufferPool creates a pool with the given capacity classes in bytes, or
+// DefaultBufferClasses.
+func NewBufferPool(classes ...int) *BufferPool {
+	if len(classes) == 0 {
+		classes = DefaultBufferClasses
+	}
+	classes = slices.Clone(classes)
+	slices.Sort(classes)
+	classes = slices.Compact(classes)
+	return &BufferPool{classes: classes, pools: make([]sync.Pool, len(classes))}
+}
+
+// Get returns an empty buffer with room for at least sizeHint bytes, or for
+// the smallest class if sizeHint is unknown (<= 0).
+func (p *BufferPool) Get(sizeHint int) *bytes.Buffer {
+	i, _ := slices.BinarySearch(p.classes, sizeHint)
+	if i == len(p.classes) {
+		b := &bytes.Buffer{}
+		b.Grow(sizeHint)
+		return b
+	}
+	if b, ok := p.pools[i].Get().(*bytes.Buffer); ok {
+		return b
+	}
+	b := &bytes.Buffer{}
+	b.Grow(p.classes[i])
+	return b
+}
+
+// Put returns b to the pool. b must not be used afterwards.
+func (p *BufferPool) Put(b *bytes.Buffer) {
+	if b == nil {
+		return
+	}
+	// The class of b is the largest one it can serve.
+	i, found := slices.BinarySearch(p.classes, b.Cap())
+	if !found {
+		i--
+	}
+	if i < 0 || b.Cap() > 2*p.classes[len(p.classes)-1] {
+		return
+	}
+	b.Reset()
+	p.pools[i].Put(b)
+}
+
+// pooledBody is a response body borrowed from a BufferPool.
+type pooledBody struct {
+	pool     *BufferPool
+	buf      *bytes.Buffer
+	released atomic.Bool
+}
+
+func (b *pooledBody) release() {
+	if b != nil && b.released.CompareAndSwap(false, true) {
+		b.pool.Put(b.buf)
+	}
+}
+
+// Release returns a body borrowed from Config.BodyPool to the pool. Body must
+// not be used afterwards, by r or any copy of it. It does nothing for bodies
+// that were not borrowed.
+func (r Response) Release() {
+	r.pooled.release()
+}
+
+// readPooled reads r into a buffer from c's pool. With BorrowBodies the
+// buffer's bytes are returned as is together with the buffer; otherwise they
+// are copied out and the buffer goes straight back to the pool.
+func (c *realClient) readPooled(r io.Reader, sizeHint int64) ([]byte, *pooledBody, error) {
+	if c.cfg.BodyPool == nil {
+		body, err := io.ReadAll(r)
+		return body, nil, err
+	}
+	hint := 0
+	if sizeHint > 0 && sizeHint < 1<<30 {
+		hint = int(sizeHint)
+	}
+	buf := c.cfg.BodyPool.Get(hint)
+	if _, err := buf.ReadFrom(r); err != nil {
+		c.cfg.BodyPool.Put(buf)
+		return nil, nil, err
+	}
+	if c.cfg.BorrowBodies {
+		return buf.Bytes(), &pooledBody{pool: c.cfg.BodyPool, buf: buf}, nil
+	}
+	body := make([]byte, buf.Len())
+	copy(body, buf.Bytes())
+	c.cfg.BodyPool.Put(buf)
+	return body, nil, nil
+}
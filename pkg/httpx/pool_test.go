@@ -0,0 +1,94 @@
+package httpx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBufferPoolSizeClasses(t *testing.T) {
+	p := NewBufferPool(1024, 8192)
+
+	if b := p.Get(0); b.Cap() < 1024 {
+		t.Errorf("Get(0) cap = %d, want >= 1024", b.Cap())
+	}
+	if b := p.Get(2000); b.Cap() < 8192 {
+		t.Errorf("Get(2000) cap = %d, want >= 8192", b.Cap())
+	}
+	if b := p.Get(100_000); b.Cap() < 100_000 {
+		t.Errorf("Get(100000) cap = %d, want >= 100000", b.Cap())
+	}
+
+	b := p.Get(2000)
+	b.WriteString("leftover")
+	p.Put(b)
+	if got := p.Get(2000); got.Len() != 0 || got.Cap() < 8192 {
+		t.Errorf("reused buffer len=%d cap=%d", got.Len(), got.Cap())
+	}
+
+	// Oversized buffers are not pooled; small ones cannot serve any class.
+	p.Put(bytes.NewBuffer(make([]byte, 0, 1<<20)))
+	p.Put(bytes.NewBuffer(make([]byte, 0, 10)))
+}
+
+func TestDoWithBodyPool(t *testing.T) {
+	payload := strings.Repeat("review ", 2000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("gzip") != "" {
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			zw.Write([]byte(payload))
+			zw.Close()
+			return
+		}
+		w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	for _, borrow := range []bool{false, true} {
+		client := New(Config{Timeout: 5 * time.Second, BodyPool: NewBufferPool(), BorrowBodies: borrow})
+		for _, u := range []string{server.URL, server.URL + "?gzip=1"} {
+			res, err := client.DoGET(context.Background(), u, nil, nil)
+			if err != nil {
+				t.Fatalf("DoGET(%s) error = %v", u, err)
+			}
+			if string(res.Body) != payload {
+				t.Errorf("borrow=%v %s: body mismatch (%d bytes)", borrow, u, len(res.Body))
+			}
+			if borrow != (res.pooled != nil) {
+				t.Errorf("borrow=%v: pooled = %v", borrow, res.pooled)
+			}
+			if !borrow && cap(res.Body) != len(res.Body) {
+				t.Errorf("copied body cap = %d, want %d", cap(res.Body), len(res.Body))
+			}
+			res.Release()
+			res.Release() // idempotent
+		}
+	}
+}
+
+func TestBorrowedBodyNotUsedWithTransforms(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	client := New(Config{Timeout: 5 * time.Second, BodyPool: NewBufferPool(), BorrowBodies: true})
+	res, err := client.Do(context.Background(), Request{
+		URL: server.URL,
+		Transforms: []Transformer{TransformerFunc(func(body []byte, _ http.Header) ([]byte, error) {
+			return body, nil
+		})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.pooled != nil || string(res.Body) != "hello" {
+		t.Errorf("body = %q, pooled = %v", res.Body, res.pooled)
+	}
+}
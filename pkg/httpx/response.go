@@ -0,0 +1,158 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Link is one entry of a Link header (RFC 8288).
+type Link struct {
+	URL string
+	// Rel may hold several space-separated relation types.
+	Rel    string
+	Params map[string]string
+}
+
+// JSON decodes the body into v.
+func (r Response) JSON(v any) error {
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return fmt.Errorf("httpx: decode json: %w", err)
+	}
+	return nil
+}
+
+// ContentType returns the lower-cased media type without parameters, e.g.
+// "application/json", or "" when the header is missing or malformed.
+func (r Response) ContentType() string {
+	mediaType, _, err := mime.ParseMediaType(r.Headers.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// IsSuccess reports a 2xx status.
+func (r Response) IsSuccess() bool {
+	return r.Status >= 200 && r.Status < 300
+}
+
+// Links parses the Link headers. Relative URLs are resolved against r.URL.
+func (r Response) Links() []Link {
+	base, _ := url.Parse(r.URL)
+	var links []Link
+	for _, header := range r.Headers.Values("Link") {
+		for _, l := range parseLinkHeader(header) {
+			if base != nil {
+				if ref, err := url.Parse(l.URL); err == nil {
+					l.URL = base.ResolveReference(ref).String()
+				}
+			}
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// Link returns the first link with relation type rel, e.g. "next".
+func (r Response) Link(rel string) (Link, bool) {
+	for _, l := range r.Links() {
+		for _, t := range strings.Fields(l.Rel) {
+			if strings.EqualFold(t, rel) {
+				return l, true
+			}
+		}
+	}
+	return Link{}, false
+}
+
+// RetryAfter returns the wait requested by the Retry-After header, given as
+// seconds or an HTTP date. Dates in the past give zero.
+func (r Response) RetryAfter() (time.Duration, bool) {
+	v := strings.TrimSpace(r.Headers.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	at, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	return max(time.Until(at), 0), true
+}
+
+func parseLinkHeader(header string) []Link {
+	var links []Link
+	s := header
+	for {
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			return links
+		}
+		end := strings.IndexByte(s[start:], '>')
+		if end < 0 {
+			return links
+		}
+		l := Link{URL: strings.TrimSpace(s[start+1 : start+end])}
+		s = s[start+end+1:]
+
+		// Parameters run until the next comma outside a quoted string.
+		params, rest := splitLinkParams(s)
+		s = rest
+		for _, p := range params {
+			k, v, _ := strings.Cut(p, "=")
+			k = strings.ToLower(strings.TrimSpace(k))
+			v = strings.Trim(strings.TrimSpace(v), `"`)
+			if k == "" {
+				continue
+			}
+			if k == "rel" {
+				if l.Rel == "" {
+					l.Rel = v
+				}
+				continue
+			}
+			if l.Params == nil {
+				l.Params = make(map[string]string)
+			}
+			l.Params[k] = v
+		}
+		links = append(links, l)
+	}
+}
+
+func splitLinkParams(s string) (params []string, rest string) {
+	quoted := false
+	last := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ';', ',':
+			if quoted {
+				continue
+			}
+			if p := strings.TrimSpace(s[last:i]); p != "" {
+				params = append(params, p)
+			}
+			last = i + 1
+			if s[i] == ',' {
+				return params, s[i+1:]
+			}
+		}
+	}
+	if p := strings.TrimSpace(s[last:]); p != "" {
+		params = append(params, p)
+	}
+	return params, ""
+}
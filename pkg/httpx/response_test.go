@@ -0,0 +1,81 @@
+package httpx
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResponseJSON(t *testing.T) {
+	var v struct {
+		Name string `json:"name"`
+	}
+	if err := (Response{Body: []byte(`{"name":"app"}`)}).JSON(&v); err != nil || v.Name != "app" {
+		t.Fatalf("JSON() = %v, name = %q", err, v.Name)
+	}
+	if err := (Response{Body: []byte(`<html>`)}).JSON(&v); err == nil {
+		t.Fatal("expected decode error")
+	}
+}
+
+func TestResponseContentTypeAndSuccess(t *testing.T) {
+	r := Response{Status: 204, Headers: http.Header{"Content-Type": {"Application/JSON; charset=utf-8"}}}
+	if got := r.ContentType(); got != "application/json" {
+		t.Errorf("ContentType() = %q", got)
+	}
+	if !r.IsSuccess() {
+		t.Error("204 should be a success")
+	}
+	if (Response{Status: 304}).IsSuccess() || (Response{}).ContentType() != "" {
+		t.Error("unexpected accessor result for empty response")
+	}
+}
+
+func TestResponseLinks(t *testing.T) {
+	r := Response{
+		URL: "https://api.example.com/v1/reviews?page=2",
+		Headers: http.Header{"Link": {
+			`<https://api.example.com/v1/reviews?page=3>; rel="next", </v1/reviews?page=1>; rel="prev first"`,
+			`<https://cdn.example.com/a,b>; rel=preload; title="a; b, c"`,
+		}},
+	}
+	links := r.Links()
+	if len(links) != 3 {
+		t.Fatalf("Links() = %+v", links)
+	}
+	if links[1].URL != "https://api.example.com/v1/reviews?page=1" {
+		t.Errorf("relative link = %q", links[1].URL)
+	}
+	if links[2].URL != "https://cdn.example.com/a,b" || links[2].Params["title"] != "a; b, c" {
+		t.Errorf("link with params = %+v", links[2])
+	}
+
+	if l, ok := r.Link("first"); !ok || l.URL != links[1].URL {
+		t.Errorf("Link(first) = %+v, %v", l, ok)
+	}
+	if _, ok := r.Link("last"); ok {
+		t.Error("Link(last) should not be found")
+	}
+}
+
+func TestResponseRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		min    time.Duration
+		max    time.Duration
+		ok     bool
+	}{
+		{"120", 120 * time.Second, 120 * time.Second, true},
+		{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 50 * time.Second, time.Minute, true},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, 0, true},
+		{"-1", 0, 0, false},
+		{"soon", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := (Response{Headers: http.Header{"Retry-After": {tt.header}}}).RetryAfter()
+		if ok != tt.ok || got < tt.min || got > tt.max {
+			t.Errorf("RetryAfter(%q) = %v, %v", tt.header, got, ok)
+		}
+	}
+}
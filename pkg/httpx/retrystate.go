@@ -0,0 +1,72 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// RetryState is passed to Config.OnBeforeAttempt before each request is
+// sent.
+type RetryState struct {
+	Attempt     int // 1 for the first request
+	MaxAttempts int // MaxRetries + 1
+	// Key is random per Do call and the same for all of its attempts,
+	// including failover endpoints and the resend after OnUnauthorized, so
+	// it can be sent as an idempotency key.
+	Key string
+	// LastStatus and LastErr describe the previous attempt. LastStatus is 0
+	// when it got no response, LastErr is nil when it was not retried.
+	LastStatus int
+	LastErr    error
+}
+
+// IsRetry reports whether an earlier attempt was already sent.
+func (s RetryState) IsRetry() bool {
+	return s.Attempt > 1
+}
+
+// AttemptHeaders returns an OnBeforeAttempt hook that sets attemptHeader to
+// the attempt number and keyHeader to RetryState.Key. Either name can be
+// empty to skip it. A keyHeader the caller set on the request is kept, so a
+// key chosen upstream survives retries too.
+func AttemptHeaders(attemptHeader, keyHeader string) func(context.Context, *http.Request, RetryState) error {
+	return func(_ context.Context, req *http.Request, s RetryState) error {
+		if attemptHeader != "" {
+			req.Header.Set(attemptHeader, strconv.Itoa(s.Attempt))
+		}
+		if keyHeader != "" && req.Header.Get(keyHeader) == "" {
+			req.Header.Set(keyHeader, s.Key)
+		}
+		return nil
+	}
+}
+
+// withRetryKey gives r the key reported as RetryState.Key, unless it has
+// one or no hook wants it.
+func (c *realClient) withRetryKey(r Request) Request {
+	if c.cfg.OnBeforeAttempt != nil && r.retryKey == "" {
+		r.retryKey = uuid.NewString()
+	}
+	return r
+}
+
+func (c *realClient) beforeAttempt(ctx context.Context, req *http.Request, r Request, sent, lastStatus int, lastErr error) error {
+	if c.cfg.OnBeforeAttempt == nil {
+		return nil
+	}
+	err := c.cfg.OnBeforeAttempt(ctx, req, RetryState{
+		Attempt:     sent + 1,
+		MaxAttempts: c.cfg.MaxRetries + 1,
+		Key:         r.retryKey,
+		LastStatus:  lastStatus,
+		LastErr:     lastErr,
+	})
+	if err != nil {
+		return fmt.Errorf("httpx: before attempt: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAttemptHeadersKeepKeyAcrossRetries(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen []http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Clone())
+		n := len(seen)
+		mu.Unlock()
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var states []RetryState
+	hook := AttemptHeaders("X-Attempt", "Idempotency-Key")
+	client := New(Config{
+		MaxRetries: 2,
+		Backoff:    ConstantBackoff{Interval: time.Millisecond},
+		OnBeforeAttempt: func(ctx context.Context, req *http.Request, s RetryState) error {
+			states = append(states, s)
+			return hook(ctx, req, s)
+		},
+	})
+	if _, err := client.Do(context.Background(), Request{Method: http.MethodPost, URL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("requests = %d, want 3", len(seen))
+	}
+	key := seen[0].Get("Idempotency-Key")
+	if key == "" {
+		t.Fatal("Idempotency-Key not set")
+	}
+	for i, h := range seen {
+		if got, want := h.Get("X-Attempt"), strconv.Itoa(i+1); got != want {
+			t.Errorf("request %d X-Attempt = %q, want %q", i, got, want)
+		}
+		if h.Get("Idempotency-Key") != key {
+			t.Errorf("request %d Idempotency-Key = %q, want %q", i, h.Get("Idempotency-Key"), key)
+		}
+	}
+	if states[0].IsRetry() || states[0].LastStatus != 0 || states[0].MaxAttempts != 3 {
+		t.Errorf("first state = %+v", states[0])
+	}
+	if !states[2].IsRetry() || states[2].LastStatus != http.StatusServiceUnavailable || states[2].LastErr == nil {
+		t.Errorf("third state = %+v", states[2])
+	}
+
+	// A new call gets a new key.
+	client.Do(context.Background(), Request{Method: http.MethodPost, URL: server.URL})
+	if seen[3].Get("Idempotency-Key") == key {
+		t.Error("second call reused the key of the first")
+	}
+}
+
+func TestAttemptHeadersKeepCallerKey(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Idempotency-Key")
+	}))
+	defer server.Close()
+
+	client := New(Config{OnBeforeAttempt: AttemptHeaders("", "Idempotency-Key")})
+	if _, err := client.Do(context.Background(), Request{
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Headers: map[string]string{"Idempotency-Key": "order-42"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got != "order-42" {
+		t.Errorf("Idempotency-Key = %q, want order-42", got)
+	}
+}
+
+func TestOnBeforeAttemptErrorAborts(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls++ }))
+	defer server.Close()
+
+	errNoKey := errors.New("no key")
+	client := New(Config{OnBeforeAttempt: func(context.Context, *http.Request, RetryState) error { return errNoKey }})
+	if _, err := client.DoGET(context.Background(), server.URL, nil, nil); !errors.Is(err, errNoKey) {
+		t.Fatalf("err = %v, want errNoKey", err)
+	}
+	if calls != 0 {
+		t.Errorf("server got %d requests, want 0", calls)
+	}
+}
@@ -0,0 +1,330 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotEventStream = errors.New("httpx: response is not text/event-stream")
+	ErrStreamClosed   = errors.New("httpx: event stream closed by server")
+)
+
+// SSEEvent is one Server-Sent Event. Event is "message" unless the server
+// named it; Data lines are joined with "\n".
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// SSEStream delivers the events of a DoSSE call. The connection is
+// re-established with Last-Event-ID whenever it drops; Events is closed when
+// the stream ends for good, after which Err reports why.
+type SSEStream struct {
+	events chan SSEEvent
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+func (s *SSEStream) Events() <-chan SSEEvent { return s.events }
+
+// Err returns the reason the stream ended. It is nil while the stream is
+// running and after Close.
+func (s *SSEStream) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close stops the stream and waits for its connection to be released.
+func (s *SSEStream) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// DoSSE opens a Server-Sent Events stream. The first connection is made
+// before DoSSE returns, so a bad URL, a non-retryable status or a response
+// that is not text/event-stream fail right away. Later disconnects are
+// retried after the server's retry: interval (or the client's backoff when
+// none was sent); MaxRetries consecutive failed reconnects end the stream.
+// A 204 from the server also ends it, as the SSE spec requires.
+//
+// The stream counts as an in-flight request until it ends; Close on the
+// client closes open streams.
+func (c *realClient) DoSSE(ctx context.Context, r Request) (*SSEStream, error) {
+	if r.URL == "" {
+		return nil, ErrEmptyURL
+	}
+	if hc := c.forHost(r.URL); hc != c {
+		return hc.DoSSE(ctx, r)
+	}
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+	u, err := c.requestURL(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	if err := c.guard.checkURL(u); err != nil {
+		return nil, err
+	}
+	body, err := newRequestBody(r, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &SSEStream{
+		events: make(chan SSEEvent),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	conn := &sseConn{c: c, r: r, url: u, body: body}
+
+	resp, err := conn.connect(ctx)
+	if err != nil {
+		cancel()
+		c.release()
+		return nil, err
+	}
+	if !c.trackStream(s) {
+		resp.Body.Close()
+		cancel()
+		c.release()
+		return nil, ErrClientClosed
+	}
+
+	go func() {
+		defer c.release()
+		defer c.untrackStream(s)
+		defer close(s.done)
+		defer close(s.events)
+		defer cancel()
+		s.err = conn.run(ctx, resp, s.events)
+		if ctx.Err() != nil {
+			s.err = nil
+		}
+	}()
+	return s, nil
+}
+
+type sseConn struct {
+	c     *realClient
+	r     Request
+	url   string
+	body  *requestBody
+	hc    *http.Client
+	sent  int
+	last  string
+	retry time.Duration
+}
+
+// connect opens the stream, retrying failed attempts like Do.
+func (s *sseConn) connect(ctx context.Context) (*http.Response, error) {
+	if s.hc == nil {
+		hc := *s.c.http
+		hc.Timeout = 0
+		s.hc = &hc
+	}
+
+	var (
+		lastErr error
+		delay   time.Duration
+	)
+	for attempt := 0; attempt <= s.c.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if !s.c.budget.allowRetry() {
+				return nil, fmt.Errorf("%w: %v", ErrRetryBudgetExhausted, lastErr)
+			}
+			if s.retry > 0 {
+				delay = s.retry
+			} else {
+				delay = s.c.backoff().Delay(attempt-1, delay)
+			}
+			if err := s.c.sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := s.open(ctx)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var status *HTTPError
+		if errors.As(err, &status) && !s.c.shouldRetry(status.Status, nil) {
+			return nil, err
+		}
+		if errors.Is(err, ErrNotEventStream) || errors.Is(err, ErrStreamClosed) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	var status *HTTPError
+	if errors.As(lastErr, &status) {
+		status.cause = ErrMaxRetries
+		return nil, status
+	}
+	return nil, fmt.Errorf("%w: %v", ErrMaxRetries, lastErr)
+}
+
+func (s *sseConn) open(ctx context.Context) (*http.Response, error) {
+	body, err := s.body.reader(s.sent)
+	if err != nil {
+		return nil, fmt.Errorf("httpx: rewind body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, s.r.Method, s.url, body)
+	if err != nil {
+		return nil, fmt.Errorf("httpx: build request: %w", err)
+	}
+	if s.body.size > 0 {
+		req.ContentLength = s.body.size
+	}
+	if s.body.getBody != nil {
+		req.GetBody = s.body.getBody
+	}
+	if s.body.contentType != "" {
+		if _, ok := headerLookup(s.r.Headers, "Content-Type"); !ok {
+			req.Header.Set("Content-Type", s.body.contentType)
+		}
+	}
+	s.c.setRequestHeaders(req, s.r.Headers)
+	if err := s.c.applyAuth(req, s.r.Headers); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	// Compressed streams are buffered by the decoder; ask for plain bytes.
+	req.Header.Set("Accept-Encoding", "identity")
+	if s.last != "" {
+		req.Header.Set("Last-Event-ID", s.last)
+	}
+
+	if err := s.c.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	s.c.budget.recordRequest()
+	req, timer := s.c.startAttempt(req)
+	resp, err := s.hc.Do(req)
+	s.sent++
+	s.c.finishAttempt(timer, req, s.sent, resp, err)
+	if err != nil {
+		return nil, fmt.Errorf("httpx: request failed: %w", err)
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		resp.Body.Close()
+		return nil, ErrStreamClosed
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		resp.Body.Close()
+		res := Response{Status: resp.StatusCode, Body: body, URL: s.url}
+		return nil, newHTTPError(res, s.sent, ErrNonRetryableResp)
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "text/event-stream" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %q", ErrNotEventStream, resp.Header.Get("Content-Type"))
+	}
+	return resp, nil
+}
+
+// run reads events until the stream ends for good.
+func (s *sseConn) run(ctx context.Context, resp *http.Response, out chan<- SSEEvent) error {
+	for {
+		// Both EOF and read errors mean the connection dropped; reconnect.
+		s.read(ctx, resp.Body, out)
+		resp.Body.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		delay := s.retry
+		if delay <= 0 {
+			delay = s.c.backoff().Delay(0, 0)
+		}
+		if err := s.c.sleep(ctx, delay); err != nil {
+			return err
+		}
+		var err error
+		if resp, err = s.connect(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// read parses the event stream in body and sends complete events to out.
+func (s *sseConn) read(ctx context.Context, body io.Reader, out chan<- SSEEvent) error {
+	br := bufio.NewReader(body)
+	var (
+		ev   SSEEvent
+		data strings.Builder
+		has  bool
+	)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			if has {
+				ev.ID = s.last
+				ev.Data = strings.TrimSuffix(data.String(), "\n")
+				if ev.Event == "" {
+					ev.Event = "message"
+				}
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			ev, has = SSEEvent{}, false
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			has = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				s.last = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				s.retry = time.Duration(ms) * time.Millisecond
+				ev.Retry = s.retry
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoSSEParsesAndReconnects(t *testing.T) {
+	var conns int32
+	lastIDs := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&conns, 1)
+		lastIDs <- r.Header.Get("Last-Event-ID")
+		if r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("Accept = %q", r.Header.Get("Accept"))
+		}
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		switch n {
+		case 1:
+			fmt.Fprint(w, "retry: 10\n: comment\n\nid: 1\ndata: hello\ndata: world\n\n")
+			fmt.Fprint(w, "event: update\r\nid: 2\r\ndata: {\"a\":1}\r\n\r\n")
+		case 2:
+			fmt.Fprint(w, "id: 3\ndata:no-space\n\n")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client := New(Config{})
+	stream, err := client.DoSSE(context.Background(), Request{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []SSEEvent
+	for ev := range stream.Events() {
+		got = append(got, ev)
+	}
+	want := []SSEEvent{
+		{ID: "1", Event: "message", Data: "hello\nworld"},
+		{ID: "2", Event: "update", Data: `{"a":1}`},
+		{ID: "3", Event: "message", Data: "no-space"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events: %+v", len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if !errors.Is(stream.Err(), ErrStreamClosed) {
+		t.Fatalf("Err() = %v, want ErrStreamClosed", stream.Err())
+	}
+	for _, want := range []string{"", "2", "3"} {
+		if id := <-lastIDs; id != want {
+			t.Fatalf("Last-Event-ID = %q, want %q", id, want)
+		}
+	}
+}
+
+func TestDoSSERejectsNonStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	client := New(Config{})
+	if _, err := client.DoSSE(context.Background(), Request{URL: server.URL}); !errors.Is(err, ErrNotEventStream) {
+		t.Fatalf("err = %v, want ErrNotEventStream", err)
+	}
+	if _, err := client.DoSSE(context.Background(), Request{URL: server.URL + "/missing"}); err == nil {
+		t.Fatal("expected error for 404")
+	}
+}
+
+func TestClientCloseEndsSSEStreams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := New(Config{})
+	stream, err := client.DoSSE(context.Background(), Request{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, ok := <-stream.Events(); ok {
+		t.Fatal("expected events channel to be closed")
+	}
+	if stream.Err() != nil {
+		t.Fatalf("Err() = %v, want nil after close", stream.Err())
+	}
+}
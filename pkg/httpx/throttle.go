@@ -0,0 +1,159 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Throttle slows down hosts that answer 429 Too Many Requests (AIMD). A host
+// runs at full speed until its first 429; from then on requests to it are
+// paced, the rate is multiplied by Decrease on every further 429 and grows by
+// Increase on every other response. Once it climbs back to MaxRate the host
+// is no longer paced.
+type Throttle struct {
+	MaxRate  float64 // requests/s a throttled host starts from and recovers to, default 10
+	MinRate  float64 // requests/s floor, default 0.1
+	Decrease float64 // multiplicative decrease on 429, default 0.5
+	Increase float64 // requests/s added per non-429 response, default 0.1
+}
+
+// ThrottleStat is the current pacing of a throttled host.
+type ThrottleStat struct {
+	Rate      float64 // requests per second
+	Throttled int     // 429s seen since the host was last at full speed
+}
+
+type hostThrottle struct {
+	rate         float64 // 0 means not paced
+	next         time.Time
+	lastDecrease time.Time
+	throttled    int
+}
+
+type throttler struct {
+	cfg   Throttle
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu    sync.Mutex
+	hosts map[string]*hostThrottle
+}
+
+func newThrottler(cfg *Throttle) *throttler {
+	if cfg == nil {
+		return nil
+	}
+	t := &throttler{cfg: *cfg, now: time.Now, sleep: systemClock{}.Sleep, hosts: make(map[string]*hostThrottle)}
+	if t.cfg.MaxRate <= 0 {
+		t.cfg.MaxRate = 10
+	}
+	if t.cfg.MinRate <= 0 {
+		t.cfg.MinRate = 0.1
+	}
+	if t.cfg.MinRate > t.cfg.MaxRate {
+		t.cfg.MinRate = t.cfg.MaxRate
+	}
+	if t.cfg.Decrease <= 0 || t.cfg.Decrease >= 1 {
+		t.cfg.Decrease = 0.5
+	}
+	if t.cfg.Increase <= 0 {
+		t.cfg.Increase = 0.1
+	}
+	return t
+}
+
+// wait blocks until a request to host may be sent at the host's current rate.
+func (t *throttler) wait(ctx context.Context, host string) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	h := t.hosts[host]
+	if h == nil || h.rate == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	now := t.now()
+	at := h.next
+	if at.Before(now) {
+		at = now
+	}
+	h.next = at.Add(rateInterval(h.rate))
+	t.mu.Unlock()
+
+	return t.sleep(ctx, at.Sub(now))
+}
+
+// observe adjusts the rate of host after a response with status.
+func (t *throttler) observe(host string, status int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := t.hosts[host]
+	if status != http.StatusTooManyRequests {
+		if h == nil || h.rate == 0 {
+			return
+		}
+		h.rate += t.cfg.Increase
+		if h.rate >= t.cfg.MaxRate {
+			delete(t.hosts, host)
+		}
+		return
+	}
+
+	now := t.now()
+	if h == nil {
+		h = &hostThrottle{}
+		t.hosts[host] = h
+	}
+	h.throttled++
+	if h.rate == 0 {
+		h.rate = t.cfg.MaxRate
+	} else if now.Sub(h.lastDecrease) < rateInterval(h.rate) {
+		// Requests already in flight at the old rate; count one decrease.
+		return
+	}
+	h.rate *= t.cfg.Decrease
+	if h.rate < t.cfg.MinRate {
+		h.rate = t.cfg.MinRate
+	}
+	h.lastDecrease = now
+	h.next = now.Add(rateInterval(h.rate))
+}
+
+func (t *throttler) stats() map[string]ThrottleStat {
+	out := make(map[string]ThrottleStat)
+	if t == nil {
+		return out
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for host, h := range t.hosts {
+		out[host] = ThrottleStat{Rate: h.rate, Throttled: h.throttled}
+	}
+	return out
+}
+
+func rateInterval(rate float64) time.Duration {
+	return time.Duration(float64(time.Second) / rate)
+}
+
+func throttleHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// ThrottleStats returns the current rate of every host being throttled.
+// Hosts running at full speed are not included.
+func (c *realClient) ThrottleStats() map[string]ThrottleStat {
+	return c.throttle.stats()
+}
@@ -0,0 +1,94 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottleAIMD(t *testing.T) {
+	now := time.Unix(1000, 0)
+	th := newThrottler(&Throttle{MaxRate: 8, MinRate: 1, Decrease: 0.5, Increase: 1})
+	th.now = func() time.Time { return now }
+
+	th.observe("a", http.StatusOK)
+	if len(th.stats()) != 0 {
+		t.Fatal("host throttled before any 429")
+	}
+
+	th.observe("a", http.StatusTooManyRequests)
+	if got := th.stats()["a"].Rate; got != 4 {
+		t.Fatalf("rate after first 429 = %v, want 4", got)
+	}
+
+	// A 429 from a request already in flight does not decrease again.
+	th.observe("a", http.StatusTooManyRequests)
+	if got := th.stats()["a"].Rate; got != 4 {
+		t.Fatalf("rate = %v, want 4", got)
+	}
+
+	now = now.Add(time.Second)
+	th.observe("a", http.StatusTooManyRequests)
+	now = now.Add(time.Second)
+	th.observe("a", http.StatusTooManyRequests)
+	now = now.Add(time.Second)
+	th.observe("a", http.StatusTooManyRequests)
+	st := th.stats()["a"]
+	if st.Rate != 1 || st.Throttled != 5 {
+		t.Fatalf("stats = %+v, want rate 1 (floor) and 5 throttled", st)
+	}
+
+	for i := 0; i < 6; i++ {
+		th.observe("a", http.StatusOK)
+	}
+	if got := th.stats()["a"].Rate; got != 7 {
+		t.Fatalf("rate after recovery = %v, want 7", got)
+	}
+	th.observe("a", http.StatusOK)
+	if _, ok := th.stats()["a"]; ok {
+		t.Fatal("host still throttled after reaching MaxRate")
+	}
+}
+
+func TestThrottleWaitPacesRequests(t *testing.T) {
+	th := newThrottler(&Throttle{MaxRate: 40, Decrease: 0.5})
+	th.observe("a", http.StatusTooManyRequests) // 20 req/s
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := th.wait(context.Background(), "a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Fatalf("3 requests at 20 req/s took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	th.observe("a", http.StatusTooManyRequests)
+	if err := th.wait(ctx, "a"); err == nil {
+		t.Fatal("expected context error")
+	}
+}
+
+func TestClientThrottleStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := New(Config{Throttle: &Throttle{MaxRate: 100}})
+	if _, err := client.DoGET(context.Background(), server.URL, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := client.ThrottleStats()
+	host := strings.TrimPrefix(server.URL, "http://")
+	if st, ok := stats[host]; !ok || st.Rate != 50 {
+		t.Fatalf("stats = %+v, want %s at 50 req/s", stats, host)
+	}
+}
@@ -0,0 +1,36 @@
+package httpx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// TLSConfig customizes TLS for transports built by New. It is ignored by
+// NewWithHTTP, where the caller owns the transport.
+type TLSConfig struct {
+	// RootCAs replaces the system roots when set.
+	RootCAs *x509.CertPool
+	// ClientCertificates are presented for mutual TLS.
+	ClientCertificates []tls.Certificate
+	// MinVersion defaults to TLS 1.2.
+	MinVersion uint16
+	ServerName string
+	// InsecureSkipVerify disables certificate verification. Only for local
+	// development against self-signed endpoints.
+	InsecureSkipVerify bool
+}
+
+func (t *TLSConfig) build() *tls.Config {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t == nil {
+		return cfg
+	}
+	if t.MinVersion != 0 {
+		cfg.MinVersion = t.MinVersion
+	}
+	cfg.RootCAs = t.RootCAs
+	cfg.Certificates = t.ClientCertificates
+	cfg.ServerName = t.ServerName
+	cfg.InsecureSkipVerify = t.InsecureSkipVerify
+	return cfg
+}
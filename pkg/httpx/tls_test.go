@@ -0,0 +1,52 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewWithCustomRootCAs(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	defer server.Close()
+
+	_, err := New(Config{Timeout: 5 * time.Second}).DoGET(context.Background(), server.URL, nil, nil)
+	if err == nil {
+		t.Fatal("expected certificate error without custom roots")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	client := New(Config{Timeout: 5 * time.Second, TLS: &TLSConfig{RootCAs: pool}})
+
+	resp, err := client.DoGET(context.Background(), server.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("DoGET() error = %v", err)
+	}
+	if string(resp.Body) != "secure" {
+		t.Errorf("expected body 'secure', got %q", string(resp.Body))
+	}
+}
+
+func TestTLSConfigBuild(t *testing.T) {
+	var nilCfg *TLSConfig
+	if got := nilCfg.build().MinVersion; got != tls.VersionTLS12 {
+		t.Errorf("expected default min version TLS 1.2, got %x", got)
+	}
+
+	cfg := (&TLSConfig{MinVersion: tls.VersionTLS13, InsecureSkipVerify: true}).build()
+	if cfg.MinVersion != tls.VersionTLS13 || !cfg.InsecureSkipVerify {
+		t.Errorf("unexpected tls config %+v", cfg)
+	}
+
+	tr, _ := newTransport(Config{TLS: &TLSConfig{ServerName: "apps.apple.com"}})
+	if tr.TLSClientConfig.ServerName != "apps.apple.com" {
+		t.Error("expected TLS config to be applied to the transport")
+	}
+}
@@ -0,0 +1,170 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+var ErrTransform = errors.New("httpx: response transform failed")
+
+// Transformer rewrites a response body. Transformers configured on a Request
+// run in order on the final response, each receiving the previous output.
+type Transformer interface {
+	Transform(body []byte, headers http.Header) ([]byte, error)
+}
+
+type TransformerFunc func(body []byte, headers http.Header) ([]byte, error)
+
+func (f TransformerFunc) Transform(body []byte, headers http.Header) ([]byte, error) {
+	return f(body, headers)
+}
+
+func applyTransforms(body []byte, headers http.Header, transforms []Transformer) ([]byte, error) {
+	for i, t := range transforms {
+		out, err := t.Transform(body, headers)
+		if err != nil {
+			return body, fmt.Errorf("%w: step %d: %v", ErrTransform, i, err)
+		}
+		body = out
+	}
+	return body, nil
+}
+
+// JSONPath extracts the given dot-separated paths (e.g. "data.items.0.name")
+// from a JSON body and returns them as a JSON object keyed by path. Paths
+// that are not present are omitted.
+func JSONPath(paths ...string) Transformer {
+	return TransformerFunc(func(body []byte, _ http.Header) ([]byte, error) {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		var doc any
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("decode json: %w", err)
+		}
+
+		out := make(map[string]any, len(paths))
+		for _, p := range paths {
+			if v, ok := lookupJSONPath(doc, p); ok {
+				out[p] = v
+			}
+		}
+		return json.Marshal(out)
+	})
+}
+
+func lookupJSONPath(doc any, path string) (any, bool) {
+	cur := doc
+	if path == "" {
+		return cur, true
+	}
+	for _, seg := range strings.Split(path, ".") {
+		switch node := cur.(type) {
+		case map[string]any:
+			v, ok := node[seg]
+			if !ok {
+				return nil, false
+			}
+			cur = v
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			cur = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"div": true, "dd": true, "dl": true, "dt": true, "footer": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "table": true, "td": true, "th": true,
+	"tr": true, "ul": true,
+}
+
+var htmlSkipTags = map[string]bool{
+	"head": true, "noscript": true, "script": true, "style": true, "svg": true, "template": true,
+}
+
+// HTMLToText strips markup, scripts and styles from an HTML body and returns
+// its visible text, one line per block element.
+func HTMLToText() Transformer {
+	return TransformerFunc(func(body []byte, _ http.Header) ([]byte, error) {
+		z := html.NewTokenizer(bytes.NewReader(body))
+		var (
+			lines []string
+			line  strings.Builder
+			skip  int
+		)
+		flush := func() {
+			if s := strings.Join(strings.Fields(line.String()), " "); s != "" {
+				lines = append(lines, s)
+			}
+			line.Reset()
+		}
+
+		for {
+			switch z.Next() {
+			case html.ErrorToken:
+				if err := z.Err(); err != io.EOF {
+					return nil, err
+				}
+				flush()
+				return []byte(strings.Join(lines, "\n")), nil
+			case html.StartTagToken:
+				name, _ := z.TagName()
+				if htmlSkipTags[string(name)] {
+					skip++
+				} else if htmlBlockTags[string(name)] {
+					flush()
+				}
+			case html.EndTagToken:
+				name, _ := z.TagName()
+				if htmlSkipTags[string(name)] && skip > 0 {
+					skip--
+				} else if htmlBlockTags[string(name)] {
+					flush()
+				}
+			case html.SelfClosingTagToken:
+				name, _ := z.TagName()
+				if htmlBlockTags[string(name)] {
+					flush()
+				}
+			case html.TextToken:
+				if skip == 0 {
+					line.Write(z.Text())
+				}
+			}
+		}
+	})
+}
+
+// Snapshot keeps at most limit bytes of the body, cutting on a UTF-8
+// boundary, so large pages can be stored for debugging without holding on to
+// the whole payload.
+func Snapshot(limit int) Transformer {
+	return TransformerFunc(func(body []byte, _ http.Header) ([]byte, error) {
+		if limit < 0 || len(body) <= limit {
+			return body, nil
+		}
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(body[cut]) {
+			cut--
+		}
+		return bytes.Clone(body[:cut]), nil
+	})
+}
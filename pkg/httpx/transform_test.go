@@ -0,0 +1,85 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJSONPathTransform(t *testing.T) {
+	body := []byte(`{"data":{"items":[{"name":"a","rating":4.5},{"name":"b"}]},"total":12345678901234}`)
+
+	out, err := JSONPath("data.items.0.name", "data.items.0.rating", "total", "missing.path").Transform(body, nil)
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	want := `{"data.items.0.name":"a","data.items.0.rating":4.5,"total":12345678901234}`
+	if string(out) != want {
+		t.Fatalf("got %s, want %s", out, want)
+	}
+
+	if _, err := JSONPath("a").Transform([]byte("<html>"), nil); err == nil {
+		t.Fatal("expected error for non-JSON body")
+	}
+}
+
+func TestHTMLToTextTransform(t *testing.T) {
+	body := []byte(`<html><head><title>x</title><style>p{}</style></head>
+<body><h1>Great   app</h1><script>var a = 1;</script><p>Works <b>well</b>.</p><ul><li>One</li><li>Two</li></ul></body></html>`)
+
+	out, err := HTMLToText().Transform(body, nil)
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	want := "Great app\nWorks well.\nOne\nTwo"
+	if string(out) != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+}
+
+func TestSnapshotTransform(t *testing.T) {
+	out, _ := Snapshot(4).Transform([]byte("héllo"), nil)
+	if string(out) != "hél" {
+		t.Fatalf("got %q, want %q", out, "hél")
+	}
+	out, _ = Snapshot(100).Transform([]byte("short"), nil)
+	if string(out) != "short" {
+		t.Fatalf("got %q", out)
+	}
+}
+
+func TestDoAppliesTransformsInOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"review":{"title":"Nice","body":"long text"}}`))
+	}))
+	defer server.Close()
+
+	client := New(Config{Timeout: 5 * time.Second})
+	resp, err := client.Do(context.Background(), Request{
+		URL:        server.URL,
+		Transforms: []Transformer{JSONPath("review.title"), Snapshot(10)},
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got := string(resp.Body); got != `{"review.t` {
+		t.Fatalf("body = %q", got)
+	}
+
+	resp, err = client.Do(context.Background(), Request{
+		URL: server.URL,
+		Transforms: []Transformer{TransformerFunc(func([]byte, http.Header) ([]byte, error) {
+			return nil, errors.New("boom")
+		})},
+	})
+	if !errors.Is(err, ErrTransform) {
+		t.Fatalf("err = %v, want ErrTransform", err)
+	}
+	if resp.Status != http.StatusOK || len(resp.Body) == 0 {
+		t.Fatalf("expected untransformed response alongside error, got %+v", resp)
+	}
+}
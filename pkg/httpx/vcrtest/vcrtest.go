@@ -0,0 +1,310 @@
+// Package vcrtest records real HTTP interactions to cassette files and
+// replays them in tests, so suites that talk to external APIs run offline
+// and deterministically:
+//
+//	rec := vcrtest.New(t, "lookup_instagram")
+//	client := httpx.NewWithHTTP(rec.Client(), httpx.Config{})
+//
+// Cassettes live in testdata/cassettes/<name>.json. Run with VCR_MODE=record
+// to refresh them against the real endpoints.
+package vcrtest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette only.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the real transport and overwrites the
+	// cassette on Stop.
+	ModeRecord
+	// ModeAuto replays an existing cassette and records when it is missing.
+	ModeAuto
+)
+
+var ErrNoInteraction = errors.New("vcrtest: no recorded interaction for request")
+
+// DefaultRedactHeaders are replaced with "REDACTED" before a cassette is
+// written.
+var DefaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+type Options struct {
+	Mode Mode
+	// Transport is used when recording; defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// Match reports whether a recorded interaction answers req. The default
+	// compares method, URL and body.
+	Match func(req *http.Request, body []byte, i Interaction) bool
+	// RedactHeaders are added to DefaultRedactHeaders.
+	RedactHeaders []string
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    Body        `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    Body        `json:"body,omitempty"`
+}
+
+// Body is stored as text when it is valid UTF-8 and as base64 otherwise.
+type Body []byte
+
+type encodedBody struct {
+	Text   string `json:"text,omitempty"`
+	Base64 string `json:"base64,omitempty"`
+}
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(encodedBody{Text: string(b)})
+	}
+	return json.Marshal(encodedBody{Base64: base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var e encodedBody
+	if err := json.Unmarshal(data, &e); err != nil {
+		return err
+	}
+	if e.Base64 != "" {
+		raw, err := base64.StdEncoding.DecodeString(e.Base64)
+		if err != nil {
+			return err
+		}
+		*b = raw
+		return nil
+	}
+	*b = Body(e.Text)
+	return nil
+}
+
+type cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper backed by a cassette.
+type Recorder struct {
+	path   string
+	mode   Mode
+	opts   Options
+	redact map[string]bool
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecorder opens the cassette at path. In ModeReplay the file must exist.
+func NewRecorder(path string, opts Options) (*Recorder, error) {
+	r := &Recorder{path: path, mode: opts.Mode, opts: opts, redact: make(map[string]bool)}
+	if r.opts.Transport == nil {
+		r.opts.Transport = http.DefaultTransport
+	}
+	if r.opts.Match == nil {
+		r.opts.Match = defaultMatch
+	}
+	for _, h := range append(append([]string{}, DefaultRedactHeaders...), opts.RedactHeaders...) {
+		r.redact[http.CanonicalHeaderKey(h)] = true
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil && r.mode != ModeRecord:
+		var c cassette
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("vcrtest: parse %s: %w", path, err)
+		}
+		r.interactions = c.Interactions
+		r.used = make([]bool, len(c.Interactions))
+		r.mode = ModeReplay
+	case errors.Is(err, os.ErrNotExist) && r.mode == ModeAuto:
+		r.mode = ModeRecord
+	case err != nil && r.mode == ModeReplay:
+		return nil, fmt.Errorf("vcrtest: read cassette: %w", err)
+	}
+	return r, nil
+}
+
+// New opens testdata/cassettes/<name>.json for t. The mode comes from the
+// VCR_MODE environment variable (record, replay or auto; default replay).
+// The cassette is saved when the test finishes.
+func New(t testing.TB, name string) *Recorder {
+	t.Helper()
+	r, err := NewRecorder(filepath.Join("testdata", "cassettes", name+".json"), Options{Mode: ModeFromEnv()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := r.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+	return r
+}
+
+// ModeFromEnv parses VCR_MODE.
+func ModeFromEnv() Mode {
+	switch strings.ToLower(os.Getenv("VCR_MODE")) {
+	case "record":
+		return ModeRecord
+	case "auto":
+		return ModeAuto
+	default:
+		return ModeReplay
+	}
+}
+
+// Mode returns the effective mode; ModeAuto resolves to replay or record.
+func (r *Recorder) Mode() Mode { return r.mode }
+
+// Client returns an http.Client using the recorder as transport, ready for
+// httpx.NewWithHTTP.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == ModeRecord {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Identical requests are answered in recorded order.
+	for i, in := range r.interactions {
+		if r.used[i] || !r.opts.Match(req, body, in) {
+			continue
+		}
+		r.used[i] = true
+		return in.Response.httpResponse(req), nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.opts.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	in := Interaction{
+		Request: RecordedRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: r.redactHeaders(req.Header),
+			Body:    body,
+		},
+		Response: RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: r.redactHeaders(resp.Header),
+			Body:    respBody,
+		},
+	}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, in)
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+// Stop writes the cassette when recording. It is a no-op in replay mode.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(cassette{Interactions: r.interactions}, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("vcrtest: encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("vcrtest: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("vcrtest: write cassette: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) redactHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for k := range out {
+		if r.redact[http.CanonicalHeaderKey(k)] {
+			out[k] = []string{"REDACTED"}
+		}
+	}
+	return out
+}
+
+func (rr RecordedResponse) httpResponse(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.Status, http.StatusText(rr.Status)),
+		StatusCode:    rr.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rr.Headers.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(rr.Body)),
+		ContentLength: int64(len(rr.Body)),
+		Request:       req,
+	}
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("vcrtest: read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func defaultMatch(req *http.Request, body []byte, i Interaction) bool {
+	return req.Method == i.Request.Method &&
+		req.URL.String() == i.Request.URL &&
+		bytes.Equal(body, i.Request.Body)
+}
@@ -0,0 +1,99 @@
+package vcrtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/quiby-ai/common/pkg/httpx"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte("hello " + r.URL.Query().Get("n")))
+	}))
+	path := filepath.Join(t.TempDir(), "cassettes", "hello.json")
+
+	rec, err := NewRecorder(path, Options{Mode: ModeRecord})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := httpx.NewWithHTTP(rec.Client(), httpx.Config{})
+	for _, n := range []string{"1", "2", "1"} {
+		if _, err := client.DoGET(context.Background(), server.URL, map[string]string{"n": n}, map[string]string{"Authorization": "Bearer t"}); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	if err := rec.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "Bearer t") || strings.Contains(string(data), "session=secret") {
+		t.Errorf("cassette contains secrets:\n%s", data)
+	}
+
+	rec, err = NewRecorder(path, Options{Mode: ModeReplay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = httpx.NewWithHTTP(rec.Client(), httpx.Config{})
+	resp, err := client.DoGET(context.Background(), server.URL, map[string]string{"n": "2"}, nil)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if string(resp.Body) != "hello 2" {
+		t.Errorf("body = %q", resp.Body)
+	}
+	if calls != 3 {
+		t.Errorf("server calls = %d, want 3", calls)
+	}
+
+	_, err = client.DoGET(context.Background(), server.URL, map[string]string{"n": "3"}, nil)
+	if !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected ErrNoInteraction, got %v", err)
+	}
+}
+
+func TestReplayMissingCassette(t *testing.T) {
+	_, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), Options{})
+	if err == nil {
+		t.Fatal("expected error for missing cassette in replay mode")
+	}
+
+	rec, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), Options{Mode: ModeAuto})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Mode() != ModeRecord {
+		t.Errorf("auto mode without cassette = %v, want record", rec.Mode())
+	}
+}
+
+func TestBodyEncoding(t *testing.T) {
+	for _, b := range []Body{Body("text"), Body{0x1f, 0x8b, 0xff}} {
+		data, err := b.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Body
+		if err := got.UnmarshalJSON(data); err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(b) {
+			t.Errorf("round trip %q -> %s -> %q", b, data, got)
+		}
+	}
+}
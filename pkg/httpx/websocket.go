@@ -0,0 +1,326 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var (
+	ErrWebSocketHandshake = errors.New("httpx: websocket handshake failed")
+	ErrWebSocketProtocol  = errors.New("httpx: websocket protocol error")
+)
+
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// maxWebSocketMessage bounds a single (reassembled) message.
+const maxWebSocketMessage = 32 << 20
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// CloseError is returned by ReadMessage once the server closed the
+// connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("httpx: websocket closed: %d %s", e.Code, e.Text)
+}
+
+// WebSocket is a client WebSocket connection opened by DialWebSocket. Reads
+// must come from one goroutine; writes may be concurrent.
+type WebSocket struct {
+	rwc         io.ReadWriteCloser
+	br          *bufio.Reader
+	subprotocol string
+
+	wmu       sync.Mutex
+	closeOnce sync.Once
+}
+
+// DialWebSocket opens a WebSocket connection to rawURL (ws://, wss://, or
+// http(s)://). The handshake goes through the client's transport, so it uses
+// the same proxies, TLS settings, DNS cache, base headers, user agent and
+// auth as regular requests. HTTP/3 is never used for the handshake.
+//
+// The returned connection outlives the client's Close; close it yourself.
+func (c *realClient) DialWebSocket(ctx context.Context, rawURL string, headers map[string]string) (*WebSocket, error) {
+	if rawURL == "" {
+		return nil, ErrEmptyURL
+	}
+	if hc := c.forHost(rawURL); hc != c {
+		return hc.DialWebSocket(ctx, rawURL, headers)
+	}
+	rawURL, err := c.normalizeURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrInvalidURL, u.Scheme)
+	}
+	if err := c.guard.checkURL(u.String()); err != nil {
+		return nil, err
+	}
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+	defer c.release()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("httpx: build request: %w", err)
+	}
+	c.setRequestHeaders(req, headers)
+	if err := c.applyAuth(req, headers); err != nil {
+		return nil, err
+	}
+	req.Header.Del("Accept-Encoding")
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return nil, fmt.Errorf("httpx: websocket key: %w", err)
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+
+	resp, err := c.websocketTransport().RoundTrip(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrWebSocketHandshake, err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: status %d", ErrWebSocketHandshake, resp.StatusCode)
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: connection not upgradable", ErrWebSocketHandshake)
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") || resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		rwc.Close()
+		return nil, fmt.Errorf("%w: invalid upgrade response", ErrWebSocketHandshake)
+	}
+
+	return &WebSocket{
+		rwc:         rwc,
+		br:          bufio.NewReader(rwc),
+		subprotocol: resp.Header.Get("Sec-WebSocket-Protocol"),
+	}, nil
+}
+
+// websocketTransport returns the transport used for upgrades, skipping the
+// HTTP/3 wrapper since QUIC cannot carry an HTTP/1.1 upgrade.
+func (c *realClient) websocketTransport() http.RoundTripper {
+	switch t := c.http.Transport.(type) {
+	case nil:
+		return http.DefaultTransport
+	case *http3Transport:
+		return t.fallback
+	default:
+		return t
+	}
+}
+
+func websocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// Subprotocol is the Sec-WebSocket-Protocol selected by the server.
+func (ws *WebSocket) Subprotocol() string { return ws.subprotocol }
+
+// ReadMessage returns the next text or binary message, reassembling
+// fragments. Pings are answered automatically. After the server closes the
+// connection it returns a *CloseError.
+func (ws *WebSocket) ReadMessage() (MessageType, []byte, error) {
+	var (
+		msgType MessageType
+		msg     []byte
+	)
+	for {
+		fin, op, payload, err := ws.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case opPing:
+			if err := ws.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			cerr := &CloseError{Code: 1005}
+			if len(payload) >= 2 {
+				cerr.Code = int(binary.BigEndian.Uint16(payload))
+				cerr.Text = string(payload[2:])
+			}
+			ws.closeOnce.Do(func() {
+				ws.writeFrame(opClose, payload[:min(len(payload), 2)])
+				ws.rwc.Close()
+			})
+			return 0, nil, cerr
+		case opText, opBinary:
+			if msgType != 0 {
+				return 0, nil, fmt.Errorf("%w: new message inside fragmented message", ErrWebSocketProtocol)
+			}
+			msgType = MessageType(op)
+		case opContinuation:
+			if msgType == 0 {
+				return 0, nil, fmt.Errorf("%w: unexpected continuation frame", ErrWebSocketProtocol)
+			}
+		default:
+			return 0, nil, fmt.Errorf("%w: unknown opcode %d", ErrWebSocketProtocol, op)
+		}
+
+		if len(msg)+len(payload) > maxWebSocketMessage {
+			return 0, nil, fmt.Errorf("%w: message too large", ErrWebSocketProtocol)
+		}
+		msg = append(msg, payload...)
+		if fin {
+			if msgType == TextMessage && !utf8.Valid(msg) {
+				return 0, nil, fmt.Errorf("%w: invalid UTF-8 in text message", ErrWebSocketProtocol)
+			}
+			return msgType, msg, nil
+		}
+	}
+}
+
+// WriteMessage sends data as a single text or binary frame.
+func (ws *WebSocket) WriteMessage(t MessageType, data []byte) error {
+	if t != TextMessage && t != BinaryMessage {
+		return fmt.Errorf("%w: invalid message type %d", ErrWebSocketProtocol, t)
+	}
+	return ws.writeFrame(byte(t), data)
+}
+
+// Ping sends a ping frame; the pong is consumed by ReadMessage.
+func (ws *WebSocket) Ping(data []byte) error {
+	return ws.writeFrame(opPing, data)
+}
+
+// Close sends a normal closure frame and closes the connection.
+func (ws *WebSocket) Close() error {
+	var err error
+	ws.closeOnce.Do(func() {
+		ws.writeFrame(opClose, []byte{0x03, 0xE8}) // 1000 normal closure
+		err = ws.rwc.Close()
+	})
+	return err
+}
+
+func (ws *WebSocket) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var hdr [2]byte
+	if _, err = io.ReadFull(ws.br, hdr[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = hdr[0]&0x80 != 0
+	op = hdr[0] & 0x0F
+	if hdr[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("%w: reserved bits set", ErrWebSocketProtocol)
+	}
+	if hdr[1]&0x80 != 0 {
+		return false, 0, nil, fmt.Errorf("%w: masked server frame", ErrWebSocketProtocol)
+	}
+
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(ws.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(ws.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= opClose && (n > 125 || !fin) {
+		return false, 0, nil, fmt.Errorf("%w: invalid control frame", ErrWebSocketProtocol)
+	}
+	if n > maxWebSocketMessage {
+		return false, 0, nil, fmt.Errorf("%w: frame too large", ErrWebSocketProtocol)
+	}
+
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(ws.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	return fin, op, payload, nil
+}
+
+// writeFrame writes one final, masked frame as required for clients.
+func (ws *WebSocket) writeFrame(op byte, payload []byte) error {
+	buf := make([]byte, 0, 14+len(payload))
+	buf = append(buf, 0x80|op)
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, 0x80|byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, 0x80|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, 0x80|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	buf = append(buf, mask[:]...)
+	start := len(buf)
+	buf = append(buf, payload...)
+	for i := range buf[start:] {
+		buf[start+i] ^= mask[i%4]
+	}
+
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	_, err := ws.rwc.Write(buf)
+	return err
+}
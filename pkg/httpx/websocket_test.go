@@ -0,0 +1,117 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serverFrame encodes an unmasked server frame.
+func serverFrame(fin bool, op byte, payload []byte) []byte {
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	return append([]byte{b0, byte(len(payload))}, payload...)
+}
+
+// readClientFrame reads one masked client frame (payloads < 126 bytes).
+func readClientFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		t.Errorf("read frame: %v", err)
+		return 0, nil
+	}
+	if hdr[1]&0x80 == 0 {
+		t.Error("client frame not masked")
+	}
+	n := int(hdr[1] & 0x7F)
+	var mask [4]byte
+	io.ReadFull(r, mask[:])
+	payload := make([]byte, n)
+	io.ReadFull(r, payload)
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return hdr[0] & 0x0F, payload
+}
+
+func TestDialWebSocket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "test-agent" || r.Header.Get("X-Base") != "1" {
+			t.Errorf("headers not applied: %v", r.Header)
+		}
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		brw.WriteString("Sec-WebSocket-Accept: " + websocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		brw.Flush()
+
+		op, payload := readClientFrame(t, brw.Reader)
+		if op != opText || string(payload) != "hello" {
+			t.Errorf("got op %d payload %q", op, payload)
+		}
+		conn.Write(serverFrame(true, opPing, []byte("p")))
+		conn.Write(serverFrame(false, opText, []byte("echo: ")))
+		conn.Write(serverFrame(true, opContinuation, payload))
+		if op, payload := readClientFrame(t, brw.Reader); op != opPong || string(payload) != "p" {
+			t.Errorf("expected pong, got op %d payload %q", op, payload)
+		}
+		closePayload := binary.BigEndian.AppendUint16(nil, 1001)
+		conn.Write(serverFrame(true, opClose, append(closePayload, "bye"...)))
+		readClientFrame(t, brw.Reader)
+	}))
+	defer server.Close()
+
+	client := New(Config{UserAgents: []string{"test-agent"}, BaseHeaders: map[string]string{"X-Base": "1"}})
+	ctx, cancel := context.WithCancel(context.Background())
+	ws, err := client.DialWebSocket(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	cancel() // the connection must outlive the dial context
+
+	if err := ws.WriteMessage(TextMessage, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	typ, msg, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != TextMessage || string(msg) != "echo: hello" {
+		t.Fatalf("got %d %q", typ, msg)
+	}
+
+	_, _, err = ws.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != 1001 || closeErr.Text != "bye" {
+		t.Fatalf("err = %v, want close 1001 bye", err)
+	}
+}
+
+func TestDialWebSocketRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, err := New(Config{}).DialWebSocket(context.Background(), server.URL, nil)
+	if !errors.Is(err, ErrWebSocketHandshake) {
+		t.Fatalf("err = %v, want ErrWebSocketHandshake", err)
+	}
+	if _, err := New(Config{}).DialWebSocket(context.Background(), "ftp://example.com", nil); !errors.Is(err, ErrInvalidURL) {
+		t.Fatalf("err = %v, want ErrInvalidURL", err)
+	}
+}
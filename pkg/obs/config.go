@@ -1,6 +1,7 @@
 package obs
 
 import (
+	"log/slog"
 	"time"
 )
 
@@ -20,6 +21,42 @@ type Config struct {
 	LogRedactText      bool              `env:"LOG_REDACT_TEXT" envDefault:"true"`
 	LogHashPII         bool              `env:"LOG_HASH_PII" envDefault:"true"`
 	ResourceAttributes map[string]string `env:"RESOURCE_ATTRIBUTES"`
+	// IncidentMode, when set, starts the service in incident mode with this
+	// incident id for DefaultIncidentDuration.
+	IncidentMode string `env:"INCIDENT_MODE" envDefault:""`
+	// ScrubParams are query parameters, in addition to DefaultScrubParams,
+	// whose values are removed from URLs in logs, spans and metric labels.
+	ScrubParams []string `env:"SCRUB_PARAMS" envSeparator:","`
+	// LogRingBufferSize keeps the last N log lines in memory, served by
+	// DebugLogsHandler. Zero disables the buffer.
+	LogRingBufferSize int `env:"LOG_RING_BUFFER_SIZE" envDefault:"0"`
+	// LogRingBufferLevel is the minimum level recorded in the ring buffer,
+	// independent of LogLevel.
+	LogRingBufferLevel string `env:"LOG_RING_BUFFER_LEVEL" envDefault:"debug"`
+	// LogSinks receive every log record next to stdout. Each handler filters
+	// by its own level; URLs are scrubbed before records reach them.
+	LogSinks []slog.Handler `env:"-"`
+	// LogSpanEventLevel is the minimum level of log records that are also
+	// added as events to the active span. "off" disables span events.
+	LogSpanEventLevel string `env:"LOG_SPAN_EVENT_LEVEL" envDefault:"warn"`
+	// ConfigFile is an optional JSON file with log_level and
+	// tracing_sample_ratio, polled every ConfigPollInterval and applied
+	// without restarting.
+	ConfigFile         string        `env:"OBS_CONFIG_FILE" envDefault:""`
+	ConfigPollInterval time.Duration `env:"OBS_CONFIG_POLL_INTERVAL" envDefault:"30s"`
+	// ReloadOnSIGHUP re-reads LOG_LEVEL, TRACING_SAMPLE_RATIO and
+	// ConfigFile when the process receives SIGHUP.
+	ReloadOnSIGHUP bool `env:"OBS_RELOAD_ON_SIGHUP" envDefault:"false"`
+	// ShutdownSummary logs a RunSummary when Shutdown runs.
+	ShutdownSummary bool `env:"OBS_SHUTDOWN_SUMMARY" envDefault:"false"`
+	// DiagSlowThreshold flushes Diag entries of work that took at least this
+	// long even without an error. Zero flushes on errors only.
+	DiagSlowThreshold time.Duration `env:"OBS_DIAG_SLOW_THRESHOLD" envDefault:"0"`
+	// DiagMaxEntries caps the entries one Diag collector keeps.
+	DiagMaxEntries int `env:"OBS_DIAG_MAX_ENTRIES" envDefault:"200"`
+	// MetricsScrapeStaleAfter reports the Prometheus exporter as failing
+	// when /metrics was not scraped for this long. Zero disables the check.
+	MetricsScrapeStaleAfter time.Duration `env:"OBS_METRICS_SCRAPE_STALE_AFTER" envDefault:"5m"`
 }
 
 func DefaultConfig() Config {
@@ -39,6 +76,12 @@ func DefaultConfig() Config {
 		LogRedactText:      true,
 		LogHashPII:         true,
 		ResourceAttributes: make(map[string]string),
+		LogRingBufferLevel: "debug",
+		LogSpanEventLevel:  "warn",
+		ConfigPollInterval: 30 * time.Second,
+		DiagMaxEntries:     defaultDiagMaxEntries,
+
+		MetricsScrapeStaleAfter: 5 * time.Minute,
 	}
 }
 
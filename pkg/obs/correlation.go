@@ -0,0 +1,79 @@
+package obs
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SagaBaggageKey is the OTel baggage member carrying the saga ID between
+// services.
+const SagaBaggageKey = "saga_id"
+
+// WithRequestID stores the request ID in ctx. It is logged as request_id and
+// sent as X-Request-ID by httpx.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// WithSagaID stores the saga ID in ctx. It is logged as saga_id, set on
+// spans started under ctx, and sent as X-Saga-ID by httpx. It is also added
+// to the OTel baggage, so any propagator-aware client carries it to the
+// next service.
+func WithSagaID(ctx context.Context, sagaID string) context.Context {
+	if sagaID == "" {
+		return ctx
+	}
+	if m, err := baggage.NewMemberRaw(SagaBaggageKey, sagaID); err == nil {
+		if b, err := baggage.FromContext(ctx).SetMember(m); err == nil {
+			ctx = baggage.ContextWithBaggage(ctx, b)
+		}
+	}
+	return withCorrelation(ctx, "", "", sagaID, "", "", "")
+}
+
+// RequestIDFromContext returns the ID stored by WithRequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// SagaIDFromContext returns the ID stored by WithSagaID, or else the saga_id
+// baggage member received from an upstream service.
+func SagaIDFromContext(ctx context.Context) string {
+	if id, _ := ctx.Value(sagaIDKey).(string); id != "" {
+		return id
+	}
+	return baggage.FromContext(ctx).Member(SagaBaggageKey).Value()
+}
+
+// CorrelationMiddleware picks up the correlation IDs of incoming requests:
+// X-Request-ID, and the saga ID from X-Saga-ID or the W3C baggage header.
+// Handlers then log and propagate them without further setup.
+func CorrelationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.Baggage{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx = WithRequestID(ctx, r.Header.Get("X-Request-ID"))
+		sagaID := r.Header.Get("X-Saga-ID")
+		if sagaID == "" {
+			sagaID = SagaIDFromContext(ctx)
+		}
+		next.ServeHTTP(w, r.WithContext(WithSagaID(ctx, sagaID)))
+	})
+}
+
+// TraceIDFromContext returns the trace ID of the active span, or the one
+// stored for correlated logging.
+func TraceIDFromContext(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	id, _ := ctx.Value(traceIDKey).(string)
+	return id
+}
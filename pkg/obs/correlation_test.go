@@ -0,0 +1,66 @@
+package obs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCorrelationIDsFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, RequestIDFromContext(ctx))
+	assert.Empty(t, SagaIDFromContext(ctx))
+	assert.Empty(t, TraceIDFromContext(ctx))
+
+	ctx = WithSagaID(WithRequestID(ctx, "req-1"), "saga-1")
+	assert.Equal(t, "req-1", RequestIDFromContext(ctx))
+	assert.Equal(t, "saga-1", SagaIDFromContext(ctx))
+
+	ctx = withCorrelation(ctx, "stored-trace", "", "", "", "", "")
+	assert.Equal(t, "stored-trace", TraceIDFromContext(ctx))
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(ctx, "op")
+	defer span.End()
+	assert.Equal(t, span.SpanContext().TraceID().String(), TraceIDFromContext(ctx), "active span wins")
+}
+
+func TestSagaIDBaggage(t *testing.T) {
+	ctx := WithSagaID(context.Background(), "saga-1")
+	assert.Equal(t, "saga-1", baggage.FromContext(ctx).Member(SagaBaggageKey).Value())
+
+	// A downstream service that only received the baggage.
+	downstream := baggage.ContextWithBaggage(context.Background(), baggage.FromContext(ctx))
+	assert.Equal(t, "saga-1", SagaIDFromContext(downstream))
+
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sagaSpanProcessor{}), sdktrace.WithSyncer(exp))
+	_, span := tp.Tracer("test").Start(downstream, "op")
+	span.End()
+	assert.Contains(t, exp.GetSpans()[0].Attributes, attribute.String(SagaBaggageKey, "saga-1"))
+}
+
+func TestCorrelationMiddleware(t *testing.T) {
+	var sagaID, requestID string
+	h := CorrelationMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sagaID, requestID = SagaIDFromContext(r.Context()), RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Baggage", "saga_id=saga-1,other=x")
+	req.Header.Set("X-Request-ID", "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "saga-1", sagaID)
+	assert.Equal(t, "req-1", requestID)
+
+	req.Header.Set("X-Saga-ID", "saga-2")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "saga-2", sagaID, "X-Saga-ID wins")
+}
@@ -0,0 +1,184 @@
+package obs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultDiagMaxEntries = 200
+
+const diagKey contextKey = "obs_diag"
+
+// Diagnostics collects debug details for one request or message and logs
+// them only if it ends in an error or runs longer than the slow threshold,
+// so the detail is there for the requests that need it without logging it
+// for every request. Methods on a nil *Diagnostics do nothing, so Diag(ctx)
+// is safe to call where no collector was started.
+type Diagnostics struct {
+	start      time.Time
+	threshold  time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries []diagEntry
+	dropped int
+	failed  error
+}
+
+type diagEntry struct {
+	offset time.Duration
+	msg    string
+	attrs  []any
+}
+
+// WithDiag starts a collector for the work done under the returned ctx. The
+// slow threshold and entry limit come from OBS_DIAG_SLOW_THRESHOLD and
+// OBS_DIAG_MAX_ENTRIES of the global config.
+func WithDiag(ctx context.Context) (context.Context, *Diagnostics) {
+	d := newDiagnostics(time.Now)
+	return context.WithValue(ctx, diagKey, d), d
+}
+
+func newDiagnostics(now func() time.Time) *Diagnostics {
+	d := &Diagnostics{start: now(), maxEntries: defaultDiagMaxEntries, now: now}
+	if o := Global(); o != nil {
+		config := o.Config()
+		d.threshold = config.DiagSlowThreshold
+		if config.DiagMaxEntries > 0 {
+			d.maxEntries = config.DiagMaxEntries
+		}
+	}
+	return d
+}
+
+// Diag returns the collector started by WithDiag, or nil.
+func Diag(ctx context.Context) *Diagnostics {
+	d, _ := ctx.Value(diagKey).(*Diagnostics)
+	return d
+}
+
+// Add records msg with attrs as key/value pairs. Entries past the limit are
+// counted but not kept.
+func (d *Diagnostics) Add(msg string, attrs ...any) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.entries) >= d.maxEntries {
+		d.dropped++
+		return
+	}
+	d.entries = append(d.entries, diagEntry{offset: d.now().Sub(d.start), msg: msg, attrs: attrs})
+}
+
+// Fail marks the work as failed, so Finish flushes even if it is given a nil
+// error.
+func (d *Diagnostics) Fail(err error) {
+	if d == nil || err == nil {
+		return
+	}
+	d.mu.Lock()
+	if d.failed == nil {
+		d.failed = err
+	}
+	d.mu.Unlock()
+}
+
+// Len returns the number of entries kept.
+func (d *Diagnostics) Len() int {
+	if d == nil {
+		return 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.entries)
+}
+
+// Finish logs the collected entries at warn level if err is non-nil, Fail
+// was called, or the work took at least the slow threshold. It reports
+// whether it logged, and clears the entries either way.
+func (d *Diagnostics) Finish(ctx context.Context, err error) bool {
+	if d == nil {
+		return false
+	}
+	elapsed := d.now().Sub(d.start)
+	d.mu.Lock()
+	entries, dropped := d.entries, d.dropped
+	d.entries, d.dropped = nil, 0
+	if err == nil {
+		err = d.failed
+	}
+	d.mu.Unlock()
+
+	reason := ""
+	switch {
+	case err != nil:
+		reason = "error"
+	case d.threshold > 0 && elapsed >= d.threshold:
+		reason = "slow"
+	default:
+		return false
+	}
+
+	for i, e := range entries {
+		attrs := append([]any{
+			"diag_seq", i,
+			"diag_offset_ms", e.offset.Milliseconds(),
+		}, e.attrs...)
+		Warn(ctx, e.msg, attrs...)
+	}
+	summary := []any{
+		"diag_reason", reason,
+		"elapsed_ms", elapsed.Milliseconds(),
+		"diag_entries", len(entries),
+		"diag_dropped", dropped,
+	}
+	if err != nil {
+		summary = append(summary, "error", err.Error())
+	}
+	Warn(ctx, "request diagnostics", summary...)
+	return true
+}
+
+// errServerStatus marks requests DiagMiddleware saw end with a 5xx status.
+var errServerStatus = errors.New("server error status")
+
+// DiagMiddleware starts a collector for each request and finishes it when
+// the handler returns. Requests answered with a 5xx status count as failed.
+func DiagMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, d := WithDiag(r.Context())
+		sw := &diagStatusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		var err error
+		if sw.status >= 500 {
+			err = errServerStatus
+		}
+		d.Finish(ctx, err)
+	})
+}
+
+type diagStatusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (w *diagStatusWriter) WriteHeader(code int) {
+	if !w.wrote {
+		w.status, w.wrote = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *diagStatusWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *diagStatusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
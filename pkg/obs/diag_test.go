@@ -0,0 +1,103 @@
+package obs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func diagRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var recs []map[string]any
+	for _, line := range bytes.Split(buf.Bytes(), []byte("\n")) {
+		var rec map[string]any
+		if json.Unmarshal(line, &rec) == nil {
+			if _, ok := rec["diag_seq"]; ok || rec["msg"] == "request diagnostics" {
+				recs = append(recs, rec)
+			}
+		}
+	}
+	return recs
+}
+
+func TestDiagFlushesOnlyOnError(t *testing.T) {
+	var buf bytes.Buffer
+	config := DefaultConfig()
+	config.ServiceName = "diag-test"
+	config.LogSinks = []slog.Handler{slog.NewJSONHandler(&buf, nil)}
+	initForReload(t, config)
+
+	ctx, d := WithDiag(context.Background())
+	Diag(ctx).Add("cache miss", "cache", "reviews")
+	Diag(ctx).Add("upstream call", "status", 200)
+	assert.False(t, d.Finish(ctx, nil))
+	assert.Empty(t, diagRecords(t, &buf))
+
+	ctx, d = WithDiag(context.Background())
+	Diag(ctx).Add("cache miss", "cache", "reviews")
+	Diag(ctx).Add("upstream call", "status", 503)
+	assert.True(t, d.Finish(ctx, errors.New("upstream unavailable")))
+
+	recs := diagRecords(t, &buf)
+	require.Len(t, recs, 3)
+	assert.Equal(t, "cache miss", recs[0]["msg"])
+	assert.Equal(t, "reviews", recs[0]["cache"])
+	assert.EqualValues(t, 1, recs[1]["diag_seq"])
+	assert.Equal(t, "request diagnostics", recs[2]["msg"])
+	assert.Equal(t, "error", recs[2]["diag_reason"])
+	assert.Equal(t, "WARN", recs[2]["level"])
+}
+
+func TestDiagSlowThresholdAndLimit(t *testing.T) {
+	now := time.Unix(0, 0)
+	d := newDiagnostics(func() time.Time { return now })
+	d.threshold = time.Second
+	d.maxEntries = 2
+	for range 5 {
+		d.Add("step")
+	}
+	assert.Equal(t, 2, d.Len())
+
+	now = now.Add(2 * time.Second)
+	assert.True(t, d.Finish(context.Background(), nil), "slow work flushes without an error")
+	assert.Equal(t, 0, d.Len())
+}
+
+func TestDiagNilSafe(t *testing.T) {
+	d := Diag(context.Background())
+	assert.Nil(t, d)
+	d.Add("ignored")
+	d.Fail(errors.New("ignored"))
+	assert.False(t, d.Finish(context.Background(), errors.New("ignored")))
+}
+
+func TestDiagMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	config := DefaultConfig()
+	config.ServiceName = "diag-test"
+	config.LogSinks = []slog.Handler{slog.NewJSONHandler(&buf, nil)}
+	initForReload(t, config)
+
+	handler := DiagMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Diag(r.Context()).Add("loaded app", "app_id", r.URL.Query().Get("app"))
+		if r.URL.Query().Get("fail") != "" {
+			http.Error(w, "boom", http.StatusBadGateway)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?app=1", nil))
+	assert.Empty(t, diagRecords(t, &buf))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?app=2&fail=1", nil))
+	recs := diagRecords(t, &buf)
+	require.Len(t, recs, 2)
+	assert.Equal(t, "loaded app", recs[0]["msg"])
+}
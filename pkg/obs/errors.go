@@ -5,6 +5,7 @@ import "errors"
 var (
 	ErrInvalidServiceName = errors.New("service name cannot be empty")
 	ErrInvalidSampleRatio = errors.New("tracing sample ratio must be between 0 and 1")
+	ErrInvalidLogLevel    = errors.New("log level must be debug, info, warn or error")
 	ErrInvalidMetricsPort = errors.New("metrics port must be between 1 and 65535")
 	ErrAlreadyInitialized = errors.New("observability already initialized")
 	ErrNotInitialized     = errors.New("observability not initialized")
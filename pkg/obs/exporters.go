@@ -0,0 +1,162 @@
+package obs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Exporter names used in the exporter attribute of obs_exporter_failures_total
+// and in exporter warnings.
+const (
+	ExporterOTLPTraces = "otlp_traces"
+	ExporterPrometheus = "prometheus"
+)
+
+const (
+	exporterWarnBackoff    = time.Minute
+	exporterWarnBackoffMax = time.Hour
+)
+
+// exporterHealth counts exporter failures and warns about them. The first
+// failure is logged right away; while an exporter keeps failing, warnings
+// back off from exporterWarnBackoff to exporterWarnBackoffMax and report how
+// many failures they skipped. A success resets the backoff.
+type exporterHealth struct {
+	warn func(ctx context.Context, msg string, attrs ...any)
+	info func(ctx context.Context, msg string, attrs ...any)
+	now  func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]int64
+	failing  map[string]*exporterWarnState
+}
+
+type exporterWarnState struct {
+	next       time.Time
+	backoff    time.Duration
+	suppressed int64
+}
+
+func newExporterHealth(logging *LoggingProvider) *exporterHealth {
+	return &exporterHealth{
+		warn:     logging.Warn,
+		info:     logging.Info,
+		now:      time.Now,
+		failures: make(map[string]int64),
+		failing:  make(map[string]*exporterWarnState),
+	}
+}
+
+func (h *exporterHealth) failure(ctx context.Context, exporter string, err error) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	h.failures[exporter]++
+	total := h.failures[exporter]
+	st := h.failing[exporter]
+	if st == nil {
+		st = &exporterWarnState{backoff: exporterWarnBackoff}
+		h.failing[exporter] = st
+	}
+	now := h.now()
+	if now.Before(st.next) {
+		st.suppressed++
+		h.mu.Unlock()
+		return
+	}
+	suppressed, backoff := st.suppressed, st.backoff
+	st.suppressed = 0
+	st.next = now.Add(backoff)
+	st.backoff = min(2*backoff, exporterWarnBackoffMax)
+	h.mu.Unlock()
+
+	h.warn(ctx, "telemetry exporter failing",
+		"exporter", exporter,
+		"error", err.Error(),
+		"failures_total", total,
+		"failures_suppressed", suppressed,
+		"next_warning_ms", backoff.Milliseconds(),
+	)
+}
+
+func (h *exporterHealth) success(ctx context.Context, exporter string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	_, wasFailing := h.failing[exporter]
+	delete(h.failing, exporter)
+	h.mu.Unlock()
+	if wasFailing {
+		h.info(ctx, "telemetry exporter recovered", "exporter", exporter)
+	}
+}
+
+func (h *exporterHealth) failuresByExporter() map[string]int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make(map[string]int64, len(h.failures))
+	for exporter, n := range h.failures {
+		out[exporter] = n
+	}
+	return out
+}
+
+func registerExporterFailures(meter metric.Meter, h *exporterHealth) error {
+	counter, err := meter.Int64ObservableCounter("obs_exporter_failures_total",
+		metric.WithDescription("Failed span exports and stale metrics scrapes, by exporter"),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for exporter, n := range h.failuresByExporter() {
+			o.ObserveInt64(counter, n, metric.WithAttributes(attribute.String("exporter", exporter)))
+		}
+		return nil
+	}, counter)
+	return err
+}
+
+// startScrapeWatcher reports the Prometheus exporter as failing while
+// MetricsProvider.HTTPHandler goes unscraped for MetricsScrapeStaleAfter.
+func (o *Observability) startScrapeWatcher() {
+	staleAfter := o.config.MetricsScrapeStaleAfter
+	if staleAfter <= 0 || o.metrics == nil || o.metrics.registry == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	o.stopScrapeWatcher = cancel
+
+	go func() {
+		ticker := time.NewTicker(staleAfter)
+		defer ticker.Stop()
+		last := o.metrics.scrapes.Load()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				last = o.checkScrapes(ctx, last)
+			}
+		}
+	}()
+}
+
+// checkScrapes compares the scrape count with the one seen last time and
+// returns the current count.
+func (o *Observability) checkScrapes(ctx context.Context, last int64) int64 {
+	n := o.metrics.scrapes.Load()
+	if n == last {
+		o.exporters.failure(ctx, ExporterPrometheus, fmt.Errorf("%s not scraped for %s", o.config.MetricsPath, o.config.MetricsScrapeStaleAfter))
+	} else {
+		o.exporters.success(ctx, ExporterPrometheus)
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package obs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+type loggedRecord struct {
+	msg   string
+	attrs map[string]any
+}
+
+func recordingHealth(now *time.Time) (*exporterHealth, *[]loggedRecord) {
+	var logged []loggedRecord
+	record := func(_ context.Context, msg string, attrs ...any) {
+		r := loggedRecord{msg: msg, attrs: map[string]any{}}
+		for i := 0; i+1 < len(attrs); i += 2 {
+			r.attrs[attrs[i].(string)] = attrs[i+1]
+		}
+		logged = append(logged, r)
+	}
+	return &exporterHealth{
+		warn:     record,
+		info:     record,
+		now:      func() time.Time { return *now },
+		failures: make(map[string]int64),
+		failing:  make(map[string]*exporterWarnState),
+	}, &logged
+}
+
+func TestExporterHealthBacksOffWarnings(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	h, logged := recordingHealth(&now)
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(countingExporter{next: erroringExporter{}, stats: &exportStats{}, health: h}))
+	export := func() {
+		_, span := tp.Tracer("t").Start(context.Background(), "op")
+		span.End()
+	}
+
+	export()
+	require.Len(t, *logged, 1)
+	first := (*logged)[0]
+	assert.Equal(t, "telemetry exporter failing", first.msg)
+	assert.Equal(t, ExporterOTLPTraces, first.attrs["exporter"])
+	assert.Equal(t, "collector unreachable", first.attrs["error"])
+	assert.EqualValues(t, time.Minute.Milliseconds(), first.attrs["next_warning_ms"])
+
+	export()
+	export()
+	assert.Len(t, *logged, 1, "warnings within the backoff are suppressed")
+
+	now = now.Add(time.Minute)
+	export()
+	require.Len(t, *logged, 2)
+	assert.EqualValues(t, 2, (*logged)[1].attrs["failures_suppressed"])
+	assert.EqualValues(t, 4, (*logged)[1].attrs["failures_total"])
+	assert.EqualValues(t, (2 * time.Minute).Milliseconds(), (*logged)[1].attrs["next_warning_ms"])
+
+	for i := 0; i < 10; i++ {
+		now = now.Add(2 * time.Hour)
+		export()
+	}
+	assert.EqualValues(t, time.Hour.Milliseconds(), (*logged)[len(*logged)-1].attrs["next_warning_ms"])
+	assert.Equal(t, map[string]int64{ExporterOTLPTraces: 14}, h.failuresByExporter())
+
+	h.success(context.Background(), ExporterOTLPTraces)
+	assert.Equal(t, "telemetry exporter recovered", (*logged)[len(*logged)-1].msg)
+	n := len(*logged)
+	export()
+	assert.Len(t, *logged, n+1, "a new failure after recovery warns right away")
+}
+
+func TestScrapeStaleness(t *testing.T) {
+	var buf bytes.Buffer
+	config := DefaultConfig()
+	config.ServiceName = "scrape-test"
+	config.MetricsScrapeStaleAfter = time.Hour
+	config.LogSinks = []slog.Handler{slog.NewJSONHandler(&buf, nil)}
+	obs := initForReload(t, config)
+	ctx := context.Background()
+
+	last := obs.checkScrapes(ctx, 0)
+	assert.Equal(t, map[string]int64{ExporterPrometheus: 1}, obs.exporters.failuresByExporter())
+	assert.Contains(t, buf.String(), `"msg":"telemetry exporter failing"`)
+	assert.Contains(t, buf.String(), "/metrics not scraped for 1h0m0s")
+
+	rec := httptest.NewRecorder()
+	obs.MetricsProvider().HTTPHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `obs_exporter_failures_total{exporter="prometheus"} 1`)
+
+	obs.checkScrapes(ctx, last)
+	var recovered bool
+	for _, line := range bytes.Split(buf.Bytes(), []byte("\n")) {
+		var r map[string]any
+		if json.Unmarshal(line, &r) == nil && r["msg"] == "telemetry exporter recovered" {
+			recovered = r["exporter"] == ExporterPrometheus
+		}
+	}
+	assert.True(t, recovered)
+}
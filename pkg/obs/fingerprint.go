@@ -0,0 +1,120 @@
+package obs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	FingerprintAttrKey     = "error_fingerprint"
+	FingerprintSpanAttrKey = "error.fingerprint"
+	ErrorKindAttrKey       = "error_kind"
+)
+
+var (
+	fingerprintNormalizers = []struct {
+		re   *regexp.Regexp
+		repl string
+	}{
+		{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+		{regexp.MustCompile(`"[^"]*"|'[^']*'`), "<str>"},
+		{regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}(?::\d+)?\b`), "<ip>"},
+		{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b|\b[0-9a-fA-F]{12,}\b`), "<hex>"},
+		{regexp.MustCompile(`\d+`), "<n>"},
+		{regexp.MustCompile(`\s+`), " "},
+	}
+
+	obsSourceDir string
+)
+
+func init() {
+	if _, file, _, ok := runtime.Caller(0); ok {
+		obsSourceDir = filepath.Dir(file)
+	}
+}
+
+// ErrorFingerprint returns a stable identifier for err that groups recurring
+// failures: it hashes the error kind, the message with volatile parts (IDs,
+// numbers, quoted values) stripped, and the function that reported it.
+func ErrorFingerprint(kind string, err error) string {
+	if err == nil {
+		return ""
+	}
+	return fingerprint(kind, err.Error(), callerFrame())
+}
+
+// RecordError marks the span in ctx as failed and attaches the error kind and
+// fingerprint to it. The fingerprint is returned so it can be logged as well.
+func RecordError(ctx context.Context, kind string, err error) string {
+	if err == nil {
+		return ""
+	}
+	fp := fingerprint(kind, err.Error(), callerFrame())
+
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.RecordError(err, trace.WithAttributes(attribute.String(FingerprintSpanAttrKey, fp)))
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(
+			attribute.String(FingerprintSpanAttrKey, fp),
+			attribute.String("error.kind", kind),
+		)
+	}
+	return fp
+}
+
+func fingerprint(kind, msg, frame string) string {
+	h := sha256.New()
+	h.Write([]byte(kind))
+	h.Write([]byte{0})
+	h.Write([]byte(normalizeErrorMessage(msg)))
+	h.Write([]byte{0})
+	h.Write([]byte(frame))
+	sum := h.Sum(nil)
+	return hex.EncodeToString(sum[:8])
+}
+
+func normalizeErrorMessage(msg string) string {
+	for _, n := range fingerprintNormalizers {
+		msg = n.re.ReplaceAllString(msg, n.repl)
+	}
+	return strings.TrimSpace(msg)
+}
+
+// callerFrame returns the first function outside of this package's non-test
+// sources, which is where the error was reported from. Line numbers are left
+// out on purpose so fingerprints survive unrelated edits to the file.
+func callerFrame() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != obsSourceDir || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.Function
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func errorKindFromAttrs(attrs []any) string {
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if key, ok := attrs[i].(string); ok && key == ErrorKindAttrKey {
+			if kind, ok := attrs[i+1].(string); ok {
+				return kind
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package obs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNormalizeErrorMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want string
+	}{
+		{"numbers", "retry 3 of 5 failed", "retry <n> of <n> failed"},
+		{"uuid", "saga 4f1c2a9e-8b7d-4c3e-9a1f-2b3c4d5e6f70 not found", "saga <uuid> not found"},
+		{"quoted", `app "com.example" missing`, "app <str> missing"},
+		{"ip", "dial tcp 10.0.0.12:9092: connection refused", "dial tcp <ip>: connection refused"},
+		{"whitespace", "too   many\tspaces", "too many spaces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeErrorMessage(tt.msg))
+		})
+	}
+}
+
+func TestErrorFingerprintStable(t *testing.T) {
+	fp := func(id int) string {
+		return ErrorFingerprint(ErrKindKafka, fmt.Errorf("write message %d: broker unavailable", id))
+	}
+
+	first := fp(1)
+	assert.Len(t, first, 16)
+	assert.Equal(t, first, fp(42))
+
+	other := ErrorFingerprint(ErrKindDatabase, errors.New("write message 1: broker unavailable"))
+	assert.NotEqual(t, first, other)
+	assert.Empty(t, ErrorFingerprint(ErrKindKafka, nil))
+}
+
+func TestRecordError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "op")
+
+	fp := RecordError(ctx, ErrKindTimeout, errors.New("deadline exceeded after 30s"))
+	span.End()
+
+	require.Len(t, recorder.Ended(), 1)
+	var found bool
+	for _, attr := range recorder.Ended()[0].Attributes() {
+		if string(attr.Key) == FingerprintSpanAttrKey {
+			found = true
+			assert.Equal(t, fp, attr.Value.AsString())
+		}
+	}
+	assert.True(t, found, "expected fingerprint attribute on span")
+}
@@ -0,0 +1,165 @@
+package obs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	IncidentAttrKey         = "incident"
+	DefaultIncidentDuration = time.Hour
+)
+
+type incidentState struct {
+	id    string
+	since time.Time
+	until time.Time
+}
+
+var currentIncident atomic.Pointer[incidentState]
+
+// SetIncidentMode turns incident mode on for DefaultIncidentDuration or off.
+// While it is on every trace is sampled, logs are written at debug level and
+// logs and spans carry incident=<id>.
+func SetIncidentMode(enabled bool) {
+	if enabled {
+		EnableIncidentMode("", DefaultIncidentDuration)
+		return
+	}
+	DisableIncidentMode()
+}
+
+// EnableIncidentMode turns incident mode on until d elapses. An empty id is
+// replaced by one derived from the current time. It returns the id in use.
+func EnableIncidentMode(id string, d time.Duration) string {
+	now := time.Now()
+	if id == "" {
+		id = "inc-" + now.UTC().Format("20060102T150405Z")
+	}
+	if d <= 0 {
+		d = DefaultIncidentDuration
+	}
+	currentIncident.Store(&incidentState{id: id, since: now, until: now.Add(d)})
+	return id
+}
+
+func DisableIncidentMode() {
+	currentIncident.Store(nil)
+}
+
+// IncidentMode returns the active incident id, if any.
+func IncidentMode() (string, bool) {
+	st := currentIncident.Load()
+	if st == nil || time.Now().After(st.until) {
+		return "", false
+	}
+	return st.id, true
+}
+
+type incidentStatus struct {
+	Active bool      `json:"active"`
+	ID     string    `json:"id,omitempty"`
+	Since  time.Time `json:"since,omitempty"`
+	Until  time.Time `json:"until,omitempty"`
+}
+
+// IncidentModeHandler exposes incident mode over HTTP. GET returns the current
+// status; POST with enabled=true|false (and optional id and duration, e.g.
+// "30m") changes it. Mount it on an internal-only listener.
+func IncidentModeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+			if err != nil {
+				http.Error(w, "enabled must be true or false", http.StatusBadRequest)
+				return
+			}
+			if !enabled {
+				DisableIncidentMode()
+				break
+			}
+			d := DefaultIncidentDuration
+			if raw := r.FormValue("duration"); raw != "" {
+				if d, err = time.ParseDuration(raw); err != nil || d <= 0 {
+					http.Error(w, "invalid duration", http.StatusBadRequest)
+					return
+				}
+			}
+			EnableIncidentMode(r.FormValue("id"), d)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := incidentStatus{}
+		if id, ok := IncidentMode(); ok {
+			st := currentIncident.Load()
+			status = incidentStatus{Active: true, ID: id, Since: st.since, Until: st.until}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+// incidentSampler samples everything during an incident and tags the spans;
+// otherwise it defers to base.
+type incidentSampler struct {
+	base sdktrace.Sampler
+}
+
+func (s incidentSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	id, ok := IncidentMode()
+	if !ok {
+		return s.base.ShouldSample(p)
+	}
+	res := sdktrace.AlwaysSample().ShouldSample(p)
+	res.Attributes = append(res.Attributes, attribute.String(IncidentAttrKey, id))
+	return res
+}
+
+func (s incidentSampler) Description() string {
+	return fmt.Sprintf("IncidentSampler{%s}", s.base.Description())
+}
+
+// incidentLeveler lowers the minimum log level to debug during an incident.
+type incidentLeveler struct {
+	base slog.Leveler
+}
+
+func (l incidentLeveler) Level() slog.Level {
+	if _, ok := IncidentMode(); ok {
+		return slog.LevelDebug
+	}
+	return l.base.Level()
+}
+
+func registerIncidentGauge(meter metric.Meter) error {
+	gauge, err := meter.Int64ObservableGauge("obs_incident_mode",
+		metric.WithDescription("1 while incident mode is active"),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		if id, ok := IncidentMode(); ok {
+			o.ObserveInt64(gauge, 1, metric.WithAttributes(attribute.String(IncidentAttrKey, id)))
+		} else {
+			o.ObserveInt64(gauge, 0)
+		}
+		return nil
+	}, gauge)
+	return err
+}
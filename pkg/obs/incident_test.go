@@ -0,0 +1,110 @@
+package obs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestIncidentModeToggle(t *testing.T) {
+	t.Cleanup(DisableIncidentMode)
+
+	_, active := IncidentMode()
+	assert.False(t, active)
+
+	SetIncidentMode(true)
+	id, active := IncidentMode()
+	assert.True(t, active)
+	assert.True(t, strings.HasPrefix(id, "inc-"))
+
+	SetIncidentMode(false)
+	_, active = IncidentMode()
+	assert.False(t, active)
+
+	EnableIncidentMode("INC-42", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, active = IncidentMode()
+	assert.False(t, active, "incident mode expires")
+}
+
+func TestIncidentSamplerSamplesEverything(t *testing.T) {
+	t.Cleanup(DisableIncidentMode)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithSampler(incidentSampler{base: sdktrace.NeverSample()}),
+	)
+	tracer := tp.Tracer("test")
+
+	_, span := tracer.Start(context.Background(), "before")
+	span.End()
+	require.Empty(t, recorder.Ended())
+
+	EnableIncidentMode("INC-7", time.Minute)
+	_, span = tracer.Start(context.Background(), "during")
+	span.End()
+
+	require.Len(t, recorder.Ended(), 1)
+	assert.Contains(t, recorder.Ended()[0].Attributes(), attribute.String(IncidentAttrKey, "INC-7"))
+}
+
+func TestIncidentModeRaisesLogLevel(t *testing.T) {
+	t.Cleanup(DisableIncidentMode)
+
+	var buf bytes.Buffer
+	logger := &Logger{
+		Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: incidentLeveler{base: slog.LevelInfo}})),
+		config: &loggingConfig{},
+	}
+
+	logger.Debug(context.Background(), "hidden")
+	assert.Empty(t, buf.String())
+
+	EnableIncidentMode("INC-9", time.Minute)
+	logger.Debug(context.Background(), "visible")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "visible", entry["msg"])
+	assert.Equal(t, "INC-9", entry[IncidentAttrKey])
+}
+
+func TestIncidentModeHandler(t *testing.T) {
+	t.Cleanup(DisableIncidentMode)
+	h := IncidentModeHandler()
+
+	form := url.Values{"enabled": {"true"}, "id": {"INC-1"}, "duration": {"10m"}}
+	req := httptest.NewRequest(http.MethodPost, "/debug/incident", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var status incidentStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.True(t, status.Active)
+	assert.Equal(t, "INC-1", status.ID)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/incident?enabled=false", nil))
+	_, active := IncidentMode()
+	assert.False(t, active)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/incident?enabled=maybe", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
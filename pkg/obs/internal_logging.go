@@ -10,6 +10,9 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type contextKey string
@@ -21,6 +24,7 @@ const (
 	messageIDKey contextKey = "messageKey"
 	reviewIDKey  contextKey = "review_id"
 	appIDKey     contextKey = "app_id"
+	requestIDKey contextKey = "request_id"
 
 	StatusOK       = "ok"
 	StatusError    = "error"
@@ -55,6 +59,9 @@ var (
 type Logger struct {
 	*slog.Logger
 	config *loggingConfig
+	ring   *LogRingBuffer
+	level  *slog.LevelVar
+	stats  *logStats
 }
 
 type loggingConfig struct {
@@ -78,17 +85,13 @@ func initLogger(config Config) *Logger {
 		LogHashPII:     config.LogHashPII,
 	}
 
-	level := parseLogLevel(loggingConfig.LogLevel)
+	level := new(slog.LevelVar)
+	level.Set(parseLogLevel(loggingConfig.LogLevel))
 
 	opts := &slog.HandlerOptions{
-		Level:     level,
-		AddSource: level == slog.LevelDebug,
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			if a.Key == slog.TimeKey {
-				return slog.String(slog.TimeKey, a.Value.Time().Format(time.RFC3339Nano))
-			}
-			return a
-		},
+		Level:       incidentLeveler{base: level},
+		AddSource:   level.Level() == slog.LevelDebug,
+		ReplaceAttr: logReplaceAttr,
 	}
 
 	var handler slog.Handler
@@ -98,6 +101,24 @@ func initLogger(config Config) *Logger {
 		handler = slog.NewJSONHandler(os.Stdout, opts)
 	}
 
+	var ring *LogRingBuffer
+	if len(config.LogSinks) > 0 || config.LogRingBufferSize > 0 {
+		handlers := []slog.Handler{handler}
+		for _, sink := range config.LogSinks {
+			handlers = append(handlers, scrubHandler{sink})
+		}
+		if config.LogRingBufferSize > 0 {
+			ring = NewLogRingBuffer(config.LogRingBufferSize, parseLogLevel(config.LogRingBufferLevel))
+			handlers = append(handlers, ring.Handler())
+		}
+		handler = NewTeeHandler(handlers...)
+	}
+	if level, ok := parseSpanEventLevel(config.LogSpanEventLevel); ok {
+		handler = spanEventHandler{Handler: handler, level: level}
+	}
+	stats := newLogStats()
+	handler = countingHandler{handler, stats}
+
 	logger := slog.New(handler)
 
 	hostname, _ := os.Hostname()
@@ -114,6 +135,27 @@ func initLogger(config Config) *Logger {
 	return &Logger{
 		Logger: logger.With(defaultAttrs...),
 		config: loggingConfig,
+		ring:   ring,
+		level:  level,
+		stats:  stats,
+	}
+}
+
+func logReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey {
+		return slog.String(slog.TimeKey, a.Value.Time().Format(time.RFC3339Nano))
+	}
+	return scrubLogAttr(a)
+}
+
+// parseLogLevelStrict is parseLogLevel for values changed at runtime, where a
+// typo should be reported instead of silently meaning info.
+func parseLogLevelStrict(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "error":
+		return parseLogLevel(level), nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrInvalidLogLevel, level)
 	}
 }
 
@@ -172,7 +214,7 @@ func (l *Logger) withContext(ctx context.Context) *Logger {
 	if spanID, ok := ctx.Value(spanIDKey).(string); ok && spanID != "" {
 		attrs = append(attrs, "span_id", spanID)
 	}
-	if sagaID, ok := ctx.Value(sagaIDKey).(string); ok && sagaID != "" {
+	if sagaID := SagaIDFromContext(ctx); sagaID != "" {
 		attrs = append(attrs, "saga_id", sagaID)
 	}
 	if messageID, ok := ctx.Value(messageIDKey).(string); ok && messageID != "" {
@@ -184,6 +226,9 @@ func (l *Logger) withContext(ctx context.Context) *Logger {
 	if appID, ok := ctx.Value(appIDKey).(string); ok && appID != "" {
 		attrs = append(attrs, "app_id", appID)
 	}
+	if requestID, ok := ctx.Value(requestIDKey).(string); ok && requestID != "" {
+		attrs = append(attrs, "request_id", requestID)
+	}
 
 	if len(attrs) == 0 {
 		return l
@@ -192,6 +237,9 @@ func (l *Logger) withContext(ctx context.Context) *Logger {
 	return &Logger{
 		Logger: l.With(attrs...),
 		config: l.config,
+		ring:   l.ring,
+		level:  l.level,
+		stats:  l.stats,
 	}
 }
 
@@ -251,8 +299,11 @@ func (l *Logger) processAttrs(attrs []any) []any {
 }
 
 func (l *Logger) Log(ctx context.Context, level slog.Level, msg string, attrs ...any) {
-	msg = l.redactPII(msg)
+	msg = ScrubText(l.redactPII(msg))
 	attrs = l.processAttrs(attrs)
+	if id, ok := IncidentMode(); ok {
+		attrs = append(attrs, IncidentAttrKey, id)
+	}
 	l.Logger.Log(ctx, level, msg, attrs...)
 }
 
@@ -269,8 +320,15 @@ func (l *Logger) Warn(ctx context.Context, msg string, attrs ...any) {
 }
 
 func (l *Logger) Error(ctx context.Context, msg string, err error, attrs ...any) {
+	kind := errorKindFromAttrs(attrs)
+	l.stats.countError(kind)
 	if err != nil {
-		attrs = append(attrs, "error", err.Error())
+		fp := fingerprint(kind, err.Error(), callerFrame())
+		attrs = append(attrs, "error", err.Error(), FingerprintAttrKey, fp)
+
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			span.SetAttributes(attribute.String(FingerprintSpanAttrKey, fp))
+		}
 	}
 	l.Log(ctx, slog.LevelError, msg, attrs...)
 }
@@ -0,0 +1,109 @@
+package obs
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IDLabelMode selects how IDLabeler turns an ID into a label value.
+type IDLabelMode int
+
+const (
+	// IDLabelBucket maps IDs to a fixed number of buckets. The number of
+	// series stays bounded however many IDs there are.
+	IDLabelBucket IDLabelMode = iota
+	// IDLabelHash replaces IDs with a short salted hash. Values stay
+	// distinct, so only use it for IDs known to be few, e.g. tenants.
+	IDLabelHash
+)
+
+const (
+	defaultIDHashLength = 12
+	noIDLabel           = "none"
+)
+
+// IDLabeler turns raw app, user or tenant IDs into metric label values, so
+// label cardinality and the exposure of IDs in metrics are decided the same
+// way in every service. Full IDs belong on spans and in logs, which TagSpan
+// and ordinary log attributes take care of. The zero value buckets IDs like
+// AppBucket.
+type IDLabeler struct {
+	Mode IDLabelMode
+	// Buckets is the number of buckets for IDLabelBucket. Default 16.
+	Buckets int
+	// Salt keys the hash for IDLabelHash. Without one, hashes of guessable
+	// IDs can be reversed by hashing candidates.
+	Salt []byte
+	// HashLength is the number of hex characters kept of the hash. Default
+	// 12.
+	HashLength int
+}
+
+// Value returns the label value for id, or "none" when id is empty.
+func (l IDLabeler) Value(id string) string {
+	if id == "" {
+		return noIDLabel
+	}
+	if l.Mode == IDLabelHash {
+		return l.hash(id)
+	}
+	return bucketID(id, l.Buckets)
+}
+
+// Label returns the metric attribute for id, named key with a "_bucket" or
+// "_hash" suffix, e.g. "user_id_bucket" for key "user_id".
+func (l IDLabeler) Label(key, id string) attribute.KeyValue {
+	suffix := "_bucket"
+	if l.Mode == IDLabelHash {
+		suffix = "_hash"
+	}
+	return attribute.String(key+suffix, l.Value(id))
+}
+
+// TagSpan records the full id under key on the span in ctx, next to the
+// bounded label used for metrics.
+func (l IDLabeler) TagSpan(ctx context.Context, key, id string) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() || id == "" {
+		return
+	}
+	span.SetAttributes(attribute.String(key, id), l.Label(key, id))
+}
+
+func (l IDLabeler) hash(id string) string {
+	var sum []byte
+	if len(l.Salt) > 0 {
+		mac := hmac.New(sha256.New, l.Salt)
+		mac.Write([]byte(id))
+		sum = mac.Sum(nil)
+	} else {
+		s := sha256.Sum256([]byte(id))
+		sum = s[:]
+	}
+	n := l.HashLength
+	if n <= 0 {
+		n = defaultIDHashLength
+	}
+	out := hex.EncodeToString(sum)
+	return out[:min(n, len(out))]
+}
+
+// bucketID maps id to one of buckets zero-padded values, "00".."15" for
+// the default 16.
+func bucketID(id string, buckets int) string {
+	if buckets <= 0 {
+		buckets = appBuckets
+	}
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	width := max(2, len(strconv.Itoa(buckets-1)))
+	return fmt.Sprintf("%0*d", width, h.Sum32()%uint32(buckets))
+}
@@ -0,0 +1,59 @@
+package obs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestIDLabelerBucket(t *testing.T) {
+	var l IDLabeler
+	assert.Equal(t, "none", l.Value(""))
+	assert.Equal(t, AppBucket("com.example.app"), l.Value("com.example.app"))
+
+	seen := map[string]bool{}
+	for i := range 1000 {
+		seen[l.Value(fmt.Sprint("user-", i))] = true
+	}
+	assert.Len(t, seen, 16)
+
+	wide := IDLabeler{Buckets: 256}
+	assert.Len(t, wide.Value("com.example.app"), 3)
+	assert.Equal(t, attribute.String("user_id_bucket", l.Value("u1")), l.Label("user_id", "u1"))
+}
+
+func TestIDLabelerHash(t *testing.T) {
+	a := IDLabeler{Mode: IDLabelHash, Salt: []byte("salt-a")}
+	b := IDLabeler{Mode: IDLabelHash, Salt: []byte("salt-b")}
+
+	assert.Len(t, a.Value("tenant-1"), defaultIDHashLength)
+	assert.Equal(t, a.Value("tenant-1"), a.Value("tenant-1"))
+	assert.NotEqual(t, a.Value("tenant-1"), a.Value("tenant-2"))
+	assert.NotEqual(t, a.Value("tenant-1"), b.Value("tenant-1"))
+	assert.Equal(t, "none", a.Value(""))
+	assert.Len(t, IDLabeler{Mode: IDLabelHash, HashLength: 6}.Value("tenant-1"), 6)
+	assert.Equal(t, "tenant_id_hash", string(a.Label("tenant_id", "tenant-1").Key))
+}
+
+func TestIDLabelerTagSpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "op")
+	IDLabeler{}.TagSpan(ctx, "app_id", "com.example.app")
+	span.End()
+
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	attrs := map[attribute.Key]string{}
+	for _, kv := range spans[0].Attributes() {
+		attrs[kv.Key] = kv.Value.AsString()
+	}
+	assert.Equal(t, "com.example.app", attrs["app_id"])
+	assert.Equal(t, AppBucket("com.example.app"), attrs["app_id_bucket"])
+}
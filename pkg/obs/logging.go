@@ -2,6 +2,7 @@ package obs
 
 import (
 	"context"
+	"log/slog"
 
 	"go.opentelemetry.io/otel/trace"
 )
@@ -24,6 +25,22 @@ func (lp *LoggingProvider) Logger() *Logger {
 	return lp.logger
 }
 
+// RingBuffer returns the in-memory log buffer, or nil when
+// Config.LogRingBufferSize is zero.
+func (lp *LoggingProvider) RingBuffer() *LogRingBuffer {
+	return lp.logger.ring
+}
+
+// Level returns the current minimum log level, ignoring incident mode.
+func (lp *LoggingProvider) Level() slog.Level {
+	return lp.logger.level.Level()
+}
+
+// SetLevel changes the minimum log level of the running logger.
+func (lp *LoggingProvider) SetLevel(level slog.Level) {
+	lp.logger.level.Set(level)
+}
+
 func (lp *LoggingProvider) WithTracing(ctx context.Context) *Logger {
 	span := trace.SpanFromContext(ctx)
 	if !span.SpanContext().IsValid() {
@@ -0,0 +1,184 @@
+package obs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// NewTeeHandler returns a handler that sends every record to all handlers.
+// Each handler keeps its own level and format; a record is built only if at
+// least one of them is enabled for its level.
+func NewTeeHandler(handlers ...slog.Handler) slog.Handler {
+	return teeHandler(handlers)
+}
+
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
+
+// scrubHandler applies URL scrubbing to handlers obs does not configure
+// itself (Config.LogSinks).
+type scrubHandler struct {
+	slog.Handler
+}
+
+func (h scrubHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, ScrubText(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(scrubLogAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, out)
+}
+
+func (h scrubHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scrubbed := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		scrubbed[i] = scrubLogAttr(a)
+	}
+	return scrubHandler{h.Handler.WithAttrs(scrubbed)}
+}
+
+func (h scrubHandler) WithGroup(name string) slog.Handler {
+	return scrubHandler{h.Handler.WithGroup(name)}
+}
+
+// LogRingBuffer keeps the most recent log lines (JSON) in memory, so verbose
+// logs can be inspected live through ServeHTTP without raising the level of
+// the main output.
+type LogRingBuffer struct {
+	mu    sync.Mutex
+	lines [][]byte
+	next  int
+	full  bool
+
+	handler slog.Handler
+}
+
+// NewLogRingBuffer creates a buffer of size lines that records logs at level
+// and above.
+func NewLogRingBuffer(size int, level slog.Leveler) *LogRingBuffer {
+	if size <= 0 {
+		size = 1000
+	}
+	b := &LogRingBuffer{lines: make([][]byte, size)}
+	b.handler = slog.NewJSONHandler(ringWriter{b}, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: logReplaceAttr,
+	})
+	return b
+}
+
+// Handler returns the slog handler writing into the buffer.
+func (b *LogRingBuffer) Handler() slog.Handler {
+	return b.handler
+}
+
+type ringWriter struct{ b *LogRingBuffer }
+
+// Write stores one line; slog handlers write each record in one call.
+func (w ringWriter) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+	w.b.mu.Lock()
+	w.b.lines[w.b.next] = line
+	w.b.next = (w.b.next + 1) % len(w.b.lines)
+	if w.b.next == 0 {
+		w.b.full = true
+	}
+	w.b.mu.Unlock()
+	return len(p), nil
+}
+
+// Lines returns up to limit of the newest lines, oldest first. A limit of zero
+// or less returns everything buffered.
+func (b *LogRingBuffer) Lines(limit int) [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var out [][]byte
+	if b.full {
+		out = append(out, b.lines[b.next:]...)
+	}
+	out = append(out, b.lines[:b.next]...)
+	if limit > 0 && len(out) > limit {
+		out = out[len(out)-limit:]
+	}
+	return out
+}
+
+// ServeHTTP writes the buffered lines as newline-delimited JSON. The optional
+// limit query parameter caps the number of lines.
+func (b *LogRingBuffer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for _, line := range b.Lines(limit) {
+		w.Write(line)
+	}
+}
+
+// DebugLogsHandler serves the ring buffer of the global logger (see
+// Config.LogRingBufferSize). Mount it on an internal-only listener, e.g. at
+// /debug/logs.
+func DebugLogsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		globalMu.RLock()
+		o := globalObs
+		globalMu.RUnlock()
+		if o == nil || o.logging == nil || o.logging.RingBuffer() == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "log ring buffer not enabled"})
+			return
+		}
+		o.logging.RingBuffer().ServeHTTP(w, r)
+	})
+}
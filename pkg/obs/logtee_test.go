@@ -0,0 +1,94 @@
+package obs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeeHandlerPerHandlerLevels(t *testing.T) {
+	var info, debug bytes.Buffer
+	logger := slog.New(NewTeeHandler(
+		slog.NewJSONHandler(&info, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		slog.NewTextHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	)).With("service", "svc")
+
+	logger.Debug("debug only")
+	logger.Info("both")
+
+	assert.NotContains(t, info.String(), "debug only")
+	assert.Contains(t, info.String(), `"msg":"both"`)
+	assert.Contains(t, info.String(), `"service":"svc"`)
+	assert.Contains(t, debug.String(), "msg=\"debug only\"")
+	assert.Contains(t, debug.String(), "msg=both service=svc")
+}
+
+func TestLogRingBuffer(t *testing.T) {
+	ring := NewLogRingBuffer(3, slog.LevelDebug)
+	logger := slog.New(ring.Handler())
+	for _, msg := range []string{"one", "two", "three", "four"} {
+		logger.Debug(msg)
+	}
+
+	lines := ring.Lines(0)
+	require.Len(t, lines, 3)
+	var first map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	assert.Equal(t, "two", first["msg"])
+	assert.Len(t, ring.Lines(2), 2)
+
+	rec := httptest.NewRecorder()
+	ring.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/logs?limit=1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, strings.Count(rec.Body.String(), "\n"))
+	assert.Contains(t, rec.Body.String(), `"msg":"four"`)
+
+	rec = httptest.NewRecorder()
+	ring.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/logs?limit=x", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestInitLoggerSinksAndRingBuffer(t *testing.T) {
+	var sink bytes.Buffer
+	config := DefaultConfig()
+	config.LogLevel = "error"
+	config.LogRingBufferSize = 10
+	config.LogSinks = []slog.Handler{slog.NewJSONHandler(&sink, &slog.HandlerOptions{Level: slog.LevelInfo})}
+
+	provider, err := newLoggingProvider(config)
+	require.NoError(t, err)
+	provider.Info(context.Background(), "fetched", "url", "https://example.com/?sig=abc")
+	provider.Debug(context.Background(), "verbose")
+
+	assert.Contains(t, sink.String(), "sig=REDACTED")
+	assert.NotContains(t, sink.String(), "verbose")
+
+	lines := provider.RingBuffer().Lines(0)
+	require.Len(t, lines, 2)
+	assert.Contains(t, string(lines[1]), `"msg":"verbose"`)
+	assert.Contains(t, string(lines[1]), `"service":"unknown"`)
+}
+
+func TestDebugLogsHandlerDisabled(t *testing.T) {
+	globalMu.Lock()
+	prev := globalObs
+	globalObs = nil
+	globalMu.Unlock()
+	t.Cleanup(func() {
+		globalMu.Lock()
+		globalObs = prev
+		globalMu.Unlock()
+	})
+
+	rec := httptest.NewRecorder()
+	DebugLogsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/logs", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -20,6 +21,7 @@ type MetricsProvider struct {
 	registry *prometheus.Registry
 	exporter *promexporter.Exporter
 	config   Config
+	scrapes  atomic.Int64
 }
 
 func newMetricsProvider(ctx context.Context, config Config) (*MetricsProvider, error) {
@@ -58,10 +60,15 @@ func newMetricsProvider(ctx context.Context, config Config) (*MetricsProvider, e
 	provider := sdkmetric.NewMeterProvider(
 		sdkmetric.WithResource(res),
 		sdkmetric.WithReader(exporter),
+		sdkmetric.WithView(scrubMetricsView()),
 	)
 
 	otel.SetMeterProvider(provider)
 
+	if err := registerIncidentGauge(provider.Meter("github.com/quiby-ai/common/pkg/obs")); err != nil {
+		return nil, fmt.Errorf("failed to register incident gauge: %w", err)
+	}
+
 	return &MetricsProvider{
 		provider: provider,
 		registry: registry,
@@ -81,9 +88,9 @@ func (mp *MetricsProvider) HTTPHandler() http.Handler {
 	if mp.registry == nil {
 		return http.NotFoundHandler()
 	}
-	return promhttp.HandlerFor(mp.registry, promhttp.HandlerOpts{
+	return countScrapes(&mp.scrapes, promhttp.HandlerFor(mp.registry, promhttp.HandlerOpts{
 		EnableOpenMetrics: true,
-	})
+	}))
 }
 
 func (mp *MetricsProvider) Registry() *prometheus.Registry {
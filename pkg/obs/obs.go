@@ -21,6 +21,11 @@ type Observability struct {
 	shutdownOnce sync.Once
 	isShutdown   bool
 	mu           sync.RWMutex
+	stopWatcher  context.CancelFunc
+	started      time.Time
+
+	exporters         *exporterHealth
+	stopScrapeWatcher context.CancelFunc
 }
 
 var (
@@ -42,18 +47,23 @@ func Init(ctx context.Context, config Config) (*Observability, error) {
 	globalMu.Unlock()
 
 	obs := &Observability{
-		config: config,
+		config:  config,
+		started: time.Now(),
 	}
 
 	var initErr error
 	obs.initOnce.Do(func() {
+		SetScrubParams(config.ScrubParams)
+
 		obs.logging, initErr = newLoggingProvider(config)
 		if initErr != nil {
 			initErr = fmt.Errorf("%w: %v", ErrLoggingInitFailed, initErr)
 			return
 		}
 
-		obs.tracing, initErr = newTracingProvider(ctx, config)
+		obs.exporters = newExporterHealth(obs.logging)
+
+		obs.tracing, initErr = newTracingProvider(ctx, config, obs.exporters)
 		if initErr != nil {
 			initErr = fmt.Errorf("%w: %v", ErrTracingInitFailed, initErr)
 			return
@@ -64,6 +74,24 @@ func Init(ctx context.Context, config Config) (*Observability, error) {
 			initErr = fmt.Errorf("%w: %v", ErrMetricsInitFailed, initErr)
 			return
 		}
+		if obs.metrics.provider != nil {
+			if err := registerExporterFailures(obs.metrics.Meter("github.com/quiby-ai/common/pkg/obs"), obs.exporters); err != nil {
+				initErr = fmt.Errorf("%w: %v", ErrMetricsInitFailed, err)
+				return
+			}
+		}
+
+		if config.IncidentMode != "" {
+			EnableIncidentMode(config.IncidentMode, DefaultIncidentDuration)
+		}
+
+		if config.ConfigFile != "" {
+			if err := obs.Reload(ctx); err != nil {
+				obs.logging.Warn(ctx, "observability config file not applied", "error", err.Error())
+			}
+		}
+		obs.startConfigWatcher()
+		obs.startScrapeWatcher()
 
 		obs.logging.Info(ctx, "observability initialized",
 			"service", config.ServiceName,
@@ -121,6 +149,13 @@ func (o *Observability) Shutdown(ctx context.Context) error {
 			return
 		}
 
+		if o.stopWatcher != nil {
+			o.stopWatcher()
+		}
+		if o.stopScrapeWatcher != nil {
+			o.stopScrapeWatcher()
+		}
+
 		var errors []error
 
 		shutdownCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
@@ -144,6 +179,10 @@ func (o *Observability) Shutdown(ctx context.Context) error {
 			}
 		}
 
+		if o.logging != nil && o.config.ShutdownSummary {
+			o.logSummary(shutdownCtx, o.Summary())
+		}
+
 		if o.logging != nil {
 			if err := o.logging.Shutdown(shutdownCtx); err != nil {
 				errors = append(errors, fmt.Errorf("failed to shutdown logging: %w", err))
@@ -0,0 +1,164 @@
+package obs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// reloadFile is the format of Config.ConfigFile. Missing fields keep the
+// value from Config or the environment.
+type reloadFile struct {
+	LogLevel           *string  `json:"log_level"`
+	TracingSampleRatio *float64 `json:"tracing_sample_ratio"`
+}
+
+// SetLogLevel changes the log level at runtime.
+func (o *Observability) SetLogLevel(level string) error {
+	l, err := parseLogLevelStrict(level)
+	if err != nil {
+		return err
+	}
+	if o.logging == nil {
+		return ErrNotInitialized
+	}
+	o.logging.SetLevel(l)
+	return nil
+}
+
+// SetTracingSampleRatio changes the sampling ratio at runtime.
+func (o *Observability) SetTracingSampleRatio(ratio float64) error {
+	if o.tracing == nil {
+		return ErrNotInitialized
+	}
+	return o.tracing.SetSampleRatio(ratio)
+}
+
+// Reload re-reads LOG_LEVEL and TRACING_SAMPLE_RATIO from the environment,
+// then Config.ConfigFile if set, and applies the result. Values missing from
+// both keep the ones the service was started with. Nothing is changed if any
+// value is invalid.
+func (o *Observability) Reload(ctx context.Context) error {
+	level, ratio := o.config.LogLevel, o.config.TracingSampleRatio
+	if v, ok := os.LookupEnv("LOG_LEVEL"); ok {
+		level = v
+	}
+	if v, ok := os.LookupEnv("TRACING_SAMPLE_RATIO"); ok {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidSampleRatio, v)
+		}
+		ratio = r
+	}
+	if o.config.ConfigFile != "" {
+		data, err := os.ReadFile(o.config.ConfigFile)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return fmt.Errorf("read obs config file: %w", err)
+		default:
+			var f reloadFile
+			if err := json.Unmarshal(data, &f); err != nil {
+				return fmt.Errorf("parse obs config file: %w", err)
+			}
+			if f.LogLevel != nil {
+				level = *f.LogLevel
+			}
+			if f.TracingSampleRatio != nil {
+				ratio = *f.TracingSampleRatio
+			}
+		}
+	}
+
+	l, err := parseLogLevelStrict(level)
+	if err != nil {
+		return err
+	}
+	if ratio < 0 || ratio > 1 {
+		return ErrInvalidSampleRatio
+	}
+	if o.logging == nil || o.tracing == nil {
+		return ErrNotInitialized
+	}
+
+	if l == o.logging.Level() && ratio == o.tracing.SampleRatio() {
+		return nil
+	}
+	o.logging.SetLevel(l)
+	o.tracing.SetSampleRatio(ratio)
+	o.logging.Info(ctx, "observability config reloaded",
+		"log_level", l.String(),
+		"tracing_sample_ratio", ratio,
+	)
+	return nil
+}
+
+// startConfigWatcher reloads when ConfigFile changes or, with
+// ReloadOnSIGHUP, when the process receives SIGHUP.
+func (o *Observability) startConfigWatcher() {
+	if o.config.ConfigFile == "" && !o.config.ReloadOnSIGHUP {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	o.stopWatcher = cancel
+
+	var sighup chan os.Signal
+	if o.config.ReloadOnSIGHUP {
+		sighup = make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+	}
+	var ticker *time.Ticker
+	var poll <-chan time.Time
+	if o.config.ConfigFile != "" {
+		interval := o.config.ConfigPollInterval
+		if interval <= 0 {
+			interval = 30 * time.Second
+		}
+		ticker = time.NewTicker(interval)
+		poll = ticker.C
+	}
+
+	last := fileStamp(o.config.ConfigFile)
+	go func() {
+		if sighup != nil {
+			defer signal.Stop(sighup)
+		}
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sighup:
+			case <-poll:
+				stamp := fileStamp(o.config.ConfigFile)
+				if stamp == last {
+					continue
+				}
+				last = stamp
+			}
+			if err := o.Reload(ctx); err != nil {
+				o.logging.Warn(ctx, "observability config reload failed", "error", err.Error())
+			}
+		}
+	}()
+}
+
+// fileStamp changes whenever the file is written, replaced or removed.
+func fileStamp(path string) string {
+	if path == "" {
+		return ""
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", fi.ModTime().UnixNano(), fi.Size())
+}
@@ -0,0 +1,89 @@
+package obs
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func initForReload(t *testing.T, config Config) *Observability {
+	t.Helper()
+	globalMu.Lock()
+	globalObs = nil
+	globalMu.Unlock()
+
+	obs, err := Init(context.Background(), config)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		obs.Shutdown(context.Background())
+		globalMu.Lock()
+		globalObs = nil
+		globalMu.Unlock()
+	})
+	return obs
+}
+
+func sampled(obs *Observability) bool {
+	_, span := obs.Tracer("reload-test").Start(context.Background(), "op")
+	defer span.End()
+	return span.SpanContext().IsSampled()
+}
+
+func TestSetLogLevelAndSampleRatio(t *testing.T) {
+	config := DefaultConfig()
+	config.ServiceName = "reload-test"
+	obs := initForReload(t, config)
+	ctx := context.Background()
+
+	assert.False(t, obs.Logger().Logger().Enabled(ctx, slog.LevelDebug))
+	require.NoError(t, obs.SetLogLevel("DEBUG"))
+	assert.True(t, obs.Logger().Logger().Enabled(ctx, slog.LevelDebug))
+	assert.ErrorIs(t, obs.SetLogLevel("verbose"), ErrInvalidLogLevel)
+
+	assert.True(t, sampled(obs))
+	require.NoError(t, obs.SetTracingSampleRatio(0))
+	assert.False(t, sampled(obs))
+	assert.ErrorIs(t, obs.SetTracingSampleRatio(1.5), ErrInvalidSampleRatio)
+	assert.Equal(t, 0.0, obs.TracingProvider().SampleRatio())
+}
+
+func TestReloadMergesEnvAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "obs.json")
+	config := DefaultConfig()
+	config.ServiceName = "reload-test"
+	config.ConfigFile = path
+	obs := initForReload(t, config)
+
+	t.Setenv("LOG_LEVEL", "warn")
+	require.NoError(t, os.WriteFile(path, []byte(`{"tracing_sample_ratio": 0.25}`), 0o644))
+	require.NoError(t, obs.Reload(context.Background()))
+	assert.Equal(t, slog.LevelWarn, obs.Logger().Level())
+	assert.Equal(t, 0.25, obs.TracingProvider().SampleRatio())
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"log_level": "error", "tracing_sample_ratio": 2}`), 0o644))
+	assert.ErrorIs(t, obs.Reload(context.Background()), ErrInvalidSampleRatio)
+	assert.Equal(t, slog.LevelWarn, obs.Logger().Level(), "invalid reload changes nothing")
+}
+
+func TestConfigFileIsWatched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "obs.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"log_level": "debug"}`), 0o644))
+
+	config := DefaultConfig()
+	config.ServiceName = "reload-test"
+	config.ConfigFile = path
+	config.ConfigPollInterval = 10 * time.Millisecond
+	obs := initForReload(t, config)
+	assert.Equal(t, slog.LevelDebug, obs.Logger().Level(), "file applied at start")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"log_level": "error", "tracing_sample_ratio": 0.5}`), 0o644))
+	assert.Eventually(t, func() bool {
+		return obs.Logger().Level() == slog.LevelError && obs.TracingProvider().SampleRatio() == 0.5
+	}, 2*time.Second, 10*time.Millisecond)
+}
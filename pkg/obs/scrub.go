@@ -0,0 +1,222 @@
+package obs
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ScrubbedValue replaces the value of denylisted query parameters.
+const ScrubbedValue = "REDACTED"
+
+// DefaultScrubParams are the query parameters whose values are always removed
+// from URLs in logs, spans and metric labels. Matching is case-insensitive.
+var DefaultScrubParams = []string{
+	"token", "access_token", "refresh_token", "id_token", "auth", "authorization",
+	"key", "api_key", "apikey", "secret", "client_secret", "password", "passwd",
+	"sig", "signature", "session", "sessionid", "code",
+	"x-amz-signature", "x-amz-credential", "x-amz-security-token",
+}
+
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+type urlScrubber struct {
+	params map[string]struct{}
+}
+
+var activeScrubber atomic.Pointer[urlScrubber]
+
+func init() {
+	SetScrubParams(nil)
+}
+
+// SetScrubParams replaces the extra denylisted query parameters, on top of
+// DefaultScrubParams. Init calls it with Config.ScrubParams.
+func SetScrubParams(extra []string) {
+	s := &urlScrubber{params: make(map[string]struct{}, len(DefaultScrubParams)+len(extra))}
+	for _, p := range append(append([]string{}, DefaultScrubParams...), extra...) {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			s.params[p] = struct{}{}
+		}
+	}
+	activeScrubber.Store(s)
+}
+
+func scrubber() *urlScrubber {
+	return activeScrubber.Load()
+}
+
+// ScrubURL removes credentials from rawURL: values of denylisted query
+// parameters and the userinfo password. Strings that do not parse as URLs are
+// returned unchanged.
+func ScrubURL(rawURL string) string {
+	return scrubber().url(rawURL)
+}
+
+// ScrubText applies ScrubURL to every URL found in s.
+func ScrubText(s string) string {
+	return scrubber().text(s)
+}
+
+func (s *urlScrubber) url(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	changed := false
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), ScrubbedValue)
+		changed = true
+	}
+	if u.RawQuery != "" {
+		if q, ok := s.query(u.RawQuery); ok {
+			u.RawQuery = q
+			changed = true
+		}
+	}
+	if u.Fragment != "" {
+		// OAuth implicit flows put tokens in the fragment.
+		if q, ok := s.query(u.EscapedFragment()); ok {
+			u.Fragment, _ = url.PathUnescape(q)
+			u.RawFragment = q
+			changed = true
+		}
+	}
+	if !changed {
+		return rawURL
+	}
+	return u.String()
+}
+
+// query scrubs a raw query string, keeping parameter order and encoding of
+// untouched parameters. It reports whether anything was removed.
+func (s *urlScrubber) query(raw string) (string, bool) {
+	parts := strings.Split(raw, "&")
+	changed := false
+	for i, part := range parts {
+		name, _, hasValue := strings.Cut(part, "=")
+		if !hasValue {
+			continue
+		}
+		key, err := url.QueryUnescape(name)
+		if err != nil {
+			key = name
+		}
+		if _, deny := s.params[strings.ToLower(key)]; deny {
+			parts[i] = name + "=" + ScrubbedValue
+			changed = true
+		}
+	}
+	return strings.Join(parts, "&"), changed
+}
+
+func (s *urlScrubber) text(v string) string {
+	if !strings.Contains(v, "://") {
+		return v
+	}
+	return urlPattern.ReplaceAllStringFunc(v, s.url)
+}
+
+func (s *urlScrubber) attr(kv attribute.KeyValue) attribute.KeyValue {
+	if kv.Value.Type() != attribute.STRING {
+		return kv
+	}
+	if v := kv.Value.AsString(); strings.Contains(v, "://") {
+		return attribute.String(string(kv.Key), s.text(v))
+	}
+	return kv
+}
+
+func (s *urlScrubber) attrs(kvs []attribute.KeyValue) []attribute.KeyValue {
+	var out []attribute.KeyValue
+	for i, kv := range kvs {
+		scrubbed := s.attr(kv)
+		if out == nil && scrubbed != kv {
+			out = make([]attribute.KeyValue, len(kvs))
+			copy(out, kvs[:i])
+		}
+		if out != nil {
+			out[i] = scrubbed
+		}
+	}
+	if out == nil {
+		return kvs
+	}
+	return out
+}
+
+// scrubLogAttr is used from the slog ReplaceAttr hook.
+func scrubLogAttr(a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindString {
+		if v := a.Value.String(); strings.Contains(v, "://") {
+			return slog.String(a.Key, scrubber().text(v))
+		}
+	}
+	return a
+}
+
+// scrubExporter scrubs URLs in span names, attributes and event attributes
+// before handing spans to the real exporter.
+type scrubExporter struct {
+	next sdktrace.SpanExporter
+}
+
+func (e scrubExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	s := scrubber()
+	out := make([]sdktrace.ReadOnlySpan, len(spans))
+	for i, span := range spans {
+		out[i] = scrubbedSpan{ReadOnlySpan: span, s: s}
+	}
+	return e.next.ExportSpans(ctx, out)
+}
+
+func (e scrubExporter) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
+
+type scrubbedSpan struct {
+	sdktrace.ReadOnlySpan
+	s *urlScrubber
+}
+
+func (s scrubbedSpan) Name() string {
+	return s.s.text(s.ReadOnlySpan.Name())
+}
+
+func (s scrubbedSpan) Attributes() []attribute.KeyValue {
+	return s.s.attrs(s.ReadOnlySpan.Attributes())
+}
+
+func (s scrubbedSpan) Events() []sdktrace.Event {
+	events := s.ReadOnlySpan.Events()
+	out := make([]sdktrace.Event, len(events))
+	for i, ev := range events {
+		ev.Attributes = s.s.attrs(ev.Attributes)
+		out[i] = ev
+	}
+	return out
+}
+
+// scrubMetricsView drops metric attributes whose value is a URL carrying a
+// denylisted parameter. Views can only filter attributes, not rewrite them,
+// and a full URL is too high-cardinality for a label anyway.
+func scrubMetricsView() sdkmetric.View {
+	return func(inst sdkmetric.Instrument) (sdkmetric.Stream, bool) {
+		return sdkmetric.Stream{
+			Name:        inst.Name,
+			Description: inst.Description,
+			Unit:        inst.Unit,
+			AttributeFilter: func(kv attribute.KeyValue) bool {
+				s := scrubber()
+				return s.attr(kv) == kv
+			},
+		}, true
+	}
+}
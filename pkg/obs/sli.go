@@ -0,0 +1,82 @@
+package obs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	StepLatencyMetric = "saga_step_duration_seconds"
+
+	// appBuckets is the number of app_bucket label values, keeping per-app
+	// breakdowns possible without one series per app.
+	appBuckets = 16
+)
+
+var stepLatencyBoundaries = []float64{
+	0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800,
+}
+
+var (
+	stepLatencyOnce sync.Once
+	stepLatency     metric.Float64Histogram
+)
+
+func stepLatencyHistogram() metric.Float64Histogram {
+	stepLatencyOnce.Do(func() {
+		h, err := otel.Meter("github.com/quiby-ai/common/pkg/obs").Float64Histogram(StepLatencyMetric,
+			metric.WithDescription("Duration of saga steps by step, status and app bucket"),
+			metric.WithUnit("s"),
+			metric.WithExplicitBucketBoundaries(stepLatencyBoundaries...),
+		)
+		if err != nil {
+			otel.Handle(err)
+		}
+		stepLatency = h
+	})
+	return stepLatency
+}
+
+// RecordStepLatency records how long a saga step took. Labels are fixed to
+// step, status (one of the Status* constants, anything else becomes "other")
+// and app_bucket derived from the app ID in ctx, so every service reports
+// step latencies the same way.
+func RecordStepLatency(ctx context.Context, step, status string, d time.Duration) {
+	h := stepLatencyHistogram()
+	if h == nil {
+		return
+	}
+
+	appID, _ := ctx.Value(appIDKey).(string)
+	h.Record(ctx, d.Seconds(), metric.WithAttributes(
+		attribute.String("step", step),
+		attribute.String("status", normalizeStepStatus(status)),
+		attribute.String("app_bucket", AppBucket(appID)),
+	))
+}
+
+// WithAppID stores the app ID in ctx for RecordStepLatency and correlated
+// logging.
+func WithAppID(ctx context.Context, appID string) context.Context {
+	return withCorrelation(ctx, "", "", "", "", "", appID)
+}
+
+// AppBucket maps an app ID to one of a fixed number of buckets ("00".."15"),
+// or "none" when empty. It is the zero IDLabeler's Value.
+func AppBucket(appID string) string {
+	return IDLabeler{}.Value(appID)
+}
+
+func normalizeStepStatus(status string) string {
+	switch status {
+	case StatusOK, StatusError, StatusRetrying, StatusSkipped:
+		return status
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,55 @@
+package obs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestAppBucket(t *testing.T) {
+	assert.Equal(t, "none", AppBucket(""))
+	b := AppBucket("com.example.app")
+	assert.Len(t, b, 2)
+	assert.Equal(t, b, AppBucket("com.example.app"))
+}
+
+func TestRecordStepLatency(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	prev := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	t.Cleanup(func() { otel.SetMeterProvider(prev) })
+
+	ctx := WithAppID(context.Background(), "com.example.app")
+	RecordStepLatency(ctx, "extract", StatusOK, 1500*time.Millisecond)
+	RecordStepLatency(ctx, "extract", "weird", time.Second)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	var hist metricdata.Histogram[float64]
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == StepLatencyMetric {
+				hist = m.Data.(metricdata.Histogram[float64])
+			}
+		}
+	}
+	require.Len(t, hist.DataPoints, 2)
+
+	statuses := map[string]bool{}
+	for _, dp := range hist.DataPoints {
+		status, _ := dp.Attributes.Value("status")
+		statuses[status.AsString()] = true
+		bucket, _ := dp.Attributes.Value(attribute.Key("app_bucket"))
+		assert.Equal(t, AppBucket("com.example.app"), bucket.AsString())
+		assert.Equal(t, stepLatencyBoundaries, dp.Bounds)
+	}
+	assert.Equal(t, map[string]bool{StatusOK: true, "other": true}, statuses)
+}
@@ -0,0 +1,104 @@
+package obs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanEventName is the name of span events created from log records.
+const SpanEventName = "log"
+
+// Attribute keys of span events created from log records.
+const (
+	SpanEventSeverityKey = "log.severity"
+	SpanEventMessageKey  = "log.message"
+)
+
+// spanEventHandler adds records at or above level as events to the span in
+// the record's context, so trace views show the logs of a span inline.
+// Attributes bound with With are not copied; they are the same for every
+// record of a logger and would only bloat the span.
+type spanEventHandler struct {
+	slog.Handler
+	level  slog.Level
+	groups []string
+}
+
+// parseSpanEventLevel maps LOG_SPAN_EVENT_LEVEL to a level. "off" and
+// "none" disable span events.
+func parseSpanEventLevel(level string) (slog.Level, bool) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "off", "none", "":
+		return 0, false
+	}
+	return parseLogLevel(level), true
+}
+
+func (h spanEventHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.level {
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			span.AddEvent(SpanEventName, trace.WithTimestamp(r.Time), trace.WithAttributes(h.eventAttrs(r)...))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h spanEventHandler) eventAttrs(r slog.Record) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, r.NumAttrs()+2)
+	attrs = append(attrs,
+		attribute.String(SpanEventSeverityKey, r.Level.String()),
+		attribute.String(SpanEventMessageKey, r.Message),
+	)
+	prefix := strings.Join(h.groups, ".")
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = appendSpanAttr(attrs, prefix, a)
+		return true
+	})
+	return attrs
+}
+
+func appendSpanAttr(attrs []attribute.KeyValue, prefix string, a slog.Attr) []attribute.KeyValue {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+	key := a.Key
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		for _, ga := range a.Value.Group() {
+			attrs = appendSpanAttr(attrs, key, ga)
+		}
+		return attrs
+	case slog.KindString:
+		a = scrubLogAttr(a)
+		return append(attrs, attribute.String(key, a.Value.String()))
+	case slog.KindInt64:
+		return append(attrs, attribute.Int64(key, a.Value.Int64()))
+	case slog.KindUint64:
+		return append(attrs, attribute.Int64(key, int64(a.Value.Uint64())))
+	case slog.KindFloat64:
+		return append(attrs, attribute.Float64(key, a.Value.Float64()))
+	case slog.KindBool:
+		return append(attrs, attribute.Bool(key, a.Value.Bool()))
+	default:
+		a = scrubLogAttr(slog.String(a.Key, fmt.Sprint(a.Value.Any())))
+		return append(attrs, attribute.String(key, a.Value.String()))
+	}
+}
+
+func (h spanEventHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return spanEventHandler{h.Handler.WithAttrs(attrs), h.level, h.groups}
+}
+
+func (h spanEventHandler) WithGroup(name string) slog.Handler {
+	groups := append(append([]string(nil), h.groups...), name)
+	return spanEventHandler{h.Handler.WithGroup(name), h.level, groups}
+}
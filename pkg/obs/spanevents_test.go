@@ -0,0 +1,51 @@
+package obs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpanEventHandler(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "op")
+
+	inner := slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := slog.New(spanEventHandler{Handler: inner, level: slog.LevelWarn})
+
+	logger.InfoContext(ctx, "below threshold")
+	logger.WithGroup("db").WarnContext(ctx, "slow query", "rows", 12, slog.Group("conn", "host", "pg-1"))
+	logger.ErrorContext(context.Background(), "no span")
+	span.End()
+
+	require.Len(t, recorder.Ended(), 1)
+	events := recorder.Ended()[0].Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, SpanEventName, events[0].Name)
+
+	attrs := attribute.NewSet(events[0].Attributes...)
+	severity, _ := attrs.Value(SpanEventSeverityKey)
+	assert.Equal(t, "WARN", severity.AsString())
+	msg, _ := attrs.Value(SpanEventMessageKey)
+	assert.Equal(t, "slow query", msg.AsString())
+	rows, _ := attrs.Value("db.rows")
+	assert.Equal(t, int64(12), rows.AsInt64())
+	host, _ := attrs.Value("db.conn.host")
+	assert.Equal(t, "pg-1", host.AsString())
+}
+
+func TestParseSpanEventLevel(t *testing.T) {
+	_, ok := parseSpanEventLevel("off")
+	assert.False(t, ok)
+	level, ok := parseSpanEventLevel("error")
+	assert.True(t, ok)
+	assert.Equal(t, slog.LevelError, level)
+}
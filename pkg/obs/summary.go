@@ -0,0 +1,176 @@
+package obs
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// RunSummary is a snapshot of what the process logged and exported since
+// Init, emitted on Shutdown when Config.ShutdownSummary is set.
+type RunSummary struct {
+	Uptime time.Duration
+	// ErrorsByKind counts Error log calls by their error_kind attribute;
+	// calls without one are counted as "unknown".
+	ErrorsByKind map[string]int64
+	// LogsDropped counts records a log handler failed to write.
+	LogsDropped int64
+	// SpansExported and SpansFailed count spans handed to the OTLP exporter
+	// and spans in batches it failed to send. Both stay zero without
+	// OTLP_ENDPOINT.
+	SpansExported int64
+	SpansFailed   int64
+	// MetricScrapes counts requests served by MetricsProvider.HTTPHandler.
+	MetricScrapes int64
+}
+
+// ErrorsTotal sums ErrorsByKind.
+func (s RunSummary) ErrorsTotal() int64 {
+	var n int64
+	for _, c := range s.ErrorsByKind {
+		n += c
+	}
+	return n
+}
+
+func (s RunSummary) logAttrs() []any {
+	return []any{
+		"uptime_ms", s.Uptime.Milliseconds(),
+		"errors_total", s.ErrorsTotal(),
+		"errors_by_kind", s.ErrorsByKind,
+		"logs_dropped", s.LogsDropped,
+		"spans_exported", s.SpansExported,
+		"spans_failed", s.SpansFailed,
+		"metric_scrapes", s.MetricScrapes,
+	}
+}
+
+// Summary returns the counters collected since Init.
+func (o *Observability) Summary() RunSummary {
+	s := RunSummary{
+		Uptime:       time.Since(o.started),
+		ErrorsByKind: map[string]int64{},
+	}
+	if o.logging != nil {
+		s.ErrorsByKind = o.logging.logger.stats.errorsByKind()
+		s.LogsDropped = o.logging.logger.stats.dropped.Load()
+	}
+	if o.tracing != nil {
+		s.SpansExported = o.tracing.exports.exported.Load()
+		s.SpansFailed = o.tracing.exports.failed.Load()
+	}
+	if o.metrics != nil {
+		s.MetricScrapes = o.metrics.scrapes.Load()
+	}
+	return s
+}
+
+// logSummary logs s at info level, or at warn level when logs or spans were
+// lost, so silent exporter failures stand out in batch job output.
+func (o *Observability) logSummary(ctx context.Context, s RunSummary) {
+	if s.LogsDropped > 0 || s.SpansFailed > 0 {
+		o.logging.Warn(ctx, "observability shutdown summary", s.logAttrs()...)
+		return
+	}
+	o.logging.Info(ctx, "observability shutdown summary", s.logAttrs()...)
+}
+
+// logStats is shared by a Logger and every Logger derived from it.
+type logStats struct {
+	dropped atomic.Int64
+
+	mu     sync.Mutex
+	errors map[string]int64
+}
+
+func newLogStats() *logStats {
+	return &logStats{errors: make(map[string]int64)}
+}
+
+func (s *logStats) countError(kind string) {
+	if s == nil {
+		return
+	}
+	if kind == "" {
+		kind = "unknown"
+	}
+	s.mu.Lock()
+	s.errors[kind]++
+	s.mu.Unlock()
+}
+
+func (s *logStats) errorsByKind() map[string]int64 {
+	out := make(map[string]int64)
+	if s == nil {
+		return out
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for kind, n := range s.errors {
+		out[kind] = n
+	}
+	return out
+}
+
+// countingHandler counts records the wrapped handler failed to write.
+type countingHandler struct {
+	slog.Handler
+	stats *logStats
+}
+
+func (h countingHandler) Handle(ctx context.Context, r slog.Record) error {
+	err := h.Handler.Handle(ctx, r)
+	if err != nil {
+		h.stats.dropped.Add(1)
+	}
+	return err
+}
+
+func (h countingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return countingHandler{h.Handler.WithAttrs(attrs), h.stats}
+}
+
+func (h countingHandler) WithGroup(name string) slog.Handler {
+	return countingHandler{h.Handler.WithGroup(name), h.stats}
+}
+
+type exportStats struct {
+	exported atomic.Int64
+	failed   atomic.Int64
+}
+
+// countingExporter counts the spans passed to next and those in batches it
+// returned an error for, and reports failed batches to health.
+type countingExporter struct {
+	next   sdktrace.SpanExporter
+	stats  *exportStats
+	health *exporterHealth
+}
+
+func (e countingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.next.ExportSpans(ctx, spans)
+	if err != nil {
+		e.stats.failed.Add(int64(len(spans)))
+		e.health.failure(ctx, ExporterOTLPTraces, err)
+	} else {
+		e.stats.exported.Add(int64(len(spans)))
+		e.health.success(ctx, ExporterOTLPTraces)
+	}
+	return err
+}
+
+func (e countingExporter) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
+
+func countScrapes(n *atomic.Int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,94 @@
+package obs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type failingHandler struct{}
+
+func (failingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (failingHandler) Handle(context.Context, slog.Record) error {
+	return errors.New("sink unavailable")
+}
+
+func (h failingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h failingHandler) WithGroup(string) slog.Handler { return h }
+
+func TestShutdownSummary(t *testing.T) {
+	var buf bytes.Buffer
+	config := DefaultConfig()
+	config.ServiceName = "summary-test"
+	config.ShutdownSummary = true
+	config.LogSinks = []slog.Handler{
+		slog.NewJSONHandler(&buf, nil),
+		failingHandler{},
+	}
+	obs := initForReload(t, config)
+	ctx := context.Background()
+
+	obs.Logger().Error(ctx, "upstream failed", errors.New("boom"), ErrorKindAttrKey, ErrKindExternal)
+	obs.Logger().Error(ctx, "upstream failed", errors.New("boom"), ErrorKindAttrKey, ErrKindExternal)
+	obs.Logger().Error(ctx, "unclassified", errors.New("boom"))
+	obs.MetricsProvider().HTTPHandler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics", nil))
+
+	s := obs.Summary()
+	assert.Equal(t, map[string]int64{ErrKindExternal: 2, "unknown": 1}, s.ErrorsByKind)
+	assert.EqualValues(t, 3, s.ErrorsTotal())
+	assert.Positive(t, s.LogsDropped)
+	assert.EqualValues(t, 1, s.MetricScrapes)
+
+	require.NoError(t, obs.Shutdown(ctx))
+
+	var summary map[string]any
+	for _, line := range bytes.Split(buf.Bytes(), []byte("\n")) {
+		var rec map[string]any
+		if json.Unmarshal(line, &rec) == nil && rec["msg"] == "observability shutdown summary" {
+			summary = rec
+		}
+	}
+	require.NotNil(t, summary, "summary not logged")
+	assert.Equal(t, "WARN", summary["level"])
+	assert.EqualValues(t, 3, summary["errors_total"])
+	assert.Equal(t, map[string]any{ErrKindExternal: 2.0, "unknown": 1.0}, summary["errors_by_kind"])
+	assert.EqualValues(t, 1, summary["metric_scrapes"])
+}
+
+type erroringExporter struct{}
+
+func (erroringExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	return errors.New("collector unreachable")
+}
+
+func (erroringExporter) Shutdown(context.Context) error { return nil }
+
+func TestCountingExporter(t *testing.T) {
+	ok := &exportStats{}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(countingExporter{next: tracetest.NewInMemoryExporter(), stats: ok}))
+	failed := &exportStats{}
+	tpFailed := sdktrace.NewTracerProvider(sdktrace.WithSyncer(countingExporter{next: erroringExporter{}, stats: failed}))
+
+	for i := 0; i < 3; i++ {
+		_, span := tp.Tracer("t").Start(context.Background(), "op")
+		span.End()
+		_, span = tpFailed.Tracer("t").Start(context.Background(), "op")
+		span.End()
+	}
+
+	assert.EqualValues(t, 3, ok.exported.Load())
+	assert.Zero(t, ok.failed.Load())
+	assert.Zero(t, failed.exported.Load())
+	assert.EqualValues(t, 3, failed.failed.Load())
+}
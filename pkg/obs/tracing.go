@@ -3,6 +3,7 @@ package obs
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -17,9 +18,44 @@ import (
 type TracingProvider struct {
 	provider *sdktrace.TracerProvider
 	config   Config
+	sampler  *ratioSampler
+	exports  *exportStats
 }
 
-func newTracingProvider(ctx context.Context, config Config) (*TracingProvider, error) {
+// ratioSampler is a TraceIDRatioBased sampler whose ratio can change while
+// the provider runs.
+type ratioSampler struct {
+	current atomic.Pointer[ratioSamplerState]
+}
+
+type ratioSamplerState struct {
+	ratio   float64
+	sampler sdktrace.Sampler
+}
+
+func newRatioSampler(ratio float64) *ratioSampler {
+	s := &ratioSampler{}
+	s.set(ratio)
+	return s
+}
+
+func (s *ratioSampler) set(ratio float64) {
+	s.current.Store(&ratioSamplerState{ratio: ratio, sampler: sdktrace.TraceIDRatioBased(ratio)})
+}
+
+func (s *ratioSampler) ratio() float64 {
+	return s.current.Load().ratio
+}
+
+func (s *ratioSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return s.current.Load().sampler.ShouldSample(p)
+}
+
+func (s *ratioSampler) Description() string {
+	return s.current.Load().sampler.Description()
+}
+
+func newTracingProvider(ctx context.Context, config Config, health *exporterHealth) (*TracingProvider, error) {
 	res, err := resource.New(ctx,
 		resource.WithAttributes(
 			semconv.ServiceName(config.ServiceName),
@@ -53,6 +89,7 @@ func newTracingProvider(ctx context.Context, config Config) (*TracingProvider, e
 	}
 
 	var spanProcessor sdktrace.SpanProcessor
+	exports := &exportStats{}
 
 	if config.OTLPEndpoint != "" {
 		opts := []otlptracehttp.Option{
@@ -69,15 +106,21 @@ func newTracingProvider(ctx context.Context, config Config) (*TracingProvider, e
 			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
 		}
 
-		spanProcessor = sdktrace.NewBatchSpanProcessor(exporter)
+		spanProcessor = sdktrace.NewBatchSpanProcessor(countingExporter{
+			next:   scrubExporter{next: exporter},
+			stats:  exports,
+			health: health,
+		})
 	} else {
 		spanProcessor = sdktrace.NewSimpleSpanProcessor(noopExporter{})
 	}
 
-	sampler := sdktrace.TraceIDRatioBased(config.TracingSampleRatio)
+	ratio := newRatioSampler(config.TracingSampleRatio)
+	sampler := incidentSampler{base: ratio}
 
 	provider := sdktrace.NewTracerProvider(
 		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(sagaSpanProcessor{}),
 		sdktrace.WithSpanProcessor(spanProcessor),
 		sdktrace.WithSampler(sampler),
 	)
@@ -92,9 +135,26 @@ func newTracingProvider(ctx context.Context, config Config) (*TracingProvider, e
 	return &TracingProvider{
 		provider: provider,
 		config:   config,
+		sampler:  ratio,
+		exports:  exports,
 	}, nil
 }
 
+// SampleRatio returns the current share of root traces sampled.
+func (tp *TracingProvider) SampleRatio() float64 {
+	return tp.sampler.ratio()
+}
+
+// SetSampleRatio changes the share of traces sampled without recreating the
+// provider.
+func (tp *TracingProvider) SetSampleRatio(ratio float64) error {
+	if ratio < 0 || ratio > 1 {
+		return ErrInvalidSampleRatio
+	}
+	tp.sampler.set(ratio)
+	return nil
+}
+
 func (tp *TracingProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
 	return tp.provider.Tracer(name, opts...)
 }
@@ -107,6 +167,20 @@ func (tp *TracingProvider) ForceFlush(ctx context.Context) error {
 	return tp.provider.ForceFlush(ctx)
 }
 
+// sagaSpanProcessor tags spans with the saga ID of the context they are
+// started in, including one received as baggage.
+type sagaSpanProcessor struct{}
+
+func (sagaSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if id := SagaIDFromContext(parent); id != "" {
+		s.SetAttributes(attribute.String(SagaBaggageKey, id))
+	}
+}
+
+func (sagaSpanProcessor) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (sagaSpanProcessor) Shutdown(context.Context) error   { return nil }
+func (sagaSpanProcessor) ForceFlush(context.Context) error { return nil }
+
 type noopExporter struct{}
 
 func (noopExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
@@ -57,7 +57,7 @@ func TestNewTracingProvider(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			provider, err := newTracingProvider(ctx, tt.config)
+			provider, err := newTracingProvider(ctx, tt.config, nil)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -89,7 +89,7 @@ func TestTracingProviderMethods(t *testing.T) {
 		TracingSampleRatio: 1.0,
 	}
 
-	provider, err := newTracingProvider(ctx, config)
+	provider, err := newTracingProvider(ctx, config, nil)
 	require.NoError(t, err)
 	require.NotNil(t, provider)
 	defer func() {
@@ -113,7 +113,7 @@ func TestTraceIDAndSpanID(t *testing.T) {
 		TracingSampleRatio: 1.0,
 	}
 
-	provider, err := newTracingProvider(ctx, config)
+	provider, err := newTracingProvider(ctx, config, nil)
 	require.NoError(t, err)
 	require.NotNil(t, provider)
 	defer func() {
@@ -147,7 +147,7 @@ func TestStartSpan(t *testing.T) {
 		TracingSampleRatio: 1.0,
 	}
 
-	provider, err := newTracingProvider(ctx, config)
+	provider, err := newTracingProvider(ctx, config, nil)
 	require.NoError(t, err)
 	require.NotNil(t, provider)
 	defer func() {